@@ -0,0 +1,379 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"app_api/apis/command"
+	"app_api/shared"
+	gmConnector "app_api/shared/gm"
+	"app_api/shared/httphelper"
+	loghelper "app_api/shared/loghelpers"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LogLevel ... the level from which the API logs
+//
+// swagger:model LogLevel
+type LogLevel struct {
+	// Level
+	//
+	// required: true
+	// enum: trace,debug,info,warning,error
+	// example: debug
+	Level string `json:"level" validate:"required,oneof=trace debug info warning error"`
+}
+
+// BreakerStatus ... the state of the circuit breaker of the GM connector
+//
+// swagger:model BreakerStatus
+type BreakerStatus struct {
+	// State
+	//
+	// required: true
+	// enum: closed,open,half-open
+	// example: open
+	State string `json:"state"`
+}
+
+// EffectiveConfig ... the config the API runs with
+//
+// swagger:model EffectiveConfig
+type EffectiveConfig struct {
+	// File ... the config file the settings were read from, if any
+	//
+	// example: /etc/app_api/config.yaml
+	File string `json:"file,omitempty"`
+
+	// Settings ... keyed as in the config file, with the value of secrets redacted
+	//
+	// required: true
+	Settings map[string]interface{} `json:"settings"`
+}
+
+// VehiclePurge ... the state kept about a vehicle which was dropped
+//
+// swagger:model VehiclePurge
+type VehiclePurge struct {
+	// VehicleID
+	//
+	// required: true
+	// example: 1234
+	VehicleID int64 `json:"vehicleId"`
+
+	// TelemetryReadings ... the readings removed from the telemetry history
+	//
+	// required: true
+	// example: 2880
+	TelemetryReadings int `json:"telemetryReadings"`
+
+	// Scheduled ... whether the poller schedules the vehicle, and polls it again on its next tick
+	//
+	// required: true
+	// example: true
+	Scheduled bool `json:"scheduled"`
+}
+
+// getLogLevel ... /admin/log-level GET
+//
+// swagger:operation GET /admin/log-level Admin getLogLevel
+//
+// Returns the level from which the API logs
+//
+// ---
+// summary: Returns the level from which the API logs
+// produces:
+// - application/json
+// schemes:
+// - https
+// responses:
+//   '200':
+//     description: >
+//       The log level.
+//     schema:
+//       $ref: "#/definitions/LogLevel"
+func (env *Env) getLogLevel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	httphelper.NewResponse(ctx, w, LogLevel{Level: loghelper.Level().String()}, nil)
+	return
+}
+
+// setLogLevel ... /admin/log-level PUT
+//
+// swagger:operation PUT /admin/log-level Admin setLogLevel
+//
+// Changes the level from which the API logs, until the API restarts or the config is reloaded with changes
+//
+// ---
+// summary: Changes the level from which the API logs
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: body
+//   in: body
+//   description: The new log level
+//   required: true
+//   schema:
+//     $ref: "#/definitions/LogLevel"
+// responses:
+//   '200':
+//     description: >
+//       The log level.
+//     schema:
+//       $ref: "#/definitions/LogLevel"
+//   '400':
+//     description: "Bad request e.g. a body that fails validation"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Request body failed validation"
+func (env *Env) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req := LogLevel{}
+
+	// validate json body
+	if err := httphelper.DecodeJSONBody(w, r, &req); err != nil {
+		httphelper.NewResponse(ctx, w, nil, err)
+		return
+	}
+
+	level, _ := log.ParseLevel(req.Level)
+	loghelper.SetLevel(level)
+
+	httphelper.NewResponse(ctx, w, LogLevel{Level: loghelper.Level().String()}, nil)
+	return
+}
+
+// getGMBreaker ... /admin/gm/breaker GET
+//
+// swagger:operation GET /admin/gm/breaker Admin getGMBreaker
+//
+// Returns the state of the circuit breaker protecting GM
+//
+// ---
+// summary: Returns the state of the circuit breaker protecting GM
+// produces:
+// - application/json
+// schemes:
+// - https
+// responses:
+//   '200':
+//     description: >
+//       The state of the breaker.
+//     schema:
+//       $ref: "#/definitions/BreakerStatus"
+//   '404':
+//     description: >
+//       The GM connector has no circuit breaker.
+func (env *Env) getGMBreaker(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	state, err := gmConnector.BreakerState(env.Services.GM)
+	if err != nil {
+		httphelper.NewResponse(ctx, w, nil, shared.NewAPIError(http.StatusNotFound, err, "The GM connector has no circuit breaker"))
+		return
+	}
+
+	httphelper.NewResponse(ctx, w, BreakerStatus{State: state}, nil)
+	return
+}
+
+// resetGMBreaker ... /admin/gm/breaker/reset POST
+//
+// swagger:operation POST /admin/gm/breaker/reset Admin resetGMBreaker
+//
+// Closes the circuit breaker protecting GM, so requests go to GM again without waiting for the cooldown
+//
+// ---
+// summary: Closes the circuit breaker protecting GM
+// produces:
+// - application/json
+// schemes:
+// - https
+// responses:
+//   '200':
+//     description: >
+//       The state of the breaker, now closed.
+//     schema:
+//       $ref: "#/definitions/BreakerStatus"
+//   '404':
+//     description: >
+//       The GM connector has no circuit breaker.
+func (env *Env) resetGMBreaker(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := gmConnector.ResetBreaker(env.Services.GM); err != nil {
+		httphelper.NewResponse(ctx, w, nil, shared.NewAPIError(http.StatusNotFound, err, "The GM connector has no circuit breaker"))
+		return
+	}
+	state, _ := gmConnector.BreakerState(env.Services.GM)
+
+	httphelper.NewResponse(ctx, w, BreakerStatus{State: state}, nil)
+	return
+}
+
+// getConfig ... /admin/config GET
+//
+// swagger:operation GET /admin/config Admin getConfig
+//
+// Returns the config the API runs with, including the changes of the latest reload, with secrets redacted
+//
+// ---
+// summary: Returns the config the API runs with
+// produces:
+// - application/json
+// schemes:
+// - https
+// responses:
+//   '200':
+//     description: >
+//       The effective config.
+//     schema:
+//       $ref: "#/definitions/EffectiveConfig"
+func (env *Env) getConfig(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if env.Services.Config == nil {
+		err := errors.New("the API wasn't started from a config")
+		httphelper.NewResponse(ctx, w, nil, shared.NewAPIError(http.StatusNotFound, err, "Config not available"))
+		return
+	}
+	cfg := env.Services.Config.Config()
+
+	httphelper.NewResponse(ctx, w, EffectiveConfig{File: cfg.File, Settings: cfg.Redacted()}, nil)
+	return
+}
+
+// purgeVehicle ... /admin/vehicles/{vehicle_id}/cache DELETE
+//
+// swagger:operation DELETE /admin/vehicles/{vehicle_id}/cache Admin purgeVehicle
+//
+// Drops the state kept about a vehicle: the baseline its events are detected against, its telemetry history, and the
+// last polls, errors and backoff of its poller schedule, so it is read from GM afresh. Its registration and polling
+// intervals are kept
+//
+// ---
+// summary: Drops the state kept about a vehicle
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// responses:
+//   '200':
+//     description: >
+//       What was dropped.
+//     schema:
+//       $ref: "#/definitions/VehiclePurge"
+func (env *Env) purgeVehicle(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vehicleID, apiErr := vehicleIDFromRequest(r)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	env.Services.Detector.Forget(vehicleID)
+
+	removed, err := env.Services.TelemetryService.Purge(vehicleID)
+	if err != nil {
+		httphelper.NewResponse(ctx, w, nil, shared.NewAPIError(http.StatusInternalServerError, err, "Failed to purge telemetry"))
+		return
+	}
+
+	// vehicles which aren't registered aren't scheduled
+	_, apiErr = env.Services.PollerService.RefreshSchedule(vehicleID)
+	if apiErr != nil && apiErr.ErrorCode != http.StatusNotFound {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	httphelper.NewResponse(ctx, w, VehiclePurge{VehicleID: vehicleID, TelemetryReadings: removed, Scheduled: apiErr == nil}, nil)
+	return
+}
+
+// listInFlightCommands ... /admin/commands GET
+//
+// swagger:operation GET /admin/commands Admin listInFlightCommands
+//
+// Returns the bulk commands still running, newest first, with the outcome of each vehicle so far
+//
+// ---
+// summary: Returns the bulk commands still running
+// produces:
+// - application/json
+// schemes:
+// - https
+// responses:
+//   '200':
+//     description: >
+//       List of running jobs, with per vehicle results.
+//     schema:
+//       type: "array"
+//       items:
+//         $ref: "#/definitions/CommandJob"
+func (env *Env) listInFlightCommands(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	jobs, apiErr := env.Services.CommandService.ListJobs()
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	running := make([]command.Job, 0, len(jobs))
+	for _, job := range jobs {
+		if job.Status != command.JOB_RUNNING {
+			continue
+		}
+		// a job finishing in between is left out
+		if job, apiErr := env.Services.CommandService.GetJob(job.ID); apiErr == nil && job.Status == command.JOB_RUNNING {
+			running = append(running, job)
+		}
+	}
+
+	httphelper.NewResponse(ctx, w, running, nil)
+	return
+}
+
+// listAuditEntries ... /admin/audit GET
+//
+// swagger:operation GET /admin/audit Admin listAuditEntries
+//
+// Returns the latest requests to the admin API, oldest first: who sent them, what they asked for and how they ended
+//
+// ---
+// summary: Returns the latest requests to the admin API
+// produces:
+// - application/json
+// schemes:
+// - https
+// responses:
+//   '200':
+//     description: >
+//       The audit entries, oldest first.
+//     schema:
+//       type: "array"
+//       items:
+//         $ref: "#/definitions/AuditEntry"
+func (env *Env) listAuditEntries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	httphelper.NewResponse(ctx, w, env.Services.Audit.Entries(), nil)
+	return
+}
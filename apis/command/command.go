@@ -0,0 +1,351 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"app_api/apis/vehicle"
+	"app_api/shared"
+	"app_api/shared/ratelimit"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DEFAULT_CONCURRENCY = 10
+	DEFAULT_MAX_JOBS    = 100
+
+	// GM doesn't tolerate commands fired at the same vehicle back to back, so by default each vehicle gets one command every 5 seconds
+	DEFAULT_VEHICLE_RATE  = 0.2
+	DEFAULT_VEHICLE_BURST = 1
+)
+
+// Service ... represents an instance of the command package service interface
+type Service interface {
+	Submit(req BulkCommandRequest) (res Job, err *shared.APIError)
+	GetJob(jobID string) (res Job, err *shared.APIError)
+	ListJobs() (res []Job, err *shared.APIError)
+	CancelJob(jobID string) (res Job, err *shared.APIError)
+
+	SetVehicleRateLimit(rate float64, burst int)
+}
+
+// Option ... configures optional behaviour of the command service
+type Option func(*service)
+
+// WithConcurrency ... sets the maximum number of commands in flight against GM for a single job
+func WithConcurrency(n int) Option {
+	return func(s *service) {
+		if n > 0 {
+			s.concurrency = n
+		}
+	}
+}
+
+// WithVehicleRateLimit ... sets how many commands per second, with bursts, can be sent to the same vehicle across every job
+func WithVehicleRateLimit(rate float64, burst int) Option {
+	return func(s *service) {
+		s.vehicleLimiter = ratelimit.NewKeyedLimiter(rate, burst)
+	}
+}
+
+// WithMaxJobs ... sets how many finished jobs are kept around for reporting before the oldest are dropped
+func WithMaxJobs(n int) Option {
+	return func(s *service) {
+		if n > 0 {
+			s.maxJobs = n
+		}
+	}
+}
+
+// NewService ... returns an instance of the command package service. Commands are sent through the vehicle service,
+// so they go through the same validation and GM response handling as a single engine action
+func NewService(vehicleService vehicle.Service, opts ...Option) Service {
+	s := &service{
+		vehicles:       vehicleService,
+		concurrency:    DEFAULT_CONCURRENCY,
+		maxJobs:        DEFAULT_MAX_JOBS,
+		vehicleLimiter: ratelimit.NewKeyedLimiter(DEFAULT_VEHICLE_RATE, DEFAULT_VEHICLE_BURST),
+		jobs:           make(map[string]*job),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+type service struct {
+	vehicles       vehicle.Service
+	concurrency    int
+	maxJobs        int
+	vehicleLimiter *ratelimit.KeyedLimiter
+
+	mu   sync.RWMutex
+	jobs map[string]*job
+}
+
+// job ... the mutable state behind a Job, guarded by its own lock so results can be reported while it runs
+type job struct {
+	mu       sync.Mutex
+	state    Job
+	cancel   context.CancelFunc
+	finished chan struct{}
+}
+
+// Submit ... starts sending the action to every vehicle in the background and returns the job to track it with
+func (s *service) Submit(req BulkCommandRequest) (res Job, err *shared.APIError) {
+	if req.Action != vehicle.ENGINE_START && req.Action != vehicle.ENGINE_STOP {
+		requestErr := fmt.Errorf("Unsupported data type: %s", req.Action)
+		err = shared.NewAPIError(http.StatusBadRequest, requestErr, "Unsupported engine action option")
+		return
+	}
+
+	if len(req.VehicleIDs) == 0 {
+		msg := "At least one vehicle is required"
+		err = shared.NewAPIError(http.StatusBadRequest, errors.New(msg), msg)
+		return
+	}
+
+	j := &job{
+		state: Job{
+			ID:        uuid.New().String(),
+			Action:    req.Action,
+			Status:    JOB_RUNNING,
+			CreatedAt: time.Now().UTC(),
+		},
+		finished: make(chan struct{}),
+	}
+
+	seen := make(map[int64]bool)
+	for _, vehicleID := range req.VehicleIDs {
+		if seen[vehicleID] {
+			continue
+		}
+		seen[vehicleID] = true
+		j.state.Commands = append(j.state.Commands, CommandResult{VehicleID: vehicleID, Status: COMMAND_PENDING})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+
+	s.mu.Lock()
+	s.jobs[j.state.ID] = j
+	s.pruneJobs()
+	s.mu.Unlock()
+
+	go s.run(ctx, j)
+
+	return j.snapshot(true), nil
+}
+
+// GetJob ... returns the job with the outcome of every command
+func (s *service) GetJob(jobID string) (res Job, err *shared.APIError) {
+	j, err := s.getJob(jobID)
+	if err != nil {
+		return
+	}
+
+	return j.snapshot(true), nil
+}
+
+// ListJobs ... returns every job that is still tracked, newest first, without per vehicle results
+func (s *service) ListJobs() (res []Job, err *shared.APIError) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res = make([]Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		res = append(res, j.snapshot(false))
+	}
+
+	sort.Slice(res, func(a, b int) bool {
+		return res[a].CreatedAt.After(res[b].CreatedAt)
+	})
+
+	return
+}
+
+// CancelJob ... stops any commands that haven't been sent yet. Commands already sent to GM can't be recalled
+func (s *service) CancelJob(jobID string) (res Job, err *shared.APIError) {
+	j, err := s.getJob(jobID)
+	if err != nil {
+		return
+	}
+
+	j.cancel()
+	<-j.finished
+
+	return j.snapshot(true), nil
+}
+
+func (s *service) getJob(jobID string) (j *job, err *shared.APIError) {
+	s.mu.RLock()
+	j, ok := s.jobs[jobID]
+	s.mu.RUnlock()
+
+	if !ok {
+		requestErr := fmt.Errorf("Command job %s not found", jobID)
+		err = shared.NewAPIError(http.StatusNotFound, requestErr, "Command job not found")
+		return
+	}
+
+	return
+}
+
+// pruneJobs ... drops the oldest finished jobs once more than maxJobs are tracked. Must be called with the lock held
+func (s *service) pruneJobs() {
+	if len(s.jobs) <= s.maxJobs {
+		return
+	}
+
+	var finished []*job
+	for _, j := range s.jobs {
+		select {
+		case <-j.finished:
+			finished = append(finished, j)
+		default:
+		}
+	}
+
+	sort.Slice(finished, func(a, b int) bool {
+		return finished[a].state.CreatedAt.Before(finished[b].state.CreatedAt)
+	})
+
+	for i := 0; i < len(finished) && len(s.jobs) > s.maxJobs; i++ {
+		delete(s.jobs, finished[i].state.ID)
+	}
+}
+
+// run ... sends the command to every vehicle with bounded concurrency, respecting the per vehicle rate limit
+func (s *service) run(ctx context.Context, j *job) {
+	defer close(j.finished)
+	defer j.cancel()
+
+	sem := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+
+	for i := range j.state.Commands {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			s.send(ctx, j, i)
+		}(i)
+	}
+
+	wg.Wait()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now().UTC()
+	for i := range j.state.Commands {
+		if j.state.Commands[i].Status == COMMAND_PENDING {
+			j.state.Commands[i].Status = COMMAND_CANCELLED
+			j.state.Commands[i].CompletedAt = &now
+		}
+	}
+
+	j.state.Status = JOB_COMPLETED
+	if ctx.Err() != nil {
+		j.state.Status = JOB_CANCELLED
+	}
+	j.state.CompletedAt = &now
+}
+
+func (s *service) send(ctx context.Context, j *job, i int) {
+	j.mu.Lock()
+	vehicleID := j.state.Commands[i].VehicleID
+	action := j.state.Action
+	j.mu.Unlock()
+
+	// a cancelled wait leaves the command pending, it's marked as cancelled once the job finishes
+	if s.vehicleLimiter.Wait(ctx, strconv.FormatInt(vehicleID, 10)) != nil {
+		return
+	}
+
+	j.mu.Lock()
+	if ctx.Err() != nil {
+		j.mu.Unlock()
+		return
+	}
+	sentAt := time.Now().UTC()
+	j.state.Commands[i].Status = COMMAND_SENDING
+	j.state.Commands[i].SentAt = &sentAt
+	j.mu.Unlock()
+
+	res, apiErr := s.vehicles.SendEngineAction(vehicleID, vehicle.EngineActionRequest{Action: action})
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	completedAt := time.Now().UTC()
+	command := &j.state.Commands[i]
+	command.CompletedAt = &completedAt
+
+	switch {
+	case apiErr != nil:
+		command.Status = COMMAND_FAILED
+		command.Error = apiErr.ClientErrorMessage
+	case res.Action != "success":
+		command.Status = COMMAND_FAILED
+		command.Error = "GM failed to execute the engine action"
+	default:
+		command.Status = COMMAND_SUCCEEDED
+	}
+}
+
+// snapshot ... returns a copy of the job that is safe to hand out while it's still running
+func (j *job) snapshot(withCommands bool) Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	res := j.state
+	res.Commands = nil
+	res.Summary = Summary{Total: len(j.state.Commands)}
+
+	for _, command := range j.state.Commands {
+		switch command.Status {
+		case COMMAND_PENDING:
+			res.Summary.Pending++
+		case COMMAND_SENDING:
+			res.Summary.Sending++
+		case COMMAND_SUCCEEDED:
+			res.Summary.Succeeded++
+		case COMMAND_FAILED:
+			res.Summary.Failed++
+		case COMMAND_CANCELLED:
+			res.Summary.Cancelled++
+		}
+	}
+
+	if withCommands {
+		res.Commands = append([]CommandResult(nil), j.state.Commands...)
+	}
+
+	return res
+}
+
+// SetVehicleRateLimit ... changes how many commands per second, with bursts, can be sent to the same vehicle, for the
+// commands of the running jobs too
+func (s *service) SetVehicleRateLimit(rate float64, burst int) {
+	s.vehicleLimiter.SetLimit(rate, burst)
+}
@@ -0,0 +1,161 @@
+package command
+
+import (
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"app_api/apis/vehicle"
+	"app_api/shared"
+	gmConnector "app_api/shared/gm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var commandService Service
+
+func TestMain(m *testing.M) {
+	vehicleService := vehicle.NewService(gmConnector.NewMockGMAPIConnector())
+	commandService = NewService(vehicleService, WithVehicleRateLimit(0, 1))
+	os.Exit(m.Run())
+}
+
+// blockingVehicleService ... holds every engine action until it's released, so jobs can be observed mid-flight
+type blockingVehicleService struct {
+	vehicle.Service
+	release chan struct{}
+
+	mu    sync.Mutex
+	calls []int64
+}
+
+func (b *blockingVehicleService) SendEngineAction(vehicleID int64, engineAction vehicle.EngineActionRequest) (vehicle.EngineActionResponse, *shared.APIError) {
+	b.mu.Lock()
+	b.calls = append(b.calls, vehicleID)
+	b.mu.Unlock()
+
+	<-b.release
+	return vehicle.EngineActionResponse{Action: "success"}, nil
+}
+
+func waitForJob(t *testing.T, service Service, jobID string, done func(Job) bool) Job {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		job, err := service.GetJob(jobID)
+		assert.Nil(t, err)
+		if done(job) || time.Now().After(deadline) {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSubmitSuccess(t *testing.T) {
+	job, err := commandService.Submit(BulkCommandRequest{VehicleIDs: []int64{1234, 1235, 1234, 1236}, Action: "STOP"})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, job.ID)
+	assert.Equal(t, 3, job.Summary.Total, "Duplicate vehicles should only be sent once.")
+
+	job = waitForJob(t, commandService, job.ID, func(j Job) bool { return j.Status != JOB_RUNNING })
+	assert.Equal(t, JOB_COMPLETED, job.Status)
+	assert.NotNil(t, job.CompletedAt)
+	assert.Equal(t, Summary{Total: 3, Succeeded: 2, Failed: 1}, job.Summary)
+
+	results := make(map[int64]CommandResult)
+	for _, command := range job.Commands {
+		results[command.VehicleID] = command
+	}
+	assert.Equal(t, COMMAND_SUCCEEDED, results[1234].Status)
+	assert.Equal(t, COMMAND_SUCCEEDED, results[1235].Status)
+	assert.Equal(t, COMMAND_FAILED, results[1236].Status)
+	assert.NotEmpty(t, results[1236].Error)
+}
+
+func TestSubmitFailureInvalidAction(t *testing.T) {
+	_, err := commandService.Submit(BulkCommandRequest{VehicleIDs: []int64{1234}, Action: "FOOBAR"})
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.ErrorCode)
+}
+
+func TestGetJobFailureNotFound(t *testing.T) {
+	_, err := commandService.GetJob("unknown")
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusNotFound, err.ErrorCode)
+}
+
+func TestListJobs(t *testing.T) {
+	job, err := commandService.Submit(BulkCommandRequest{VehicleIDs: []int64{1234}, Action: "START"})
+	assert.Nil(t, err)
+
+	jobs, err := commandService.ListJobs()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, jobs)
+	assert.Equal(t, job.ID, jobs[0].ID, "The newest job should be listed first.")
+	assert.Nil(t, jobs[0].Commands, "Listing jobs should only include the summary.")
+}
+
+func TestCancelJob(t *testing.T) {
+	blocking := &blockingVehicleService{release: make(chan struct{})}
+	service := NewService(blocking, WithConcurrency(1), WithVehicleRateLimit(0, 1))
+
+	job, err := service.Submit(BulkCommandRequest{VehicleIDs: []int64{1, 2, 3}, Action: "STOP"})
+	assert.Nil(t, err)
+
+	waitForJob(t, service, job.ID, func(j Job) bool { return j.Summary.Sending == 1 })
+
+	cancelled := make(chan Job)
+	go func() {
+		res, _ := service.CancelJob(job.ID)
+		cancelled <- res
+	}()
+
+	// the command already sent to GM still completes
+	time.Sleep(10 * time.Millisecond)
+	close(blocking.release)
+	job = <-cancelled
+
+	assert.Equal(t, JOB_CANCELLED, job.Status)
+	assert.Equal(t, Summary{Total: 3, Succeeded: 1, Cancelled: 2}, job.Summary)
+	assert.Equal(t, []int64{1}, blocking.calls)
+}
+
+func TestVehicleRateLimit(t *testing.T) {
+	blocking := &blockingVehicleService{release: make(chan struct{})}
+	close(blocking.release)
+	service := NewService(blocking, WithVehicleRateLimit(0.001, 1))
+
+	first, err := service.Submit(BulkCommandRequest{VehicleIDs: []int64{1}, Action: "START"})
+	assert.Nil(t, err)
+	waitForJob(t, service, first.ID, func(j Job) bool { return j.Status != JOB_RUNNING })
+
+	// the second command to the same vehicle has to wait for the limiter, so it can still be cancelled
+	second, err := service.Submit(BulkCommandRequest{VehicleIDs: []int64{1}, Action: "STOP"})
+	assert.Nil(t, err)
+
+	second, err = service.CancelJob(second.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, Summary{Total: 1, Cancelled: 1}, second.Summary)
+	assert.Len(t, blocking.calls, 1)
+}
+
+func TestPruneJobs(t *testing.T) {
+	blocking := &blockingVehicleService{release: make(chan struct{})}
+	close(blocking.release)
+	service := NewService(blocking, WithMaxJobs(2), WithVehicleRateLimit(0, 1))
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		job, err := service.Submit(BulkCommandRequest{VehicleIDs: []int64{int64(i)}, Action: "START"})
+		assert.Nil(t, err)
+		waitForJob(t, service, job.ID, func(j Job) bool { return j.Status != JOB_RUNNING })
+		ids = append(ids, job.ID)
+	}
+
+	jobs, _ := service.ListJobs()
+	assert.Len(t, jobs, 2)
+
+	_, err := service.GetJob(ids[0])
+	assert.NotNil(t, err, "The oldest finished job should be dropped.")
+}
@@ -0,0 +1,128 @@
+package command
+
+import (
+	"time"
+
+	"app_api/shared"
+)
+
+const (
+	JOB_RUNNING   = "running"
+	JOB_COMPLETED = "completed"
+	JOB_CANCELLED = "cancelled"
+
+	COMMAND_PENDING   = "pending"
+	COMMAND_SENDING   = "sending"
+	COMMAND_SUCCEEDED = "succeeded"
+	COMMAND_FAILED    = "failed"
+	COMMAND_CANCELLED = "cancelled"
+)
+
+// BulkCommandRequest ... request body for sending the same engine action to many vehicles
+//
+// swagger:model BulkCommandRequest
+type BulkCommandRequest struct {
+	// VehicleIDs ... the vehicles to send the command to. Duplicates are only sent once. Required unless a group is given
+	//
+	// example: [1234, 1235]
+	VehicleIDs []int64 `json:"vehicleIds" validate:"max=1000"`
+
+	// Group ... send the command to every vehicle registered in the group, in addition to VehicleIDs
+	//
+	// example: depot-7
+	Group string `json:"group" validate:"pattern=^[a-z0-9][a-z0-9_-]{0,63}$"`
+
+	// Action
+	//
+	// required: true
+	// enum: START,STOP
+	// example: STOP
+	Action string `json:"action" validate:"required,oneof=START STOP"`
+}
+
+// Validate ... at least one vehicle has to be selected, either directly or through a group
+func (req BulkCommandRequest) Validate() []shared.FieldError {
+	if len(req.VehicleIDs) == 0 && req.Group == "" {
+		return []shared.FieldError{{Field: "vehicleIds", Rule: "required", Message: "vehicleIds is required when no group is given"}}
+	}
+	return nil
+}
+
+// CommandResult ... the outcome of the command for a single vehicle
+//
+// swagger:model CommandResult
+type CommandResult struct {
+	// VehicleID
+	//
+	// required: true
+	// example: 1234
+	VehicleID int64 `json:"vehicleId"`
+
+	// Status
+	//
+	// required: true
+	// enum: pending,sending,succeeded,failed,cancelled
+	// example: succeeded
+	Status string `json:"status"`
+
+	// Error ... the reason the command failed
+	//
+	// example: Failed to send engine action
+	Error string `json:"error,omitempty"`
+
+	// SentAt ... when the command was sent to GM
+	SentAt *time.Time `json:"sentAt,omitempty"`
+
+	// CompletedAt ... when GM responded, or the command was cancelled
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// Summary ... the number of commands in each status for a job
+//
+// swagger:model CommandSummary
+type Summary struct {
+	Total     int `json:"total"`
+	Pending   int `json:"pending"`
+	Sending   int `json:"sending"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Cancelled int `json:"cancelled"`
+}
+
+// Job response ... a bulk command and the outcome of each of its vehicles
+//
+// swagger:model CommandJob
+type Job struct {
+	// ID
+	//
+	// required: true
+	// example: 9eb198d6-91b7-46f6-b8f5-83ee71351b3f
+	ID string `json:"id"`
+
+	// Action
+	//
+	// required: true
+	// example: STOP
+	Action string `json:"action"`
+
+	// Status
+	//
+	// required: true
+	// enum: running,completed,cancelled
+	// example: running
+	Status string `json:"status"`
+
+	// CreatedAt
+	//
+	// required: true
+	CreatedAt time.Time `json:"createdAt"`
+
+	// CompletedAt ... when every command finished or was cancelled
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+
+	// required: true
+	Summary Summary `json:"summary"`
+
+	// Commands ... per vehicle results. Omitted when listing jobs
+	Commands []CommandResult `json:"commands,omitempty"`
+}
@@ -0,0 +1,174 @@
+package events
+
+import (
+	"sync"
+)
+
+const (
+	// DEFAULT_SUBSCRIPTION_BUFFER ... how many events a subscriber can fall behind before events are dropped for it
+	DEFAULT_SUBSCRIPTION_BUFFER = 256
+
+	// DEFAULT_REPLAY_BUFFER ... how many of the latest events are kept for subscribers resuming with SubscribeFrom
+	DEFAULT_REPLAY_BUFFER = 1000
+)
+
+// BusOption ... configures optional behaviour of the bus
+type BusOption func(*Bus)
+
+// WithReplayBuffer ... sets how many of the latest events are kept for subscribers resuming with SubscribeFrom. Zero disables replay
+func WithReplayBuffer(n int) BusOption {
+	return func(b *Bus) {
+		if n >= 0 {
+			b.replay = make([]Event, n)
+		}
+	}
+}
+
+// Bus ... fans events out to every subscriber. Publishing never blocks: a subscriber that isn't keeping up has events dropped,
+// so a slow consumer can't hold up detection or the other subscribers
+type Bus struct {
+	mu          sync.Mutex
+	lastID      int64
+	subscribers map[*Subscription]struct{}
+
+	// replay ... a ring of the latest events, the newest at replayNext-1
+	replay      []Event
+	replayNext  int
+	replayCount int
+}
+
+// Subscription ... receives the events published after it was created, until it is closed
+type Subscription struct {
+	bus     *Bus
+	c       chan Event
+	filter  func(Event) bool
+	startID int64
+	dropped int64
+	closed  bool
+}
+
+// NewBus ... returns a bus without subscribers
+func NewBus(opts ...BusOption) *Bus {
+	b := &Bus{
+		subscribers: make(map[*Subscription]struct{}),
+		replay:      make([]Event, DEFAULT_REPLAY_BUFFER),
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Publish ... assigns the event the next ID and delivers it to every subscriber whose filter accepts it. Returns the published event
+func (b *Bus) Publish(event Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+
+	if len(b.replay) > 0 {
+		b.replay[b.replayNext] = event
+		b.replayNext = (b.replayNext + 1) % len(b.replay)
+		if b.replayCount < len(b.replay) {
+			b.replayCount++
+		}
+	}
+
+	for sub := range b.subscribers {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+
+		select {
+		case sub.c <- event:
+		default:
+			sub.dropped++
+		}
+	}
+
+	return event
+}
+
+// Subscribe ... returns a subscription to the events accepted by filter, or every event if filter is nil.
+// A buffer of zero or less uses DEFAULT_SUBSCRIPTION_BUFFER
+func (b *Bus) Subscribe(buffer int, filter func(Event) bool) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.subscribe(buffer, filter, nil)
+}
+
+// SubscribeFrom ... like Subscribe, but the subscription first receives the events accepted by filter that were published
+// after lastID, so a subscriber that went away can resume without missing events. Returns false, replaying nothing, when
+// some of those events have already left the replay buffer, or lastID wasn't assigned by this bus, e.g. before a restart
+func (b *Bus) SubscribeFrom(lastID int64, buffer int, filter func(Event) bool) (*Subscription, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	oldestID := b.lastID - int64(b.replayCount) + 1
+	if lastID < 0 || lastID > b.lastID || lastID+1 < oldestID {
+		return b.subscribe(buffer, filter, nil), false
+	}
+
+	var missed []Event
+	for id := lastID + 1; id <= b.lastID; id++ {
+		event := b.replay[(b.replayNext-int(b.lastID-id)-1+len(b.replay))%len(b.replay)]
+		if filter == nil || filter(event) {
+			missed = append(missed, event)
+		}
+	}
+	return b.subscribe(buffer, filter, missed), true
+}
+
+// subscribe ... registers a subscription with room for the missed events on top of its buffer. b.mu must be held
+func (b *Bus) subscribe(buffer int, filter func(Event) bool, missed []Event) *Subscription {
+	if buffer <= 0 {
+		buffer = DEFAULT_SUBSCRIPTION_BUFFER
+	}
+
+	sub := &Subscription{
+		bus:     b,
+		c:       make(chan Event, buffer+len(missed)),
+		filter:  filter,
+		startID: b.lastID,
+	}
+	for _, event := range missed {
+		sub.c <- event
+	}
+
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// StartID ... the ID of the last event published before the subscription was created. Every later event, if the filter
+// accepts it, is delivered to the subscription
+func (s *Subscription) StartID() int64 {
+	return s.startID
+}
+
+// Events ... the channel events are delivered on. It is closed when the subscription is closed
+func (s *Subscription) Events() <-chan Event {
+	return s.c
+}
+
+// Dropped ... the number of events dropped because the subscriber's buffer was full
+func (s *Subscription) Dropped() int64 {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.dropped
+}
+
+// Close ... stops delivery and closes the events channel. It is safe to call more than once
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	delete(s.bus.subscribers, s)
+	close(s.c)
+}
@@ -0,0 +1,114 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBusFanOut(t *testing.T) {
+	bus := NewBus()
+
+	all := bus.Subscribe(10, nil)
+	defer all.Close()
+	doors := bus.Subscribe(10, func(e Event) bool { return e.Type == EVENT_DOOR_UNLOCKED })
+	defer doors.Close()
+
+	published := bus.Publish(Event{Type: EVENT_FUEL_LOW, VehicleID: 1234})
+	assert.Equal(t, int64(1), published.ID)
+	bus.Publish(Event{Type: EVENT_DOOR_UNLOCKED, VehicleID: 1234})
+
+	assert.Equal(t, EVENT_FUEL_LOW, (<-all.Events()).Type)
+	second := <-all.Events()
+	assert.Equal(t, EVENT_DOOR_UNLOCKED, second.Type)
+	assert.Equal(t, int64(2), second.ID)
+
+	filtered := <-doors.Events()
+	assert.Equal(t, int64(2), filtered.ID)
+	assert.Empty(t, doors.Events())
+}
+
+func TestBusDropsForSlowSubscriber(t *testing.T) {
+	bus := NewBus()
+
+	slow := bus.Subscribe(1, nil)
+	defer slow.Close()
+
+	for i := 0; i < 3; i++ {
+		bus.Publish(Event{Type: EVENT_FUEL_LOW})
+	}
+
+	assert.Equal(t, int64(2), slow.Dropped())
+	assert.Equal(t, int64(1), (<-slow.Events()).ID, "The buffered event is kept, later events are dropped")
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	bus := NewBus()
+
+	sub := bus.Subscribe(1, nil)
+	sub.Close()
+	sub.Close()
+
+	bus.Publish(Event{Type: EVENT_FUEL_LOW})
+
+	_, open := <-sub.Events()
+	assert.False(t, open)
+}
+
+func TestSubscribeFrom(t *testing.T) {
+	bus := NewBus(WithReplayBuffer(3))
+
+	sub, resumed := bus.SubscribeFrom(0, 10, nil)
+	assert.True(t, resumed, "Nothing has been published yet")
+	assert.Equal(t, int64(0), sub.StartID())
+	sub.Close()
+
+	for i := 0; i < 5; i++ {
+		bus.Publish(Event{Type: EVENT_FUEL_LOW, VehicleID: int64(1234 + i%2)})
+	}
+
+	sub, resumed = bus.SubscribeFrom(2, 10, nil)
+	assert.True(t, resumed)
+	assert.Equal(t, int64(5), sub.StartID())
+	for _, id := range []int64{3, 4, 5} {
+		assert.Equal(t, id, (<-sub.Events()).ID)
+	}
+	sub.Close()
+
+	sub, resumed = bus.SubscribeFrom(2, 10, func(e Event) bool { return e.VehicleID == 1235 })
+	assert.True(t, resumed)
+	assert.Equal(t, int64(4), (<-sub.Events()).ID)
+	assert.Empty(t, sub.Events())
+
+	bus.Publish(Event{Type: EVENT_FUEL_LOW, VehicleID: 1235})
+	assert.Equal(t, int64(6), (<-sub.Events()).ID, "Live events follow the missed events")
+	sub.Close()
+
+	sub, resumed = bus.SubscribeFrom(6, 10, nil)
+	assert.True(t, resumed, "Up to date")
+	assert.Empty(t, sub.Events())
+	sub.Close()
+
+	sub, resumed = bus.SubscribeFrom(2, 10, nil)
+	assert.False(t, resumed, "Event 3 has left the replay buffer")
+	assert.Empty(t, sub.Events())
+	sub.Close()
+
+	sub, resumed = bus.SubscribeFrom(7, 10, nil)
+	assert.False(t, resumed, "From before a restart")
+	sub.Close()
+}
+
+func TestSubscribeFromWithoutReplay(t *testing.T) {
+	bus := NewBus(WithReplayBuffer(0))
+	bus.Publish(Event{Type: EVENT_FUEL_LOW})
+	bus.Publish(Event{Type: EVENT_FUEL_LOW})
+
+	sub, resumed := bus.SubscribeFrom(1, 10, nil)
+	assert.False(t, resumed)
+	sub.Close()
+
+	sub, resumed = bus.SubscribeFrom(2, 10, nil)
+	assert.True(t, resumed)
+	sub.Close()
+}
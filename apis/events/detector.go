@@ -0,0 +1,176 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"app_api/apis/vehicle"
+	gmConnector "app_api/shared/gm"
+)
+
+const (
+	DEFAULT_FUEL_LOW_THRESHOLD        = 15.0
+	DEFAULT_BATTERY_CHARGED_THRESHOLD = 95.0
+)
+
+// DetectorOption ... configures optional behaviour of the detector
+type DetectorOption func(*Detector)
+
+// WithFuelLowThreshold ... sets the fuel percentage below which a fuel_low event is emitted
+func WithFuelLowThreshold(percentage float64) DetectorOption {
+	return func(d *Detector) {
+		d.fuelLow = percentage
+	}
+}
+
+// WithBatteryChargedThreshold ... sets the battery percentage at or above which a battery_charged event is emitted
+func WithBatteryChargedThreshold(percentage float64) DetectorOption {
+	return func(d *Detector) {
+		d.batteryCharged = percentage
+	}
+}
+
+// Detector ... observes the readings of the vehicle service and publishes an event whenever a reading differs from the
+// previous reading of the same vehicle. The first reading of a vehicle only sets the baseline, as there is nothing to compare it to.
+// Levels emit an event when they cross their threshold, not on every reading past it
+type Detector struct {
+	bus            *Bus
+	fuelLow        float64
+	batteryCharged float64
+
+	mu     sync.Mutex
+	states map[int64]*vehicleState
+}
+
+// vehicleState ... the last observed state of a vehicle. Nil fields haven't been observed yet
+type vehicleState struct {
+	doors         map[string]bool
+	engineRunning *bool
+	fuel          *float64
+	battery       *float64
+}
+
+// NewDetector ... returns a detector publishing to the bus, to be registered with vehicle.WithObserver
+func NewDetector(bus *Bus, opts ...DetectorOption) *Detector {
+	d := &Detector{
+		bus:            bus,
+		fuelLow:        DEFAULT_FUEL_LOW_THRESHOLD,
+		batteryCharged: DEFAULT_BATTERY_CHARGED_THRESHOLD,
+		states:         make(map[int64]*vehicleState),
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// state ... callers must hold the lock
+func (d *Detector) state(vehicleID int64) *vehicleState {
+	st, ok := d.states[vehicleID]
+	if !ok {
+		st = &vehicleState{}
+		d.states[vehicleID] = st
+	}
+	return st
+}
+
+// publish ... callers must hold the lock, so the events of a vehicle are published in the order they were detected
+func (d *Detector) publish(eventType string, vehicleID int64, observedAt time.Time, data EventData) {
+	d.bus.Publish(Event{Type: eventType, VehicleID: vehicleID, Time: observedAt, Data: data})
+}
+
+// SetThresholds ... changes the fuel percentage below which fuel_low is emitted, and the battery percentage from which
+// battery_charged is, for the readings observed from now on
+func (d *Detector) SetThresholds(fuelLow, batteryCharged float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.fuelLow, d.batteryCharged = fuelLow, batteryCharged
+}
+
+// Forget ... drops the last observed state of a vehicle, so its next reading sets a new baseline rather than being
+// compared to a stale one
+func (d *Detector) Forget(vehicleID int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.states, vehicleID)
+}
+
+// ObserveDoors ... emits door_locked and door_unlocked for every door whose lock changed
+func (d *Detector) ObserveDoors(vehicleID int64, doors []gmConnector.GMVehicleDoorData, observedAt time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	st := d.state(vehicleID)
+	previous := st.doors
+	st.doors = make(map[string]bool, len(doors))
+
+	for _, door := range doors {
+		st.doors[door.Location] = door.Locked
+
+		if previous == nil {
+			continue
+		}
+		wasLocked, ok := previous[door.Location]
+		if !ok || wasLocked == door.Locked {
+			continue
+		}
+
+		eventType := EVENT_DOOR_UNLOCKED
+		if door.Locked {
+			eventType = EVENT_DOOR_LOCKED
+		}
+		d.publish(eventType, vehicleID, observedAt, EventData{Location: door.Location})
+	}
+}
+
+// ObserveEnergy ... emits fuel_low when the fuel drops below its threshold, and battery_charged when the battery reaches its threshold
+func (d *Detector) ObserveEnergy(vehicleID int64, fuel vehicle.Fuel, battery vehicle.Battery, observedAt time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	st := d.state(vehicleID)
+
+	if fuel.Percentage != nil {
+		if st.fuel != nil && *st.fuel >= d.fuelLow && *fuel.Percentage < d.fuelLow {
+			d.publish(EVENT_FUEL_LOW, vehicleID, observedAt, levelData(*fuel.Percentage, *st.fuel, d.fuelLow))
+		}
+		st.fuel = copyFloat(*fuel.Percentage)
+	}
+
+	if battery.Percentage != nil {
+		if st.battery != nil && *st.battery < d.batteryCharged && *battery.Percentage >= d.batteryCharged {
+			d.publish(EVENT_BATTERY_CHARGED, vehicleID, observedAt, levelData(*battery.Percentage, *st.battery, d.batteryCharged))
+		}
+		st.battery = copyFloat(*battery.Percentage)
+	}
+}
+
+// ObserveEngineAction ... emits engine_started and engine_stopped when an executed engine action changed the engine state.
+// GM doesn't report whether the engine is running, so the first action of a vehicle always emits
+func (d *Detector) ObserveEngineAction(vehicleID int64, action string, observedAt time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	st := d.state(vehicleID)
+	running := action == vehicle.ENGINE_START
+	if st.engineRunning != nil && *st.engineRunning == running {
+		return
+	}
+	st.engineRunning = &running
+
+	eventType := EVENT_ENGINE_STOPPED
+	if running {
+		eventType = EVENT_ENGINE_STARTED
+	}
+	d.publish(eventType, vehicleID, observedAt, EventData{})
+}
+
+func levelData(percentage, previous, threshold float64) EventData {
+	return EventData{Percentage: copyFloat(percentage), Previous: copyFloat(previous), Threshold: copyFloat(threshold)}
+}
+
+func copyFloat(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,130 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"app_api/apis/vehicle"
+	gmConnector "app_api/shared/gm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var observedAt = time.Date(2020, 11, 2, 18, 0, 0, 0, time.UTC)
+
+// newTestDetector ... returns a detector along with a subscription to every event it publishes
+func newTestDetector(t *testing.T, opts ...DetectorOption) (*Detector, *Subscription) {
+	bus := NewBus()
+	sub := bus.Subscribe(100, nil)
+	t.Cleanup(sub.Close)
+	return NewDetector(bus, opts...), sub
+}
+
+// published ... drains the events published so far
+func published(sub *Subscription) (res []Event) {
+	for {
+		select {
+		case event := <-sub.Events():
+			event.ID = 0
+			res = append(res, event)
+		default:
+			return
+		}
+	}
+}
+
+func TestDetectDoorChanges(t *testing.T) {
+	d, sub := newTestDetector(t)
+
+	d.ObserveDoors(1234, []gmConnector.GMVehicleDoorData{{Location: "frontLeft", Locked: true}, {Location: "frontRight", Locked: true}}, observedAt)
+	assert.Empty(t, published(sub), "The first reading only sets the baseline")
+
+	d.ObserveDoors(1234, []gmConnector.GMVehicleDoorData{{Location: "frontLeft", Locked: false}, {Location: "frontRight", Locked: true}}, observedAt)
+	d.ObserveDoors(1234, []gmConnector.GMVehicleDoorData{{Location: "frontLeft", Locked: false}, {Location: "frontRight", Locked: true}}, observedAt)
+	d.ObserveDoors(1234, []gmConnector.GMVehicleDoorData{{Location: "frontLeft", Locked: true}, {Location: "frontRight", Locked: true}}, observedAt)
+
+	// other vehicles have their own baseline
+	d.ObserveDoors(1235, []gmConnector.GMVehicleDoorData{{Location: "frontLeft", Locked: false}}, observedAt)
+
+	assert.Equal(t, []Event{
+		{Type: EVENT_DOOR_UNLOCKED, VehicleID: 1234, Time: observedAt, Data: EventData{Location: "frontLeft"}},
+		{Type: EVENT_DOOR_LOCKED, VehicleID: 1234, Time: observedAt, Data: EventData{Location: "frontLeft"}},
+	}, published(sub))
+}
+
+func TestDetectorForget(t *testing.T) {
+	d, sub := newTestDetector(t)
+
+	d.ObserveDoors(1234, []gmConnector.GMVehicleDoorData{{Location: "frontLeft", Locked: true}}, observedAt)
+	d.Forget(1234)
+	d.ObserveDoors(1234, []gmConnector.GMVehicleDoorData{{Location: "frontLeft", Locked: false}}, observedAt)
+	assert.Empty(t, published(sub), "The first reading after Forget sets a new baseline")
+
+	d.ObserveDoors(1234, []gmConnector.GMVehicleDoorData{{Location: "frontLeft", Locked: true}}, observedAt)
+	assert.Len(t, published(sub), 1)
+}
+
+func TestDetectFuelLow(t *testing.T) {
+	d, sub := newTestDetector(t, WithFuelLowThreshold(20))
+
+	d.ObserveEnergy(1234, vehicle.Fuel{}, vehicle.Battery{}, observedAt)
+	d.ObserveEnergy(1234, vehicle.Fuel{Percentage: copyFloat(25)}, vehicle.Battery{}, observedAt)
+	d.ObserveEnergy(1234, vehicle.Fuel{Percentage: copyFloat(19.5)}, vehicle.Battery{}, observedAt)
+	d.ObserveEnergy(1234, vehicle.Fuel{Percentage: copyFloat(12)}, vehicle.Battery{}, observedAt)
+
+	assert.Equal(t, []Event{
+		{Type: EVENT_FUEL_LOW, VehicleID: 1234, Time: observedAt, Data: EventData{Percentage: copyFloat(19.5), Previous: copyFloat(25), Threshold: copyFloat(20)}},
+	}, published(sub), "Only crossing the threshold emits")
+}
+
+func TestDetectorSetThresholds(t *testing.T) {
+	d, sub := newTestDetector(t)
+
+	d.ObserveEnergy(1234, vehicle.Fuel{Percentage: copyFloat(25)}, vehicle.Battery{Percentage: copyFloat(70)}, observedAt)
+	d.SetThresholds(20, 80)
+	d.ObserveEnergy(1234, vehicle.Fuel{Percentage: copyFloat(19)}, vehicle.Battery{Percentage: copyFloat(85)}, observedAt)
+
+	events := published(sub)
+	require.Len(t, events, 2)
+	assert.Equal(t, copyFloat(20), events[0].Data.Threshold)
+	assert.Equal(t, copyFloat(80), events[1].Data.Threshold)
+}
+
+func TestDetectBatteryCharged(t *testing.T) {
+	d, sub := newTestDetector(t)
+
+	d.ObserveEnergy(1234, vehicle.Fuel{}, vehicle.Battery{Percentage: copyFloat(90)}, observedAt)
+	d.ObserveEnergy(1234, vehicle.Fuel{}, vehicle.Battery{Percentage: copyFloat(96)}, observedAt)
+	d.ObserveEnergy(1234, vehicle.Fuel{}, vehicle.Battery{Percentage: copyFloat(100)}, observedAt)
+
+	events := published(sub)
+	assert.Len(t, events, 1)
+	assert.Equal(t, EVENT_BATTERY_CHARGED, events[0].Type)
+	assert.Equal(t, 96.0, *events[0].Data.Percentage)
+}
+
+func TestDetectEngineChanges(t *testing.T) {
+	d, sub := newTestDetector(t)
+
+	d.ObserveEngineAction(1234, vehicle.ENGINE_START, observedAt)
+	d.ObserveEngineAction(1234, vehicle.ENGINE_START, observedAt)
+	d.ObserveEngineAction(1234, vehicle.ENGINE_STOP, observedAt)
+
+	events := published(sub)
+	assert.Len(t, events, 2)
+	assert.Equal(t, EVENT_ENGINE_STARTED, events[0].Type)
+	assert.Equal(t, EVENT_ENGINE_STOPPED, events[1].Type)
+}
+
+func TestDetectorObservesVehicleService(t *testing.T) {
+	d, sub := newTestDetector(t)
+	service := vehicle.NewService(gmConnector.NewMockGMAPIConnector(), vehicle.WithObserver(d))
+
+	_, err := service.SendEngineAction(1234, vehicle.EngineActionRequest{Action: vehicle.ENGINE_START})
+	assert.Nil(t, err)
+
+	events := published(sub)
+	assert.Len(t, events, 1)
+	assert.Equal(t, EVENT_ENGINE_STARTED, events[0].Type)
+}
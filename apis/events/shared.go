@@ -0,0 +1,72 @@
+package events
+
+import "time"
+
+const (
+	EVENT_DOOR_UNLOCKED   = "door_unlocked"
+	EVENT_DOOR_LOCKED     = "door_locked"
+	EVENT_ENGINE_STARTED  = "engine_started"
+	EVENT_ENGINE_STOPPED  = "engine_stopped"
+	EVENT_FUEL_LOW        = "fuel_low"
+	EVENT_BATTERY_CHARGED = "battery_charged"
+)
+
+// Types ... every event type the detector emits
+var Types = []string{EVENT_DOOR_UNLOCKED, EVENT_DOOR_LOCKED, EVENT_ENGINE_STARTED, EVENT_ENGINE_STOPPED, EVENT_FUEL_LOW, EVENT_BATTERY_CHARGED}
+
+// Event ... a change in the state of a vehicle
+//
+// swagger:model Event
+type Event struct {
+	// ID ... assigned by the bus, increasing in publish order
+	//
+	// required: true
+	// example: 42
+	ID int64 `json:"id"`
+
+	// Type
+	//
+	// required: true
+	// enum: door_unlocked,door_locked,engine_started,engine_stopped,fuel_low,battery_charged
+	// example: door_unlocked
+	Type string `json:"type"`
+
+	// VehicleID
+	//
+	// required: true
+	// example: 1234
+	VehicleID int64 `json:"vehicleId"`
+
+	// Time ... when the change was observed
+	//
+	// required: true
+	Time time.Time `json:"time"`
+
+	// required: true
+	Data EventData `json:"data"`
+}
+
+// EventData ... the details of an event. Only the fields relevant to the event type are set
+//
+// swagger:model EventData
+type EventData struct {
+	// Location ... the door that was locked or unlocked
+	//
+	// example: frontLeft
+	Location string `json:"location,omitempty"`
+
+	// Percentage ... the fuel or battery level that triggered the event
+	//
+	// example: 14.2
+	Percentage *float64 `json:"percentage,omitempty"`
+
+	// Previous ... the previously observed fuel or battery level
+	//
+	// example: 15.8
+	Previous *float64 `json:"previous,omitempty"`
+
+	// Threshold ... the level that was crossed
+	//
+	// example: 15
+	Threshold *float64 `json:"threshold,omitempty"`
+}
@@ -0,0 +1,237 @@
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"app_api/apis/registry"
+	"app_api/apis/vehicle"
+	"app_api/shared"
+
+	graphqlgo "github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Service ... represents an instance of the graphql package service interface
+type Service interface {
+	Execute(ctx context.Context, req Request) (res Response, err *shared.APIError)
+}
+
+// Option ... configures optional behaviour of the graphql service
+type Option func(*service)
+
+// WithMaxDepth ... sets how deeply a query can nest fields. Zero or less removes the limit
+func WithMaxDepth(n int) Option {
+	return func(s *service) {
+		s.maxDepth = n
+	}
+}
+
+// WithMaxComplexity ... sets the highest estimated complexity a query can have: the number of fields it resolves, counting the
+// fields of a list once for every element it can have. Zero or less removes the limit
+func WithMaxComplexity(n int) Option {
+	return func(s *service) {
+		s.maxComplexity = n
+	}
+}
+
+// WithPersistedQueries ... registers queries clients can run by their hex SHA-256 alone, see LoadPersistedQueries
+func WithPersistedQueries(queries map[string]string) Option {
+	return func(s *service) {
+		for hash, query := range queries {
+			s.persisted[strings.ToLower(hash)] = query
+		}
+	}
+}
+
+// WithAllowlist ... only runs the persisted queries, whether a client sends the hash or the whole query
+func WithAllowlist() Option {
+	return func(s *service) {
+		s.allowlist = true
+	}
+}
+
+// NewService ... returns an instance of the graphql package service. Vehicles are read and commanded through the vehicle service,
+// and groups resolved through the registry
+func NewService(vehicleService vehicle.Service, registryService registry.Service, opts ...Option) (Service, error) {
+	schema, err := newSchema(vehicleService, registryService)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &service{
+		schema:        schema,
+		vehicles:      vehicleService,
+		maxDepth:      DEFAULT_MAX_DEPTH,
+		maxComplexity: DEFAULT_MAX_COMPLEXITY,
+		persisted:     make(map[string]string),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+type service struct {
+	schema        graphqlgo.Schema
+	vehicles      vehicle.Service
+	maxDepth      int
+	maxComplexity int
+	persisted     map[string]string
+	allowlist     bool
+}
+
+// Execute ... runs a query or mutation. Requests that can't run at all, e.g. an unknown persisted query or one over the limits,
+// fail with an error. Once a request runs, errors are reported in the response, next to the data that could be resolved
+func (s *service) Execute(ctx context.Context, req Request) (res Response, err *shared.APIError) {
+	query, err := s.query(req)
+	if err != nil {
+		return
+	}
+
+	doc, parseErr := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(query), Name: "GraphQL request"})})
+	if parseErr != nil {
+		res.Errors = responseErrors(gqlerrors.FormatErrors(parseErr))
+		return
+	}
+
+	validation := graphqlgo.ValidateDocument(&s.schema, doc, graphqlgo.SpecifiedRules)
+	if !validation.IsValid {
+		res.Errors = responseErrors(validation.Errors)
+		return
+	}
+
+	if c, ok := measure(doc, req.OperationName, req.Variables); ok {
+		if err = checkLimits(c, s.maxDepth, s.maxComplexity); err != nil {
+			return
+		}
+	}
+
+	result := graphqlgo.Execute(graphqlgo.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       context.WithValue(ctx, contextKeyLoader, newLoader(ctx, s.vehicles)),
+	})
+
+	res.Data = result.Data
+	res.Errors = responseErrors(result.Errors)
+	return
+}
+
+// query ... the document to run, looking up persisted queries by their hash
+func (s *service) query(req Request) (query string, err *shared.APIError) {
+	query = req.Query
+
+	var hash string
+	if req.Extensions != nil && req.Extensions.PersistedQuery != nil {
+		hash = strings.ToLower(req.Extensions.PersistedQuery.SHA256Hash)
+		if query == "" {
+			persisted, ok := s.persisted[hash]
+			if !ok {
+				msg := "PersistedQueryNotFound"
+				err = shared.NewAPIError(http.StatusBadRequest, fmt.Errorf("no persisted query with hash %s", hash), msg)
+				return
+			}
+			return persisted, nil
+		}
+
+		if hash != QueryHash(query) {
+			msg := "Query does not match its sha256Hash"
+			err = shared.NewAPIError(http.StatusBadRequest, errors.New(msg), msg)
+			return
+		}
+	}
+
+	if query == "" {
+		msg := "Query is required"
+		err = shared.NewAPIError(http.StatusBadRequest, errors.New(msg), msg)
+		return
+	}
+
+	if s.allowlist {
+		if _, ok := s.persisted[QueryHash(query)]; !ok {
+			msg := "Only persisted queries are allowed"
+			err = shared.NewAPIError(http.StatusForbidden, errors.New("query is not in the persisted query allowlist"), msg)
+			return
+		}
+	}
+
+	return
+}
+
+func responseErrors(errs []gqlerrors.FormattedError) []ResponseError {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	res := make([]ResponseError, 0, len(errs))
+	for _, e := range errs {
+		res = append(res, ResponseError{Message: e.Message, Locations: e.Locations, Path: e.Path, Extensions: extensions(e)})
+	}
+	return res
+}
+
+// extensions ... the extensions of the error. graphql-go wraps errors returned from thunks twice, dropping their extensions on
+// the way, so they are looked up on the original error
+func extensions(e gqlerrors.FormattedError) map[string]interface{} {
+	if e.Extensions != nil {
+		return e.Extensions
+	}
+
+	err := e.OriginalError()
+	for err != nil {
+		if extended, ok := err.(gqlerrors.ExtendedError); ok {
+			return extended.Extensions()
+		}
+
+		switch wrapped := err.(type) {
+		case *gqlerrors.Error:
+			err = wrapped.OriginalError
+		case gqlerrors.FormattedError:
+			err = wrapped.OriginalError()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// QueryHash ... the hex SHA-256 a persisted query is known by
+func QueryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadPersistedQueries ... reads persisted queries from a JSON file, an object of queries keyed by their hex SHA-256.
+// Fails if a key isn't the hash of its query
+func LoadPersistedQueries(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var queries map[string]string
+	if err := json.Unmarshal(data, &queries); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	for hash, query := range queries {
+		if strings.ToLower(hash) != QueryHash(query) {
+			return nil, fmt.Errorf("%s: %s is not the sha256 of its query", path, hash)
+		}
+	}
+	return queries, nil
+}
@@ -0,0 +1,255 @@
+package graphql
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"app_api/apis/registry"
+	"app_api/apis/vehicle"
+	"app_api/shared"
+	"app_api/shared/auth"
+	gmConnector "app_api/shared/gm"
+	"app_api/shared/store/storetest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingConnector ... counts the calls made to GM
+type countingConnector struct {
+	gmConnector.GMAPIConnector
+
+	mu     sync.Mutex
+	energy []int64
+	doors  []int64
+}
+
+func (c *countingConnector) GetVehicleEnergyStatus(vehicleID int64) (*float64, *float64, *shared.APIError) {
+	c.mu.Lock()
+	c.energy = append(c.energy, vehicleID)
+	c.mu.Unlock()
+	return c.GMAPIConnector.GetVehicleEnergyStatus(vehicleID)
+}
+
+func (c *countingConnector) GetVehicleDoors(vehicleID int64) ([]gmConnector.GMVehicleDoorData, *shared.APIError) {
+	c.mu.Lock()
+	c.doors = append(c.doors, vehicleID)
+	c.mu.Unlock()
+	return c.GMAPIConnector.GetVehicleDoors(vehicleID)
+}
+
+// countingService ... counts the batches fetched through the vehicle service
+type countingService struct {
+	vehicle.Service
+	batches [][]int64
+}
+
+func (s *countingService) GetVehicleSnapshots(vehicleIDs []int64, sections []string) ([]vehicle.BatchResult, *shared.APIError) {
+	s.batches = append(s.batches, vehicleIDs)
+	return s.Service.GetVehicleSnapshots(vehicleIDs, sections)
+}
+
+type testService struct {
+	Service
+	gm       *countingConnector
+	vehicles *countingService
+	registry registry.Service
+}
+
+// newTestService ... serves the mock GM connector, counting the requests to it, and an empty registry
+func newTestService(t *testing.T, opts ...Option) *testService {
+	registryService, err := registry.NewService(storetest.Open(t))
+	assert.NoError(t, err)
+
+	gm := &countingConnector{GMAPIConnector: gmConnector.NewMockGMAPIConnector()}
+	vehicles := &countingService{Service: vehicle.NewService(gm)}
+
+	s, err := NewService(vehicles, registryService, opts...)
+	assert.NoError(t, err)
+
+	return &testService{Service: s, gm: gm, vehicles: vehicles, registry: registryService}
+}
+
+func (ts *testService) run(t *testing.T, query string, variables map[string]interface{}) Response {
+	res, apiErr := ts.Execute(context.Background(), Request{Query: query, Variables: variables})
+	assert.Nil(t, apiErr)
+	return res
+}
+
+func TestQueryVehicles(t *testing.T) {
+	ts := newTestService(t)
+
+	res := ts.run(t, `{
+		vehicles(ids: ["1234", "1235", "1234"]) {
+			id vin doorCount
+			fuel { percentage }
+			battery { percentage }
+			doors { location locked }
+		}
+	}`, nil)
+	assert.Empty(t, res.Errors)
+
+	vehicles := res.Data.(map[string]interface{})["vehicles"].([]interface{})
+	if assert.Len(t, vehicles, 2, "Duplicates are listed once") {
+		first := vehicles[0].(map[string]interface{})
+		assert.Equal(t, "1234", first["id"])
+		assert.Equal(t, "123123412412", first["vin"])
+		assert.Equal(t, 4, first["doorCount"])
+		assert.Equal(t, 33.5, first["fuel"].(map[string]interface{})["percentage"])
+		assert.Nil(t, first["battery"].(map[string]interface{})["percentage"])
+		assert.Len(t, first["doors"], 2)
+
+		second := vehicles[1].(map[string]interface{})
+		assert.Equal(t, 88.55, second["battery"].(map[string]interface{})["percentage"])
+	}
+
+	assert.Len(t, ts.vehicles.batches, 1, "Every field is fetched in one batch")
+	assert.ElementsMatch(t, []int64{1234, 1235}, ts.gm.energy, "Fuel and battery take one call to GM per vehicle")
+	assert.ElementsMatch(t, []int64{1234, 1235}, ts.gm.doors)
+}
+
+func TestQueryOnlyFetchesSelectedFields(t *testing.T) {
+	ts := newTestService(t)
+
+	res := ts.run(t, `query Vehicle($id: ID!) { vehicle(id: $id) { id vin } again: vehicle(id: $id) { color } }`, map[string]interface{}{"id": "1234"})
+	assert.Empty(t, res.Errors)
+	assert.Equal(t, map[string]interface{}{
+		"vehicle": map[string]interface{}{"id": "1234", "vin": "123123412412"},
+		"again":   map[string]interface{}{"color": "Metallic Silver"},
+	}, res.Data)
+
+	assert.Len(t, ts.vehicles.batches, 1)
+	assert.Equal(t, []int64{1234}, ts.vehicles.batches[0], "The vehicle is fetched once for both aliases")
+	assert.Empty(t, ts.gm.energy)
+	assert.Empty(t, ts.gm.doors)
+}
+
+func TestQueryGroup(t *testing.T) {
+	ts := newTestService(t)
+
+	_, apiErr := ts.registry.CreateGroup(registry.GroupRequest{Name: "depot"})
+	assert.Nil(t, apiErr)
+	_, apiErr = ts.registry.AddGroupVehicle("depot", 1235)
+	assert.Nil(t, apiErr)
+
+	res := ts.run(t, `{ vehicles(ids: ["1234"], group: "depot", limit: 1, offset: 1) { id } }`, nil)
+	assert.Empty(t, res.Errors)
+	assert.Equal(t, map[string]interface{}{"vehicles": []interface{}{map[string]interface{}{"id": "1235"}}}, res.Data)
+
+	res = ts.run(t, `{ vehicles { id } }`, nil)
+	if assert.Len(t, res.Errors, 1) {
+		assert.Equal(t, "ids or group is required", res.Errors[0].Message)
+		assert.Equal(t, 400, res.Errors[0].Extensions["code"])
+	}
+}
+
+func TestFieldErrors(t *testing.T) {
+	ts := newTestService(t)
+
+	res := ts.run(t, `{ vehicles(ids: ["1234", "1236"]) { id vin } }`, nil)
+	vehicles := res.Data.(map[string]interface{})["vehicles"].([]interface{})
+	assert.Equal(t, "123123412412", vehicles[0].(map[string]interface{})["vin"])
+	assert.Nil(t, vehicles[1].(map[string]interface{})["vin"], "A failed field is null, the rest of the query resolves")
+
+	if assert.Len(t, res.Errors, 1) {
+		assert.Equal(t, []interface{}{"vehicles", 1, "vin"}, res.Errors[0].Path)
+		assert.Equal(t, 500, res.Errors[0].Extensions["code"])
+	}
+
+	res = ts.run(t, `{ vehicle(id: "abc") { id } }`, nil)
+	if assert.Len(t, res.Errors, 1) {
+		assert.Equal(t, `Invalid vehicle ID "abc"`, res.Errors[0].Message)
+	}
+
+	res = ts.run(t, `{ vehicle(id: "1234") { mileage } }`, nil)
+	assert.Nil(t, res.Data, "Invalid queries don't run")
+	assert.Len(t, res.Errors, 1)
+}
+
+func TestEngineActionMutation(t *testing.T) {
+	ts := newTestService(t)
+	mutation := `mutation { engineAction(vehicleId: "1234", action: START) { vehicleId status } }`
+
+	res, apiErr := ts.Execute(auth.WithPrincipal(context.Background(), auth.Anonymous), Request{Query: mutation})
+	assert.Nil(t, apiErr)
+	assert.Empty(t, res.Errors)
+	assert.Equal(t, map[string]interface{}{"engineAction": map[string]interface{}{"vehicleId": "1234", "status": "success"}}, res.Data)
+
+	reader := auth.Principal{KeyID: "reader", Scopes: []string{auth.SCOPE_VEHICLES_READ}}
+	res, apiErr = ts.Execute(auth.WithPrincipal(context.Background(), reader), Request{Query: mutation})
+	assert.Nil(t, apiErr)
+	if assert.Len(t, res.Errors, 1) {
+		assert.Equal(t, 403, res.Errors[0].Extensions["code"])
+	}
+}
+
+func TestLimits(t *testing.T) {
+	ts := newTestService(t, WithMaxDepth(2), WithMaxComplexity(50))
+
+	_, apiErr := ts.Execute(context.Background(), Request{Query: `{ vehicle(id: "1234") { doors { location } } }`})
+	if assert.NotNil(t, apiErr) {
+		assert.Equal(t, 400, apiErr.ErrorCode)
+		assert.Equal(t, "Query is nested 3 levels deep, at most 2 are allowed", apiErr.ClientErrorMessage)
+	}
+
+	_, apiErr = ts.Execute(context.Background(), Request{Query: `{ vehicles(group: "depot") { id vin } }`})
+	if assert.NotNil(t, apiErr) {
+		assert.Equal(t, "Query has a complexity of 201, at most 50 is allowed", apiErr.ClientErrorMessage)
+	}
+
+	_, apiErr = ts.Execute(context.Background(), Request{Query: `{ vehicles(ids: ["1234", "1235"]) { id vin } }`})
+	assert.Nil(t, apiErr)
+
+	_, apiErr = ts.Execute(context.Background(), Request{Query: `{ __schema { types { name fields { name type { name ofType { name } } } } } }`})
+	assert.Nil(t, apiErr, "Introspection is not limited")
+}
+
+func TestPersistedQueries(t *testing.T) {
+	query := `{ vehicle(id: "1234") { vin } }`
+	ts := newTestService(t, WithPersistedQueries(map[string]string{QueryHash(query): query}), WithAllowlist())
+
+	byHash := &RequestExtensions{PersistedQuery: &PersistedQuery{Version: 1, SHA256Hash: QueryHash(query)}}
+	res, apiErr := ts.Execute(context.Background(), Request{Extensions: byHash})
+	assert.Nil(t, apiErr)
+	assert.Equal(t, map[string]interface{}{"vehicle": map[string]interface{}{"vin": "123123412412"}}, res.Data)
+
+	_, apiErr = ts.Execute(context.Background(), Request{Query: query})
+	assert.Nil(t, apiErr, "A persisted query can also be sent whole")
+
+	_, apiErr = ts.Execute(context.Background(), Request{Query: `{ vehicle(id: "1234") { color } }`})
+	if assert.NotNil(t, apiErr) {
+		assert.Equal(t, 403, apiErr.ErrorCode)
+	}
+
+	unknown := &RequestExtensions{PersistedQuery: &PersistedQuery{Version: 1, SHA256Hash: QueryHash("{}")}}
+	_, apiErr = ts.Execute(context.Background(), Request{Extensions: unknown})
+	if assert.NotNil(t, apiErr) {
+		assert.Equal(t, "PersistedQueryNotFound", apiErr.ClientErrorMessage)
+	}
+
+	_, apiErr = ts.Execute(context.Background(), Request{Query: `{ vehicle(id: "1235") { vin } }`, Extensions: byHash})
+	if assert.NotNil(t, apiErr) {
+		assert.Equal(t, "Query does not match its sha256Hash", apiErr.ClientErrorMessage)
+	}
+}
+
+func TestLoadPersistedQueries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "graphql")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	query := `{ vehicle(id: "1234") { vin } }`
+	path := filepath.Join(dir, "queries.json")
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"`+QueryHash(query)+`": "{ vehicle(id: \"1234\") { vin } }"}`), 0644))
+	queries, err := LoadPersistedQueries(path)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{QueryHash(query): query}, queries)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"`+QueryHash("{}")+`": "{ vehicle(id: \"1234\") { vin } }"}`), 0644))
+	_, err = LoadPersistedQueries(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,176 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"app_api/apis/vehicle"
+	"app_api/shared"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+const (
+	DEFAULT_MAX_DEPTH      = 6
+	DEFAULT_MAX_COMPLEXITY = 1000
+)
+
+// cost ... the estimated cost of a selection: how deeply it nests, and how many fields it resolves, counting the fields of a
+// list once for every element the list can have
+type cost struct {
+	depth      int
+	complexity int
+}
+
+// measure ... estimates the cost of the operation to run. Introspection fields are free, so tools can always load the schema.
+// Returns false if there is no such operation, which execution reports
+func measure(doc *ast.Document, operationName string, variables map[string]interface{}) (cost, bool) {
+	m := measurer{fragments: make(map[string]*ast.FragmentDefinition), variables: variables, visiting: make(map[string]bool)}
+
+	var operation *ast.OperationDefinition
+	operations := 0
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			operations++
+			if operationName == "" || (def.Name != nil && def.Name.Value == operationName) {
+				operation = def
+			}
+		case *ast.FragmentDefinition:
+			m.fragments[def.Name.Value] = def
+		}
+	}
+	if operation == nil || (operationName == "" && operations > 1) {
+		return cost{}, false
+	}
+
+	return m.selectionSet(operation.SelectionSet, 0), true
+}
+
+type measurer struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	// visiting ... the fragments being measured, so a fragment spreading itself isn't followed forever
+	visiting map[string]bool
+}
+
+func (m *measurer) selectionSet(set *ast.SelectionSet, depth int) cost {
+	res := cost{depth: depth}
+	if set == nil {
+		return res
+	}
+
+	for _, selection := range set.Selections {
+		var c cost
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+			c = m.selectionSet(selection.SelectionSet, depth+1)
+			c.complexity = 1 + c.complexity*m.listSize(selection)
+		case *ast.InlineFragment:
+			c = m.selectionSet(selection.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := m.fragments[name]
+			if !ok || m.visiting[name] {
+				continue
+			}
+			m.visiting[name] = true
+			c = m.selectionSet(fragment.SelectionSet, depth)
+			delete(m.visiting, name)
+		}
+
+		if c.depth > res.depth {
+			res.depth = c.depth
+		}
+		res.complexity += c.complexity
+	}
+	return res
+}
+
+// listSize ... the most elements the field can resolve to
+func (m *measurer) listSize(field *ast.Field) int {
+	switch field.Name.Value {
+	case "vehicles":
+		size := vehicle.DEFAULT_BATCH_LIMIT
+		if limit, ok := m.intArgument(field, "limit"); ok && limit > 0 && limit < size {
+			size = limit
+		}
+		if _, group := m.argument(field, "group"); !group {
+			if ids, ok := m.listLength(field, "ids"); ok && ids < size {
+				size = ids
+			}
+		}
+		return size
+	case "doors":
+		return MAX_DOORS
+	default:
+		return 1
+	}
+}
+
+// argument ... the value of the argument, resolving variables. Returns false if it wasn't given
+func (m *measurer) argument(field *ast.Field, name string) (interface{}, bool) {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != name {
+			continue
+		}
+		if variable, ok := arg.Value.(*ast.Variable); ok {
+			value, ok := m.variables[variable.Name.Value]
+			return value, ok && value != nil
+		}
+		return arg.Value, true
+	}
+	return nil, false
+}
+
+func (m *measurer) intArgument(field *ast.Field, name string) (int, bool) {
+	value, ok := m.argument(field, name)
+	if !ok {
+		return 0, false
+	}
+
+	switch value := value.(type) {
+	case *ast.IntValue:
+		n, err := strconv.Atoi(value.Value)
+		return n, err == nil
+	case float64:
+		return int(value), true
+	case int:
+		return value, true
+	}
+	return 0, false
+}
+
+func (m *measurer) listLength(field *ast.Field, name string) (int, bool) {
+	value, ok := m.argument(field, name)
+	if !ok {
+		return 0, false
+	}
+
+	switch value := value.(type) {
+	case *ast.ListValue:
+		return len(value.Values), true
+	case []interface{}:
+		return len(value), true
+	}
+	return 0, false
+}
+
+// checkLimits ... rejects an operation that nests deeper than maxDepth, or whose estimated complexity exceeds maxComplexity
+func checkLimits(c cost, maxDepth, maxComplexity int) *shared.APIError {
+	if maxDepth > 0 && c.depth > maxDepth {
+		msg := fmt.Sprintf("Query is nested %d levels deep, at most %d are allowed", c.depth, maxDepth)
+		return shared.NewAPIError(http.StatusBadRequest, errors.New(msg), msg)
+	}
+	if maxComplexity > 0 && c.complexity > maxComplexity {
+		msg := fmt.Sprintf("Query has a complexity of %d, at most %d is allowed", c.complexity, maxComplexity)
+		return shared.NewAPIError(http.StatusBadRequest, errors.New(msg), msg)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package graphql
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeasure(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		operation string
+		variables map[string]interface{}
+		expected  cost
+	}{
+		{"Scalar fields", `{ vehicle(id: "1") { id vin } }`, "", nil, cost{depth: 2, complexity: 3}},
+		{"Lists multiply their fields", `{ vehicle(id: "1") { doors { location locked } } }`, "", nil, cost{depth: 3, complexity: 1 + 1 + MAX_DOORS*2}},
+		{"Vehicles by ids", `{ vehicles(ids: ["1", "2", "3"]) { id } }`, "", nil, cost{depth: 2, complexity: 1 + 3}},
+		{"Vehicles by group", `{ vehicles(group: "depot") { id } }`, "", nil, cost{depth: 2, complexity: 1 + 100}},
+		{"Vehicles limit", `{ vehicles(group: "depot", limit: 10) { id } }`, "", nil, cost{depth: 2, complexity: 1 + 10}},
+		{
+			"Variables",
+			`query Q($ids: [ID!], $limit: Int) { vehicles(ids: $ids, limit: $limit) { id } }`, "",
+			map[string]interface{}{"ids": []interface{}{"1", "2"}, "limit": float64(5)},
+			cost{depth: 2, complexity: 1 + 2},
+		},
+		{
+			"Fragments",
+			`{ vehicle(id: "1") { ...info ... on Vehicle { fuel { percentage } } } } fragment info on Vehicle { id vin }`, "", nil,
+			cost{depth: 3, complexity: 1 + 2 + 2},
+		},
+		{"Introspection is free", `{ __schema { types { name } } vehicle(id: "1") { id } }`, "", nil, cost{depth: 2, complexity: 2}},
+		{"Named operation", `query A { vehicle(id: "1") { id } } query B { vehicles(group: "g") { id } }`, "B", nil, cost{depth: 2, complexity: 101}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: test.query})
+			assert.NoError(t, err)
+
+			c, ok := measure(doc, test.operation, test.variables)
+			assert.True(t, ok)
+			assert.Equal(t, test.expected, c)
+		})
+	}
+}
+
+func TestMeasureUnknownOperation(t *testing.T) {
+	doc, err := parser.Parse(parser.ParseParams{Source: `query A { vehicle(id: "1") { id } } query B { vehicle(id: "1") { id } }`})
+	assert.NoError(t, err)
+
+	_, ok := measure(doc, "", nil)
+	assert.False(t, ok, "The operation to run is ambiguous")
+
+	_, ok = measure(doc, "C", nil)
+	assert.False(t, ok)
+}
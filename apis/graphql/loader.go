@@ -0,0 +1,121 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"app_api/apis/vehicle"
+	"app_api/shared"
+	loghelper "app_api/shared/loghelpers"
+)
+
+// loader ... fetches vehicle data for a single request. Resolvers ask for a section of a vehicle and get back a thunk; the
+// sections asked for by every resolver of a level of the query are fetched together, as one batch through the vehicle
+// service, the first time one of those thunks is called. Every section of a vehicle is fetched at most once per request,
+// and fuel and battery, asked for together, take a single call to GM
+type loader struct {
+	ctx      context.Context
+	vehicles vehicle.Service
+
+	mu      sync.Mutex
+	pending map[int64]map[string]bool
+	loaded  map[sectionKey]*loadedSection
+}
+
+type sectionKey struct {
+	vehicleID int64
+	section   string
+}
+
+type loadedSection struct {
+	snapshot *vehicle.Snapshot
+	err      *shared.APIError
+}
+
+func newLoader(ctx context.Context, vehicleService vehicle.Service) *loader {
+	return &loader{
+		ctx:      ctx,
+		vehicles: vehicleService,
+		pending:  make(map[int64]map[string]bool),
+		loaded:   make(map[sectionKey]*loadedSection),
+	}
+}
+
+// load ... queues the section of the vehicle to be fetched with the next batch, and returns a thunk for the snapshot holding it
+func (l *loader) load(vehicleID int64, section string) func() (*vehicle.Snapshot, *shared.APIError) {
+	key := sectionKey{vehicleID, section}
+
+	l.mu.Lock()
+	if _, ok := l.loaded[key]; !ok {
+		if l.pending[vehicleID] == nil {
+			l.pending[vehicleID] = make(map[string]bool)
+		}
+		l.pending[vehicleID][section] = true
+	}
+	l.mu.Unlock()
+
+	return func() (*vehicle.Snapshot, *shared.APIError) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if _, ok := l.loaded[key]; !ok {
+			l.flush()
+		}
+		res := l.loaded[key]
+		return res.snapshot, res.err
+	}
+}
+
+// flush ... fetches every pending section. Vehicles asking for the same sections share a batch. l.mu must be held
+func (l *loader) flush() {
+	batches := make(map[string][]int64)
+	for vehicleID, sections := range l.pending {
+		names := make([]string, 0, len(sections))
+		for section := range sections {
+			names = append(names, section)
+		}
+		sort.Strings(names)
+
+		key := strings.Join(names, ",")
+		batches[key] = append(batches[key], vehicleID)
+	}
+	l.pending = make(map[int64]map[string]bool)
+
+	for key, vehicleIDs := range batches {
+		sections := strings.Split(key, ",")
+
+		results, apiErr := l.vehicles.GetVehicleSnapshots(vehicleIDs, sections)
+		if apiErr != nil {
+			loghelper.LogErrors(l.ctx, apiErr)
+			for _, vehicleID := range vehicleIDs {
+				l.store(vehicleID, sections, &loadedSection{err: apiErr})
+			}
+			continue
+		}
+
+		for _, result := range results {
+			res := &loadedSection{snapshot: result.Snapshot, err: result.Err()}
+			if result.Snapshot != nil {
+				for _, e := range result.Snapshot.Errors() {
+					loghelper.LogErrors(l.ctx, e)
+				}
+			} else {
+				loghelper.LogErrors(l.ctx, res.err)
+			}
+			l.store(result.VehicleID, sections, res)
+		}
+	}
+}
+
+func (l *loader) store(vehicleID int64, sections []string, res *loadedSection) {
+	if res.snapshot == nil && res.err == nil {
+		res.err = shared.NewAPIError(http.StatusInternalServerError, errors.New("batch result without a snapshot"), "Failed to get vehicle")
+	}
+	for _, section := range sections {
+		l.loaded[sectionKey{vehicleID, section}] = res
+	}
+}
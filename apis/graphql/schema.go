@@ -0,0 +1,305 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"app_api/apis/registry"
+	"app_api/apis/vehicle"
+	"app_api/shared"
+	"app_api/shared/auth"
+	loghelper "app_api/shared/loghelpers"
+
+	graphqlgo "github.com/graphql-go/graphql"
+)
+
+// MAX_DOORS ... the most doors a vehicle has, used to estimate the cost of a doors field
+const MAX_DOORS = 4
+
+type key string
+
+// contextKeyLoader ... key for the request's loader in context
+const contextKeyLoader key = "graphqlLoader"
+
+// vehicleRef ... the source of every Vehicle field. Its data is only fetched for the fields the query selects
+type vehicleRef struct {
+	id int64
+}
+
+// fieldError ... an error reported on the field it occurred in, with the HTTP status the error has over REST as its code
+type fieldError struct {
+	apiErr *shared.APIError
+}
+
+func (e fieldError) Error() string {
+	return e.apiErr.ClientErrorMessage
+}
+
+// Extensions ... added to the GraphQL error
+func (e fieldError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.apiErr.ErrorCode}
+}
+
+// newFieldError ... logs the error, as a failed REST request would, and returns it to be reported on the field
+func newFieldError(ctx context.Context, apiErr *shared.APIError) error {
+	loghelper.LogErrors(ctx, apiErr)
+	return fieldError{apiErr}
+}
+
+func badArgument(ctx context.Context, msg string) error {
+	return newFieldError(ctx, shared.NewAPIError(http.StatusBadRequest, errors.New(msg), msg))
+}
+
+// newSchema ... the GraphQL schema of the vehicle domain
+func newSchema(vehicleService vehicle.Service, registryService registry.Service) (graphqlgo.Schema, error) {
+	doorType := graphqlgo.NewObject(graphqlgo.ObjectConfig{
+		Name:        "Door",
+		Description: "The lock status of a door",
+		Fields: graphqlgo.Fields{
+			"location": &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.String)},
+			"locked":   &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.Boolean)},
+		},
+	})
+
+	energyType := graphqlgo.NewObject(graphqlgo.ObjectConfig{
+		Name:        "Energy",
+		Description: "A fuel or battery level",
+		Fields: graphqlgo.Fields{
+			"percentage": &graphqlgo.Field{
+				Type:        graphqlgo.Float,
+				Description: "Null when the vehicle has no fuel tank, or no battery",
+			},
+		},
+	})
+
+	vehicleType := graphqlgo.NewObject(graphqlgo.ObjectConfig{
+		Name:        "Vehicle",
+		Description: "A vehicle. Every field is fetched from GM only when selected, and a field that can't be fetched is null with an error",
+		Fields: graphqlgo.Fields{
+			"id": &graphqlgo.Field{
+				Type: graphqlgo.NewNonNull(graphqlgo.ID),
+				Resolve: func(p graphqlgo.ResolveParams) (interface{}, error) {
+					return strconv.FormatInt(p.Source.(vehicleRef).id, 10), nil
+				},
+			},
+			"vin": &graphqlgo.Field{
+				Type: graphqlgo.String,
+				Resolve: resolveInfo(func(v *vehicle.Vehicle) interface{} {
+					return v.Vin
+				}),
+			},
+			"color": &graphqlgo.Field{
+				Type: graphqlgo.String,
+				Resolve: resolveInfo(func(v *vehicle.Vehicle) interface{} {
+					return v.Color
+				}),
+			},
+			"doorCount": &graphqlgo.Field{
+				Type: graphqlgo.Int,
+				Resolve: resolveInfo(func(v *vehicle.Vehicle) interface{} {
+					return v.DoorCount
+				}),
+			},
+			"driveTrain": &graphqlgo.Field{
+				Type: graphqlgo.String,
+				Resolve: resolveInfo(func(v *vehicle.Vehicle) interface{} {
+					return v.DriveTrain
+				}),
+			},
+			"doors": &graphqlgo.Field{
+				Type: graphqlgo.NewList(graphqlgo.NewNonNull(doorType)),
+				Resolve: resolveSection(vehicle.SECTION_DOORS, func(s *vehicle.Snapshot) (interface{}, *shared.APIError) {
+					return s.Doors.Data, s.Doors.Err()
+				}),
+			},
+			"fuel": &graphqlgo.Field{
+				Type: energyType,
+				Resolve: resolveSection(vehicle.SECTION_FUEL, func(s *vehicle.Snapshot) (interface{}, *shared.APIError) {
+					return s.Fuel.Data, s.Fuel.Err()
+				}),
+			},
+			"battery": &graphqlgo.Field{
+				Type: energyType,
+				Resolve: resolveSection(vehicle.SECTION_BATTERY, func(s *vehicle.Snapshot) (interface{}, *shared.APIError) {
+					return s.Battery.Data, s.Battery.Err()
+				}),
+			},
+		},
+	})
+
+	engineActionType := graphqlgo.NewEnum(graphqlgo.EnumConfig{
+		Name: "EngineAction",
+		Values: graphqlgo.EnumValueConfigMap{
+			vehicle.ENGINE_START: &graphqlgo.EnumValueConfig{Value: vehicle.ENGINE_START},
+			vehicle.ENGINE_STOP:  &graphqlgo.EnumValueConfig{Value: vehicle.ENGINE_STOP},
+		},
+	})
+
+	engineActionResultType := graphqlgo.NewObject(graphqlgo.ObjectConfig{
+		Name: "EngineActionResult",
+		Fields: graphqlgo.Fields{
+			"vehicleId": &graphqlgo.Field{Type: graphqlgo.NewNonNull(graphqlgo.ID)},
+			"status": &graphqlgo.Field{
+				Type:        graphqlgo.NewNonNull(graphqlgo.String),
+				Description: "success, or error if GM failed to execute the action",
+			},
+		},
+	})
+
+	query := graphqlgo.NewObject(graphqlgo.ObjectConfig{
+		Name: "Query",
+		Fields: graphqlgo.Fields{
+			"vehicle": &graphqlgo.Field{
+				Type: vehicleType,
+				Args: graphqlgo.FieldConfigArgument{
+					"id": &graphqlgo.ArgumentConfig{Type: graphqlgo.NewNonNull(graphqlgo.ID)},
+				},
+				Resolve: func(p graphqlgo.ResolveParams) (interface{}, error) {
+					vehicleID, err := parseVehicleID(p.Context, p.Args["id"])
+					if err != nil {
+						return nil, err
+					}
+					return vehicleRef{vehicleID}, nil
+				},
+			},
+			"vehicles": &graphqlgo.Field{
+				Type:        graphqlgo.NewNonNull(graphqlgo.NewList(graphqlgo.NewNonNull(vehicleType))),
+				Description: "The vehicles given by ids, and those registered in group. Duplicates are only listed once",
+				Args: graphqlgo.FieldConfigArgument{
+					"ids":    &graphqlgo.ArgumentConfig{Type: graphqlgo.NewList(graphqlgo.NewNonNull(graphqlgo.ID))},
+					"group":  &graphqlgo.ArgumentConfig{Type: graphqlgo.String},
+					"offset": &graphqlgo.ArgumentConfig{Type: graphqlgo.Int, DefaultValue: 0},
+					"limit":  &graphqlgo.ArgumentConfig{Type: graphqlgo.Int, DefaultValue: vehicle.DEFAULT_BATCH_LIMIT},
+				},
+				Resolve: func(p graphqlgo.ResolveParams) (interface{}, error) {
+					return resolveVehicles(p, registryService)
+				},
+			},
+		},
+	})
+
+	mutation := graphqlgo.NewObject(graphqlgo.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphqlgo.Fields{
+			"engineAction": &graphqlgo.Field{
+				Type:        graphqlgo.NewNonNull(engineActionResultType),
+				Description: "Starts or stops the engine of a vehicle. Requires the vehicles:command scope",
+				Args: graphqlgo.FieldConfigArgument{
+					"vehicleId": &graphqlgo.ArgumentConfig{Type: graphqlgo.NewNonNull(graphqlgo.ID)},
+					"action":    &graphqlgo.ArgumentConfig{Type: graphqlgo.NewNonNull(engineActionType)},
+				},
+				Resolve: func(p graphqlgo.ResolveParams) (interface{}, error) {
+					principal, _ := auth.PrincipalFromContext(p.Context)
+					if apiErr := principal.Authorize(auth.SCOPE_VEHICLES_COMMAND); apiErr != nil {
+						return nil, newFieldError(p.Context, apiErr)
+					}
+
+					vehicleID, err := parseVehicleID(p.Context, p.Args["vehicleId"])
+					if err != nil {
+						return nil, err
+					}
+
+					action, _ := p.Args["action"].(string)
+					res, apiErr := vehicleService.SendEngineAction(vehicleID, vehicle.EngineActionRequest{Action: action})
+					if apiErr != nil {
+						return nil, newFieldError(p.Context, apiErr)
+					}
+
+					return map[string]interface{}{"vehicleId": strconv.FormatInt(vehicleID, 10), "status": res.Action}, nil
+				},
+			},
+		},
+	})
+
+	return graphqlgo.NewSchema(graphqlgo.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+// resolveVehicles ... pages through the vehicles given by ids and group, as a batch request over REST does
+func resolveVehicles(p graphqlgo.ResolveParams, registryService registry.Service) (interface{}, error) {
+	var req vehicle.BatchRequest
+
+	ids, _ := p.Args["ids"].([]interface{})
+	for _, id := range ids {
+		vehicleID, err := parseVehicleID(p.Context, id)
+		if err != nil {
+			return nil, err
+		}
+		req.VehicleIDs = append(req.VehicleIDs, vehicleID)
+	}
+
+	if group, _ := p.Args["group"].(string); group != "" {
+		groupIDs, apiErr := registryService.ResolveVehicleIDs(registry.Filter{Group: group})
+		if apiErr != nil {
+			return nil, newFieldError(p.Context, apiErr)
+		}
+		req.VehicleIDs = append(req.VehicleIDs, groupIDs...)
+		req.Group = group
+	}
+
+	if len(ids) == 0 && req.Group == "" {
+		return nil, badArgument(p.Context, "ids or group is required")
+	}
+
+	offset, _ := p.Args["offset"].(int)
+	limit, _ := p.Args["limit"].(int)
+	if offset < 0 {
+		return nil, badArgument(p.Context, "offset must be at least 0")
+	}
+	if limit < 1 || limit > vehicle.DEFAULT_BATCH_LIMIT {
+		return nil, badArgument(p.Context, fmt.Sprintf("limit must be between 1 and %d", vehicle.DEFAULT_BATCH_LIMIT))
+	}
+	req.Offset = int64(offset)
+	pageLimit := int64(limit)
+	req.Limit = &pageLimit
+
+	vehicleIDs, _ := req.Page()
+	res := make([]vehicleRef, 0, len(vehicleIDs))
+	for _, vehicleID := range vehicleIDs {
+		res = append(res, vehicleRef{vehicleID})
+	}
+	return res, nil
+}
+
+// resolveSection ... returns a resolver reading a field from the snapshot section of the vehicle. The section is fetched by
+// the request's loader, batched with the sections the other fields of the query need
+func resolveSection(section string, field func(*vehicle.Snapshot) (interface{}, *shared.APIError)) graphqlgo.FieldResolveFn {
+	return func(p graphqlgo.ResolveParams) (interface{}, error) {
+		l := p.Context.Value(contextKeyLoader).(*loader)
+		thunk := l.load(p.Source.(vehicleRef).id, section)
+
+		return func() (interface{}, error) {
+			snapshot, apiErr := thunk()
+			if apiErr != nil {
+				return nil, fieldError{apiErr}
+			}
+
+			value, apiErr := field(snapshot)
+			if apiErr != nil {
+				return nil, fieldError{apiErr}
+			}
+			return value, nil
+		}, nil
+	}
+}
+
+// resolveInfo ... returns a resolver reading a field from the vehicle section
+func resolveInfo(field func(*vehicle.Vehicle) interface{}) graphqlgo.FieldResolveFn {
+	return resolveSection(vehicle.SECTION_VEHICLE, func(s *vehicle.Snapshot) (interface{}, *shared.APIError) {
+		if s.Vehicle.Data == nil {
+			return nil, s.Vehicle.Err()
+		}
+		return field(s.Vehicle.Data), nil
+	})
+}
+
+func parseVehicleID(ctx context.Context, id interface{}) (int64, error) {
+	s, _ := id.(string)
+	vehicleID, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || vehicleID <= 0 {
+		return 0, badArgument(ctx, fmt.Sprintf("Invalid vehicle ID %q", s))
+	}
+	return vehicleID, nil
+}
@@ -0,0 +1,80 @@
+package graphql
+
+import "github.com/graphql-go/graphql/language/location"
+
+// Request ... a GraphQL request. Query may be left out when the persistedQuery extension names a known query
+//
+// swagger:model GraphQLRequest
+type Request struct {
+	// Query ... the GraphQL document
+	//
+	// example: { vehicles(ids: ["1234", "1235"]) { id vin fuel { percentage } } }
+	Query string `json:"query"`
+
+	// OperationName ... the operation to run when the document has more than one
+	//
+	// example: Fleet
+	OperationName string `json:"operationName"`
+
+	// Variables ... the values of the variables the operation declares
+	Variables map[string]interface{} `json:"variables"`
+
+	Extensions *RequestExtensions `json:"extensions,omitempty"`
+}
+
+// RequestExtensions ... extensions to the GraphQL request
+//
+// swagger:model GraphQLRequestExtensions
+type RequestExtensions struct {
+	PersistedQuery *PersistedQuery `json:"persistedQuery,omitempty"`
+}
+
+// PersistedQuery ... names a query by its hash, so clients don't have to send the document
+//
+// swagger:model GraphQLPersistedQuery
+type PersistedQuery struct {
+	// Version
+	//
+	// example: 1
+	Version int `json:"version"`
+
+	// SHA256Hash ... the hex SHA-256 of the query document
+	//
+	// example: 2c3c0b7b7c4b0f6f5e0c9d1b8f1d4c9a6b2e1f0d3c5a7b9e8d6f4a2c1b3e5d7f
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+// Response ... the result of a GraphQL request. Errors of individual fields are listed in errors, next to the data that
+// could be resolved
+//
+// swagger:model GraphQLResponse
+type Response struct {
+	// Data ... shaped like the query
+	Data interface{} `json:"data,omitempty"`
+
+	Errors []ResponseError `json:"errors,omitempty"`
+}
+
+// ResponseError ... a GraphQL error
+//
+// swagger:model GraphQLError
+type ResponseError struct {
+	// Message
+	//
+	// required: true
+	// example: Failed to get vehicle
+	Message string `json:"message"`
+
+	// Locations ... where in the query the error occurred
+	Locations []location.SourceLocation `json:"locations,omitempty"`
+
+	// Path ... the field the error occurred in
+	//
+	// example: ["vehicles", 1, "vin"]
+	Path []interface{} `json:"path,omitempty"`
+
+	// Extensions ... code is the HTTP status the same error has over REST
+	//
+	// example: {"code": 500}
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
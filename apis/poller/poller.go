@@ -0,0 +1,636 @@
+package poller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"app_api/apis/registry"
+	"app_api/apis/vehicle"
+	"app_api/shared"
+	loghelper "app_api/shared/loghelpers"
+	"app_api/shared/store"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// TICK_INTERVAL ... how often the poller looks for sections that are due
+	TICK_INTERVAL = time.Second
+
+	// DEFAULT_SYNC_INTERVAL ... how often the registered vehicles are reloaded, picking up vehicles that were registered or removed
+	DEFAULT_SYNC_INTERVAL = time.Minute
+)
+
+var (
+	schedulesBucket = []byte("poller_schedules")
+	stateBucket     = []byte("poller_state")
+	pausedKey       = []byte("paused")
+)
+
+// Service ... represents an instance of the poller package service interface.
+// The poller reads the status of every registered vehicle from GM through the vehicle service on a schedule,
+// so readings are recorded even when no client is asking for them
+type Service interface {
+	Run(ctx context.Context)
+	Status() Status
+	Pause() (res Status, err *shared.APIError)
+	Resume() (res Status, err *shared.APIError)
+
+	ListSchedules() (res []Schedule, err *shared.APIError)
+	GetSchedule(vehicleID int64) (res Schedule, err *shared.APIError)
+	SetSchedule(vehicleID int64, req ScheduleRequest) (res Schedule, err *shared.APIError)
+	ResetSchedule(vehicleID int64) (res Schedule, err *shared.APIError)
+	PauseSchedule(vehicleID int64) (res Schedule, err *shared.APIError)
+	ResumeSchedule(vehicleID int64) (res Schedule, err *shared.APIError)
+	RefreshSchedule(vehicleID int64) (res Schedule, err *shared.APIError)
+
+	SetConcurrency(n int)
+}
+
+// Option ... configures optional behaviour of the poller service
+type Option func(*service)
+
+// WithConcurrency ... sets the maximum number of polls in flight against GM at once
+func WithConcurrency(n int) Option {
+	return func(s *service) {
+		if n > 0 {
+			s.concurrency = n
+		}
+	}
+}
+
+// WithDefaultInterval ... sets how often a section is polled for vehicles without their own schedule. Zero stops polling it by default
+func WithDefaultInterval(section string, interval time.Duration) Option {
+	return func(s *service) {
+		if interval >= 0 {
+			s.defaults[section] = interval
+		}
+	}
+}
+
+// WithSyncInterval ... sets how often the registered vehicles are reloaded
+func WithSyncInterval(interval time.Duration) Option {
+	return func(s *service) {
+		if interval > 0 {
+			s.syncInterval = interval
+		}
+	}
+}
+
+// NewService ... returns an instance of the poller package service. Schedules and the paused state are persisted in the given database,
+// the poll history is kept in memory
+func NewService(db *bolt.DB, vehicleService vehicle.Service, registryService registry.Service, opts ...Option) (Service, error) {
+	s := &service{
+		db:           db,
+		vehicles:     vehicleService,
+		registry:     registryService,
+		concurrency:  DEFAULT_CONCURRENCY,
+		defaults:     make(map[string]time.Duration),
+		syncInterval: DEFAULT_SYNC_INTERVAL,
+		entries:      make(map[int64]*entry),
+		rand:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for section, interval := range DefaultIntervals {
+		s.defaults[section] = interval
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{schedulesBucket, stateBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		s.paused = tx.Bucket(stateBucket).Get(pausedKey) != nil
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+type service struct {
+	db       *bolt.DB
+	vehicles vehicle.Service
+	registry registry.Service
+
+	defaults     map[string]time.Duration
+	syncInterval time.Duration
+	now          func() time.Time
+	wg           sync.WaitGroup
+
+	mu          sync.Mutex
+	concurrency int
+	paused      bool
+	entries     map[int64]*entry
+	lastSync    time.Time
+	inFlight    int
+	polls       int64
+	failures    int64
+	rand        *rand.Rand
+}
+
+// storedSchedule ... the persisted part of a schedule. Nil intervals use the defaults
+type storedSchedule struct {
+	Intervals map[string]int64 `json:"intervals,omitempty"`
+	Paused    bool             `json:"paused"`
+}
+
+// entry ... the schedule of a registered vehicle
+type entry struct {
+	vehicleID int64
+	stored    storedSchedule
+	sections  map[string]*sectionState
+}
+
+type sectionState struct {
+	interval    time.Duration
+	next        time.Time
+	lastPolled  *time.Time
+	lastError   string
+	polls       int64
+	failures    int64
+	consecutive int64
+	inFlight    bool
+}
+
+func (s *service) timeNow() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now().UTC()
+}
+
+// Run ... polls due sections until the context is cancelled, then waits for the polls in flight
+func (s *service) Run(ctx context.Context) {
+	ticker := time.NewTicker(TICK_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		s.tick(s.timeNow())
+
+		select {
+		case <-ctx.Done():
+			s.wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick ... reloads the registered vehicles when a sync is due, then starts a poll for every due section while there is capacity.
+// Sections that don't fit are picked up by a later tick
+func (s *service) tick(now time.Time) {
+	s.mu.Lock()
+	syncDue := now.Sub(s.lastSync) >= s.syncInterval
+	s.mu.Unlock()
+
+	if syncDue {
+		s.sync(now)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused {
+		return
+	}
+
+	for _, e := range s.entries {
+		if e.stored.Paused {
+			continue
+		}
+		for _, section := range Sections {
+			st := e.sections[section]
+			if st.interval == 0 || st.inFlight || now.Before(st.next) {
+				continue
+			}
+
+			if s.inFlight >= s.concurrency {
+				return
+			}
+
+			st.inFlight = true
+			s.inFlight++
+			s.wg.Add(1)
+			go s.poll(e, section)
+		}
+	}
+}
+
+// poll ... reads a section of a vehicle through the vehicle service, which records the reading, and schedules the next poll
+// of the entry it was started for
+func (s *service) poll(e *entry, section string) {
+	defer s.wg.Done()
+
+	vehicleID := e.vehicleID
+	var apiErr *shared.APIError
+	switch section {
+	case vehicle.SECTION_DOORS:
+		_, apiErr = s.vehicles.GetVehicleDoors(vehicleID)
+	case vehicle.SECTION_FUEL:
+		_, apiErr = s.vehicles.GetVehicleFuel(vehicleID)
+	case vehicle.SECTION_BATTERY:
+		_, apiErr = s.vehicles.GetVehicleBattery(vehicleID)
+	}
+
+	if apiErr != nil {
+		apiErr.SetInternalErrorMessage(fmt.Sprintf("poller: failed to poll %s of vehicle %d", section, vehicleID))
+		loghelper.LogErrorsNoCTX(apiErr)
+	}
+
+	now := s.timeNow()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inFlight--
+	s.polls++
+	if apiErr != nil {
+		s.failures++
+	}
+
+	// the vehicle may have been removed from the registry while it was polled, and even registered again: the sections of
+	// a new entry have polls of their own
+	if s.entries[vehicleID] != e {
+		return
+	}
+
+	st := e.sections[section]
+	st.inFlight = false
+	st.polls++
+	st.lastPolled = &now
+	if apiErr != nil {
+		st.failures++
+		st.consecutive++
+		st.lastError = apiErr.ClientErrorMessage
+	} else {
+		st.consecutive = 0
+		st.lastError = ""
+	}
+	st.next = now.Add(s.delay(st))
+}
+
+// delay ... the time until the next poll of a section: its interval, doubled for every consecutive failure up to MAX_BACKOFF,
+// with up to 10% jitter either way so vehicles registered together drift apart. Callers must hold the lock
+func (s *service) delay(st *sectionState) time.Duration {
+	d := st.interval
+	if st.consecutive > 0 {
+		limit := MAX_BACKOFF
+		if d > limit {
+			limit = d
+		}
+
+		shift := st.consecutive
+		if shift > 10 {
+			shift = 10
+		}
+		d = d << uint(shift)
+		if d > limit {
+			d = limit
+		}
+	}
+
+	return d + time.Duration((s.rand.Float64()*0.2-0.1)*float64(d))
+}
+
+// spread ... schedules the first poll of a section at a random point within its interval, so a fleet registered at once
+// isn't polled all at once. Callers must hold the lock
+func (s *service) spread(st *sectionState, now time.Time) {
+	if st.interval == 0 {
+		st.next = time.Time{}
+		return
+	}
+	st.next = now.Add(time.Duration(s.rand.Int63n(int64(st.interval))))
+}
+
+// sync ... starts scheduling newly registered vehicles, and stops scheduling vehicles no longer registered
+func (s *service) sync(now time.Time) {
+	vehicleIDs, apiErr := s.registry.ResolveVehicleIDs(registry.Filter{})
+	if apiErr != nil {
+		apiErr.SetInternalErrorMessage("poller: failed to load registered vehicles")
+		loghelper.LogErrorsNoCTX(apiErr)
+
+		// retry on the next sync rather than on every tick
+		s.mu.Lock()
+		s.lastSync = now
+		s.mu.Unlock()
+		return
+	}
+
+	stored, err := s.loadSchedules()
+	if err != nil {
+		loghelper.LogErrorsNoCTX(shared.NewAPIError(http.StatusInternalServerError, err, "Failed to load schedules").
+			SetInternalErrorMessage("poller: failed to load schedules"))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	registered := make(map[int64]bool, len(vehicleIDs))
+	for _, vehicleID := range vehicleIDs {
+		registered[vehicleID] = true
+		if _, ok := s.entries[vehicleID]; !ok {
+			s.addEntry(vehicleID, stored[vehicleID], now)
+		}
+	}
+
+	for vehicleID := range s.entries {
+		if !registered[vehicleID] {
+			delete(s.entries, vehicleID)
+		}
+	}
+
+	s.lastSync = now
+}
+
+// addEntry ... starts scheduling a vehicle. Callers must hold the lock
+func (s *service) addEntry(vehicleID int64, stored storedSchedule, now time.Time) *entry {
+	e := &entry{
+		vehicleID: vehicleID,
+		stored:    stored,
+		sections:  make(map[string]*sectionState, len(Sections)),
+	}
+	for _, section := range Sections {
+		e.sections[section] = &sectionState{}
+	}
+	s.applyIntervals(e, now)
+
+	s.entries[vehicleID] = e
+	return e
+}
+
+// applyIntervals ... updates the intervals of the sections of a vehicle from its schedule, re-spreading the sections whose interval changed.
+// Callers must hold the lock
+func (s *service) applyIntervals(e *entry, now time.Time) {
+	for _, section := range Sections {
+		interval := s.defaults[section]
+		if seconds, ok := e.stored.Intervals[section]; ok {
+			interval = time.Duration(seconds) * time.Second
+		}
+
+		st := e.sections[section]
+		if st.interval != interval || st.next.IsZero() {
+			st.interval = interval
+			s.spread(st, now)
+		}
+	}
+}
+
+// Status ... returns the state of the poller as a whole
+func (s *service) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status()
+}
+
+// status ... callers must hold the lock
+func (s *service) status() (res Status) {
+	res.Status = POLLER_RUNNING
+	if s.paused {
+		res.Status = POLLER_PAUSED
+	}
+	res.Vehicles = len(s.entries)
+	res.InFlight = s.inFlight
+	res.Polls = s.polls
+	res.Failures = s.failures
+	if !s.lastSync.IsZero() {
+		lastSync := s.lastSync
+		res.LastSyncAt = &lastSync
+	}
+	return
+}
+
+// SetConcurrency ... changes the maximum number of polls in flight against GM at once. Polls beyond it which are already
+// in flight complete
+func (s *service) SetConcurrency(n int) {
+	if n <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.concurrency = n
+}
+
+// Pause ... stops starting new polls for every vehicle. Polls in flight complete
+func (s *service) Pause() (res Status, err *shared.APIError) {
+	return s.setPaused(true)
+}
+
+// Resume ... starts polling again, re-spreading the next polls so the backlog built up while paused isn't sent at once
+func (s *service) Resume() (res Status, err *shared.APIError) {
+	return s.setPaused(false)
+}
+
+func (s *service) setPaused(paused bool) (res Status, err *shared.APIError) {
+	txErr := s.db.Update(func(tx *bolt.Tx) error {
+		if paused {
+			return tx.Bucket(stateBucket).Put(pausedKey, []byte{1})
+		}
+		return tx.Bucket(stateBucket).Delete(pausedKey)
+	})
+	if txErr != nil {
+		err = shared.NewAPIError(http.StatusInternalServerError, txErr, "Failed to update poller")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused && !paused {
+		now := s.timeNow()
+		for _, e := range s.entries {
+			for _, st := range e.sections {
+				s.spread(st, now)
+			}
+		}
+	}
+	s.paused = paused
+
+	return s.status(), nil
+}
+
+// ListSchedules ... returns the schedule of every registered vehicle, ordered by vehicle ID
+func (s *service) ListSchedules() (res []Schedule, err *shared.APIError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res = make([]Schedule, 0, len(s.entries))
+	for _, e := range s.entries {
+		res = append(res, s.schedule(e))
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].VehicleID < res[j].VehicleID })
+	return
+}
+
+// GetSchedule ... returns the schedule of a registered vehicle
+func (s *service) GetSchedule(vehicleID int64) (res Schedule, err *shared.APIError) {
+	return s.updateSchedule(vehicleID, nil)
+}
+
+// SetSchedule ... sets how often the sections of a registered vehicle are polled
+func (s *service) SetSchedule(vehicleID int64, req ScheduleRequest) (res Schedule, err *shared.APIError) {
+	return s.updateSchedule(vehicleID, func(stored *storedSchedule) {
+		stored.Intervals = req.Intervals
+	})
+}
+
+// ResetSchedule ... returns a vehicle to the default intervals, and resumes it if it was paused
+func (s *service) ResetSchedule(vehicleID int64) (res Schedule, err *shared.APIError) {
+	return s.updateSchedule(vehicleID, func(stored *storedSchedule) {
+		*stored = storedSchedule{}
+	})
+}
+
+// PauseSchedule ... stops polling a vehicle until it is resumed
+func (s *service) PauseSchedule(vehicleID int64) (res Schedule, err *shared.APIError) {
+	return s.updateSchedule(vehicleID, func(stored *storedSchedule) {
+		stored.Paused = true
+	})
+}
+
+// ResumeSchedule ... starts polling a paused vehicle again
+func (s *service) ResumeSchedule(vehicleID int64) (res Schedule, err *shared.APIError) {
+	return s.updateSchedule(vehicleID, func(stored *storedSchedule) {
+		stored.Paused = false
+	})
+}
+
+// RefreshSchedule ... drops what the poller knows of a registered vehicle, its last polls, errors and backoff, and
+// polls every section of it again on the next tick. A poll in flight completes as usual
+func (s *service) RefreshSchedule(vehicleID int64) (res Schedule, err *shared.APIError) {
+	if _, err = s.updateSchedule(vehicleID, nil); err != nil {
+		return
+	}
+
+	now := s.timeNow()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[vehicleID]
+	if !ok {
+		requestErr := fmt.Errorf("vehicle %d is no longer scheduled", vehicleID)
+		err = shared.NewAPIError(http.StatusNotFound, requestErr, "Vehicle is not registered")
+		return
+	}
+	for _, st := range e.sections {
+		*st = sectionState{interval: st.interval, next: now, inFlight: st.inFlight}
+	}
+	return s.schedule(e), nil
+}
+
+// updateSchedule ... applies update to the persisted schedule of a registered vehicle, then to the running schedule. A nil update only
+// reads the schedule. Vehicles registered since the last sync are scheduled straight away
+func (s *service) updateSchedule(vehicleID int64, update func(stored *storedSchedule)) (res Schedule, err *shared.APIError) {
+	if _, err = s.registry.GetVehicle(vehicleID); err != nil {
+		return
+	}
+
+	var stored storedSchedule
+	txErr := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(schedulesBucket)
+		key := store.Int64Key(vehicleID)
+
+		if v := bucket.Get(key); v != nil {
+			if err := json.Unmarshal(v, &stored); err != nil {
+				return err
+			}
+		}
+
+		if update == nil {
+			return nil
+		}
+		update(&stored)
+
+		if stored.Intervals == nil && !stored.Paused {
+			return bucket.Delete(key)
+		}
+		v, err := json.Marshal(stored)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, v)
+	})
+	if txErr != nil {
+		err = shared.NewAPIError(http.StatusInternalServerError, txErr, "Failed to update schedule")
+		return
+	}
+
+	now := s.timeNow()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[vehicleID]
+	if !ok {
+		e = s.addEntry(vehicleID, stored, now)
+	}
+
+	if e.stored.Paused && !stored.Paused {
+		for _, st := range e.sections {
+			s.spread(st, now)
+		}
+	}
+	e.stored = stored
+	s.applyIntervals(e, now)
+
+	return s.schedule(e), nil
+}
+
+// loadSchedules ... returns every persisted schedule by vehicle ID
+func (s *service) loadSchedules() (res map[int64]storedSchedule, err error) {
+	res = make(map[int64]storedSchedule)
+	err = s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(schedulesBucket).ForEach(func(k, v []byte) error {
+			var stored storedSchedule
+			if err := json.Unmarshal(v, &stored); err != nil {
+				return err
+			}
+			res[store.KeyInt64(k)] = stored
+			return nil
+		})
+	})
+	return
+}
+
+// schedule ... returns the response for the schedule of a vehicle. Callers must hold the lock
+func (s *service) schedule(e *entry) (res Schedule) {
+	res.VehicleID = e.vehicleID
+	res.Status = SCHEDULE_ACTIVE
+	if e.stored.Paused {
+		res.Status = SCHEDULE_PAUSED
+	}
+	res.Custom = e.stored.Intervals != nil
+
+	res.Sections = make([]SectionSchedule, 0, len(Sections))
+	for _, section := range Sections {
+		st := e.sections[section]
+		sectionSchedule := SectionSchedule{
+			Section:             section,
+			IntervalSeconds:     int64(st.interval / time.Second),
+			LastPolledAt:        st.lastPolled,
+			LastError:           st.lastError,
+			Polls:               st.polls,
+			Failures:            st.failures,
+			ConsecutiveFailures: st.consecutive,
+		}
+		if st.interval > 0 && !e.stored.Paused && !s.paused {
+			next := st.next
+			sectionSchedule.NextPollAt = &next
+		}
+		res.Sections = append(res.Sections, sectionSchedule)
+	}
+	return
+}
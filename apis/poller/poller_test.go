@@ -0,0 +1,310 @@
+package poller
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"app_api/apis/registry"
+	"app_api/apis/vehicle"
+	gmConnector "app_api/shared/gm"
+	"app_api/shared/store/storetest"
+
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+var start = time.Date(2020, 11, 2, 18, 0, 0, 0, time.UTC)
+
+// countingObserver ... counts the readings the vehicle service got from GM for each vehicle
+type countingObserver struct {
+	mu     sync.Mutex
+	energy map[int64]int
+	doors  map[int64]int
+}
+
+func (o *countingObserver) ObserveEnergy(vehicleID int64, fuel vehicle.Fuel, battery vehicle.Battery, observedAt time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.energy[vehicleID]++
+}
+
+func (o *countingObserver) ObserveDoors(vehicleID int64, doors []gmConnector.GMVehicleDoorData, observedAt time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.doors[vehicleID]++
+}
+
+type testPoller struct {
+	*service
+	registry registry.Service
+	observer *countingObserver
+	db       *bolt.DB
+	now      time.Time
+}
+
+// newTestPoller ... returns a poller over the mock GM connector, with the clock stopped at start
+func newTestPoller(t *testing.T, opts ...Option) *testPoller {
+	db := storetest.Open(t)
+
+	registryService, err := registry.NewService(db)
+	assert.NoError(t, err)
+
+	observer := &countingObserver{energy: make(map[int64]int), doors: make(map[int64]int)}
+	vehicleService := vehicle.NewService(gmConnector.NewMockGMAPIConnector(), vehicle.WithObserver(observer))
+
+	s, err := NewService(db, vehicleService, registryService, opts...)
+	assert.NoError(t, err)
+
+	p := &testPoller{service: s.(*service), registry: registryService, observer: observer, db: db, now: start}
+	p.service.now = func() time.Time { return p.now }
+	return p
+}
+
+// advance ... moves the clock forward and runs a tick, waiting for the polls it started
+func (p *testPoller) advance(d time.Duration) {
+	p.now = p.now.Add(d)
+	p.tick(p.now)
+	p.wg.Wait()
+}
+
+func TestPollsRegisteredVehicles(t *testing.T) {
+	p := newTestPoller(t)
+
+	_, err := p.registry.RegisterVehicle(1234, registry.RegistrationRequest{})
+	assert.Nil(t, err)
+
+	// the first tick syncs the registry and spreads the first polls within each interval
+	p.advance(0)
+	assert.Equal(t, 1, p.Status().Vehicles)
+
+	p.advance(15 * time.Minute)
+	assert.Equal(t, 1, p.observer.doors[1234])
+	assert.Equal(t, 2, p.observer.energy[1234], "fuel and battery are polled separately")
+
+	// doors are polled every 5 minutes, give or take 10%
+	p.advance(6 * time.Minute)
+	assert.Equal(t, 2, p.observer.doors[1234])
+
+	status := p.Status()
+	assert.Equal(t, POLLER_RUNNING, status.Status)
+	assert.Equal(t, int64(4), status.Polls)
+	assert.Equal(t, int64(0), status.Failures)
+}
+
+func TestPollFailureBacksOff(t *testing.T) {
+	p := newTestPoller(t, WithDefaultInterval(vehicle.SECTION_FUEL, 0), WithDefaultInterval(vehicle.SECTION_BATTERY, 0))
+
+	// the mock GM connector fails for unknown vehicles
+	_, err := p.registry.RegisterVehicle(1236, registry.RegistrationRequest{})
+	assert.Nil(t, err)
+
+	p.advance(0)
+	p.advance(5 * time.Minute)
+
+	schedule, err := p.GetSchedule(1236)
+	assert.Nil(t, err)
+	doors := schedule.Sections[0]
+	assert.Equal(t, vehicle.SECTION_DOORS, doors.Section)
+	assert.Equal(t, int64(1), doors.Failures)
+	assert.Equal(t, int64(1), doors.ConsecutiveFailures)
+	assert.Equal(t, "Failed to get vehicle", doors.LastError)
+
+	// the retry waits for twice the interval
+	assert.True(t, doors.NextPollAt.Sub(p.now) >= 9*time.Minute)
+	assert.Equal(t, int64(1), p.Status().Failures)
+}
+
+func TestRefreshSchedule(t *testing.T) {
+	p := newTestPoller(t, WithDefaultInterval(vehicle.SECTION_FUEL, 0), WithDefaultInterval(vehicle.SECTION_BATTERY, 0))
+
+	_, err := p.registry.RegisterVehicle(1236, registry.RegistrationRequest{})
+	assert.Nil(t, err)
+	p.advance(0)
+	p.advance(5 * time.Minute)
+
+	schedule, err := p.RefreshSchedule(1236)
+	assert.Nil(t, err)
+	doors := schedule.Sections[0]
+	assert.Equal(t, int64(0), doors.Failures)
+	assert.Equal(t, int64(0), doors.ConsecutiveFailures)
+	assert.Empty(t, doors.LastError)
+	assert.Nil(t, doors.LastPolledAt)
+	assert.Equal(t, p.now, *doors.NextPollAt, "the backoff is dropped, and the vehicle polled on the next tick")
+
+	_, err = p.RefreshSchedule(4321)
+	assert.NotNil(t, err)
+	assert.Equal(t, 404, err.ErrorCode)
+}
+
+func TestSetSchedule(t *testing.T) {
+	p := newTestPoller(t)
+
+	_, err := p.SetSchedule(1234, ScheduleRequest{Intervals: map[string]int64{vehicle.SECTION_DOORS: 60}})
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusNotFound, err.ErrorCode, "Only registered vehicles can be scheduled")
+
+	_, err = p.registry.RegisterVehicle(1234, registry.RegistrationRequest{})
+	assert.Nil(t, err)
+
+	schedule, err := p.SetSchedule(1234, ScheduleRequest{Intervals: map[string]int64{vehicle.SECTION_DOORS: 60, vehicle.SECTION_FUEL: 0}})
+	assert.Nil(t, err)
+	assert.True(t, schedule.Custom)
+	assert.Equal(t, int64(60), schedule.Sections[0].IntervalSeconds)
+	assert.Equal(t, int64(0), schedule.Sections[1].IntervalSeconds)
+	assert.Nil(t, schedule.Sections[1].NextPollAt)
+	assert.Equal(t, int64(15*60), schedule.Sections[2].IntervalSeconds, "Sections left out keep the default")
+
+	p.advance(0)
+	p.advance(70 * time.Second)
+	p.advance(70 * time.Second)
+	assert.Equal(t, 2, p.observer.doors[1234])
+
+	// the schedule survives a restart
+	restarted, newErr := NewService(p.db, p.vehicles, p.registry)
+	assert.NoError(t, newErr)
+	schedule, err = restarted.GetSchedule(1234)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(60), schedule.Sections[0].IntervalSeconds)
+
+	schedule, err = p.ResetSchedule(1234)
+	assert.Nil(t, err)
+	assert.False(t, schedule.Custom)
+	assert.Equal(t, int64(5*60), schedule.Sections[0].IntervalSeconds)
+}
+
+func TestPauseAndResume(t *testing.T) {
+	p := newTestPoller(t)
+
+	_, err := p.registry.RegisterVehicle(1234, registry.RegistrationRequest{})
+	assert.Nil(t, err)
+	_, err = p.registry.RegisterVehicle(1235, registry.RegistrationRequest{})
+	assert.Nil(t, err)
+
+	schedule, err := p.PauseSchedule(1234)
+	assert.Nil(t, err)
+	assert.Equal(t, SCHEDULE_PAUSED, schedule.Status)
+	assert.Nil(t, schedule.Sections[0].NextPollAt)
+
+	p.advance(0)
+	p.advance(time.Hour)
+	assert.Equal(t, 0, p.observer.doors[1234])
+	assert.Equal(t, 1, p.observer.doors[1235])
+
+	status, err := p.Pause()
+	assert.Nil(t, err)
+	assert.Equal(t, POLLER_PAUSED, status.Status)
+
+	p.advance(time.Hour)
+	assert.Equal(t, 1, p.observer.doors[1235])
+
+	// the paused state survives a restart
+	restarted, newErr := NewService(p.db, p.vehicles, p.registry)
+	assert.NoError(t, newErr)
+	assert.Equal(t, POLLER_PAUSED, restarted.Status().Status)
+
+	_, err = p.Resume()
+	assert.Nil(t, err)
+	_, err = p.ResumeSchedule(1234)
+	assert.Nil(t, err)
+
+	p.advance(5*time.Minute + 30*time.Second)
+	assert.Equal(t, 1, p.observer.doors[1234])
+	assert.Equal(t, 2, p.observer.doors[1235])
+}
+
+func TestSyncRemovesUnregisteredVehicles(t *testing.T) {
+	p := newTestPoller(t)
+
+	_, err := p.registry.RegisterVehicle(1234, registry.RegistrationRequest{})
+	assert.Nil(t, err)
+
+	p.advance(0)
+	schedules, err := p.ListSchedules()
+	assert.Nil(t, err)
+	assert.Len(t, schedules, 1)
+
+	assert.Nil(t, p.registry.DeleteVehicle(1234))
+	p.advance(DEFAULT_SYNC_INTERVAL)
+
+	schedules, err = p.ListSchedules()
+	assert.Nil(t, err)
+	assert.Empty(t, schedules)
+}
+
+func TestPollOfRemovedEntryLeavesNewEntry(t *testing.T) {
+	p := newTestPoller(t)
+
+	_, err := p.registry.RegisterVehicle(1234, registry.RegistrationRequest{})
+	assert.Nil(t, err)
+	p.advance(0)
+
+	p.mu.Lock()
+	removed := p.entries[1234]
+	// a poll of the doors starts, as a tick would
+	removed.sections[vehicle.SECTION_DOORS].inFlight = true
+	p.inFlight++
+	p.mu.Unlock()
+
+	// while it is in flight the vehicle is removed and registered again, and the doors of its new entry are polled too
+	assert.Nil(t, p.registry.DeleteVehicle(1234))
+	p.advance(DEFAULT_SYNC_INTERVAL)
+	_, err = p.registry.RegisterVehicle(1234, registry.RegistrationRequest{})
+	assert.Nil(t, err)
+	p.advance(DEFAULT_SYNC_INTERVAL)
+
+	p.mu.Lock()
+	registered := p.entries[1234]
+	assert.NotSame(t, removed, registered)
+	registered.sections[vehicle.SECTION_DOORS].inFlight = true
+	p.mu.Unlock()
+
+	p.wg.Add(1)
+	p.poll(removed, vehicle.SECTION_DOORS)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := registered.sections[vehicle.SECTION_DOORS]
+	assert.True(t, st.inFlight, "the new entry's poll is still in flight")
+	assert.Nil(t, st.lastPolled)
+	assert.Zero(t, st.polls)
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	p := newTestPoller(t, WithConcurrency(1))
+
+	_, err := p.registry.RegisterVehicle(1234, registry.RegistrationRequest{})
+	assert.Nil(t, err)
+
+	p.advance(0)
+
+	// three sections are due, but only one poll can be in flight per tick while the others wait
+	p.now = p.now.Add(15 * time.Minute)
+	p.tick(p.now)
+	assert.True(t, p.Status().InFlight <= 1)
+	p.wg.Wait()
+
+	for i := 0; i < 3; i++ {
+		p.advance(0)
+	}
+	assert.Equal(t, 1, p.observer.doors[1234])
+	assert.Equal(t, 2, p.observer.energy[1234])
+
+	// once raised, the three sections due are polled in a single tick
+	p.SetConcurrency(3)
+	_, err = p.RefreshSchedule(1234)
+	assert.Nil(t, err)
+	p.advance(0)
+	assert.Equal(t, 2, p.observer.doors[1234])
+	assert.Equal(t, 4, p.observer.energy[1234])
+}
+
+func TestScheduleRequestValidate(t *testing.T) {
+	assert.Empty(t, ScheduleRequest{Intervals: map[string]int64{"doors": 30, "fuel": 0}}.Validate())
+
+	errs := ScheduleRequest{Intervals: map[string]int64{"doors": 10, "engine": 60}}.Validate()
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "intervals.doors", errs[0].Field)
+	assert.Equal(t, "intervals.engine", errs[1].Field)
+}
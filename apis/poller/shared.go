@@ -0,0 +1,196 @@
+package poller
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"app_api/apis/vehicle"
+	"app_api/shared"
+)
+
+const (
+	POLLER_RUNNING = "running"
+	POLLER_PAUSED  = "paused"
+
+	SCHEDULE_ACTIVE = "active"
+	SCHEDULE_PAUSED = "paused"
+
+	DEFAULT_CONCURRENCY = 5
+
+	// MIN_INTERVAL_SECONDS / MAX_INTERVAL_SECONDS ... bounds of a per-vehicle interval. Zero is also allowed, and stops polling the section
+	MIN_INTERVAL_SECONDS = 30
+	MAX_INTERVAL_SECONDS = 24 * 60 * 60
+
+	// MAX_BACKOFF ... the longest a failing section waits before being retried, unless its interval is longer
+	MAX_BACKOFF = time.Hour
+)
+
+// Sections ... the vehicle sections that can be polled. Fuel and battery come from the same GM call, so polling either records both
+var Sections = []string{vehicle.SECTION_DOORS, vehicle.SECTION_FUEL, vehicle.SECTION_BATTERY}
+
+// DefaultIntervals ... how often each section of a registered vehicle is polled unless the vehicle has its own schedule
+var DefaultIntervals = map[string]time.Duration{
+	vehicle.SECTION_DOORS:   5 * time.Minute,
+	vehicle.SECTION_FUEL:    15 * time.Minute,
+	vehicle.SECTION_BATTERY: 15 * time.Minute,
+}
+
+// ScheduleRequest ... request body for setting how often the sections of a vehicle are polled
+//
+// swagger:model ScheduleRequest
+type ScheduleRequest struct {
+	// Intervals ... seconds between polls of each section. Sections left out keep the default interval, 0 stops polling the section
+	//
+	// required: true
+	// example: {"doors": 60, "fuel": 0}
+	Intervals map[string]int64 `json:"intervals" validate:"required"`
+}
+
+// Validate ... every section must be known, with an interval of zero or within the allowed bounds
+func (req ScheduleRequest) Validate() (errs []shared.FieldError) {
+	sections := make([]string, 0, len(req.Intervals))
+	for section := range req.Intervals {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+
+	for _, section := range sections {
+		field := fmt.Sprintf("intervals.%s", section)
+		if !contains(Sections, section) {
+			errs = append(errs, shared.FieldError{Field: field, Rule: "oneof", Message: fmt.Sprintf("%s must be one of [doors, fuel, battery]", field)})
+			continue
+		}
+
+		seconds := req.Intervals[section]
+		if seconds != 0 && (seconds < MIN_INTERVAL_SECONDS || seconds > MAX_INTERVAL_SECONDS) {
+			message := fmt.Sprintf("%s must be 0, or between %d and %d seconds", field, MIN_INTERVAL_SECONDS, MAX_INTERVAL_SECONDS)
+			errs = append(errs, shared.FieldError{Field: field, Rule: "min", Message: message})
+		}
+	}
+	return
+}
+
+// SectionSchedule ... when a section of a vehicle is polled, and how its polls went
+//
+// swagger:model SectionSchedule
+type SectionSchedule struct {
+	// Section
+	//
+	// required: true
+	// enum: doors,fuel,battery
+	// example: doors
+	Section string `json:"section"`
+
+	// IntervalSeconds ... seconds between polls. 0 when the section isn't polled
+	//
+	// required: true
+	// example: 300
+	IntervalSeconds int64 `json:"intervalSeconds"`
+
+	// LastPolledAt
+	LastPolledAt *time.Time `json:"lastPolledAt,omitempty"`
+
+	// NextPollAt ... omitted while the section, vehicle or poller is paused
+	NextPollAt *time.Time `json:"nextPollAt,omitempty"`
+
+	// LastError ... why the last poll failed. Cleared by a successful poll
+	//
+	// example: Failed to get vehicle
+	LastError string `json:"lastError,omitempty"`
+
+	// Polls ... the number of polls since the service started
+	//
+	// required: true
+	// example: 12
+	Polls int64 `json:"polls"`
+
+	// Failures ... the number of failed polls since the service started
+	//
+	// required: true
+	// example: 1
+	Failures int64 `json:"failures"`
+
+	// ConsecutiveFailures ... failing sections are retried with an exponential backoff
+	//
+	// required: true
+	// example: 0
+	ConsecutiveFailures int64 `json:"consecutiveFailures"`
+}
+
+// Schedule response ... how a registered vehicle is polled
+//
+// swagger:model Schedule
+type Schedule struct {
+	// VehicleID
+	//
+	// required: true
+	// example: 1234
+	VehicleID int64 `json:"vehicleId"`
+
+	// Status
+	//
+	// required: true
+	// enum: active,paused
+	// example: active
+	Status string `json:"status"`
+
+	// Custom ... whether the intervals were set for this vehicle rather than being the defaults
+	//
+	// required: true
+	// example: false
+	Custom bool `json:"custom"`
+
+	// Sections
+	//
+	// required: true
+	Sections []SectionSchedule `json:"sections"`
+}
+
+// Status response ... the state of the poller as a whole
+//
+// swagger:model PollerStatus
+type Status struct {
+	// Status
+	//
+	// required: true
+	// enum: running,paused
+	// example: running
+	Status string `json:"status"`
+
+	// Vehicles ... the number of registered vehicles being scheduled
+	//
+	// required: true
+	// example: 42
+	Vehicles int `json:"vehicles"`
+
+	// InFlight ... the number of polls currently waiting on GM
+	//
+	// required: true
+	// example: 3
+	InFlight int `json:"inFlight"`
+
+	// Polls ... the number of polls since the service started
+	//
+	// required: true
+	// example: 1200
+	Polls int64 `json:"polls"`
+
+	// Failures ... the number of failed polls since the service started
+	//
+	// required: true
+	// example: 7
+	Failures int64 `json:"failures"`
+
+	// LastSyncAt ... when the registered vehicles were last loaded
+	LastSyncAt *time.Time `json:"lastSyncAt,omitempty"`
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
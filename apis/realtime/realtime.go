@@ -0,0 +1,415 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"app_api/apis/events"
+	"app_api/apis/registry"
+	"app_api/apis/vehicle"
+	"app_api/shared"
+	"app_api/shared/auth"
+	loghelper "app_api/shared/loghelpers"
+	"app_api/shared/validator"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// DEFAULT_SEND_BUFFER ... how many messages can wait to be written to a connection
+	DEFAULT_SEND_BUFFER   = 64
+	DEFAULT_WRITE_TIMEOUT = 10 * time.Second
+	// DEFAULT_MAX_DROPPED ... how many events in a row can be dropped for a connection before it is closed as too slow
+	DEFAULT_MAX_DROPPED = 256
+
+	// clients must answer pings, or send messages, within pongWait
+	pongWait     = 60 * time.Second
+	pingInterval = pongWait * 9 / 10
+
+	maxMessageSize = 4096
+)
+
+var errTooSlow = errors.New("connection fell too far behind")
+
+// Service ... represents an instance of the realtime package service interface
+type Service interface {
+	Serve(conn *websocket.Conn, principal auth.Principal)
+}
+
+// Option ... configures optional behaviour of the realtime service
+type Option func(*service)
+
+// WithSendBuffer ... sets how many messages can wait to be written to a connection before events are dropped for it
+func WithSendBuffer(n int) Option {
+	return func(s *service) {
+		if n > 0 {
+			s.sendBuffer = n
+		}
+	}
+}
+
+// WithWriteTimeout ... sets how long a write, or a wait for room to queue an ack, can take before the connection is closed
+func WithWriteTimeout(d time.Duration) Option {
+	return func(s *service) {
+		if d > 0 {
+			s.writeTimeout = d
+		}
+	}
+}
+
+// WithMaxDropped ... sets how many events in a row can be dropped for a connection before it is closed as too slow
+func WithMaxDropped(n int) Option {
+	return func(s *service) {
+		if n > 0 {
+			s.maxDropped = n
+		}
+	}
+}
+
+// NewService ... returns an instance of the realtime package service. Commands are sent through the vehicle service, events come
+// from the bus, and group subscriptions are resolved through the registry
+func NewService(vehicleService vehicle.Service, bus *events.Bus, registryService registry.Service, opts ...Option) Service {
+	s := &service{
+		vehicles:     vehicleService,
+		bus:          bus,
+		registry:     registryService,
+		sendBuffer:   DEFAULT_SEND_BUFFER,
+		writeTimeout: DEFAULT_WRITE_TIMEOUT,
+		maxDropped:   DEFAULT_MAX_DROPPED,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+type service struct {
+	vehicles vehicle.Service
+	bus      *events.Bus
+	registry registry.Service
+
+	sendBuffer   int
+	writeTimeout time.Duration
+	maxDropped   int
+}
+
+// connection ... a client connected over a socket. Messages from the client are handled one at a time, so a client
+// can't have more than one command in flight, and a client that stops reading has events dropped rather than queued
+type connection struct {
+	s         *service
+	ws        *websocket.Conn
+	principal auth.Principal
+
+	send chan Message
+	done chan struct{}
+	once sync.Once
+	// closeErr ... why the server closed the connection, sent to the client in the close frame
+	closeErr error
+
+	mu       sync.Mutex
+	vehicles map[int64]bool
+	groups   map[string]bool
+}
+
+// Serve ... handles a connection until either side closes it. The principal is the API key the connection was opened with,
+// and must be granted the same scopes as the equivalent REST routes: vehicles:read to subscribe, vehicles:command for commands
+func (s *service) Serve(ws *websocket.Conn, principal auth.Principal) {
+	c := s.newConnection(ws, principal)
+
+	sub := s.bus.Subscribe(s.sendBuffer, c.wants)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		c.writeLoop()
+	}()
+	go func() {
+		defer wg.Done()
+		c.eventLoop(sub)
+	}()
+
+	c.readLoop()
+
+	c.close(nil)
+	sub.Close()
+	wg.Wait()
+	ws.Close()
+}
+
+func (s *service) newConnection(ws *websocket.Conn, principal auth.Principal) *connection {
+	return &connection{
+		s:         s,
+		ws:        ws,
+		principal: principal,
+		send:      make(chan Message, s.sendBuffer),
+		done:      make(chan struct{}),
+		vehicles:  make(map[int64]bool),
+		groups:    make(map[string]bool),
+	}
+}
+
+// close ... ends the connection, the first error given is sent to the client
+func (c *connection) close(err error) {
+	c.once.Do(func() {
+		c.closeErr = err
+		close(c.done)
+	})
+}
+
+// readLoop ... handles the messages of the client until it disconnects or the connection is closed
+func (c *connection) readLoop() {
+	c.ws.SetReadLimit(maxMessageSize)
+	c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.ws.SetReadDeadline(time.Now().Add(pongWait))
+
+		var m Message
+		if err := json.Unmarshal(data, &m); err != nil {
+			c.reply(errorMessage("", shared.NewAPIError(http.StatusBadRequest, err, "Message must be a JSON object")))
+			continue
+		}
+
+		c.reply(c.handle(m))
+	}
+}
+
+// handle ... returns the ack or error answering a message of the client
+func (c *connection) handle(m Message) Message {
+	if errs := validator.Validate(m); len(errs) > 0 {
+		apiErr := shared.NewAPIError(http.StatusBadRequest, errors.New("message failed validation"), "Message failed validation").SetValidationErrors(errs)
+		return errorMessage(m.ID, apiErr)
+	}
+
+	switch m.Type {
+	case MESSAGE_SUBSCRIBE:
+		if apiErr := c.principal.Authorize(auth.SCOPE_VEHICLES_READ); apiErr != nil {
+			return errorMessage(m.ID, apiErr)
+		}
+		for _, group := range m.Groups {
+			if _, apiErr := c.s.registry.GetGroup(group); apiErr != nil {
+				return errorMessage(m.ID, apiErr)
+			}
+		}
+		return Message{Type: MESSAGE_ACK, ID: m.ID, Result: c.subscribe(m.VehicleIDs, m.Groups)}
+
+	case MESSAGE_UNSUBSCRIBE:
+		return Message{Type: MESSAGE_ACK, ID: m.ID, Result: c.unsubscribe(m.VehicleIDs, m.Groups)}
+
+	case MESSAGE_COMMAND:
+		if apiErr := c.principal.Authorize(auth.SCOPE_VEHICLES_COMMAND); apiErr != nil {
+			return errorMessage(m.ID, apiErr)
+		}
+		res, apiErr := c.s.vehicles.SendEngineAction(m.VehicleID, vehicle.EngineActionRequest{Action: m.Action})
+		if apiErr != nil {
+			return errorMessage(m.ID, apiErr)
+		}
+		return Message{Type: MESSAGE_ACK, ID: m.ID, Result: res}
+	}
+
+	// unreachable, the type is validated
+	return errorMessage(m.ID, shared.NewAPIError(http.StatusBadRequest, fmt.Errorf("unsupported message type %q", m.Type), "Unsupported message type"))
+}
+
+// reply ... queues an answer to the client. Unlike events, answers aren't dropped: a client that doesn't make room for one
+// within the write timeout is disconnected
+func (c *connection) reply(m Message) {
+	timer := time.NewTimer(c.s.writeTimeout)
+	defer timer.Stop()
+
+	select {
+	case c.send <- m:
+	case <-c.done:
+	case <-timer.C:
+		c.close(errTooSlow)
+	}
+}
+
+// eventLoop ... queues the events the connection is subscribed to. Events that don't fit in the send buffer are dropped, and the
+// client is told how many once it catches up
+func (c *connection) eventLoop(sub *events.Subscription) {
+	var dropped int
+	var busDropped int64
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if !c.matches(event) {
+				continue
+			}
+
+			// the bus drops events too, if this loop falls behind
+			if total := sub.Dropped(); total > busDropped {
+				dropped += int(total - busDropped)
+				busDropped = total
+			}
+
+			if dropped > 0 {
+				notice := Message{Type: MESSAGE_ERROR, Error: &Error{
+					Code:    http.StatusTooManyRequests,
+					Message: fmt.Sprintf("%d events were dropped as the connection fell behind", dropped),
+				}}
+				if c.trySend(notice) {
+					dropped = 0
+				}
+			}
+
+			if dropped > 0 || !c.trySend(Message{Type: MESSAGE_EVENT, Event: &event}) {
+				dropped++
+				if dropped > c.s.maxDropped {
+					c.close(errTooSlow)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *connection) trySend(m Message) bool {
+	select {
+	case c.send <- m:
+		return true
+	default:
+		return false
+	}
+}
+
+// writeLoop ... writes the queued messages and pings the client, until the connection is closed
+func (c *connection) writeLoop() {
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			code, text := websocket.CloseNormalClosure, ""
+			if c.closeErr != nil {
+				code, text = websocket.CloseTryAgainLater, c.closeErr.Error()
+			}
+			c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(c.s.writeTimeout))
+			// unblocks the read loop if the server is the one closing
+			c.ws.Close()
+			return
+		case m := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(c.s.writeTimeout))
+			if err := c.ws.WriteJSON(m); err != nil {
+				c.close(nil)
+			}
+		case <-ping.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.s.writeTimeout)); err != nil {
+				c.close(nil)
+			}
+		}
+	}
+}
+
+// wants ... the bus filter of the connection. It runs while the bus is locked, so group membership is checked later by matches
+func (c *connection) wants(event events.Event) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.vehicles[event.VehicleID] || len(c.groups) > 0
+}
+
+// matches ... returns true if the connection is subscribed to the vehicle of the event, or a group the vehicle is in
+func (c *connection) matches(event events.Event) bool {
+	c.mu.Lock()
+	if c.vehicles[event.VehicleID] {
+		c.mu.Unlock()
+		return true
+	}
+	groups := make(map[string]bool, len(c.groups))
+	for group := range c.groups {
+		groups[group] = true
+	}
+	c.mu.Unlock()
+
+	if len(groups) == 0 {
+		return false
+	}
+
+	registration, apiErr := c.s.registry.GetVehicle(event.VehicleID)
+	if apiErr != nil {
+		// unregistered vehicles aren't in any group
+		return false
+	}
+	for _, group := range registration.Groups {
+		if groups[group] {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *connection) subscribe(vehicleIDs []int64, groups []string) Subscription {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range vehicleIDs {
+		c.vehicles[id] = true
+	}
+	for _, group := range groups {
+		c.groups[group] = true
+	}
+	return c.subscription()
+}
+
+// unsubscribe ... without vehicles or groups, unsubscribes from everything
+func (c *connection) unsubscribe(vehicleIDs []int64, groups []string) Subscription {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(vehicleIDs) == 0 && len(groups) == 0 {
+		c.vehicles = make(map[int64]bool)
+		c.groups = make(map[string]bool)
+	}
+	for _, id := range vehicleIDs {
+		delete(c.vehicles, id)
+	}
+	for _, group := range groups {
+		delete(c.groups, group)
+	}
+	return c.subscription()
+}
+
+// subscription ... c.mu must be held
+func (c *connection) subscription() Subscription {
+	res := Subscription{VehicleIDs: make([]int64, 0, len(c.vehicles)), Groups: make([]string, 0, len(c.groups))}
+	for id := range c.vehicles {
+		res.VehicleIDs = append(res.VehicleIDs, id)
+	}
+	for group := range c.groups {
+		res.Groups = append(res.Groups, group)
+	}
+	sort.Slice(res.VehicleIDs, func(i, j int) bool { return res.VehicleIDs[i] < res.VehicleIDs[j] })
+	sort.Strings(res.Groups)
+	return res
+}
+
+// errorMessage ... answers a message with the error, which is also logged like the errors of REST requests
+func errorMessage(id string, apiErr *shared.APIError) Message {
+	loghelper.LogErrorsNoCTX(apiErr)
+	return Message{Type: MESSAGE_ERROR, ID: id, Error: &Error{
+		Code:             apiErr.ErrorCode,
+		Message:          apiErr.ClientErrorMessage,
+		ValidationErrors: apiErr.ValidationErrors,
+	}}
+}
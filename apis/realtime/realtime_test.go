@@ -0,0 +1,242 @@
+package realtime
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"app_api/apis/events"
+	"app_api/apis/registry"
+	"app_api/apis/vehicle"
+	"app_api/shared/auth"
+	gmConnector "app_api/shared/gm"
+	"app_api/shared/store/storetest"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+type testServer struct {
+	*httptest.Server
+	service  *service
+	bus      *events.Bus
+	registry registry.Service
+	// served ... receives once Serve returns for a connection
+	served chan struct{}
+}
+
+// newTestServer ... serves sockets for the principal over the mock GM connector and an empty registry
+func newTestServer(t *testing.T, principal auth.Principal, opts ...Option) *testServer {
+	registryService, err := registry.NewService(storetest.Open(t))
+	assert.NoError(t, err)
+
+	bus := events.NewBus()
+	s := NewService(vehicle.NewService(gmConnector.NewMockGMAPIConnector()), bus, registryService, opts...).(*service)
+
+	served := make(chan struct{}, 10)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		s.Serve(ws, principal)
+		served <- struct{}{}
+	}))
+
+	t.Cleanup(server.Close)
+
+	return &testServer{Server: server, service: s, bus: bus, registry: registryService, served: served}
+}
+
+func (ts *testServer) dial(t *testing.T) *websocket.Conn {
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	assert.NoError(t, err)
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+// exchange ... sends a message and returns the answer
+func exchange(t *testing.T, ws *websocket.Conn, m interface{}) Message {
+	assert.NoError(t, ws.WriteJSON(m))
+	return read(t, ws)
+}
+
+func read(t *testing.T, ws *websocket.Conn) (m Message) {
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	assert.NoError(t, ws.ReadJSON(&m))
+	return
+}
+
+// result ... decodes the result of an ack
+func result(t *testing.T, m Message, dst interface{}) {
+	b, err := json.Marshal(m.Result)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(b, dst))
+}
+
+func TestSubscribe(t *testing.T) {
+	ts := newTestServer(t, auth.Anonymous)
+	ws := ts.dial(t)
+
+	ack := exchange(t, ws, Message{Type: MESSAGE_SUBSCRIBE, ID: "1", VehicleIDs: []int64{1235, 1234}})
+	assert.Equal(t, MESSAGE_ACK, ack.Type)
+	assert.Equal(t, "1", ack.ID)
+	var sub Subscription
+	result(t, ack, &sub)
+	assert.Equal(t, Subscription{VehicleIDs: []int64{1234, 1235}, Groups: []string{}}, sub)
+
+	ts.bus.Publish(events.Event{Type: events.EVENT_FUEL_LOW, VehicleID: 9999})
+	published := ts.bus.Publish(events.Event{Type: events.EVENT_DOOR_UNLOCKED, VehicleID: 1234})
+
+	m := read(t, ws)
+	assert.Equal(t, MESSAGE_EVENT, m.Type)
+	assert.Equal(t, published.ID, m.Event.ID, "Events of other vehicles aren't sent")
+
+	ack = exchange(t, ws, Message{Type: MESSAGE_UNSUBSCRIBE, ID: "2", VehicleIDs: []int64{1234}})
+	result(t, ack, &sub)
+	assert.Equal(t, []int64{1235}, sub.VehicleIDs)
+
+	ts.bus.Publish(events.Event{Type: events.EVENT_DOOR_LOCKED, VehicleID: 1234})
+	published = ts.bus.Publish(events.Event{Type: events.EVENT_DOOR_LOCKED, VehicleID: 1235})
+	assert.Equal(t, published.ID, read(t, ws).Event.ID)
+
+	ack = exchange(t, ws, Message{Type: MESSAGE_UNSUBSCRIBE, ID: "3"})
+	result(t, ack, &sub)
+	assert.Empty(t, sub.VehicleIDs, "Unsubscribing without vehicles or groups unsubscribes from everything")
+}
+
+func TestSubscribeGroup(t *testing.T) {
+	ts := newTestServer(t, auth.Anonymous)
+	ws := ts.dial(t)
+
+	m := exchange(t, ws, Message{Type: MESSAGE_SUBSCRIBE, ID: "1", Groups: []string{"depot-7"}})
+	assert.Equal(t, MESSAGE_ERROR, m.Type)
+	assert.Equal(t, http.StatusNotFound, m.Error.Code)
+
+	_, err := ts.registry.CreateGroup(registry.GroupRequest{Name: "depot-7"})
+	assert.Nil(t, err)
+	assert.Equal(t, MESSAGE_ACK, exchange(t, ws, Message{Type: MESSAGE_SUBSCRIBE, ID: "2", Groups: []string{"depot-7"}}).Type)
+
+	// vehicles added to the group after subscribing are followed
+	_, err = ts.registry.AddGroupVehicle("depot-7", 1235)
+	assert.Nil(t, err)
+
+	ts.bus.Publish(events.Event{Type: events.EVENT_ENGINE_STARTED, VehicleID: 1234})
+	published := ts.bus.Publish(events.Event{Type: events.EVENT_ENGINE_STARTED, VehicleID: 1235})
+	assert.Equal(t, published.ID, read(t, ws).Event.ID)
+}
+
+func TestCommand(t *testing.T) {
+	ts := newTestServer(t, auth.Anonymous)
+	ws := ts.dial(t)
+
+	ack := exchange(t, ws, Message{Type: MESSAGE_COMMAND, ID: "1", VehicleID: 1234, Action: "START"})
+	assert.Equal(t, MESSAGE_ACK, ack.Type)
+	var res vehicle.EngineActionResponse
+	result(t, ack, &res)
+	assert.Equal(t, "success", res.Action)
+
+	m := exchange(t, ws, Message{Type: MESSAGE_COMMAND, ID: "2", VehicleID: 9999, Action: "STOP"})
+	assert.Equal(t, MESSAGE_ERROR, m.Type)
+	assert.Equal(t, "2", m.ID)
+	assert.Equal(t, http.StatusInternalServerError, m.Error.Code)
+}
+
+func TestInvalidMessages(t *testing.T) {
+	ts := newTestServer(t, auth.Anonymous)
+	ws := ts.dial(t)
+
+	assert.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte("not json")))
+	m := read(t, ws)
+	assert.Equal(t, MESSAGE_ERROR, m.Type)
+	assert.Equal(t, "Message must be a JSON object", m.Error.Message)
+
+	m = exchange(t, ws, Message{Type: MESSAGE_COMMAND, ID: "1", VehicleID: 1234, Action: "HONK"})
+	assert.Equal(t, http.StatusBadRequest, m.Error.Code)
+	assert.Equal(t, "action", m.Error.ValidationErrors[0].Field)
+
+	m = exchange(t, ws, Message{Type: MESSAGE_SUBSCRIBE, ID: "2"})
+	assert.Equal(t, "vehicleIds", m.Error.ValidationErrors[0].Field)
+
+	m = exchange(t, ws, Message{Type: MESSAGE_EVENT, ID: "3"})
+	assert.Equal(t, "type", m.Error.ValidationErrors[0].Field, "Server message types can't be sent by the client")
+}
+
+func TestScopes(t *testing.T) {
+	ts := newTestServer(t, auth.Principal{KeyID: "reader", Scopes: []string{auth.SCOPE_VEHICLES_READ}})
+	ws := ts.dial(t)
+
+	assert.Equal(t, MESSAGE_ACK, exchange(t, ws, Message{Type: MESSAGE_SUBSCRIBE, ID: "1", VehicleIDs: []int64{1234}}).Type)
+
+	m := exchange(t, ws, Message{Type: MESSAGE_COMMAND, ID: "2", VehicleID: 1234, Action: "START"})
+	assert.Equal(t, MESSAGE_ERROR, m.Type)
+	assert.Equal(t, http.StatusForbidden, m.Error.Code)
+	assert.Equal(t, "API key lacks the vehicles:command scope", m.Error.Message)
+
+	ts = newTestServer(t, auth.Principal{KeyID: "none"})
+	ws = ts.dial(t)
+	assert.Equal(t, http.StatusForbidden, exchange(t, ws, Message{Type: MESSAGE_SUBSCRIBE, ID: "1", VehicleIDs: []int64{1234}}).Error.Code)
+}
+
+func TestSlowConsumer(t *testing.T) {
+	s := NewService(nil, events.NewBus(), nil, WithSendBuffer(2), WithMaxDropped(3)).(*service)
+	c := s.newConnection(nil, auth.Anonymous)
+	c.subscribe([]int64{1234}, nil)
+
+	sub := s.bus.Subscribe(10, c.wants)
+	done := make(chan struct{})
+	go func() {
+		c.eventLoop(sub)
+		close(done)
+	}()
+
+	// nothing is written, so the send buffer fills up and later events are dropped
+	for i := 0; i < 4; i++ {
+		s.bus.Publish(events.Event{Type: events.EVENT_FUEL_LOW, VehicleID: 1234})
+	}
+	assert.Eventually(t, func() bool { return len(c.send) == 2 }, time.Second, time.Millisecond)
+
+	assert.Equal(t, int64(1), (<-c.send).Event.ID)
+	assert.Equal(t, int64(2), (<-c.send).Event.ID)
+
+	// once there's room, the client is told how many events it missed before the next event
+	s.bus.Publish(events.Event{Type: events.EVENT_FUEL_LOW, VehicleID: 1234})
+	notice := <-c.send
+	assert.Equal(t, MESSAGE_ERROR, notice.Type)
+	assert.Equal(t, http.StatusTooManyRequests, notice.Error.Code)
+	assert.Equal(t, "2 events were dropped as the connection fell behind", notice.Error.Message)
+	assert.Equal(t, int64(5), (<-c.send).Event.ID)
+
+	// a client that keeps falling behind is disconnected
+	for i := 0; i < 6; i++ {
+		s.bus.Publish(events.Event{Type: events.EVENT_FUEL_LOW, VehicleID: 1234})
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the connection wasn't closed")
+	}
+	assert.Equal(t, errTooSlow, c.closeErr)
+	sub.Close()
+}
+
+func TestClientDisconnect(t *testing.T) {
+	ts := newTestServer(t, auth.Anonymous)
+	ws := ts.dial(t)
+
+	assert.Equal(t, MESSAGE_ACK, exchange(t, ws, Message{Type: MESSAGE_SUBSCRIBE, ID: "1", VehicleIDs: []int64{1234}}).Type)
+	assert.NoError(t, ws.Close())
+
+	select {
+	case <-ts.served:
+	case <-time.After(time.Second):
+		t.Fatal("Serve didn't return once the client disconnected")
+	}
+
+	// events for the closed connection are no longer queued
+	ts.bus.Publish(events.Event{Type: events.EVENT_FUEL_LOW, VehicleID: 1234})
+}
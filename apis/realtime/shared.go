@@ -0,0 +1,126 @@
+package realtime
+
+import (
+	"app_api/apis/events"
+	"app_api/shared"
+)
+
+const (
+	// sent by the client
+	MESSAGE_SUBSCRIBE   = "subscribe"
+	MESSAGE_UNSUBSCRIBE = "unsubscribe"
+	MESSAGE_COMMAND     = "command"
+
+	// sent by the server
+	MESSAGE_ACK   = "ack"
+	MESSAGE_EVENT = "event"
+	MESSAGE_ERROR = "error"
+)
+
+// Message ... every message sent over the socket, in either direction. Only the fields of its type are set.
+//
+//	{"type": "subscribe", "id": "1", "vehicleIds": [1234], "groups": ["depot-7"]}
+//	{"type": "unsubscribe", "id": "2", "vehicleIds": [1234]}
+//	{"type": "command", "id": "3", "vehicleId": 1234, "action": "START"}
+//	{"type": "ack", "id": "3", "result": {"status": "success"}}
+//	{"type": "event", "event": {"id": 42, "type": "door_unlocked", "vehicleId": 1234, ...}}
+//	{"type": "error", "id": "3", "error": {"code": 403, "message": "API key lacks the vehicles:command scope"}}
+//
+// swagger:model SocketMessage
+type Message struct {
+	// Type
+	//
+	// required: true
+	// enum: subscribe,unsubscribe,command,ack,event,error
+	// example: subscribe
+	Type string `json:"type" validate:"required,oneof=subscribe unsubscribe command"`
+
+	// ID ... chosen by the client, and echoed in the ack or error answering the message
+	//
+	// example: 1
+	ID string `json:"id,omitempty" validate:"max=64"`
+
+	// VehicleIDs ... the vehicles to subscribe to or unsubscribe from
+	//
+	// example: [1234]
+	VehicleIDs []int64 `json:"vehicleIds,omitempty" validate:"max=1000"`
+
+	// Groups ... the groups to subscribe to or unsubscribe from. Events of every vehicle in the group are sent, including
+	// vehicles added to the group later
+	//
+	// example: ["depot-7"]
+	Groups []string `json:"groups,omitempty" validate:"max=50,pattern=^[a-z0-9][a-z0-9_-]{0,63}$"`
+
+	// VehicleID ... the vehicle a command is sent to
+	//
+	// example: 1234
+	VehicleID int64 `json:"vehicleId,omitempty"`
+
+	// Action ... the engine action of a command
+	//
+	// enum: START,STOP
+	// example: START
+	Action string `json:"action,omitempty" validate:"oneof=START STOP"`
+
+	// Result ... of the message an ack answers: the subscription after a subscribe or unsubscribe, the EngineActionResponse of a command
+	Result interface{} `json:"result,omitempty"`
+
+	Event *events.Event `json:"event,omitempty"`
+
+	Error *Error `json:"error,omitempty"`
+}
+
+// Validate ... the fields required depend on the type
+func (m Message) Validate() (errs []shared.FieldError) {
+	switch m.Type {
+	case MESSAGE_SUBSCRIBE:
+		if len(m.VehicleIDs) == 0 && len(m.Groups) == 0 {
+			errs = append(errs, shared.FieldError{Field: "vehicleIds", Rule: "required", Message: "vehicleIds or groups is required"})
+		}
+	case MESSAGE_COMMAND:
+		if m.VehicleID == 0 {
+			errs = append(errs, shared.FieldError{Field: "vehicleId", Rule: "required", Message: "vehicleId is required"})
+		}
+		if m.Action == "" {
+			errs = append(errs, shared.FieldError{Field: "action", Rule: "required", Message: "action is required"})
+		}
+	}
+	return
+}
+
+// Error ... why a message failed, or a notice that events were dropped
+//
+// swagger:model SocketError
+type Error struct {
+	// Code ... the HTTP status code the failure would have had over REST
+	//
+	// required: true
+	// example: 403
+	Code int `json:"code"`
+
+	// Message
+	//
+	// required: true
+	// example: API key lacks the vehicles:command scope
+	Message string `json:"message"`
+
+	// ValidationErrors ... the fields of the message that failed validation
+	ValidationErrors []shared.FieldError `json:"validationErrors,omitempty"`
+}
+
+// Subscription response ... the vehicles and groups a connection receives events of
+//
+// swagger:model SocketSubscription
+type Subscription struct {
+	// VehicleIDs
+	//
+	// required: true
+	// example: [1234]
+	VehicleIDs []int64 `json:"vehicleIds"`
+
+	// Groups
+	//
+	// required: true
+	// example: ["depot-7"]
+	Groups []string `json:"groups"`
+}
@@ -0,0 +1,541 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+
+	"app_api/shared"
+	"app_api/shared/store"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	vehiclesBucket = []byte("registry_vehicles")
+	groupsBucket   = []byte("registry_groups")
+
+	namePattern = regexp.MustCompile(NAME_PATTERN)
+
+	errVehicleNotFound = errors.New("vehicle not registered")
+	errGroupNotFound   = errors.New("group not found")
+	errGroupExists     = errors.New("group already exists")
+	errNotGroupMember  = errors.New("vehicle is not a member of the group")
+)
+
+// Service ... represents an instance of the registry package service interface
+type Service interface {
+	RegisterVehicle(vehicleID int64, req RegistrationRequest) (res Registration, err *shared.APIError)
+	GetVehicle(vehicleID int64) (res Registration, err *shared.APIError)
+	DeleteVehicle(vehicleID int64) (err *shared.APIError)
+	ListVehicles(filter Filter) (res []Registration, err *shared.APIError)
+	ResolveVehicleIDs(filter Filter) (vehicleIDs []int64, err *shared.APIError)
+
+	GetTags(vehicleID int64) (tags []string, err *shared.APIError)
+	AddTags(vehicleID int64, tags []string) (res []string, err *shared.APIError)
+	RemoveTag(vehicleID int64, tag string) (res []string, err *shared.APIError)
+
+	CreateGroup(req GroupRequest) (res Group, err *shared.APIError)
+	GetGroup(name string) (res Group, err *shared.APIError)
+	ListGroups() (res []Group, err *shared.APIError)
+	UpdateGroup(name string, req GroupUpdateRequest) (res Group, err *shared.APIError)
+	DeleteGroup(name string) (err *shared.APIError)
+	AddGroupVehicle(name string, vehicleID int64) (res Group, err *shared.APIError)
+	RemoveGroupVehicle(name string, vehicleID int64) (res Group, err *shared.APIError)
+}
+
+// NewService ... returns an instance of the registry package service, persisted in the given database
+func NewService(db *bolt.DB) (Service, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{vehiclesBucket, groupsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &service{db: db}, nil
+}
+
+type service struct {
+	db  *bolt.DB
+	now func() time.Time
+}
+
+// storedGroup ... group membership lives on the vehicle records, so it isn't persisted with the group
+type storedGroup struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// RegisterVehicle ... creates or replaces the registration for a vehicle
+func (s *service) RegisterVehicle(vehicleID int64, req RegistrationRequest) (res Registration, err *shared.APIError) {
+	txErr := s.db.Update(func(tx *bolt.Tx) error {
+		for _, group := range req.Groups {
+			if tx.Bucket(groupsBucket).Get([]byte(group)) == nil {
+				return fmt.Errorf("%w: %s", errGroupNotFound, group)
+			}
+		}
+
+		now := s.timeNow()
+		existing, getErr := getVehicle(tx, vehicleID)
+		switch {
+		case errors.Is(getErr, errVehicleNotFound):
+			res.CreatedAt = now
+		case getErr != nil:
+			return getErr
+		default:
+			res.CreatedAt = existing.CreatedAt
+		}
+
+		res.VehicleID = vehicleID
+		res.Nickname = req.Nickname
+		res.Tags = normalizeNames(req.Tags)
+		res.Groups = normalizeNames(req.Groups)
+		res.UpdatedAt = now
+
+		return putVehicle(tx, res)
+	})
+	if txErr != nil {
+		err = registryError(txErr, "Failed to register vehicle")
+		return
+	}
+
+	return
+}
+
+// GetVehicle ... returns the registration for a vehicle
+func (s *service) GetVehicle(vehicleID int64) (res Registration, err *shared.APIError) {
+	txErr := s.db.View(func(tx *bolt.Tx) (getErr error) {
+		res, getErr = getVehicle(tx, vehicleID)
+		return
+	})
+	if txErr != nil {
+		err = registryError(txErr, "Failed to get vehicle registration")
+	}
+	return
+}
+
+// DeleteVehicle ... removes the vehicle from the registry, along with its tags and group memberships
+func (s *service) DeleteVehicle(vehicleID int64) (err *shared.APIError) {
+	txErr := s.db.Update(func(tx *bolt.Tx) error {
+		if _, getErr := getVehicle(tx, vehicleID); getErr != nil {
+			return getErr
+		}
+		return tx.Bucket(vehiclesBucket).Delete(store.Int64Key(vehicleID))
+	})
+	if txErr != nil {
+		err = registryError(txErr, "Failed to delete vehicle registration")
+	}
+	return
+}
+
+// ListVehicles ... returns every registered vehicle matching the filter, ordered by vehicle ID
+func (s *service) ListVehicles(filter Filter) (res []Registration, err *shared.APIError) {
+	res = []Registration{}
+
+	txErr := s.db.View(func(tx *bolt.Tx) error {
+		if filter.Group != "" && tx.Bucket(groupsBucket).Get([]byte(filter.Group)) == nil {
+			return fmt.Errorf("%w: %s", errGroupNotFound, filter.Group)
+		}
+
+		return tx.Bucket(vehiclesBucket).ForEach(func(k, v []byte) error {
+			var registration Registration
+			if unmarshalErr := json.Unmarshal(v, &registration); unmarshalErr != nil {
+				return unmarshalErr
+			}
+
+			if filter.matches(registration) {
+				res = append(res, registration)
+			}
+			return nil
+		})
+	})
+	if txErr != nil {
+		err = registryError(txErr, "Failed to list vehicles")
+	}
+	return
+}
+
+// ResolveVehicleIDs ... returns the IDs of every registered vehicle matching the filter, e.g. every vehicle in a group
+func (s *service) ResolveVehicleIDs(filter Filter) (vehicleIDs []int64, err *shared.APIError) {
+	registrations, err := s.ListVehicles(filter)
+	if err != nil {
+		return
+	}
+
+	vehicleIDs = make([]int64, 0, len(registrations))
+	for _, registration := range registrations {
+		vehicleIDs = append(vehicleIDs, registration.VehicleID)
+	}
+	return
+}
+
+// GetTags ... returns the tags of a registered vehicle
+func (s *service) GetTags(vehicleID int64) (tags []string, err *shared.APIError) {
+	registration, err := s.GetVehicle(vehicleID)
+	if err != nil {
+		return
+	}
+	return registration.Tags, nil
+}
+
+// AddTags ... adds tags to a vehicle, registering the vehicle if it isn't already
+func (s *service) AddTags(vehicleID int64, tags []string) (res []string, err *shared.APIError) {
+	registration, err := s.updateVehicle(vehicleID, true, func(registration *Registration) error {
+		registration.Tags = normalizeNames(append(registration.Tags, tags...))
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	return registration.Tags, nil
+}
+
+// RemoveTag ... removes a single tag from a registered vehicle
+func (s *service) RemoveTag(vehicleID int64, tag string) (res []string, err *shared.APIError) {
+	registration, err := s.updateVehicle(vehicleID, false, func(registration *Registration) error {
+		registration.Tags = removeName(registration.Tags, tag)
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	return registration.Tags, nil
+}
+
+// CreateGroup ... creates an empty group
+func (s *service) CreateGroup(req GroupRequest) (res Group, err *shared.APIError) {
+	if !namePattern.MatchString(req.Name) {
+		requestErr := fmt.Errorf("Invalid group name: %s", req.Name)
+		err = shared.NewAPIError(http.StatusBadRequest, requestErr, "Group names must be lowercase letters, numbers, '-' or '_'")
+		return
+	}
+
+	txErr := s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(groupsBucket).Get([]byte(req.Name)) != nil {
+			return fmt.Errorf("%w: %s", errGroupExists, req.Name)
+		}
+
+		now := s.timeNow()
+		return putGroup(tx, storedGroup{Name: req.Name, Description: req.Description, CreatedAt: now, UpdatedAt: now})
+	})
+	if txErr != nil {
+		err = registryError(txErr, "Failed to create group")
+		return
+	}
+
+	return s.GetGroup(req.Name)
+}
+
+// GetGroup ... returns a group with its members
+func (s *service) GetGroup(name string) (res Group, err *shared.APIError) {
+	txErr := s.db.View(func(tx *bolt.Tx) error {
+		group, getErr := getGroup(tx, name)
+		if getErr != nil {
+			return getErr
+		}
+
+		members, membersErr := groupMembers(tx)
+		if membersErr != nil {
+			return membersErr
+		}
+
+		res = group.withMembers(members[name])
+		return nil
+	})
+	if txErr != nil {
+		err = registryError(txErr, "Failed to get group")
+	}
+	return
+}
+
+// ListGroups ... returns every group with its members, ordered by name
+func (s *service) ListGroups() (res []Group, err *shared.APIError) {
+	res = []Group{}
+
+	txErr := s.db.View(func(tx *bolt.Tx) error {
+		members, membersErr := groupMembers(tx)
+		if membersErr != nil {
+			return membersErr
+		}
+
+		return tx.Bucket(groupsBucket).ForEach(func(k, v []byte) error {
+			var group storedGroup
+			if unmarshalErr := json.Unmarshal(v, &group); unmarshalErr != nil {
+				return unmarshalErr
+			}
+
+			res = append(res, group.withMembers(members[group.Name]))
+			return nil
+		})
+	})
+	if txErr != nil {
+		err = registryError(txErr, "Failed to list groups")
+	}
+	return
+}
+
+// UpdateGroup ... updates the description of a group
+func (s *service) UpdateGroup(name string, req GroupUpdateRequest) (res Group, err *shared.APIError) {
+	txErr := s.db.Update(func(tx *bolt.Tx) error {
+		group, getErr := getGroup(tx, name)
+		if getErr != nil {
+			return getErr
+		}
+
+		group.Description = req.Description
+		group.UpdatedAt = s.timeNow()
+		return putGroup(tx, group)
+	})
+	if txErr != nil {
+		err = registryError(txErr, "Failed to update group")
+		return
+	}
+
+	return s.GetGroup(name)
+}
+
+// DeleteGroup ... deletes a group and removes it from its members. The vehicles stay registered
+func (s *service) DeleteGroup(name string) (err *shared.APIError) {
+	txErr := s.db.Update(func(tx *bolt.Tx) error {
+		if _, getErr := getGroup(tx, name); getErr != nil {
+			return getErr
+		}
+
+		var members []Registration
+		forEachErr := tx.Bucket(vehiclesBucket).ForEach(func(k, v []byte) error {
+			var registration Registration
+			if unmarshalErr := json.Unmarshal(v, &registration); unmarshalErr != nil {
+				return unmarshalErr
+			}
+			if containsName(registration.Groups, name) {
+				members = append(members, registration)
+			}
+			return nil
+		})
+		if forEachErr != nil {
+			return forEachErr
+		}
+
+		// the bucket can't be modified while it's being iterated, so members are updated afterwards
+		now := s.timeNow()
+		for _, registration := range members {
+			registration.Groups = removeName(registration.Groups, name)
+			registration.UpdatedAt = now
+			if putErr := putVehicle(tx, registration); putErr != nil {
+				return putErr
+			}
+		}
+
+		return tx.Bucket(groupsBucket).Delete([]byte(name))
+	})
+	if txErr != nil {
+		err = registryError(txErr, "Failed to delete group")
+	}
+	return
+}
+
+// AddGroupVehicle ... adds a vehicle to a group, registering the vehicle if it isn't already
+func (s *service) AddGroupVehicle(name string, vehicleID int64) (res Group, err *shared.APIError) {
+	_, err = s.updateVehicle(vehicleID, true, func(registration *Registration) error {
+		registration.Groups = normalizeNames(append(registration.Groups, name))
+		return nil
+	}, name)
+	if err != nil {
+		return
+	}
+
+	return s.GetGroup(name)
+}
+
+// RemoveGroupVehicle ... removes a vehicle from a group. The vehicle stays registered
+func (s *service) RemoveGroupVehicle(name string, vehicleID int64) (res Group, err *shared.APIError) {
+	_, err = s.updateVehicle(vehicleID, false, func(registration *Registration) error {
+		if !containsName(registration.Groups, name) {
+			return fmt.Errorf("%w: vehicle %d, group %s", errNotGroupMember, vehicleID, name)
+		}
+		registration.Groups = removeName(registration.Groups, name)
+		return nil
+	}, name)
+	if err != nil {
+		return
+	}
+
+	return s.GetGroup(name)
+}
+
+// updateVehicle ... applies update to a vehicle's registration in a single transaction.
+// If create is set, unregistered vehicles are registered; requiredGroups must exist for the update to be applied
+func (s *service) updateVehicle(vehicleID int64, create bool, update func(*Registration) error, requiredGroups ...string) (res Registration, err *shared.APIError) {
+	txErr := s.db.Update(func(tx *bolt.Tx) error {
+		for _, group := range requiredGroups {
+			if _, getErr := getGroup(tx, group); getErr != nil {
+				return getErr
+			}
+		}
+
+		now := s.timeNow()
+		registration, getErr := getVehicle(tx, vehicleID)
+		switch {
+		case errors.Is(getErr, errVehicleNotFound) && create:
+			registration = Registration{VehicleID: vehicleID, Tags: []string{}, Groups: []string{}, CreatedAt: now}
+		case getErr != nil:
+			return getErr
+		}
+
+		if updateErr := update(&registration); updateErr != nil {
+			return updateErr
+		}
+
+		registration.UpdatedAt = now
+		res = registration
+		return putVehicle(tx, registration)
+	})
+	if txErr != nil {
+		err = registryError(txErr, "Failed to update vehicle registration")
+	}
+	return
+}
+
+func (s *service) timeNow() time.Time {
+	if s.now != nil {
+		return s.now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+func (f Filter) matches(registration Registration) bool {
+	if f.Group != "" && !containsName(registration.Groups, f.Group) {
+		return false
+	}
+	if f.Tag != "" && !containsName(registration.Tags, f.Tag) {
+		return false
+	}
+	return true
+}
+
+func (g storedGroup) withMembers(members []int64) Group {
+	if members == nil {
+		members = []int64{}
+	}
+	return Group{
+		Name:        g.Name,
+		Description: g.Description,
+		VehicleIDs:  members,
+		CreatedAt:   g.CreatedAt,
+		UpdatedAt:   g.UpdatedAt,
+	}
+}
+
+func getVehicle(tx *bolt.Tx, vehicleID int64) (res Registration, err error) {
+	v := tx.Bucket(vehiclesBucket).Get(store.Int64Key(vehicleID))
+	if v == nil {
+		err = fmt.Errorf("%w: %d", errVehicleNotFound, vehicleID)
+		return
+	}
+	err = json.Unmarshal(v, &res)
+	return
+}
+
+func putVehicle(tx *bolt.Tx, registration Registration) error {
+	v, err := json.Marshal(registration)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(vehiclesBucket).Put(store.Int64Key(registration.VehicleID), v)
+}
+
+func getGroup(tx *bolt.Tx, name string) (res storedGroup, err error) {
+	v := tx.Bucket(groupsBucket).Get([]byte(name))
+	if v == nil {
+		err = fmt.Errorf("%w: %s", errGroupNotFound, name)
+		return
+	}
+	err = json.Unmarshal(v, &res)
+	return
+}
+
+func putGroup(tx *bolt.Tx, group storedGroup) error {
+	v, err := json.Marshal(group)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(groupsBucket).Put([]byte(group.Name), v)
+}
+
+// groupMembers ... returns the vehicle IDs of every group, in vehicle ID order
+func groupMembers(tx *bolt.Tx) (map[string][]int64, error) {
+	members := make(map[string][]int64)
+	err := tx.Bucket(vehiclesBucket).ForEach(func(k, v []byte) error {
+		var registration Registration
+		if err := json.Unmarshal(v, &registration); err != nil {
+			return err
+		}
+		for _, group := range registration.Groups {
+			members[group] = append(members[group], registration.VehicleID)
+		}
+		return nil
+	})
+	return members, err
+}
+
+// normalizeNames ... de-duplicates and sorts tag and group names
+func normalizeNames(names []string) []string {
+	seen := make(map[string]bool)
+	res := []string{}
+	for _, name := range names {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func removeName(names []string, name string) []string {
+	res := []string{}
+	for _, n := range names {
+		if n != name {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// registryError ... maps storage errors to API errors
+func registryError(err error, clientErr string) *shared.APIError {
+	switch {
+	case errors.Is(err, errVehicleNotFound):
+		return shared.NewAPIError(http.StatusNotFound, err, "Vehicle is not registered")
+	case errors.Is(err, errGroupNotFound):
+		return shared.NewAPIError(http.StatusNotFound, err, "Group not found")
+	case errors.Is(err, errNotGroupMember):
+		return shared.NewAPIError(http.StatusNotFound, err, "Vehicle is not a member of the group")
+	case errors.Is(err, errGroupExists):
+		return shared.NewAPIError(http.StatusConflict, err, "Group already exists")
+	default:
+		return shared.NewAPIError(http.StatusInternalServerError, err, clientErr)
+	}
+}
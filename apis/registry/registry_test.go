@@ -0,0 +1,208 @@
+package registry
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"app_api/shared/store"
+	"app_api/shared/store/storetest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestService ... returns an empty registry
+func newTestService(t *testing.T) Service {
+	service, err := NewService(storetest.Open(t))
+	assert.NoError(t, err)
+	return service
+}
+
+func TestRegisterVehicleSuccess(t *testing.T) {
+	service := newTestService(t)
+
+	_, err := service.CreateGroup(GroupRequest{Name: "depot-7"})
+	assert.Nil(t, err)
+
+	res, err := service.RegisterVehicle(1234, RegistrationRequest{Nickname: "Blue van", Tags: []string{"van", "electric", "van"}, Groups: []string{"depot-7"}})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1234), res.VehicleID)
+	assert.Equal(t, []string{"electric", "van"}, res.Tags)
+	assert.Equal(t, []string{"depot-7"}, res.Groups)
+
+	got, err := service.GetVehicle(1234)
+	assert.Nil(t, err)
+	assert.Equal(t, res, got)
+
+	// re-registering keeps the original creation time
+	updated, err := service.RegisterVehicle(1234, RegistrationRequest{Nickname: "Red van"})
+	assert.Nil(t, err)
+	assert.Equal(t, res.CreatedAt, updated.CreatedAt)
+	assert.Equal(t, "Red van", updated.Nickname)
+	assert.Empty(t, updated.Groups)
+}
+
+func TestRegisterVehicleFailureUnknownGroup(t *testing.T) {
+	service := newTestService(t)
+
+	_, err := service.RegisterVehicle(1234, RegistrationRequest{Groups: []string{"depot-7"}})
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusNotFound, err.ErrorCode)
+}
+
+func TestGetVehicleFailureNotRegistered(t *testing.T) {
+	service := newTestService(t)
+
+	_, err := service.GetVehicle(1234)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusNotFound, err.ErrorCode)
+}
+
+func TestDeleteVehicle(t *testing.T) {
+	service := newTestService(t)
+
+	_, err := service.RegisterVehicle(1234, RegistrationRequest{})
+	assert.Nil(t, err)
+
+	assert.Nil(t, service.DeleteVehicle(1234))
+
+	err = service.DeleteVehicle(1234)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusNotFound, err.ErrorCode)
+}
+
+func TestTags(t *testing.T) {
+	service := newTestService(t)
+
+	tags, err := service.AddTags(1234, []string{"van", "electric"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"electric", "van"}, tags)
+
+	tags, err = service.AddTags(1234, []string{"van", "leased"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"electric", "leased", "van"}, tags)
+
+	tags, err = service.RemoveTag(1234, "electric")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"leased", "van"}, tags)
+
+	tags, err = service.GetTags(1234)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"leased", "van"}, tags)
+
+	_, err = service.RemoveTag(1235, "van")
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusNotFound, err.ErrorCode)
+}
+
+func TestGroups(t *testing.T) {
+	service := newTestService(t)
+
+	group, err := service.CreateGroup(GroupRequest{Name: "depot-7", Description: "Depot 7"})
+	assert.Nil(t, err)
+	assert.Equal(t, "depot-7", group.Name)
+	assert.Equal(t, []int64{}, group.VehicleIDs)
+
+	_, err = service.CreateGroup(GroupRequest{Name: "depot-7"})
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusConflict, err.ErrorCode)
+
+	_, err = service.CreateGroup(GroupRequest{Name: "Depot 7"})
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.ErrorCode)
+
+	group, err = service.AddGroupVehicle("depot-7", 1235)
+	assert.Nil(t, err)
+	group, err = service.AddGroupVehicle("depot-7", 1234)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1234, 1235}, group.VehicleIDs)
+
+	group, err = service.UpdateGroup("depot-7", GroupUpdateRequest{Description: "Depot seven"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Depot seven", group.Description)
+
+	group, err = service.RemoveGroupVehicle("depot-7", 1235)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1234}, group.VehicleIDs)
+
+	_, err = service.RemoveGroupVehicle("depot-7", 1235)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusNotFound, err.ErrorCode)
+
+	groups, err := service.ListGroups()
+	assert.Nil(t, err)
+	assert.Len(t, groups, 1)
+
+	assert.Nil(t, service.DeleteGroup("depot-7"))
+
+	registration, err := service.GetVehicle(1234)
+	assert.Nil(t, err, "Deleting a group should keep its vehicles registered.")
+	assert.Empty(t, registration.Groups)
+
+	_, err = service.GetGroup("depot-7")
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusNotFound, err.ErrorCode)
+}
+
+func TestListVehiclesFilter(t *testing.T) {
+	service := newTestService(t)
+
+	_, err := service.CreateGroup(GroupRequest{Name: "depot-7"})
+	assert.Nil(t, err)
+	_, err = service.CreateGroup(GroupRequest{Name: "depot-8"})
+	assert.Nil(t, err)
+
+	_, err = service.RegisterVehicle(1234, RegistrationRequest{Tags: []string{"van"}, Groups: []string{"depot-7"}})
+	assert.Nil(t, err)
+	_, err = service.RegisterVehicle(1235, RegistrationRequest{Tags: []string{"sedan"}, Groups: []string{"depot-7", "depot-8"}})
+	assert.Nil(t, err)
+	_, err = service.RegisterVehicle(1236, RegistrationRequest{Tags: []string{"van"}})
+	assert.Nil(t, err)
+
+	all, err := service.ListVehicles(Filter{})
+	assert.Nil(t, err)
+	assert.Len(t, all, 3)
+
+	ids, err := service.ResolveVehicleIDs(Filter{Group: "depot-7"})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1234, 1235}, ids)
+
+	ids, err = service.ResolveVehicleIDs(Filter{Tag: "van"})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1234, 1236}, ids)
+
+	ids, err = service.ResolveVehicleIDs(Filter{Group: "depot-7", Tag: "van"})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1234}, ids)
+
+	_, err = service.ResolveVehicleIDs(Filter{Group: "depot-9"})
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusNotFound, err.ErrorCode)
+}
+
+func TestPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "registry")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "registry.db")
+
+	db, err := store.Open(path)
+	assert.NoError(t, err)
+	service, err := NewService(db)
+	assert.NoError(t, err)
+	_, apiErr := service.AddTags(1234, []string{"van"})
+	assert.Nil(t, apiErr)
+	db.Close()
+
+	db, err = store.Open(path)
+	assert.NoError(t, err)
+	defer db.Close()
+	service, err = NewService(db)
+	assert.NoError(t, err)
+
+	tags, apiErr := service.GetTags(1234)
+	assert.Nil(t, apiErr)
+	assert.Equal(t, []string{"van"}, tags)
+}
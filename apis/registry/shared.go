@@ -0,0 +1,135 @@
+package registry
+
+import "time"
+
+// NAME_PATTERN ... group names and tags are used in query strings and paths, so they are kept to a URL safe alphabet
+const NAME_PATTERN = `^[a-z0-9][a-z0-9_-]{0,63}$`
+
+// Registration response ... a vehicle known to the fleet
+//
+// swagger:model Registration
+type Registration struct {
+	// VehicleID
+	//
+	// required: true
+	// example: 1234
+	VehicleID int64 `json:"vehicleId"`
+
+	// Nickname
+	//
+	// example: Blue van
+	Nickname string `json:"nickname"`
+
+	// Tags
+	//
+	// required: true
+	// example: ["electric", "van"]
+	Tags []string `json:"tags"`
+
+	// Groups ... the groups the vehicle is a member of
+	//
+	// required: true
+	// example: ["depot-7"]
+	Groups []string `json:"groups"`
+
+	// required: true
+	CreatedAt time.Time `json:"createdAt"`
+
+	// required: true
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// RegistrationRequest ... request body for registering a vehicle, replacing any previous registration
+//
+// swagger:model RegistrationRequest
+type RegistrationRequest struct {
+	// Nickname
+	//
+	// maximum length: 100
+	// example: Blue van
+	Nickname string `json:"nickname" validate:"max=100"`
+
+	// Tags
+	//
+	// example: ["electric", "van"]
+	Tags []string `json:"tags" validate:"max=50,pattern=^[a-z0-9][a-z0-9_-]{0,63}$"`
+
+	// Groups ... the groups the vehicle is a member of. The groups must already exist
+	//
+	// example: ["depot-7"]
+	Groups []string `json:"groups" validate:"max=50,pattern=^[a-z0-9][a-z0-9_-]{0,63}$"`
+}
+
+// TagsRequest ... request body for adding tags to a vehicle
+//
+// swagger:model TagsRequest
+type TagsRequest struct {
+	// Tags
+	//
+	// required: true
+	// example: ["electric", "van"]
+	Tags []string `json:"tags" validate:"required,min=1,max=50,pattern=^[a-z0-9][a-z0-9_-]{0,63}$"`
+}
+
+// Group response ... a named set of vehicles, e.g. a depot
+//
+// swagger:model Group
+type Group struct {
+	// Name
+	//
+	// required: true
+	// example: depot-7
+	Name string `json:"name"`
+
+	// Description
+	//
+	// example: Vehicles parked at depot 7
+	Description string `json:"description"`
+
+	// VehicleIDs ... the members of the group
+	//
+	// required: true
+	// example: [1234, 1235]
+	VehicleIDs []int64 `json:"vehicleIds"`
+
+	// required: true
+	CreatedAt time.Time `json:"createdAt"`
+
+	// required: true
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// GroupRequest ... request body for creating a group
+//
+// swagger:model GroupRequest
+type GroupRequest struct {
+	// Name
+	//
+	// required: true
+	// pattern: ^[a-z0-9][a-z0-9_-]{0,63}$
+	// example: depot-7
+	Name string `json:"name" validate:"required,pattern=^[a-z0-9][a-z0-9_-]{0,63}$"`
+
+	// Description
+	//
+	// maximum length: 500
+	// example: Vehicles parked at depot 7
+	Description string `json:"description" validate:"max=500"`
+}
+
+// GroupUpdateRequest ... request body for updating a group
+//
+// swagger:model GroupUpdateRequest
+type GroupUpdateRequest struct {
+	// Description
+	//
+	// maximum length: 500
+	// example: Vehicles parked at depot 7
+	Description string `json:"description" validate:"max=500"`
+}
+
+// Filter ... selects registered vehicles. Empty fields match every vehicle
+type Filter struct {
+	Group string
+	Tag   string
+}
@@ -0,0 +1,158 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"app_api/shared"
+	"app_api/shared/auth"
+	loghelper "app_api/shared/loghelpers"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// METADATA_REQUEST_ID ... metadata key of the request ID. A client may send its own, the server always answers with one in the header
+	METADATA_REQUEST_ID = "x-request-id"
+
+	// METADATA_API_KEY ... metadata key of the API key. It may also be sent as "authorization: Bearer <key>"
+	METADATA_API_KEY = "x-api-key"
+
+	// METADATA_AUTHORIZATION ... metadata key of a bearer API key
+	METADATA_AUTHORIZATION = "authorization"
+)
+
+// methodScopes ... the scope each method requires, as the equivalent REST route does. Methods not listed are denied
+var methodScopes = map[string]string{
+	"/smartcar.vehicle.v1.VehicleService/GetVehicle":       auth.SCOPE_VEHICLES_READ,
+	"/smartcar.vehicle.v1.VehicleService/GetDoors":         auth.SCOPE_VEHICLES_READ,
+	"/smartcar.vehicle.v1.VehicleService/GetFuel":          auth.SCOPE_VEHICLES_READ,
+	"/smartcar.vehicle.v1.VehicleService/GetBattery":       auth.SCOPE_VEHICLES_READ,
+	"/smartcar.vehicle.v1.VehicleService/SendEngineAction": auth.SCOPE_VEHICLES_COMMAND,
+	"/smartcar.vehicle.v1.VehicleService/StreamUpdates":    auth.SCOPE_VEHICLES_READ,
+}
+
+type interceptors struct {
+	authenticator *auth.Authenticator
+}
+
+// wrappedStream ... a server stream with a replaced context
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *wrappedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (i *interceptors) unaryRequestID(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(i.requestID(ctx, info.FullMethod), req)
+}
+
+func (i *interceptors) streamRequestID(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &wrappedStream{ServerStream: stream, ctx: i.requestID(stream.Context(), info.FullMethod)})
+}
+
+// requestID ... adds the request ID and method to the context, as the Logger middleware does for REST requests, so errors
+// logged further down can be traced back to the call. The client's request ID is kept if it sent one
+func (i *interceptors) requestID(ctx context.Context, method string) context.Context {
+	var reqID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(METADATA_REQUEST_ID); len(values) > 0 {
+			reqID = values[0]
+		}
+	}
+	if reqID == "" {
+		reqID = uuid.New().String()
+	}
+
+	// the header is sent with the response or the first streamed message, whichever comes first
+	_ = grpc.SetHeader(ctx, metadata.Pairs(METADATA_REQUEST_ID, reqID))
+
+	ctx = context.WithValue(ctx, loghelper.ContextKeyRequestID, reqID)
+	return context.WithValue(ctx, loghelper.ContextKeyRequestPath, fmt.Sprintf("GRPC.%s", method))
+}
+
+func (i *interceptors) unaryLogger(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	res, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, start, err)
+	return res, err
+}
+
+func (i *interceptors) streamLogger(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	logCall(stream.Context(), info.FullMethod, start, err)
+	return err
+}
+
+// logCall ... logs a finished call. Streams are logged when they end, so Duration is how long the client was connected
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	var remoteAddress string
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddress = p.Addr.String()
+	}
+
+	log.WithContext(ctx).WithFields(log.Fields{
+		"Method":        method,
+		"RemoteAddress": remoteAddress,
+		"RequestID":     loghelper.GetRequestID(ctx),
+		"Code":          status.Code(err).String(),
+		"Duration":      time.Since(start).String(),
+	}).Info()
+}
+
+func (i *interceptors) unaryAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := i.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (i *interceptors) streamAuth(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := i.authorize(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &wrappedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authorize ... authenticates the API key in the metadata and checks it was granted the scope of the method. Returns the context
+// with the principal added
+func (i *interceptors) authorize(ctx context.Context, method string) (context.Context, error) {
+	scope, ok := methodScopes[method]
+	if !ok {
+		return nil, statusError(ctx, shared.NewAPIError(http.StatusForbidden, errors.New("no scope for method "+method), "Method not allowed"))
+	}
+
+	var apiKey, authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(METADATA_API_KEY); len(values) > 0 {
+			apiKey = values[0]
+		}
+		if values := md.Get(METADATA_AUTHORIZATION); len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+
+	principal, apiErr := i.authenticator.AuthenticateKey(auth.KeyFromHeaders(apiKey, authorization))
+	if apiErr != nil {
+		return nil, statusError(ctx, apiErr)
+	}
+	if apiErr := principal.Authorize(scope); apiErr != nil {
+		return nil, statusError(ctx, apiErr)
+	}
+
+	return auth.WithPrincipal(ctx, principal), nil
+}
@@ -0,0 +1,224 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"app_api/apis/events"
+	"app_api/apis/vehicle"
+	vehiclev1 "app_api/proto/vehicle/v1"
+	"app_api/shared"
+	"app_api/shared/auth"
+	loghelper "app_api/shared/loghelpers"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DEFAULT_STREAM_BUFFER ... how many events a stream can fall behind before events are dropped for it
+const DEFAULT_STREAM_BUFFER = 64
+
+var errUnsupportedAction = errors.New("action must be one of [ENGINE_ACTION_START, ENGINE_ACTION_STOP]")
+
+// NewServer ... returns a gRPC server for the VehicleService of proto/vehicle/v1, answered by the same vehicle service as
+// the REST API. Every call is logged, tagged with a request ID, and must carry an API key granted the scope of the method
+func NewServer(vehicleService vehicle.Service, bus *events.Bus, authenticator *auth.Authenticator, opts ...grpc.ServerOption) *grpc.Server {
+	interceptors := &interceptors{authenticator: authenticator}
+
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(interceptors.unaryRequestID, interceptors.unaryLogger, interceptors.unaryAuth),
+		grpc.ChainStreamInterceptor(interceptors.streamRequestID, interceptors.streamLogger, interceptors.streamAuth),
+	}, opts...)
+
+	s := grpc.NewServer(opts...)
+	vehiclev1.RegisterVehicleServiceServer(s, &server{vehicles: vehicleService, bus: bus})
+	return s
+}
+
+type server struct {
+	vehiclev1.UnimplementedVehicleServiceServer
+
+	vehicles vehicle.Service
+	bus      *events.Bus
+}
+
+// GetVehicle ... returns the info of a vehicle
+func (s *server) GetVehicle(ctx context.Context, req *vehiclev1.VehicleRequest) (*vehiclev1.Vehicle, error) {
+	res, apiErr := s.vehicles.GetVehicle(req.GetVehicleId())
+	if apiErr != nil {
+		return nil, statusError(ctx, apiErr)
+	}
+
+	return &vehiclev1.Vehicle{Vin: res.Vin, Color: res.Color, DoorCount: res.DoorCount, DriveTrain: res.DriveTrain}, nil
+}
+
+// GetDoors ... returns the lock status of every door of a vehicle
+func (s *server) GetDoors(ctx context.Context, req *vehiclev1.VehicleRequest) (*vehiclev1.Doors, error) {
+	doors, apiErr := s.vehicles.GetVehicleDoors(req.GetVehicleId())
+	if apiErr != nil {
+		return nil, statusError(ctx, apiErr)
+	}
+
+	res := &vehiclev1.Doors{Doors: make([]*vehiclev1.Door, 0, len(doors))}
+	for _, door := range doors {
+		res.Doors = append(res.Doors, &vehiclev1.Door{Location: door.Location, Locked: door.Locked})
+	}
+	return res, nil
+}
+
+// GetFuel ... returns the fuel level of a vehicle
+func (s *server) GetFuel(ctx context.Context, req *vehiclev1.VehicleRequest) (*vehiclev1.Fuel, error) {
+	res, apiErr := s.vehicles.GetVehicleFuel(req.GetVehicleId())
+	if apiErr != nil {
+		return nil, statusError(ctx, apiErr)
+	}
+
+	return &vehiclev1.Fuel{Percentage: double(res.Percentage)}, nil
+}
+
+// GetBattery ... returns the battery level of a vehicle
+func (s *server) GetBattery(ctx context.Context, req *vehiclev1.VehicleRequest) (*vehiclev1.Battery, error) {
+	res, apiErr := s.vehicles.GetVehicleBattery(req.GetVehicleId())
+	if apiErr != nil {
+		return nil, statusError(ctx, apiErr)
+	}
+
+	return &vehiclev1.Battery{Percentage: double(res.Percentage)}, nil
+}
+
+// SendEngineAction ... starts or stops the engine of a vehicle
+func (s *server) SendEngineAction(ctx context.Context, req *vehiclev1.EngineActionRequest) (*vehiclev1.EngineActionResponse, error) {
+	var action string
+	switch req.GetAction() {
+	case vehiclev1.EngineAction_ENGINE_ACTION_START:
+		action = vehicle.ENGINE_START
+	case vehiclev1.EngineAction_ENGINE_ACTION_STOP:
+		action = vehicle.ENGINE_STOP
+	default:
+		return nil, invalidArgument(ctx, errUnsupportedAction)
+	}
+
+	res, apiErr := s.vehicles.SendEngineAction(req.GetVehicleId(), vehicle.EngineActionRequest{Action: action})
+	if apiErr != nil {
+		return nil, statusError(ctx, apiErr)
+	}
+
+	return &vehiclev1.EngineActionResponse{Status: res.Action}, nil
+}
+
+// StreamUpdates ... sends the events of the requested vehicles until the client cancels. Events are dropped for a client that
+// falls more than DEFAULT_STREAM_BUFFER events behind
+func (s *server) StreamUpdates(req *vehiclev1.StreamUpdatesRequest, stream vehiclev1.VehicleService_StreamUpdatesServer) error {
+	var filter func(events.Event) bool
+	if len(req.GetVehicleIds()) > 0 {
+		vehicleIDs := make(map[int64]bool, len(req.GetVehicleIds()))
+		for _, id := range req.GetVehicleIds() {
+			vehicleIDs[id] = true
+		}
+		filter = func(event events.Event) bool { return vehicleIDs[event.VehicleID] }
+	}
+
+	var sub *events.Subscription
+	if req.GetLastEventId() > 0 {
+		// a stream that can't be resumed starts from the next event, as a new stream would
+		sub, _ = s.bus.SubscribeFrom(req.GetLastEventId(), DEFAULT_STREAM_BUFFER, filter)
+	} else {
+		sub = s.bus.Subscribe(DEFAULT_STREAM_BUFFER, filter)
+	}
+	defer sub.Close()
+
+	// the header, with the request ID, tells the client the stream is subscribed and no later event will be missed
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case event, ok := <-sub.Events():
+			if !ok {
+				return status.Error(codes.Unavailable, "The event stream was closed")
+			}
+
+			msg, err := eventMessage(event)
+			if err != nil {
+				return statusError(ctx, shared.NewAPIError(http.StatusInternalServerError, err, "Failed to send event"))
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func eventMessage(event events.Event) (*vehiclev1.Event, error) {
+	msg := &vehiclev1.Event{
+		Id:        event.ID,
+		Type:      event.Type,
+		VehicleId: event.VehicleID,
+		Data: &vehiclev1.EventData{
+			Location:   event.Data.Location,
+			Percentage: double(event.Data.Percentage),
+			Previous:   double(event.Data.Previous),
+			Threshold:  double(event.Data.Threshold),
+		},
+	}
+
+	if !event.Time.IsZero() {
+		t, err := ptypes.TimestampProto(event.Time)
+		if err != nil {
+			return nil, err
+		}
+		msg.Time = t
+	}
+	return msg, nil
+}
+
+// double ... nil stays unset, e.g. the fuel level of an electric vehicle
+func double(v *float64) *wrappers.DoubleValue {
+	if v == nil {
+		return nil
+	}
+	return &wrappers.DoubleValue{Value: *v}
+}
+
+func invalidArgument(ctx context.Context, err error) error {
+	return statusError(ctx, shared.NewAPIError(http.StatusBadRequest, err, fmt.Sprintf("Request failed validation: %s", err)))
+}
+
+// statusError ... logs the error as the REST API would, and returns it with the gRPC code matching its HTTP status
+func statusError(ctx context.Context, apiErr *shared.APIError) error {
+	loghelper.LogErrors(ctx, apiErr)
+	return status.Error(code(apiErr.ErrorCode), apiErr.ClientErrorMessage)
+}
+
+func code(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
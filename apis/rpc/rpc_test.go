@@ -0,0 +1,181 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"app_api/apis/events"
+	"app_api/apis/vehicle"
+	vehiclev1 "app_api/proto/vehicle/v1"
+	"app_api/shared/auth"
+	gmConnector "app_api/shared/gm"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient ... serves the API over an in-memory connection, answered from the mock GM connector
+func newTestClient(t *testing.T, bus *events.Bus, authenticator *auth.Authenticator) vehiclev1.VehicleServiceClient {
+	lis := bufconn.Listen(1024 * 1024)
+	s := NewServer(vehicle.NewService(gmConnector.NewMockGMAPIConnector()), bus, authenticator)
+	go s.Serve(lis)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithInsecure())
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		s.Stop()
+	})
+	return vehiclev1.NewVehicleServiceClient(conn)
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), METADATA_API_KEY, key)
+}
+
+func TestUnaryReads(t *testing.T) {
+	client := newTestClient(t, events.NewBus(), auth.New(nil))
+	ctx := context.Background()
+
+	v, err := client.GetVehicle(ctx, &vehiclev1.VehicleRequest{VehicleId: 1234})
+	assert.NoError(t, err)
+	assert.Equal(t, "123123412412", v.GetVin())
+	assert.Equal(t, "Metallic Silver", v.GetColor())
+	assert.Equal(t, int64(4), v.GetDoorCount())
+	assert.Equal(t, "v8", v.GetDriveTrain())
+
+	doors, err := client.GetDoors(ctx, &vehiclev1.VehicleRequest{VehicleId: 1234})
+	assert.NoError(t, err)
+	if assert.Len(t, doors.GetDoors(), 2) {
+		assert.Equal(t, "frontLeft", doors.GetDoors()[0].GetLocation())
+		assert.True(t, doors.GetDoors()[0].GetLocked())
+	}
+
+	fuel, err := client.GetFuel(ctx, &vehiclev1.VehicleRequest{VehicleId: 1234})
+	assert.NoError(t, err)
+	assert.Equal(t, 33.5, fuel.GetPercentage().GetValue())
+
+	fuel, err = client.GetFuel(ctx, &vehiclev1.VehicleRequest{VehicleId: 1235})
+	assert.NoError(t, err)
+	assert.Nil(t, fuel.GetPercentage(), "An electric vehicle has no fuel level")
+
+	battery, err := client.GetBattery(ctx, &vehiclev1.VehicleRequest{VehicleId: 1235})
+	assert.NoError(t, err)
+	assert.Equal(t, 88.55, battery.GetPercentage().GetValue())
+}
+
+func TestErrors(t *testing.T) {
+	client := newTestClient(t, events.NewBus(), auth.New(nil))
+	ctx := context.Background()
+
+	_, err := client.GetVehicle(ctx, &vehiclev1.VehicleRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetVehicle(ctx, &vehiclev1.VehicleRequest{VehicleId: 1236})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, "Failed to get vehicle", status.Convert(err).Message())
+
+	_, err = client.SendEngineAction(ctx, &vehiclev1.EngineActionRequest{VehicleId: 1234})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestSendEngineAction(t *testing.T) {
+	client := newTestClient(t, events.NewBus(), auth.New(nil))
+
+	res, err := client.SendEngineAction(context.Background(), &vehiclev1.EngineActionRequest{VehicleId: 1234, Action: vehiclev1.EngineAction_ENGINE_ACTION_START})
+	assert.NoError(t, err)
+	assert.Equal(t, "success", res.GetStatus())
+}
+
+func TestAuth(t *testing.T) {
+	client := newTestClient(t, events.NewBus(), auth.New(map[string][]string{
+		"reader": {auth.SCOPE_VEHICLES_READ},
+		"driver": {auth.SCOPE_VEHICLES_READ, auth.SCOPE_VEHICLES_COMMAND},
+	}))
+	read := &vehiclev1.VehicleRequest{VehicleId: 1234}
+	start := &vehiclev1.EngineActionRequest{VehicleId: 1234, Action: vehiclev1.EngineAction_ENGINE_ACTION_START}
+
+	_, err := client.GetVehicle(context.Background(), read)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.GetVehicle(withKey("wrong"), read)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.GetVehicle(withKey("reader"), read)
+	assert.NoError(t, err)
+
+	_, err = client.SendEngineAction(withKey("reader"), start)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	bearer := metadata.AppendToOutgoingContext(context.Background(), METADATA_AUTHORIZATION, "Bearer driver")
+	_, err = client.SendEngineAction(bearer, start)
+	assert.NoError(t, err)
+
+	stream, err := client.StreamUpdates(context.Background(), &vehiclev1.StreamUpdatesRequest{})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestRequestID(t *testing.T) {
+	client := newTestClient(t, events.NewBus(), auth.New(nil))
+
+	var header metadata.MD
+	_, err := client.GetVehicle(context.Background(), &vehiclev1.VehicleRequest{VehicleId: 1234}, grpc.Header(&header))
+	assert.NoError(t, err)
+	if assert.Len(t, header.Get(METADATA_REQUEST_ID), 1) {
+		assert.NotEmpty(t, header.Get(METADATA_REQUEST_ID)[0])
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), METADATA_REQUEST_ID, "trace-1")
+	_, err = client.GetVehicle(ctx, &vehiclev1.VehicleRequest{VehicleId: 1236}, grpc.Header(&header))
+	assert.Error(t, err)
+	assert.Equal(t, []string{"trace-1"}, header.Get(METADATA_REQUEST_ID), "The client's request ID is kept, also on errors")
+}
+
+func TestStreamUpdates(t *testing.T) {
+	bus := events.NewBus()
+	client := newTestClient(t, bus, auth.New(nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.StreamUpdates(ctx, &vehiclev1.StreamUpdatesRequest{VehicleIds: []int64{1234}})
+	assert.NoError(t, err)
+	_, err = stream.Header()
+	assert.NoError(t, err, "The header is sent once the stream is subscribed")
+
+	level := 14.2
+	now := time.Now()
+	bus.Publish(testEvent(1235, events.EVENT_FUEL_LOW, now, nil))
+	bus.Publish(testEvent(1234, events.EVENT_FUEL_LOW, now, &level))
+
+	event, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), event.GetId())
+	assert.Equal(t, int64(1234), event.GetVehicleId())
+	assert.Equal(t, events.EVENT_FUEL_LOW, event.GetType())
+	assert.Equal(t, 14.2, event.GetData().GetPercentage().GetValue())
+	assert.Nil(t, event.GetData().GetPrevious())
+	assert.Equal(t, now.UnixNano(), event.GetTime().AsTime().UnixNano())
+
+	resumed, err := client.StreamUpdates(ctx, &vehiclev1.StreamUpdatesRequest{LastEventId: 1})
+	assert.NoError(t, err)
+	event, err = resumed.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), event.GetId(), "Events published after last_event_id are replayed")
+}
+
+// testEvent ... an event of the type for the vehicle
+func testEvent(vehicleID int64, eventType string, at time.Time, percentage *float64) events.Event {
+	return events.Event{VehicleID: vehicleID, Type: eventType, Time: at, Data: events.EventData{Percentage: percentage}}
+}
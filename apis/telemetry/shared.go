@@ -0,0 +1,205 @@
+package telemetry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	"app_api/shared"
+)
+
+const (
+	METRIC_FUEL           = "fuel"
+	METRIC_BATTERY        = "battery"
+	METRIC_DOORS_UNLOCKED = "doors_unlocked"
+
+	AGGREGATION_MIN  = "min"
+	AGGREGATION_MAX  = "max"
+	AGGREGATION_AVG  = "avg"
+	AGGREGATION_LAST = "last"
+
+	// DEFAULT_HISTORY_RANGE ... how far back a history query goes when no start time is given
+	DEFAULT_HISTORY_RANGE = 24 * time.Hour
+
+	// DEFAULT_RETENTION ... how long readings are kept unless a retention is configured for the metric
+	DEFAULT_RETENTION = 30 * 24 * time.Hour
+
+	// WRITE_BUFFER ... how many readings wait for the background writer before new ones are dropped
+	WRITE_BUFFER = 4096
+
+	// FLUSH_INTERVAL ... how often the background writer stores the readings waiting for it, in a single transaction
+	FLUSH_INTERVAL = 100 * time.Millisecond
+
+	// MAX_HISTORY_POINTS ... the most points a single history query can return, raw or downsampled
+	MAX_HISTORY_POINTS = 10000
+)
+
+// Metrics ... every metric the store records
+var Metrics = []string{METRIC_FUEL, METRIC_BATTERY, METRIC_DOORS_UNLOCKED}
+
+// earliestTime, latestTime ... the range of times that can be counted in nanoseconds since the Unix epoch
+var (
+	earliestTime = time.Unix(0, math.MinInt64).UTC()
+	latestTime   = time.Unix(0, math.MaxInt64).UTC()
+)
+
+// Aggregations ... the supported ways of combining the readings within a downsampling interval
+var Aggregations = []string{AGGREGATION_MIN, AGGREGATION_MAX, AGGREGATION_AVG, AGGREGATION_LAST}
+
+// HistoryQuery ... selects the readings of a metric to return, and how to downsample them
+type HistoryQuery struct {
+	From time.Time
+	To   time.Time
+
+	// Interval ... the width of each downsampled point. Zero returns the raw readings
+	Interval time.Duration
+
+	// Aggregation ... how readings within an interval are combined
+	Aggregation string
+}
+
+// ParseHistoryQuery ... reads the from, to, interval and aggregation query parameters of a history request.
+// Times are RFC3339, the interval is a duration such as 15m or 1h
+func ParseHistoryQuery(values url.Values, now time.Time) (query HistoryQuery, err *shared.APIError) {
+	if query.To, err = parseTime(values, "to", now); err != nil {
+		return
+	}
+	if query.From, err = parseTime(values, "from", query.To.Add(-DEFAULT_HISTORY_RANGE)); err != nil {
+		return
+	}
+
+	if !query.From.Before(query.To) {
+		requestErr := fmt.Errorf("Invalid time range: %s - %s", query.From, query.To)
+		err = shared.NewAPIError(http.StatusBadRequest, requestErr, "from must be before to")
+		return
+	}
+
+	if raw := values.Get("interval"); raw != "" {
+		interval, parseErr := time.ParseDuration(raw)
+		if parseErr != nil || interval < time.Second {
+			if parseErr == nil {
+				parseErr = fmt.Errorf("Interval too small: %s", raw)
+			}
+			err = shared.NewAPIError(http.StatusBadRequest, parseErr, "interval must be a duration of at least 1s, e.g. 15m")
+			return
+		}
+		query.Interval = interval
+
+		if query.To.Sub(query.From)/interval >= MAX_HISTORY_POINTS {
+			requestErr := fmt.Errorf("Interval %s too small for range %s - %s", interval, query.From, query.To)
+			err = shared.NewAPIError(http.StatusBadRequest, requestErr, fmt.Sprintf("The time range can span at most %d intervals", MAX_HISTORY_POINTS))
+			return
+		}
+	}
+
+	query.Aggregation = values.Get("aggregation")
+	if query.Aggregation != "" && query.Interval == 0 {
+		err = shared.NewAPIError(http.StatusBadRequest, errors.New("Aggregation without interval"), "aggregation requires an interval")
+		return
+	}
+	if query.Interval > 0 {
+		if query.Aggregation == "" {
+			query.Aggregation = AGGREGATION_LAST
+		}
+		if !contains(Aggregations, query.Aggregation) {
+			requestErr := fmt.Errorf("Unsupported aggregation: %s", query.Aggregation)
+			err = shared.NewAPIError(http.StatusBadRequest, requestErr, "aggregation must be one of [min, max, avg, last]")
+			return
+		}
+	}
+
+	return
+}
+
+// parseTime ... reads an RFC3339 query parameter, which has to be between earliestTime and latestTime
+func parseTime(values url.Values, name string, fallback time.Time) (time.Time, *shared.APIError) {
+	raw := values.Get(name)
+	if raw == "" {
+		return fallback, nil
+	}
+
+	t, parseErr := time.Parse(time.RFC3339, raw)
+	if parseErr != nil {
+		return t, shared.NewAPIError(http.StatusBadRequest, parseErr, fmt.Sprintf("%s must be an RFC3339 timestamp", name))
+	}
+	if t.Before(earliestTime) || t.After(latestTime) {
+		requestErr := fmt.Errorf("Time out of range: %s", t)
+		clientErr := fmt.Sprintf("%s must be between %s and %s", name, earliestTime.Format(time.RFC3339), latestTime.Format(time.RFC3339))
+		return t, shared.NewAPIError(http.StatusBadRequest, requestErr, clientErr)
+	}
+	return t, nil
+}
+
+// Point ... a reading, or the aggregate of the readings within an interval
+//
+// swagger:model TelemetryPoint
+type Point struct {
+	// Time ... when the reading was observed, or the start of the interval
+	//
+	// required: true
+	Time time.Time `json:"time"`
+
+	// Value
+	//
+	// required: true
+	// example: 44.3
+	Value float64 `json:"value"`
+
+	// Count ... the number of readings aggregated into the point
+	//
+	// required: true
+	// example: 4
+	Count int64 `json:"count"`
+}
+
+// History response ... the readings of a metric for a vehicle over a time range
+//
+// swagger:model TelemetryHistory
+type History struct {
+	// VehicleID
+	//
+	// required: true
+	// example: 1234
+	VehicleID int64 `json:"vehicleId"`
+
+	// Metric ... doors_unlocked is the number of doors unlocked
+	//
+	// required: true
+	// enum: fuel,battery,doors_unlocked
+	// example: battery
+	Metric string `json:"metric"`
+
+	// required: true
+	From time.Time `json:"from"`
+
+	// required: true
+	To time.Time `json:"to"`
+
+	// Interval ... the width of each point. Omitted for raw readings
+	//
+	// example: 1h0m0s
+	Interval string `json:"interval,omitempty"`
+
+	// Aggregation ... how readings within an interval were combined. Omitted for raw readings
+	//
+	// enum: min,max,avg,last
+	// example: avg
+	Aggregation string `json:"aggregation,omitempty"`
+
+	// Points ... ordered by time. Intervals without readings are left out
+	//
+	// required: true
+	Points []Point `json:"points"`
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
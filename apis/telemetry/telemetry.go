@@ -0,0 +1,437 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"app_api/apis/vehicle"
+	"app_api/shared"
+	gmConnector "app_api/shared/gm"
+	"app_api/shared/loghelpers"
+	"app_api/shared/store"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Readings are stored in telemetry/<metric>/<vehicle ID>, keyed by the time they were observed in unix nanoseconds
+// so a time range is a single cursor scan
+var telemetryBucket = []byte("telemetry")
+
+// unixEpoch ... the earliest time a reading can be keyed by
+var unixEpoch = time.Unix(0, 0)
+
+var errTooManyPoints = errors.New("too many readings in range")
+
+// Service ... represents an instance of the telemetry package service interface.
+// It observes the vehicle service, so every fuel, battery and door reading fetched from GM is recorded
+type Service interface {
+	vehicle.Observer
+
+	GetHistory(vehicleID int64, metric string, query HistoryQuery) (res History, err *shared.APIError)
+	Prune() (removed int, err error)
+	Purge(vehicleID int64) (removed int, err error)
+	RunRetention(ctx context.Context, interval time.Duration)
+	Close()
+}
+
+// Option ... configures optional behaviour of the telemetry service
+type Option func(*service)
+
+// WithRetention ... sets how long the readings of a metric are kept before Prune removes them
+func WithRetention(metric string, retention time.Duration) Option {
+	return func(s *service) {
+		if retention > 0 {
+			s.retention[metric] = retention
+		}
+	}
+}
+
+// NewService ... returns an instance of the telemetry package service, persisted in the given database. Readings are
+// written in the background, until Close
+func NewService(db *bolt.DB, opts ...Option) (Service, error) {
+	s := &service{
+		db:        db,
+		retention: make(map[string]time.Duration),
+		readings:  make(chan reading, WRITE_BUFFER),
+		flushes:   make(chan chan struct{}),
+		closing:   make(chan struct{}),
+		closed:    make(chan struct{}),
+	}
+	for _, metric := range Metrics {
+		s.retention[metric] = DEFAULT_RETENTION
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists(telemetryBucket)
+		if err != nil {
+			return err
+		}
+		for _, metric := range Metrics {
+			if _, err := root.CreateBucketIfNotExists([]byte(metric)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go s.write()
+	return s, nil
+}
+
+type service struct {
+	db        *bolt.DB
+	retention map[string]time.Duration
+	now       func() time.Time
+
+	// readings ... wait for the background writer, which acknowledges flushes once it has stored them
+	readings  chan reading
+	flushes   chan chan struct{}
+	closing   chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// reading ... a value of a metric observed for a vehicle, waiting to be stored
+type reading struct {
+	vehicleID  int64
+	metric     string
+	value      float64
+	observedAt time.Time
+}
+
+func (s *service) timeNow() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now().UTC()
+}
+
+// ObserveEnergy ... records the fuel and battery levels of a vehicle. Levels the vehicle doesn't report are skipped
+func (s *service) ObserveEnergy(vehicleID int64, fuel vehicle.Fuel, battery vehicle.Battery, observedAt time.Time) {
+	readings := make(map[string]float64)
+	if fuel.Percentage != nil {
+		readings[METRIC_FUEL] = *fuel.Percentage
+	}
+	if battery.Percentage != nil {
+		readings[METRIC_BATTERY] = *battery.Percentage
+	}
+
+	s.record(vehicleID, readings, observedAt)
+}
+
+// ObserveDoors ... records the number of unlocked doors of a vehicle
+func (s *service) ObserveDoors(vehicleID int64, doors []gmConnector.GMVehicleDoorData, observedAt time.Time) {
+	var unlocked float64
+	for _, door := range doors {
+		if !door.Locked {
+			unlocked++
+		}
+	}
+
+	s.record(vehicleID, map[string]float64{METRIC_DOORS_UNLOCKED: unlocked}, observedAt)
+}
+
+// record ... hands the readings to the background writer, without waiting for them to be stored, so observing never
+// slows down the request the readings came from. Readings are dropped, and logged, while the writer is too far behind
+func (s *service) record(vehicleID int64, readings map[string]float64, observedAt time.Time) {
+	for metric, value := range readings {
+		select {
+		case s.readings <- reading{vehicleID: vehicleID, metric: metric, value: value, observedAt: observedAt}:
+		default:
+			loghelper.LogErrorsNoCTX(shared.NewAPIError(http.StatusInternalServerError, errors.New("write buffer full"), "Failed to record telemetry").
+				SetInternalErrorMessage(fmt.Sprintf("telemetry: dropped the %s reading of vehicle %d", metric, vehicleID)))
+		}
+	}
+}
+
+// write ... stores the readings handed to it every FLUSH_INTERVAL, or on a flush, until the service is closed
+func (s *service) write() {
+	ticker := time.NewTicker(FLUSH_INTERVAL)
+	defer ticker.Stop()
+
+	var batch []reading
+	// drain ... takes the readings waiting in the buffer, so a flush stores every reading recorded before it
+	drain := func() {
+		for {
+			select {
+			case r := <-s.readings:
+				batch = append(batch, r)
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case r := <-s.readings:
+			batch = append(batch, r)
+			if len(batch) >= WRITE_BUFFER {
+				batch = s.store(batch)
+			}
+		case <-ticker.C:
+			batch = s.store(batch)
+		case done := <-s.flushes:
+			drain()
+			batch = s.store(batch)
+			close(done)
+		case <-s.closing:
+			drain()
+			s.store(batch)
+			close(s.closed)
+			return
+		}
+	}
+}
+
+// store ... writes the readings in one transaction, returning the batch emptied for reuse. A failed write is logged
+func (s *service) store(batch []reading) []reading {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket(telemetryBucket)
+		for _, r := range batch {
+			bucket, err := root.Bucket([]byte(r.metric)).CreateBucketIfNotExists(store.Int64Key(r.vehicleID))
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(store.Int64Key(r.observedAt.UnixNano()), encodeValue(r.value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		loghelper.LogErrorsNoCTX(shared.NewAPIError(http.StatusInternalServerError, err, "Failed to record telemetry").
+			SetInternalErrorMessage(fmt.Sprintf("telemetry: failed to store %d readings", len(batch))))
+	}
+	return batch[:0]
+}
+
+// flush ... waits for the readings recorded so far to be stored, so they are read back. Returns at once when closed
+func (s *service) flush() {
+	done := make(chan struct{})
+	select {
+	case s.flushes <- done:
+		<-done
+	case <-s.closed:
+	}
+}
+
+// Close ... stores the readings waiting for the background writer and stops it. Readings recorded after are dropped
+func (s *service) Close() {
+	s.closeOnce.Do(func() { close(s.closing) })
+	<-s.closed
+}
+
+// GetHistory ... returns the readings of a metric for a vehicle within the query's time range, downsampled if the query has an interval
+func (s *service) GetHistory(vehicleID int64, metric string, query HistoryQuery) (res History, err *shared.APIError) {
+	if !contains(Metrics, metric) {
+		requestErr := fmt.Errorf("Unsupported metric: %s", metric)
+		err = shared.NewAPIError(http.StatusBadRequest, requestErr, "Unsupported telemetry metric")
+		return
+	}
+	// the readings still waiting for the writer are part of the history
+	s.flush()
+
+	res = History{
+		VehicleID:   vehicleID,
+		Metric:      metric,
+		From:        query.From,
+		To:          query.To,
+		Aggregation: query.Aggregation,
+		Points:      []Point{},
+	}
+	if query.Interval > 0 {
+		res.Interval = query.Interval.String()
+	}
+
+	txErr := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(telemetryBucket).Bucket([]byte(metric)).Bucket(store.Int64Key(vehicleID))
+		if bucket == nil {
+			return nil
+		}
+
+		// readings are keyed by their Unix time, which is never negative, so a range starting before the epoch is scanned from it.
+		// Downsampled points stay aligned to the start of the range
+		origin, from, to := query.From, query.From, query.To
+		if from.Before(unixEpoch) {
+			from = unixEpoch
+			if query.Interval > 0 {
+				origin = origin.Add(unixEpoch.Sub(origin) / query.Interval * query.Interval)
+			}
+		}
+		if to.Before(unixEpoch) {
+			to = unixEpoch
+		}
+
+		end := store.Int64Key(to.UnixNano())
+		c := bucket.Cursor()
+		for k, v := c.Seek(store.Int64Key(from.UnixNano())); k != nil && bytes.Compare(k, end) < 0; k, v = c.Next() {
+			observedAt := time.Unix(0, store.KeyInt64(k)).UTC()
+			value := decodeValue(v)
+
+			if query.Interval == 0 {
+				if len(res.Points) == MAX_HISTORY_POINTS {
+					return errTooManyPoints
+				}
+				res.Points = append(res.Points, Point{Time: observedAt, Value: value, Count: 1})
+				continue
+			}
+
+			start := origin.Add(observedAt.Sub(origin) / query.Interval * query.Interval)
+			if n := len(res.Points); n == 0 || !res.Points[n-1].Time.Equal(start) {
+				res.Points = append(res.Points, Point{Time: start, Value: value, Count: 1})
+				continue
+			}
+			res.Points[len(res.Points)-1].add(value, query.Aggregation)
+		}
+		return nil
+	})
+	if errors.Is(txErr, errTooManyPoints) {
+		clientErr := fmt.Sprintf("The time range has more than %d readings, use an interval to downsample them", MAX_HISTORY_POINTS)
+		err = shared.NewAPIError(http.StatusBadRequest, txErr, clientErr)
+		return
+	}
+	if txErr != nil {
+		err = shared.NewAPIError(http.StatusInternalServerError, txErr, "Failed to get telemetry history")
+		return
+	}
+
+	// averages are accumulated as sums while scanning
+	if query.Aggregation == AGGREGATION_AVG {
+		for i := range res.Points {
+			res.Points[i].Value = math.Round(res.Points[i].Value/float64(res.Points[i].Count)*100) / 100
+		}
+	}
+
+	return
+}
+
+// add ... folds another reading, later than every reading already in the point, into the point
+func (p *Point) add(value float64, aggregation string) {
+	p.Count++
+	switch aggregation {
+	case AGGREGATION_MIN:
+		p.Value = math.Min(p.Value, value)
+	case AGGREGATION_MAX:
+		p.Value = math.Max(p.Value, value)
+	case AGGREGATION_AVG:
+		p.Value += value
+	default:
+		p.Value = value
+	}
+}
+
+// Purge ... removes every reading of a vehicle, of every metric, including those still waiting to be stored
+func (s *service) Purge(vehicleID int64) (removed int, err error) {
+	s.flush()
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket(telemetryBucket)
+		for _, metric := range Metrics {
+			metricBucket := root.Bucket([]byte(metric))
+			bucket := metricBucket.Bucket(store.Int64Key(vehicleID))
+			if bucket == nil {
+				continue
+			}
+			removed += bucket.Stats().KeyN
+			if err := metricBucket.DeleteBucket(store.Int64Key(vehicleID)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// Prune ... removes every reading older than the retention of its metric
+func (s *service) Prune() (removed int, err error) {
+	s.flush()
+	now := s.timeNow()
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket(telemetryBucket)
+		for _, metric := range Metrics {
+			cutoff := store.Int64Key(now.Add(-s.retention[metric]).UnixNano())
+			metricBucket := root.Bucket([]byte(metric))
+
+			var emptied [][]byte
+			err := metricBucket.ForEach(func(vehicleKey, _ []byte) error {
+				bucket := metricBucket.Bucket(vehicleKey)
+
+				// deleting while iterating a bolt cursor skips keys, so the expired keys are collected first
+				var expired [][]byte
+				c := bucket.Cursor()
+				for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.Next() {
+					expired = append(expired, k)
+				}
+				for _, k := range expired {
+					if err := bucket.Delete(k); err != nil {
+						return err
+					}
+				}
+				removed += len(expired)
+
+				if k, _ := bucket.Cursor().First(); k == nil {
+					emptied = append(emptied, vehicleKey)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, vehicleKey := range emptied {
+				if err := metricBucket.DeleteBucket(vehicleKey); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// RunRetention ... prunes expired readings every interval until the context is cancelled
+func (s *service) RunRetention(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Prune(); err != nil {
+				loghelper.LogErrorsNoCTX(shared.NewAPIError(http.StatusInternalServerError, err, "Failed to prune telemetry").
+					SetInternalErrorMessage("telemetry: retention run failed"))
+			}
+		}
+	}
+}
+
+func encodeValue(value float64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, math.Float64bits(value))
+	return b
+}
+
+func decodeValue(b []byte) float64 {
+	return math.Float64frombits(binary.BigEndian.Uint64(b))
+}
@@ -0,0 +1,240 @@
+package telemetry
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"app_api/apis/vehicle"
+	gmConnector "app_api/shared/gm"
+	"app_api/shared/store"
+	"app_api/shared/store/storetest"
+
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+var start = time.Date(2020, 11, 2, 18, 0, 0, 0, time.UTC)
+
+// newTestService ... returns an empty telemetry store, closed once the test completes
+func newTestService(t *testing.T, opts ...Option) *service {
+	s, err := NewService(storetest.Open(t), opts...)
+	assert.NoError(t, err)
+	t.Cleanup(s.Close)
+	return s.(*service)
+}
+
+func observeBattery(s *service, vehicleID int64, level float64, observedAt time.Time) {
+	s.ObserveEnergy(vehicleID, vehicle.Fuel{}, vehicle.Battery{Percentage: &level}, observedAt)
+}
+
+func TestObserveEnergyRawHistory(t *testing.T) {
+	s := newTestService(t)
+
+	fuel, battery := 33.5, 80.0
+	s.ObserveEnergy(1234, vehicle.Fuel{Percentage: &fuel}, vehicle.Battery{Percentage: &battery}, start)
+	observeBattery(s, 1234, 79, start.Add(time.Minute))
+	observeBattery(s, 1235, 10, start.Add(time.Minute))
+
+	query := HistoryQuery{From: start, To: start.Add(time.Hour)}
+
+	res, err := s.GetHistory(1234, METRIC_BATTERY, query)
+	assert.Nil(t, err)
+	assert.Equal(t, []Point{
+		{Time: start, Value: 80, Count: 1},
+		{Time: start.Add(time.Minute), Value: 79, Count: 1},
+	}, res.Points)
+	assert.Empty(t, res.Interval)
+
+	res, err = s.GetHistory(1234, METRIC_FUEL, query)
+	assert.Nil(t, err)
+	assert.Equal(t, []Point{{Time: start, Value: 33.5, Count: 1}}, res.Points)
+
+	// the range end is exclusive
+	res, err = s.GetHistory(1234, METRIC_BATTERY, HistoryQuery{From: start, To: start.Add(time.Minute)})
+	assert.Nil(t, err)
+	assert.Len(t, res.Points, 1)
+}
+
+func TestObserveDoors(t *testing.T) {
+	s := newTestService(t)
+
+	s.ObserveDoors(1234, []gmConnector.GMVehicleDoorData{{Location: "frontLeft", Locked: false}, {Location: "frontRight", Locked: true}}, start)
+
+	res, err := s.GetHistory(1234, METRIC_DOORS_UNLOCKED, HistoryQuery{From: start, To: start.Add(time.Hour)})
+	assert.Nil(t, err)
+	assert.Equal(t, []Point{{Time: start, Value: 1, Count: 1}}, res.Points)
+}
+
+func TestClose(t *testing.T) {
+	s := newTestService(t)
+
+	observeBattery(s, 1234, 80, start)
+	observeBattery(s, 1234, 79, start.Add(time.Minute))
+	s.Close()
+
+	// the readings waiting for the writer are stored on close, without a read flushing them
+	var stored int
+	assert.NoError(t, s.db.View(func(tx *bolt.Tx) error {
+		stored = tx.Bucket(telemetryBucket).Bucket([]byte(METRIC_BATTERY)).Bucket(store.Int64Key(1234)).Stats().KeyN
+		return nil
+	}))
+	assert.Equal(t, 2, stored)
+
+	// readings recorded once closed are dropped rather than waited on
+	observeBattery(s, 1234, 78, start.Add(2*time.Minute))
+	res, err := s.GetHistory(1234, METRIC_BATTERY, HistoryQuery{From: start, To: start.Add(time.Hour)})
+	assert.Nil(t, err)
+	assert.Len(t, res.Points, 2)
+	s.Close()
+}
+
+func TestHistoryDownsampling(t *testing.T) {
+	s := newTestService(t)
+
+	observeBattery(s, 1234, 80, start)
+	observeBattery(s, 1234, 70, start.Add(20*time.Minute))
+	observeBattery(s, 1234, 75, start.Add(40*time.Minute))
+	observeBattery(s, 1234, 60, start.Add(2*time.Hour+5*time.Minute))
+
+	tests := []struct {
+		aggregation string
+		expected    []float64
+	}{
+		{AGGREGATION_MIN, []float64{70, 60}},
+		{AGGREGATION_MAX, []float64{80, 60}},
+		{AGGREGATION_AVG, []float64{75, 60}},
+		{AGGREGATION_LAST, []float64{75, 60}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.aggregation, func(t *testing.T) {
+			res, err := s.GetHistory(1234, METRIC_BATTERY, HistoryQuery{From: start, To: start.Add(3 * time.Hour), Interval: time.Hour, Aggregation: tt.aggregation})
+			assert.Nil(t, err)
+			assert.Equal(t, "1h0m0s", res.Interval)
+			assert.Equal(t, []Point{
+				{Time: start, Value: tt.expected[0], Count: 3},
+				{Time: start.Add(2 * time.Hour), Value: tt.expected[1], Count: 1},
+			}, res.Points)
+		})
+	}
+}
+
+func TestGetHistoryBeforeEpoch(t *testing.T) {
+	s := newTestService(t)
+
+	observeBattery(s, 1234, 80, start)
+	observeBattery(s, 1234, 70, start.Add(20*time.Minute))
+
+	beforeEpoch := time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	res, err := s.GetHistory(1234, METRIC_BATTERY, HistoryQuery{From: beforeEpoch, To: start.Add(time.Hour)})
+	assert.Nil(t, err)
+	assert.Equal(t, []Point{
+		{Time: start, Value: 80, Count: 1},
+		{Time: start.Add(20 * time.Minute), Value: 70, Count: 1},
+	}, res.Points)
+
+	// points stay aligned to the start of the range
+	res, err = s.GetHistory(1234, METRIC_BATTERY, HistoryQuery{From: beforeEpoch, To: start.Add(time.Hour), Interval: 24 * time.Hour, Aggregation: AGGREGATION_LAST})
+	assert.Nil(t, err)
+	assert.Equal(t, []Point{{Time: time.Date(2020, 11, 2, 0, 0, 0, 0, time.UTC), Value: 70, Count: 2}}, res.Points)
+
+	res, err = s.GetHistory(1234, METRIC_BATTERY, HistoryQuery{From: beforeEpoch.Add(-time.Hour), To: beforeEpoch})
+	assert.Nil(t, err)
+	assert.Empty(t, res.Points)
+}
+
+func TestGetHistoryNoReadings(t *testing.T) {
+	s := newTestService(t)
+
+	res, err := s.GetHistory(1234, METRIC_FUEL, HistoryQuery{From: start, To: start.Add(time.Hour)})
+	assert.Nil(t, err)
+	assert.Equal(t, []Point{}, res.Points)
+
+	_, err = s.GetHistory(1234, "engine", HistoryQuery{From: start, To: start.Add(time.Hour)})
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.ErrorCode)
+}
+
+func TestPrune(t *testing.T) {
+	s := newTestService(t, WithRetention(METRIC_FUEL, time.Hour))
+	s.now = func() time.Time { return start.Add(2 * time.Hour) }
+
+	fuel := 40.0
+	s.ObserveEnergy(1234, vehicle.Fuel{Percentage: &fuel}, vehicle.Battery{}, start)
+	s.ObserveEnergy(1234, vehicle.Fuel{Percentage: &fuel}, vehicle.Battery{}, start.Add(90*time.Minute))
+	observeBattery(s, 1234, 80, start)
+
+	removed, err := s.Prune()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	res, apiErr := s.GetHistory(1234, METRIC_FUEL, HistoryQuery{From: start, To: start.Add(3 * time.Hour)})
+	assert.Nil(t, apiErr)
+	assert.Equal(t, []Point{{Time: start.Add(90 * time.Minute), Value: 40, Count: 1}}, res.Points)
+
+	// battery keeps the default retention
+	res, apiErr = s.GetHistory(1234, METRIC_BATTERY, HistoryQuery{From: start, To: start.Add(3 * time.Hour)})
+	assert.Nil(t, apiErr)
+	assert.Len(t, res.Points, 1)
+}
+
+func TestPurge(t *testing.T) {
+	s := newTestService(t)
+
+	fuel := 40.0
+	s.ObserveEnergy(1234, vehicle.Fuel{Percentage: &fuel}, vehicle.Battery{}, start)
+	observeBattery(s, 1234, 80, start)
+	observeBattery(s, 1234, 79, start.Add(time.Minute))
+	observeBattery(s, 1235, 10, start)
+
+	removed, err := s.Purge(1234)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, removed)
+
+	res, apiErr := s.GetHistory(1234, METRIC_BATTERY, HistoryQuery{From: start, To: start.Add(time.Hour)})
+	assert.Nil(t, apiErr)
+	assert.Empty(t, res.Points)
+
+	// other vehicles keep their readings
+	res, apiErr = s.GetHistory(1235, METRIC_BATTERY, HistoryQuery{From: start, To: start.Add(time.Hour)})
+	assert.Nil(t, apiErr)
+	assert.Len(t, res.Points, 1)
+
+	removed, err = s.Purge(1234)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, removed)
+}
+
+func TestParseHistoryQuery(t *testing.T) {
+	now := start.Add(time.Hour)
+
+	query, err := ParseHistoryQuery(url.Values{}, now)
+	assert.Nil(t, err)
+	assert.Equal(t, HistoryQuery{From: now.Add(-DEFAULT_HISTORY_RANGE), To: now}, query)
+
+	query, err = ParseHistoryQuery(url.Values{"from": {"2020-11-02T18:00:00Z"}, "interval": {"15m"}}, now)
+	assert.Nil(t, err)
+	assert.Equal(t, HistoryQuery{From: start, To: now, Interval: 15 * time.Minute, Aggregation: AGGREGATION_LAST}, query)
+
+	invalid := []url.Values{
+		{"from": {"yesterday"}},
+		{"from": {"1000-01-01T00:00:00Z"}},
+		{"to": {"3000-01-01T00:00:00Z"}},
+		{"to": {"2020-11-02T17:00:00Z"}, "from": {"2020-11-02T18:00:00Z"}},
+		{"interval": {"fortnight"}},
+		{"interval": {"1ms"}},
+		{"interval": {"1s"}},
+		{"aggregation": {"avg"}},
+		{"interval": {"1h"}, "aggregation": {"median"}},
+	}
+	for _, values := range invalid {
+		_, err := ParseHistoryQuery(values, now)
+		assert.NotNil(t, err, values.Encode())
+		if err != nil {
+			assert.Equal(t, http.StatusBadRequest, err.ErrorCode)
+		}
+	}
+}
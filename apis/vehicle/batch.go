@@ -0,0 +1,167 @@
+package vehicle
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"app_api/shared"
+)
+
+const (
+	DEFAULT_BATCH_LIMIT       = 100
+	DEFAULT_BATCH_CONCURRENCY = 10
+)
+
+// BatchRequest ... request body for fetching many vehicles at once
+//
+// swagger:model BatchRequest
+type BatchRequest struct {
+	// VehicleIDs ... the vehicles to fetch. Duplicates are only fetched once. Required unless a group is given
+	//
+	// example: [1234, 1235]
+	VehicleIDs []int64 `json:"vehicleIds" validate:"max=1000"`
+
+	// Group ... fetch every vehicle registered in the group, in addition to VehicleIDs
+	//
+	// example: depot-7
+	Group string `json:"group" validate:"pattern=^[a-z0-9][a-z0-9_-]{0,63}$"`
+
+	// Sections ... the snapshot sections to fetch for every vehicle. Defaults to every section
+	//
+	// example: ["doors", "fuel"]
+	Sections []string `json:"sections" validate:"oneof=vehicle doors fuel battery"`
+
+	// Offset ... the index of the first vehicle to fetch from VehicleIDs
+	//
+	// minimum: 0
+	// example: 0
+	Offset int64 `json:"offset" validate:"min=0"`
+
+	// Limit ... the maximum number of vehicles to fetch. Defaults to 100 when left out
+	//
+	// minimum: 1
+	// maximum: 100
+	// example: 100
+	Limit *int64 `json:"limit,omitempty" validate:"min=1,max=100"`
+}
+
+// BatchResult ... the outcome of fetching a single vehicle in a batch
+//
+// swagger:model BatchResult
+type BatchResult struct {
+	// VehicleID
+	//
+	// required: true
+	// example: 1234
+	VehicleID int64 `json:"vehicleId"`
+
+	// Status
+	//
+	// required: true
+	// enum: ok,error
+	// example: ok
+	Status string `json:"status"`
+
+	// Error ... the reason the vehicle could not be fetched
+	//
+	// example: Failed to get vehicle snapshot
+	Error string `json:"error,omitempty"`
+
+	Snapshot *Snapshot `json:"snapshot,omitempty"`
+
+	apiErr *shared.APIError
+}
+
+// Err ... returns the error the vehicle failed with, so it can be logged by the caller
+func (b BatchResult) Err() *shared.APIError {
+	return b.apiErr
+}
+
+// Validate ... at least one vehicle has to be selected, either directly or through a group
+func (req BatchRequest) Validate() []shared.FieldError {
+	if len(req.VehicleIDs) == 0 && req.Group == "" {
+		return []shared.FieldError{{Field: "vehicleIds", Rule: "required", Message: "vehicleIds is required when no group is given"}}
+	}
+	return nil
+}
+
+// PageLimit ... returns the limit of the request, or the default when it wasn't set
+func (req BatchRequest) PageLimit() int64 {
+	if req.Limit == nil {
+		return DEFAULT_BATCH_LIMIT
+	}
+	return *req.Limit
+}
+
+// Page ... returns the de-duplicated vehicle IDs selected by the offset and limit of the request, along with the total number of vehicles
+func (req BatchRequest) Page() (vehicleIDs []int64, total int64) {
+	seen := make(map[int64]bool)
+	var unique []int64
+	for _, vehicleID := range req.VehicleIDs {
+		if seen[vehicleID] {
+			continue
+		}
+		seen[vehicleID] = true
+		unique = append(unique, vehicleID)
+	}
+
+	total = int64(len(unique))
+
+	if req.Offset >= total {
+		return []int64{}, total
+	}
+
+	end := req.Offset + req.PageLimit()
+	if end > total {
+		end = total
+	}
+
+	return unique[req.Offset:end], total
+}
+
+// GetVehicleSnapshots ... fetches a snapshot for every vehicle, with at most batchConcurrency vehicles in flight against GM.
+// Results are returned in the same order as vehicleIDs, and a failed vehicle doesn't fail the batch.
+func (s *service) GetVehicleSnapshots(vehicleIDs []int64, sections []string) (res []BatchResult, err *shared.APIError) {
+	for _, section := range sections {
+		if !isSnapshotSection(section) {
+			requestErr := fmt.Errorf("Unsupported snapshot section: %s", section)
+			err = shared.NewAPIError(http.StatusBadRequest, requestErr, fmt.Sprintf("Unsupported section %q", section))
+			return
+		}
+	}
+
+	res = make([]BatchResult, len(vehicleIDs))
+
+	sem := make(chan struct{}, s.batchConcurrency)
+	var wg sync.WaitGroup
+
+	for i, vehicleID := range vehicleIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, vehicleID int64) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			result := BatchResult{VehicleID: vehicleID}
+
+			snapshot, apiErr := s.GetVehicleSnapshot(vehicleID, sections)
+			if apiErr != nil {
+				result.Status = SECTION_STATUS_ERROR
+				result.Error = apiErr.ClientErrorMessage
+				result.apiErr = apiErr
+			} else {
+				result.Status = SECTION_STATUS_OK
+				result.Snapshot = &snapshot
+			}
+
+			res[i] = result
+		}(i, vehicleID)
+	}
+
+	wg.Wait()
+	return
+}
@@ -0,0 +1,70 @@
+package vehicle
+
+import (
+	"net/http"
+	"testing"
+
+	gmConnector "app_api/shared/gm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchRequestPage(t *testing.T) {
+	req := BatchRequest{VehicleIDs: []int64{1, 2, 2, 3, 4, 1, 5}}
+
+	ids, total := req.Page()
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, ids)
+	assert.Equal(t, int64(5), total)
+	assert.Equal(t, int64(DEFAULT_BATCH_LIMIT), req.PageLimit())
+
+	limit := int64(2)
+	req.Offset, req.Limit = 1, &limit
+	ids, total = req.Page()
+	assert.Equal(t, []int64{2, 3}, ids)
+	assert.Equal(t, int64(5), total)
+
+	req.Offset = 10
+	ids, _ = req.Page()
+	assert.Empty(t, ids)
+}
+
+func TestGetVehicleSnapshotsPartialFailure(t *testing.T) {
+	res, err := vehicleService.GetVehicleSnapshots([]int64{1234, 1236, 1235}, []string{SECTION_FUEL})
+	assert.Nil(t, err)
+	assert.Len(t, res, 3)
+
+	assert.Equal(t, int64(1234), res[0].VehicleID)
+	assert.Equal(t, SECTION_STATUS_OK, res[0].Status)
+	assert.NotNil(t, res[0].Snapshot.Fuel)
+	assert.Nil(t, res[0].Snapshot.Doors)
+
+	assert.Equal(t, int64(1236), res[1].VehicleID)
+	assert.Equal(t, SECTION_STATUS_ERROR, res[1].Status)
+	assert.Nil(t, res[1].Snapshot)
+	assert.NotNil(t, res[1].Err())
+
+	assert.Equal(t, int64(1235), res[2].VehicleID)
+	assert.Equal(t, SECTION_STATUS_OK, res[2].Status)
+}
+
+func TestGetVehicleSnapshotsBoundedConcurrency(t *testing.T) {
+	service := NewService(gmConnector.NewMockGMAPIConnector(), WithBatchConcurrency(1))
+
+	ids := make([]int64, 20)
+	for i := range ids {
+		ids[i] = 1234
+	}
+
+	res, err := service.GetVehicleSnapshots(ids, nil)
+	assert.Nil(t, err)
+	assert.Len(t, res, 20)
+	for _, result := range res {
+		assert.Equal(t, SECTION_STATUS_OK, result.Status)
+	}
+}
+
+func TestGetVehicleSnapshotsFailureUnsupportedSection(t *testing.T) {
+	_, err := vehicleService.GetVehicleSnapshots([]int64{1234}, []string{"engine"})
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.ErrorCode)
+}
@@ -0,0 +1,256 @@
+package vehicle
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"app_api/shared"
+)
+
+const (
+	SECTION_VEHICLE = "vehicle"
+	SECTION_DOORS   = "doors"
+	SECTION_FUEL    = "fuel"
+	SECTION_BATTERY = "battery"
+
+	SECTION_STATUS_OK    = "ok"
+	SECTION_STATUS_ERROR = "error"
+)
+
+// SnapshotSections ... every section that can be requested for a snapshot, in the order they are documented
+var SnapshotSections = []string{SECTION_VEHICLE, SECTION_DOORS, SECTION_FUEL, SECTION_BATTERY}
+
+// SectionStatus ... reports whether an individual section of a snapshot could be fetched
+//
+// swagger:model SectionStatus
+type SectionStatus struct {
+	// Status
+	//
+	// required: true
+	// enum: ok,error
+	// example: ok
+	Status string `json:"status"`
+
+	// Error ... the reason the section could not be fetched
+	//
+	// example: Failed to get vehicle doors
+	Error string `json:"error,omitempty"`
+
+	apiErr *shared.APIError
+}
+
+// VehicleSection ... snapshot section for the vehicle overview
+//
+// swagger:model VehicleSection
+type VehicleSection struct {
+	SectionStatus
+	Data *Vehicle `json:"data,omitempty"`
+}
+
+// DoorsSection ... snapshot section for the doors
+//
+// swagger:model DoorsSection
+type DoorsSection struct {
+	SectionStatus
+	Data []Door `json:"data,omitempty"`
+}
+
+// FuelSection ... snapshot section for the fuel level
+//
+// swagger:model FuelSection
+type FuelSection struct {
+	SectionStatus
+	Data *Fuel `json:"data,omitempty"`
+}
+
+// BatterySection ... snapshot section for the battery level
+//
+// swagger:model BatterySection
+type BatterySection struct {
+	SectionStatus
+	Data *Battery `json:"data,omitempty"`
+}
+
+// Snapshot response ... merged view of a vehicle. Only the requested sections are present
+//
+// swagger:model Snapshot
+type Snapshot struct {
+	// VehicleID
+	//
+	// required: true
+	// example: 1234
+	VehicleID int64 `json:"vehicleId"`
+
+	Vehicle *VehicleSection `json:"vehicle,omitempty"`
+	Doors   *DoorsSection   `json:"doors,omitempty"`
+	Fuel    *FuelSection    `json:"fuel,omitempty"`
+	Battery *BatterySection `json:"battery,omitempty"`
+}
+
+// Errors ... returns the errors of every failed section, so they can be logged by the caller
+func (s Snapshot) Errors() (errs []*shared.APIError) {
+	for _, status := range s.statuses() {
+		if status.apiErr != nil {
+			errs = append(errs, status.apiErr)
+		}
+	}
+	return
+}
+
+func (s Snapshot) statuses() (statuses []*SectionStatus) {
+	if s.Vehicle != nil {
+		statuses = append(statuses, &s.Vehicle.SectionStatus)
+	}
+	if s.Doors != nil {
+		statuses = append(statuses, &s.Doors.SectionStatus)
+	}
+	if s.Fuel != nil {
+		statuses = append(statuses, &s.Fuel.SectionStatus)
+	}
+	if s.Battery != nil {
+		statuses = append(statuses, &s.Battery.SectionStatus)
+	}
+	return
+}
+
+// ParseSnapshotSections ... parses a comma separated list of sections e.g. "doors,fuel". An empty list selects every section
+func ParseSnapshotSections(fields string) (sections []string, err *shared.APIError) {
+	if strings.TrimSpace(fields) == "" {
+		return SnapshotSections, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" || seen[field] {
+			continue
+		}
+
+		if !isSnapshotSection(field) {
+			clientErr := fmt.Sprintf("Unsupported field %q, must be one of [%s]", field, strings.Join(SnapshotSections, ", "))
+			err = shared.NewAPIError(http.StatusBadRequest, errors.New(clientErr), clientErr)
+			return
+		}
+
+		seen[field] = true
+		sections = append(sections, field)
+	}
+
+	return
+}
+
+func isSnapshotSection(section string) bool {
+	for _, s := range SnapshotSections {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
+
+// GetVehicleSnapshot ... fetches the requested sections for a given car concurrently and merges them into one document.
+// A failed section is reported in its own status rather than failing the snapshot, unless every requested section failed.
+func (s *service) GetVehicleSnapshot(vehicleID int64, sections []string) (res Snapshot, err *shared.APIError) {
+	if err = checkVehicleID(vehicleID); err != nil {
+		return
+	}
+	if len(sections) == 0 {
+		sections = SnapshotSections
+	}
+
+	requested := make(map[string]bool)
+	for _, section := range sections {
+		if !isSnapshotSection(section) {
+			requestErr := fmt.Errorf("Unsupported snapshot section: %s", section)
+			err = shared.NewAPIError(http.StatusBadRequest, requestErr, fmt.Sprintf("Unsupported field %q", section))
+			return
+		}
+		requested[section] = true
+	}
+
+	res.VehicleID = vehicleID
+
+	var wg sync.WaitGroup
+
+	if requested[SECTION_VEHICLE] {
+		res.Vehicle = &VehicleSection{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vehicle, apiErr := s.GetVehicle(vehicleID)
+			if res.Vehicle.setStatus(apiErr) {
+				res.Vehicle.Data = &vehicle
+			}
+		}()
+	}
+
+	if requested[SECTION_DOORS] {
+		res.Doors = &DoorsSection{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			gmDoors, apiErr := s.GetVehicleDoors(vehicleID)
+			if res.Doors.setStatus(apiErr) {
+				res.Doors.Data = make([]Door, 0, len(gmDoors))
+				for _, door := range gmDoors {
+					res.Doors.Data = append(res.Doors.Data, Door{Location: door.Location, Locked: door.Locked})
+				}
+			}
+		}()
+	}
+
+	// Fuel and battery come from the same GM service, so only one call is made for both
+	if requested[SECTION_FUEL] || requested[SECTION_BATTERY] {
+		if requested[SECTION_FUEL] {
+			res.Fuel = &FuelSection{}
+		}
+		if requested[SECTION_BATTERY] {
+			res.Battery = &BatterySection{}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fuel, battery, apiErr := s.getEnergy(vehicleID)
+			if res.Fuel != nil && res.Fuel.setStatus(apiErr) {
+				res.Fuel.Data = &fuel
+			}
+			if res.Battery != nil && res.Battery.setStatus(apiErr) {
+				res.Battery.Data = &battery
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for _, status := range res.statuses() {
+		if status.Status == SECTION_STATUS_OK {
+			return
+		}
+	}
+
+	requestErr := fmt.Errorf("Every requested snapshot section failed for vehicle %d", vehicleID)
+	err = shared.NewAPIError(http.StatusInternalServerError, requestErr, "Failed to get vehicle snapshot")
+	return
+}
+
+// Err ... returns the error the section failed with, or nil if it succeeded
+func (st SectionStatus) Err() *shared.APIError {
+	return st.apiErr
+}
+
+// setStatus ... records the outcome of fetching a section, returns true if the section succeeded
+func (st *SectionStatus) setStatus(apiErr *shared.APIError) bool {
+	if apiErr != nil {
+		st.Status = SECTION_STATUS_ERROR
+		st.Error = apiErr.ClientErrorMessage
+		st.apiErr = apiErr
+		return false
+	}
+
+	st.Status = SECTION_STATUS_OK
+	return true
+}
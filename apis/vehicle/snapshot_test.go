@@ -0,0 +1,66 @@
+package vehicle
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetVehicleSnapshotSuccess(t *testing.T) {
+	level := 33.5
+
+	res, err := vehicleService.GetVehicleSnapshot(1234, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1234), res.VehicleID)
+
+	assert.Equal(t, SECTION_STATUS_OK, res.Vehicle.Status)
+	assert.Equal(t, &Vehicle{"123123412412", "Metallic Silver", 4, "v8"}, res.Vehicle.Data)
+
+	assert.Equal(t, SECTION_STATUS_OK, res.Doors.Status)
+	assert.Equal(t, []Door{{"frontLeft", true}, {"frontRight", true}}, res.Doors.Data)
+
+	assert.Equal(t, SECTION_STATUS_OK, res.Fuel.Status)
+	assert.Equal(t, &Fuel{&level}, res.Fuel.Data)
+
+	assert.Equal(t, SECTION_STATUS_OK, res.Battery.Status)
+	assert.Equal(t, &Battery{}, res.Battery.Data)
+
+	assert.Empty(t, res.Errors())
+}
+
+func TestGetVehicleSnapshotSelectedSections(t *testing.T) {
+	res, err := vehicleService.GetVehicleSnapshot(1235, []string{SECTION_DOORS, SECTION_BATTERY})
+	assert.Nil(t, err)
+
+	assert.Nil(t, res.Vehicle)
+	assert.Nil(t, res.Fuel)
+	assert.NotNil(t, res.Doors)
+	assert.NotNil(t, res.Battery)
+}
+
+func TestGetVehicleSnapshotFailureInvalidVehicleID(t *testing.T) {
+	_, err := vehicleService.GetVehicleSnapshot(1236, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusInternalServerError, err.ErrorCode)
+}
+
+func TestGetVehicleSnapshotFailureUnsupportedSection(t *testing.T) {
+	_, err := vehicleService.GetVehicleSnapshot(1234, []string{"engine"})
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.ErrorCode)
+}
+
+func TestParseSnapshotSections(t *testing.T) {
+	sections, err := ParseSnapshotSections("")
+	assert.Nil(t, err)
+	assert.Equal(t, SnapshotSections, sections)
+
+	sections, err = ParseSnapshotSections(" Doors,fuel,,doors")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{SECTION_DOORS, SECTION_FUEL}, sections)
+
+	_, err = ParseSnapshotSections("doors,engine")
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.ErrorCode)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"time"
 
 	"app_api/shared"
 	gmConnector "app_api/shared/gm"
@@ -22,21 +23,76 @@ type Service interface {
 	GetVehicleFuel(vehicleID int64) (res Fuel, err *shared.APIError)
 	GetVehicleBattery(vehicleID int64) (res Battery, err *shared.APIError)
 	SendEngineAction(vehicleID int64, engineAction EngineActionRequest) (engineSubmissionStatus EngineActionResponse, err *shared.APIError)
+	GetVehicleSnapshot(vehicleID int64, sections []string) (res Snapshot, err *shared.APIError)
+	GetVehicleSnapshots(vehicleIDs []int64, sections []string) (res []BatchResult, err *shared.APIError)
+}
+
+// Option ... configures optional behaviour of the vehicle service
+type Option func(*service)
+
+// WithBatchConcurrency ... sets the maximum number of vehicles fetched from GM at once for a batch
+func WithBatchConcurrency(n int) Option {
+	return func(s *service) {
+		if n > 0 {
+			s.batchConcurrency = n
+		}
+	}
+}
+
+// Observer ... is notified of every status reading the service gets from GM, e.g. to keep a history of them.
+// It is called synchronously with the request, so implementations should return quickly
+type Observer interface {
+	ObserveEnergy(vehicleID int64, fuel Fuel, battery Battery, observedAt time.Time)
+	ObserveDoors(vehicleID int64, doors []gmConnector.GMVehicleDoorData, observedAt time.Time)
+}
+
+// EngineObserver ... optionally implemented by an Observer to be notified of every engine action GM executed
+type EngineObserver interface {
+	ObserveEngineAction(vehicleID int64, action string, observedAt time.Time)
+}
+
+// WithObserver ... registers an observer of the readings fetched from GM
+func WithObserver(o Observer) Option {
+	return func(s *service) {
+		s.observers = append(s.observers, o)
+	}
 }
 
 // NewService ... returns an instance of the vehicle package service
-func NewService(gmAPIConnector gmConnector.GMAPIConnector) Service {
-	return &service{
-		gm: gmAPIConnector,
+func NewService(gmAPIConnector gmConnector.GMAPIConnector, opts ...Option) Service {
+	s := &service{
+		gm:               gmAPIConnector,
+		batchConcurrency: DEFAULT_BATCH_CONCURRENCY,
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
 type service struct {
-	gm gmConnector.GMAPIConnector
+	gm               gmConnector.GMAPIConnector
+	batchConcurrency int
+	observers        []Observer
+}
+
+// checkVehicleID ... GM's vehicle IDs are positive, so any other is rejected before asking GM, whichever API it came from
+func checkVehicleID(vehicleID int64) *shared.APIError {
+	if vehicleID > 0 {
+		return nil
+	}
+	requestErr := fmt.Errorf("Invalid vehicle ID: %d", vehicleID)
+	return shared.NewAPIError(http.StatusBadRequest, requestErr, "Vehicle ID must be a positive integer")
 }
 
 // GetVehicle ... returns an overview for a given car
 func (s *service) GetVehicle(vehicleID int64) (res Vehicle, err *shared.APIError) {
+	if err = checkVehicleID(vehicleID); err != nil {
+		return
+	}
+
 	gmVehicleData, err := s.gm.GetVehicle(vehicleID)
 	if err != nil {
 		return
@@ -68,48 +124,66 @@ func (s *service) GetVehicle(vehicleID int64) (res Vehicle, err *shared.APIError
 
 // GetVehicleDoors ... returns the status of the doors for a given car
 func (s *service) GetVehicleDoors(vehicleID int64) (res []gmConnector.GMVehicleDoorData, err *shared.APIError) {
-	res, err = s.gm.GetVehicleDoors(vehicleID)
-	if err != nil {
+	if err = checkVehicleID(vehicleID); err != nil {
 		return
 	}
 
-	return
-}
-
-// GetVehicleFuel ... returns the status of the fuel for a given car
-func (s *service) GetVehicleFuel(vehicleID int64) (res Fuel, err *shared.APIError) {
-	fuel, _, err := s.gm.GetVehicleEnergyStatus(vehicleID)
+	res, err = s.gm.GetVehicleDoors(vehicleID)
 	if err != nil {
 		return
 	}
 
-	if fuel == nil {
-		return
+	observedAt := time.Now().UTC()
+	for _, o := range s.observers {
+		o.ObserveDoors(vehicleID, res, observedAt)
 	}
 
-	percentage := math.Round(*fuel*100) / 100
-	res.Percentage = &percentage
+	return
+}
 
+// GetVehicleFuel ... returns the status of the fuel for a given car
+func (s *service) GetVehicleFuel(vehicleID int64) (res Fuel, err *shared.APIError) {
+	res, _, err = s.getEnergy(vehicleID)
 	return
 }
 
 // GetVehicleBattery ... returns the status of the fiel for a given car
 func (s *service) GetVehicleBattery(vehicleID int64) (res Battery, err *shared.APIError) {
-	_, battery, err := s.gm.GetVehicleEnergyStatus(vehicleID)
-	if err != nil {
+	_, res, err = s.getEnergy(vehicleID)
+	return
+}
+
+// getEnergy ... fetches both energy levels from GM in one call, rounded to two decimal places
+func (s *service) getEnergy(vehicleID int64) (fuel Fuel, battery Battery, err *shared.APIError) {
+	if err = checkVehicleID(vehicleID); err != nil {
 		return
 	}
 
-	if battery == nil {
+	fuelLevel, batteryLevel, err := s.gm.GetVehicleEnergyStatus(vehicleID)
+	if err != nil {
 		return
 	}
 
-	percentage := math.Round(*battery*100) / 100
-	res.Percentage = &percentage
+	fuel.Percentage = roundPercentage(fuelLevel)
+	battery.Percentage = roundPercentage(batteryLevel)
+
+	observedAt := time.Now().UTC()
+	for _, o := range s.observers {
+		o.ObserveEnergy(vehicleID, fuel, battery, observedAt)
+	}
 
 	return
 }
 
+func roundPercentage(level *float64) *float64 {
+	if level == nil {
+		return nil
+	}
+
+	percentage := math.Round(*level*100) / 100
+	return &percentage
+}
+
 // EngineActionRequest response
 //
 // swagger:model EngineActionRequest
@@ -117,8 +191,9 @@ type EngineActionRequest struct {
 	// Action
 	//
 	// required: true
+	// enum: START,STOP
 	// example: START
-	Action string `json:"action"`
+	Action string `json:"action" validate:"required,oneof=START STOP"`
 }
 
 // EngineActionResponse response
@@ -142,9 +217,14 @@ func (s *service) SendEngineAction(vehicleID int64, engineAction EngineActionReq
 	case ENGINE_STOP:
 		action = gmConnector.ENGINE_STOP
 	default:
+		// Requests coming through the router are already validated against the struct tags, this guards other callers
 		errorMessage := "Unsupported engine action option"
 		engineActionError := fmt.Errorf("Unsupported data type: %s", engineAction.Action)
-		err = shared.NewAPIError(http.StatusInternalServerError, engineActionError, errorMessage)
+		err = shared.NewAPIError(http.StatusBadRequest, engineActionError, errorMessage)
+		return
+	}
+
+	if err = checkVehicleID(vehicleID); err != nil {
 		return
 	}
 
@@ -156,6 +236,13 @@ func (s *service) SendEngineAction(vehicleID int64, engineAction EngineActionReq
 	switch engineResponse.Status {
 	case gmConnector.EXECUTED:
 		engineSubmissionStatus.Action = "success"
+
+		observedAt := time.Now().UTC()
+		for _, o := range s.observers {
+			if engineObserver, ok := o.(EngineObserver); ok {
+				engineObserver.ObserveEngineAction(vehicleID, engineAction.Action, observedAt)
+			}
+		}
 	case gmConnector.FAILED:
 		engineSubmissionStatus.Action = "error"
 	default:
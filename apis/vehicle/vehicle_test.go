@@ -1,8 +1,14 @@
 package vehicle
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	gmConnector "app_api/shared/gm"
 
@@ -31,10 +37,19 @@ func TestGetVehicleFailureInvalidVehicleID(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestGetVehicleFailureNonPositiveVehicleID(t *testing.T) {
+	for _, vehicleID := range []int64{0, -1} {
+		_, err := vehicleService.GetVehicle(vehicleID)
+		if assert.NotNil(t, err) {
+			assert.Equal(t, http.StatusBadRequest, err.ErrorCode)
+		}
+	}
+}
+
 func TestGetVehicleDoorsSuccess(t *testing.T) {
 	expectedRes := []gmConnector.GMVehicleDoorData{}
-	expectedRes = append(expectedRes, gmConnector.GMVehicleDoorData{"frontLeft", true})
-	expectedRes = append(expectedRes, gmConnector.GMVehicleDoorData{"frontRight", true})
+	expectedRes = append(expectedRes, gmConnector.GMVehicleDoorData{Location: "frontLeft", Locked: true})
+	expectedRes = append(expectedRes, gmConnector.GMVehicleDoorData{Location: "frontRight", Locked: true})
 
 	res, err := vehicleService.GetVehicleDoors(1234)
 	assert.Nil(t, err)
@@ -112,3 +127,78 @@ func TestSendEngineActionFailureInvalidAction(t *testing.T) {
 	_, err := vehicleService.SendEngineAction(1235, engineAction)
 	assert.NotNil(t, err)
 }
+
+// The swagger definition is generated from comments, so the enum for the action has to be kept in sync with the validation rule by hand
+func TestEngineActionRequestSwaggerEnumInSync(t *testing.T) {
+	b, err := ioutil.ReadFile("../../swagger/swagger.json")
+	assert.NoError(t, err)
+
+	var spec struct {
+		Definitions map[string]struct {
+			Properties map[string]struct {
+				Enum []string `json:"enum"`
+			} `json:"properties"`
+		} `json:"definitions"`
+	}
+	assert.NoError(t, json.Unmarshal(b, &spec))
+
+	field, _ := reflect.TypeOf(EngineActionRequest{}).FieldByName("Action")
+	var oneOf []string
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if strings.HasPrefix(rule, "oneof=") {
+			oneOf = strings.Fields(strings.TrimPrefix(rule, "oneof="))
+		}
+	}
+
+	assert.NotEmpty(t, oneOf)
+	assert.Equal(t, oneOf, spec.Definitions["EngineActionRequest"].Properties["action"].Enum)
+}
+
+type recordingObserver struct {
+	energy []int64
+	doors  []int64
+	engine []string
+}
+
+func (o *recordingObserver) ObserveEnergy(vehicleID int64, fuel Fuel, battery Battery, observedAt time.Time) {
+	o.energy = append(o.energy, vehicleID)
+}
+
+func (o *recordingObserver) ObserveDoors(vehicleID int64, doors []gmConnector.GMVehicleDoorData, observedAt time.Time) {
+	o.doors = append(o.doors, vehicleID)
+}
+
+func (o *recordingObserver) ObserveEngineAction(vehicleID int64, action string, observedAt time.Time) {
+	o.engine = append(o.engine, action)
+}
+
+func TestObserverNotifiedOfReadings(t *testing.T) {
+	observer := &recordingObserver{}
+	service := NewService(testGMAPIConnector, WithObserver(observer))
+
+	_, err := service.GetVehicleFuel(1234)
+	assert.Nil(t, err)
+	_, err = service.GetVehicleBattery(1235)
+	assert.Nil(t, err)
+	_, err = service.GetVehicleDoors(1234)
+	assert.Nil(t, err)
+
+	// failed readings aren't observed
+	_, err = service.GetVehicleFuel(1236)
+	assert.NotNil(t, err)
+
+	assert.Equal(t, []int64{1234, 1235}, observer.energy)
+	assert.Equal(t, []int64{1234}, observer.doors)
+}
+
+func TestEngineObserverNotifiedOfExecutedActions(t *testing.T) {
+	observer := &recordingObserver{}
+	service := NewService(testGMAPIConnector, WithObserver(observer))
+
+	_, err := service.SendEngineAction(1234, EngineActionRequest{Action: ENGINE_START})
+	assert.Nil(t, err)
+	_, err = service.SendEngineAction(1236, EngineActionRequest{Action: ENGINE_STOP})
+	assert.NotNil(t, err)
+
+	assert.Equal(t, []string{ENGINE_START}, observer.engine)
+}
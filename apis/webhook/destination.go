@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// privateNetworks ... the address ranges of private networks, which deliveries aren't sent to along with loopback,
+// link-local and unspecified addresses, so a subscription can't reach the services running next to this one
+var privateNetworks = parseNetworks(
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"100.64.0.0/10",
+	"fc00::/7",
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// newClient ... returns the client deliveries are sent with by default. It only connects to public addresses, checked
+// once the receiver's host is resolved so a name can't point it elsewhere, and doesn't follow redirects
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: DEFAULT_TIMEOUT,
+		Control: func(network, address string, c syscall.RawConn) error {
+			return checkDestination(address)
+		},
+	}
+
+	return &http.Client{
+		Timeout: DEFAULT_TIMEOUT,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: DEFAULT_TIMEOUT,
+			MaxIdleConnsPerHost: DEFAULT_WORKERS,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkDestination ... returns an error if an address deliveries would connect to isn't public
+func checkDestination(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("webhook destination %s is not an IP address", host)
+	}
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("webhook destination %s is not a public address", ip)
+	}
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return fmt.Errorf("webhook destination %s is not a public address", ip)
+		}
+	}
+	return nil
+}
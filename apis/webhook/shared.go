@@ -0,0 +1,168 @@
+package webhook
+
+import (
+	"time"
+
+	"app_api/apis/events"
+)
+
+const (
+	DELIVERY_PENDING   = "pending"
+	DELIVERY_RETRYING  = "retrying"
+	DELIVERY_SUCCEEDED = "succeeded"
+	DELIVERY_DEAD      = "dead"
+
+	HEADER_SUBSCRIPTION = "X-Webhook-Id"
+	HEADER_DELIVERY     = "X-Webhook-Delivery"
+	HEADER_EVENT        = "X-Webhook-Event"
+	HEADER_TIMESTAMP    = "X-Webhook-Timestamp"
+	HEADER_SIGNATURE    = "X-Webhook-Signature"
+)
+
+// SubscriptionRequest ... request body for creating or replacing a webhook subscription.
+// Without vehicle or group filters every vehicle matches, without event types every event type matches
+//
+// swagger:model WebhookSubscriptionRequest
+type SubscriptionRequest struct {
+	// URL ... where events are POSTed. Only public addresses are delivered to, and redirects aren't followed
+	//
+	// required: true
+	// example: https://example.com/hooks/smartcar
+	URL string `json:"url" validate:"required,max=2048,pattern=^https?://[^ ]+$"`
+
+	// Secret ... the key events are signed with. Generated when left out on creation, kept when left out on update
+	//
+	// minimum length: 16
+	// maximum length: 256
+	// example: 6c7d2fb8e0b34a5b9f1e3d2c1b0a9f8e
+	Secret string `json:"secret" validate:"min=16,max=256"`
+
+	// VehicleIDs ... only deliver events of these vehicles
+	//
+	// example: [1234]
+	VehicleIDs []int64 `json:"vehicleIds" validate:"max=1000"`
+
+	// Groups ... only deliver events of vehicles registered in these groups
+	//
+	// example: ["depot-7"]
+	Groups []string `json:"groups" validate:"max=50,pattern=^[a-z0-9][a-z0-9_-]{0,63}$"`
+
+	// EventTypes ... only deliver these types of events
+	//
+	// example: ["door_unlocked", "fuel_low"]
+	EventTypes []string `json:"eventTypes" validate:"oneof=door_unlocked door_locked engine_started engine_stopped fuel_low battery_charged"`
+}
+
+// Subscription response ... a webhook receiving vehicle events
+//
+// swagger:model WebhookSubscription
+type Subscription struct {
+	// ID
+	//
+	// required: true
+	// example: 0f8fad5b-d9cb-469f-a165-70867728950e
+	ID string `json:"id"`
+
+	// URL
+	//
+	// required: true
+	// example: https://example.com/hooks/smartcar
+	URL string `json:"url"`
+
+	// Secret ... only returned when the subscription is created
+	//
+	// example: 6c7d2fb8e0b34a5b9f1e3d2c1b0a9f8e
+	Secret string `json:"secret,omitempty"`
+
+	// VehicleIDs
+	//
+	// required: true
+	// example: [1234]
+	VehicleIDs []int64 `json:"vehicleIds"`
+
+	// Groups
+	//
+	// required: true
+	// example: ["depot-7"]
+	Groups []string `json:"groups"`
+
+	// EventTypes
+	//
+	// required: true
+	// example: ["door_unlocked", "fuel_low"]
+	EventTypes []string `json:"eventTypes"`
+
+	// required: true
+	CreatedAt time.Time `json:"createdAt"`
+
+	// required: true
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Attempt ... a single try at delivering an event
+//
+// swagger:model WebhookAttempt
+type Attempt struct {
+	// required: true
+	At time.Time `json:"at"`
+
+	// StatusCode ... the response code of the receiver. Omitted when no response was received
+	//
+	// example: 502
+	StatusCode int `json:"statusCode,omitempty"`
+
+	// Error ... why the attempt failed
+	//
+	// example: receiver responded with 502
+	Error string `json:"error,omitempty"`
+
+	// DurationMs ... how long the receiver took to respond
+	//
+	// required: true
+	// example: 120
+	DurationMs int64 `json:"durationMs"`
+}
+
+// Delivery response ... an event sent, or being sent, to a subscription
+//
+// swagger:model WebhookDelivery
+type Delivery struct {
+	// ID ... also sent in the X-Webhook-Delivery header, so receivers can drop duplicates
+	//
+	// required: true
+	// example: 7c9e6679-7425-40de-944b-e07fc1f90ae7
+	ID string `json:"id"`
+
+	// SubscriptionID
+	//
+	// required: true
+	// example: 0f8fad5b-d9cb-469f-a165-70867728950e
+	SubscriptionID string `json:"subscriptionId"`
+
+	// Status
+	//
+	// required: true
+	// enum: pending,retrying,succeeded,dead
+	// example: succeeded
+	Status string `json:"status"`
+
+	// required: true
+	Event events.Event `json:"event"`
+
+	// Attempts ... oldest first
+	//
+	// required: true
+	Attempts []Attempt `json:"attempts"`
+
+	// NextAttemptAt ... when the delivery is retried next
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+
+	// required: true
+	CreatedAt time.Time `json:"createdAt"`
+
+	// CompletedAt ... when the delivery succeeded or was dead-lettered
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+
+	// seq ... the key of the delivery in the log of its subscription
+	seq uint64
+}
@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const signaturePrefix = "sha256="
+
+var (
+	errInvalidSignature = errors.New("webhook signature does not match")
+	errStaleTimestamp   = errors.New("webhook timestamp is outside the tolerance")
+)
+
+// Sign ... returns the X-Webhook-Signature of a delivery: the hex HMAC-SHA256, keyed with the subscription secret,
+// of the X-Webhook-Timestamp and the body joined by a period. Signing the timestamp stops a captured delivery from being replayed later
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify ... checks the signature and timestamp headers of a received delivery. Deliveries signed more than
+// tolerance away from now are rejected, a tolerance of zero or less skips the timestamp check
+func Verify(secret, timestampHeader, signatureHeader string, body []byte, tolerance time.Duration, now time.Time) error {
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return errStaleTimestamp
+	}
+
+	if tolerance > 0 {
+		skew := now.Sub(time.Unix(timestamp, 0))
+		if skew > tolerance || skew < -tolerance {
+			return errStaleTimestamp
+		}
+	}
+
+	if !strings.HasPrefix(signatureHeader, signaturePrefix) || !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signatureHeader)) {
+		return errInvalidSignature
+	}
+	return nil
+}
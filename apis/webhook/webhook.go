@@ -0,0 +1,834 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"app_api/apis/events"
+	"app_api/apis/registry"
+	"app_api/shared"
+	loghelper "app_api/shared/loghelpers"
+	"app_api/shared/store"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	DEFAULT_MAX_ATTEMPTS  = 6
+	DEFAULT_RETRY_BACKOFF = 5 * time.Second
+	DEFAULT_TIMEOUT       = 10 * time.Second
+	DEFAULT_WORKERS       = 4
+
+	// DEFAULT_PICK_UP_INTERVAL ... how often the delivery logs are read for the deliveries due which the workers weren't
+	// handed, as their queue was full, a retry came due after a restart, or a previous run stopped before sending them
+	DEFAULT_PICK_UP_INTERVAL = time.Second
+
+	// DEFAULT_DELIVERY_LOG ... how many of its latest deliveries are kept for each subscription
+	DEFAULT_DELIVERY_LOG = 100
+)
+
+var (
+	// Subscriptions are keyed by ID. Deliveries and dead letters have a nested bucket per subscription, deliveries keyed
+	// by sequence so the log is in creation order, dead letters keyed by delivery ID
+	subscriptionsBucket = []byte("webhook_subscriptions")
+	deliveriesBucket    = []byte("webhook_deliveries")
+	deadLettersBucket   = []byte("webhook_dead_letters")
+
+	errSubscriptionNotFound = errors.New("webhook subscription not found")
+	errDeadLetterNotFound   = errors.New("dead letter not found")
+)
+
+// Service ... represents an instance of the webhook package service interface
+type Service interface {
+	CreateSubscription(req SubscriptionRequest) (res Subscription, err *shared.APIError)
+	GetSubscription(id string) (res Subscription, err *shared.APIError)
+	ListSubscriptions() (res []Subscription, err *shared.APIError)
+	UpdateSubscription(id string, req SubscriptionRequest) (res Subscription, err *shared.APIError)
+	DeleteSubscription(id string) (err *shared.APIError)
+
+	ListDeliveries(id string) (res []Delivery, err *shared.APIError)
+	ListDeadLetters(id string) (res []Delivery, err *shared.APIError)
+	RetryDeadLetter(id, deliveryID string) (res Delivery, err *shared.APIError)
+
+	Run(ctx context.Context)
+}
+
+// Option ... configures optional behaviour of the webhook service
+type Option func(*service)
+
+// WithHTTPClient ... sets the client deliveries are sent with
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *service) {
+		s.client = client
+	}
+}
+
+// WithMaxAttempts ... sets how many times a delivery is tried before it is dead-lettered
+func WithMaxAttempts(n int) Option {
+	return func(s *service) {
+		if n > 0 {
+			s.maxAttempts = n
+		}
+	}
+}
+
+// WithRetryBackoff ... sets the wait before the first retry, doubled for every retry after it
+func WithRetryBackoff(d time.Duration) Option {
+	return func(s *service) {
+		if d > 0 {
+			s.backoff = d
+		}
+	}
+}
+
+// WithWorkers ... sets how many deliveries are sent at once
+func WithWorkers(n int) Option {
+	return func(s *service) {
+		if n > 0 {
+			s.workers = n
+		}
+	}
+}
+
+// WithPickUpInterval ... sets how often the delivery logs are read for the deliveries due which the workers weren't handed
+func WithPickUpInterval(d time.Duration) Option {
+	return func(s *service) {
+		if d > 0 {
+			s.pickUpInterval = d
+		}
+	}
+}
+
+// WithDeliveryLogSize ... sets how many of its latest deliveries are kept for each subscription
+func WithDeliveryLogSize(n int) Option {
+	return func(s *service) {
+		if n > 0 {
+			s.logSize = n
+		}
+	}
+}
+
+// NewService ... returns an instance of the webhook package service. Once running, every event published on the bus is
+// delivered to the subscriptions it matches. Group filters are resolved through the registry
+func NewService(db *bolt.DB, bus *events.Bus, registryService registry.Service, opts ...Option) (Service, error) {
+	s := &service{
+		db:             db,
+		bus:            bus,
+		registry:       registryService,
+		client:         newClient(),
+		maxAttempts:    DEFAULT_MAX_ATTEMPTS,
+		backoff:        DEFAULT_RETRY_BACKOFF,
+		workers:        DEFAULT_WORKERS,
+		logSize:        DEFAULT_DELIVERY_LOG,
+		pickUpInterval: DEFAULT_PICK_UP_INTERVAL,
+		wake:           make(chan struct{}, 1),
+		subscriptions:  make(map[string]Subscription),
+		queued:         make(map[string]bool),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.queue = make(chan *Delivery, s.workers*64)
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{subscriptionsBucket, deliveriesBucket, deadLettersBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+
+		return tx.Bucket(subscriptionsBucket).ForEach(func(k, v []byte) error {
+			var sub Subscription
+			if err := json.Unmarshal(v, &sub); err != nil {
+				return err
+			}
+			s.subscriptions[sub.ID] = sub
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+type service struct {
+	db       *bolt.DB
+	bus      *events.Bus
+	registry registry.Service
+
+	client         *http.Client
+	maxAttempts    int
+	backoff        time.Duration
+	workers        int
+	logSize        int
+	pickUpInterval time.Duration
+	queue          chan *Delivery
+	wake           chan struct{}
+	ctx            context.Context
+	now            func() time.Time
+
+	// subscriptions ... every subscription, with its secret, so events are matched without a database read. mu also guards
+	// ctx, and queued, the IDs of the deliveries handed to the workers and not attempted yet
+	mu            sync.RWMutex
+	subscriptions map[string]Subscription
+	queued        map[string]bool
+}
+
+func (s *service) timeNow() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now().UTC()
+}
+
+// CreateSubscription ... starts delivering matching events to a URL. The secret is only returned here
+func (s *service) CreateSubscription(req SubscriptionRequest) (res Subscription, err *shared.APIError) {
+	now := s.timeNow()
+	res = Subscription{ID: uuid.New().String(), CreatedAt: now}
+	res.apply(req, now)
+
+	if res.Secret == "" {
+		secret := make([]byte, 32)
+		if _, randErr := rand.Read(secret); randErr != nil {
+			err = shared.NewAPIError(http.StatusInternalServerError, randErr, "Failed to create webhook subscription")
+			return
+		}
+		res.Secret = hex.EncodeToString(secret)
+	}
+
+	if err = s.putSubscription(res, "Failed to create webhook subscription"); err != nil {
+		return
+	}
+
+	return res, nil
+}
+
+// GetSubscription ... returns a subscription, without its secret
+func (s *service) GetSubscription(id string) (res Subscription, err *shared.APIError) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res, ok := s.subscriptions[id]
+	if !ok {
+		err = webhookError(fmt.Errorf("%w: %s", errSubscriptionNotFound, id), "")
+		return
+	}
+	return res.redacted(), nil
+}
+
+// ListSubscriptions ... returns every subscription, without their secrets, oldest first
+func (s *service) ListSubscriptions() (res []Subscription, err *shared.APIError) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res = make([]Subscription, 0, len(s.subscriptions))
+	for _, sub := range s.subscriptions {
+		res = append(res, sub.redacted())
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].CreatedAt.Equal(res[j].CreatedAt) {
+			return res[i].ID < res[j].ID
+		}
+		return res[i].CreatedAt.Before(res[j].CreatedAt)
+	})
+	return
+}
+
+// UpdateSubscription ... replaces the URL and filters of a subscription. The secret is only replaced if a new one is given
+func (s *service) UpdateSubscription(id string, req SubscriptionRequest) (res Subscription, err *shared.APIError) {
+	s.mu.RLock()
+	res, ok := s.subscriptions[id]
+	s.mu.RUnlock()
+	if !ok {
+		err = webhookError(fmt.Errorf("%w: %s", errSubscriptionNotFound, id), "")
+		return
+	}
+
+	res.apply(req, s.timeNow())
+	if err = s.putSubscription(res, "Failed to update webhook subscription"); err != nil {
+		return
+	}
+
+	return res.redacted(), nil
+}
+
+// DeleteSubscription ... stops deliveries to a subscription, and removes its delivery log and dead letters
+func (s *service) DeleteSubscription(id string) (err *shared.APIError) {
+	txErr := s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(subscriptionsBucket).Get([]byte(id)) == nil {
+			return fmt.Errorf("%w: %s", errSubscriptionNotFound, id)
+		}
+		if err := tx.Bucket(subscriptionsBucket).Delete([]byte(id)); err != nil {
+			return err
+		}
+
+		for _, name := range [][]byte{deliveriesBucket, deadLettersBucket} {
+			if tx.Bucket(name).Bucket([]byte(id)) == nil {
+				continue
+			}
+			if err := tx.Bucket(name).DeleteBucket([]byte(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if txErr != nil {
+		return webhookError(txErr, "Failed to delete webhook subscription")
+	}
+
+	s.mu.Lock()
+	delete(s.subscriptions, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// ListDeliveries ... returns the latest deliveries of a subscription, newest first
+func (s *service) ListDeliveries(id string) (res []Delivery, err *shared.APIError) {
+	return s.listDeliveries(id, deliveriesBucket, "Failed to list webhook deliveries")
+}
+
+// ListDeadLetters ... returns the deliveries of a subscription that ran out of attempts, newest first
+func (s *service) ListDeadLetters(id string) (res []Delivery, err *shared.APIError) {
+	res, err = s.listDeliveries(id, deadLettersBucket, "Failed to list webhook dead letters")
+	if err != nil {
+		return
+	}
+
+	// dead letters are keyed by ID rather than sequence
+	sort.Slice(res, func(i, j int) bool { return res[i].CreatedAt.After(res[j].CreatedAt) })
+	return
+}
+
+// RetryDeadLetter ... takes a delivery out of the dead letters and tries it again, with a fresh set of attempts
+func (s *service) RetryDeadLetter(id, deliveryID string) (res Delivery, err *shared.APIError) {
+	var d Delivery
+	txErr := s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(subscriptionsBucket).Get([]byte(id)) == nil {
+			return fmt.Errorf("%w: %s", errSubscriptionNotFound, id)
+		}
+
+		bucket := tx.Bucket(deadLettersBucket).Bucket([]byte(id))
+		if bucket == nil || bucket.Get([]byte(deliveryID)) == nil {
+			return fmt.Errorf("%w: %s", errDeadLetterNotFound, deliveryID)
+		}
+		if err := json.Unmarshal(bucket.Get([]byte(deliveryID)), &d); err != nil {
+			return err
+		}
+		return bucket.Delete([]byte(deliveryID))
+	})
+	if txErr != nil {
+		err = webhookError(txErr, "Failed to retry dead letter")
+		return
+	}
+
+	// the retry is a new entry in the delivery log, keeping the delivery ID so receivers can still drop duplicates
+	d.Status = DELIVERY_PENDING
+	d.Attempts = []Attempt{}
+	d.CompletedAt = nil
+	d.NextAttemptAt = nil
+	d.seq = 0
+	if saveErr := s.saveDelivery(&d); saveErr != nil {
+		err = webhookError(saveErr, "Failed to retry dead letter")
+		return
+	}
+
+	// the workers own the queued delivery from here on
+	res = d
+	s.enqueue(&d)
+	return res, nil
+}
+
+// Run ... delivers the events published on the bus until the context is cancelled. Deliveries left pending or retrying
+// by a previous run are picked up from their logs, retries once they are due
+func (s *service) Run(ctx context.Context) {
+	sub := s.bus.Subscribe(1024, nil)
+	defer sub.Close()
+
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case d := <-s.queue:
+					s.attempt(d)
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(s.pickUpInterval)
+		defer ticker.Stop()
+		for {
+			// a full batch means more may be due right away
+			for s.pickUp(ctx) {
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case event := <-sub.Events():
+			s.dispatch(event)
+		}
+	}
+}
+
+// dispatch ... creates a delivery of the event for every subscription it matches
+func (s *service) dispatch(event events.Event) {
+	s.mu.RLock()
+	var matched []Subscription
+	needsGroups := false
+	for _, sub := range s.subscriptions {
+		if sub.matchesType(event.Type) {
+			matched = append(matched, sub)
+			needsGroups = needsGroups || len(sub.Groups) > 0
+		}
+	}
+	s.mu.RUnlock()
+
+	var groups []string
+	if needsGroups {
+		// unregistered vehicles aren't in any group
+		if registration, err := s.registry.GetVehicle(event.VehicleID); err == nil {
+			groups = registration.Groups
+		}
+	}
+
+	now := s.timeNow()
+	for _, sub := range matched {
+		if !sub.matchesVehicle(event.VehicleID, groups) {
+			continue
+		}
+
+		d := &Delivery{
+			ID:             uuid.New().String(),
+			SubscriptionID: sub.ID,
+			Status:         DELIVERY_PENDING,
+			Event:          event,
+			Attempts:       []Attempt{},
+			CreatedAt:      now,
+		}
+		if err := s.saveDelivery(d); err != nil {
+			logError(err, fmt.Sprintf("webhook: failed to save delivery of event %d to %s", event.ID, sub.ID))
+			continue
+		}
+		s.enqueue(d)
+	}
+}
+
+// enqueue ... hands a saved delivery to the workers without waiting. When their queue is full it is left in the log, and
+// picked up from there once the queue has room. Deliveries left when the service stops are picked up on the next run
+func (s *service) enqueue(d *Delivery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx == nil || s.queued[d.ID] {
+		return
+	}
+
+	select {
+	case s.queue <- d:
+		s.queued[d.ID] = true
+	default:
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// pickUp ... hands the workers the deliveries due in the logs which they haven't been handed, pending ones and retries
+// whose time has come, waiting for room in their queue. Returns true when it handed them a full queue, as more may be due
+func (s *service) pickUp(ctx context.Context) bool {
+	batch := cap(s.queue)
+	now := s.timeNow()
+
+	// the queued deliveries are copied rather than locked during the read, as writers may wait for it
+	s.mu.RLock()
+	queued := make(map[string]bool, len(s.queued))
+	for id := range s.queued {
+		queued[id] = true
+	}
+	s.mu.RUnlock()
+
+	var due []*Delivery
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(deliveriesBucket).ForEach(func(subID, _ []byte) error {
+			c := tx.Bucket(deliveriesBucket).Bucket(subID).Cursor()
+			for k, v := c.First(); k != nil && len(due) < batch; k, v = c.Next() {
+				d := &Delivery{}
+				if err := json.Unmarshal(v, d); err != nil {
+					return err
+				}
+				if d.due(now) && !queued[d.ID] {
+					d.seq = uint64(store.KeyInt64(k))
+					due = append(due, d)
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		logError(err, "webhook: failed to load the deliveries due")
+		return false
+	}
+
+	for _, d := range due {
+		s.mu.Lock()
+		if s.queued[d.ID] {
+			s.mu.Unlock()
+			continue
+		}
+		s.queued[d.ID] = true
+		s.mu.Unlock()
+
+		select {
+		case s.queue <- d:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return len(due) == batch
+}
+
+// release ... lets a delivery be handed to the workers again, once its attempt is saved
+func (s *service) release(d *Delivery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.queued, d.ID)
+}
+
+// attempt ... sends a delivery once, then records the outcome and schedules a retry or dead-letters the delivery
+func (s *service) attempt(d *Delivery) {
+	s.mu.RLock()
+	sub, ok := s.subscriptions[d.SubscriptionID]
+	s.mu.RUnlock()
+	if !ok {
+		// deleted along with its log
+		s.release(d)
+		return
+	}
+
+	// a delivery picked up from the log may have been attempted since it was read
+	current, err := s.loadDelivery(d.SubscriptionID, d.seq)
+	if err != nil || current == nil || current.ID != d.ID || current.Status != d.Status || len(current.Attempts) != len(d.Attempts) {
+		if err != nil {
+			logError(err, fmt.Sprintf("webhook: failed to load delivery %s", d.ID))
+		}
+		s.release(d)
+		return
+	}
+
+	attempt := s.send(sub, d)
+	d.Attempts = append(d.Attempts, attempt)
+	d.NextAttemptAt = nil
+
+	var retryIn time.Duration
+	switch {
+	case attempt.Error == "":
+		d.Status = DELIVERY_SUCCEEDED
+		d.CompletedAt = &attempt.At
+	case len(d.Attempts) >= s.maxAttempts:
+		d.Status = DELIVERY_DEAD
+		d.CompletedAt = &attempt.At
+	default:
+		d.Status = DELIVERY_RETRYING
+		retryIn = s.backoff << uint(len(d.Attempts)-1)
+		next := attempt.At.Add(retryIn)
+		d.NextAttemptAt = &next
+	}
+
+	if err := s.saveDelivery(d); err != nil {
+		logError(err, fmt.Sprintf("webhook: failed to save delivery %s", d.ID))
+	}
+	s.release(d)
+
+	if d.Status == DELIVERY_RETRYING {
+		time.AfterFunc(retryIn, func() { s.enqueue(d) })
+	}
+}
+
+// send ... POSTs the event to the subscription, signed with its secret
+func (s *service) send(sub Subscription, d *Delivery) (attempt Attempt) {
+	attempt.At = s.timeNow()
+
+	body, err := json.Marshal(d.Event)
+	if err != nil {
+		attempt.Error = err.Error()
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return
+	}
+
+	timestamp := attempt.At.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HEADER_SUBSCRIPTION, sub.ID)
+	req.Header.Set(HEADER_DELIVERY, d.ID)
+	req.Header.Set(HEADER_EVENT, d.Event.Type)
+	req.Header.Set(HEADER_TIMESTAMP, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HEADER_SIGNATURE, Sign(sub.Secret, timestamp, body))
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return
+	}
+	resp.Body.Close()
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("receiver responded with %d", resp.StatusCode)
+	}
+	return
+}
+
+// loadDelivery ... reads the delivery at seq in the log of a subscription, nil if it was trimmed from it
+func (s *service) loadDelivery(subscriptionID string, seq uint64) (res *Delivery, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		log := tx.Bucket(deliveriesBucket).Bucket([]byte(subscriptionID))
+		if log == nil {
+			return nil
+		}
+		v := log.Get(store.Int64Key(int64(seq)))
+		if v == nil {
+			return nil
+		}
+		res = &Delivery{}
+		return json.Unmarshal(v, res)
+	})
+	if res != nil {
+		res.seq = seq
+	}
+	return
+}
+
+// saveDelivery ... writes a delivery to the log of its subscription, trimming the log to its size, and files dead deliveries
+// in the dead letters
+func (s *service) saveDelivery(d *Delivery) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(subscriptionsBucket).Get([]byte(d.SubscriptionID)) == nil {
+			return fmt.Errorf("%w: %s", errSubscriptionNotFound, d.SubscriptionID)
+		}
+
+		v, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+
+		log, err := tx.Bucket(deliveriesBucket).CreateBucketIfNotExists([]byte(d.SubscriptionID))
+		if err != nil {
+			return err
+		}
+
+		// a delivery trimmed from the log while it was in flight stays out of it
+		switch {
+		case d.seq == 0:
+			if d.seq, err = log.NextSequence(); err != nil {
+				return err
+			}
+			fallthrough
+		case log.Get(store.Int64Key(int64(d.seq))) != nil:
+			if err := log.Put(store.Int64Key(int64(d.seq)), v); err != nil {
+				return err
+			}
+		}
+
+		// trim the oldest completed entries past the log size. Deliveries still to be sent stay, as the workers pick them
+		// up from the log
+		if excess := log.Stats().KeyN - s.logSize; excess > 0 {
+			var expired [][]byte
+			c := log.Cursor()
+			for k, v := c.First(); k != nil && len(expired) < excess; k, v = c.Next() {
+				var entry Delivery
+				if err := json.Unmarshal(v, &entry); err != nil {
+					return err
+				}
+				if entry.Status != DELIVERY_PENDING && entry.Status != DELIVERY_RETRYING {
+					expired = append(expired, k)
+				}
+			}
+			for _, k := range expired {
+				if err := log.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+
+		if d.Status != DELIVERY_DEAD {
+			return nil
+		}
+		deadLetters, err := tx.Bucket(deadLettersBucket).CreateBucketIfNotExists([]byte(d.SubscriptionID))
+		if err != nil {
+			return err
+		}
+		return deadLetters.Put([]byte(d.ID), v)
+	})
+}
+
+func (s *service) listDeliveries(id string, bucketName []byte, clientErr string) (res []Delivery, err *shared.APIError) {
+	res = []Delivery{}
+
+	txErr := s.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(subscriptionsBucket).Get([]byte(id)) == nil {
+			return fmt.Errorf("%w: %s", errSubscriptionNotFound, id)
+		}
+
+		bucket := tx.Bucket(bucketName).Bucket([]byte(id))
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var d Delivery
+			if err := json.Unmarshal(v, &d); err != nil {
+				return err
+			}
+			res = append(res, d)
+		}
+		return nil
+	})
+	if txErr != nil {
+		err = webhookError(txErr, clientErr)
+	}
+	return
+}
+
+func (s *service) putSubscription(sub Subscription, clientErr string) *shared.APIError {
+	v, err := json.Marshal(sub)
+	if err != nil {
+		return webhookError(err, clientErr)
+	}
+
+	txErr := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(subscriptionsBucket).Put([]byte(sub.ID), v)
+	})
+	if txErr != nil {
+		return webhookError(txErr, clientErr)
+	}
+
+	s.mu.Lock()
+	s.subscriptions[sub.ID] = sub
+	s.mu.Unlock()
+	return nil
+}
+
+// apply ... sets the fields of a subscription from a request
+func (sub *Subscription) apply(req SubscriptionRequest, now time.Time) {
+	sub.URL = req.URL
+	if req.Secret != "" {
+		sub.Secret = req.Secret
+	}
+
+	sub.VehicleIDs = append([]int64{}, req.VehicleIDs...)
+	sort.Slice(sub.VehicleIDs, func(i, j int) bool { return sub.VehicleIDs[i] < sub.VehicleIDs[j] })
+	sub.Groups = append([]string{}, req.Groups...)
+	sort.Strings(sub.Groups)
+	sub.EventTypes = append([]string{}, req.EventTypes...)
+	sort.Strings(sub.EventTypes)
+
+	sub.UpdatedAt = now
+}
+
+func (sub Subscription) redacted() Subscription {
+	sub.Secret = ""
+	return sub
+}
+
+func (sub Subscription) matchesType(eventType string) bool {
+	if len(sub.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range sub.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesVehicle ... a vehicle matches if it is listed, or is in one of the groups. Without either filter every vehicle matches
+func (sub Subscription) matchesVehicle(vehicleID int64, groups []string) bool {
+	if len(sub.VehicleIDs) == 0 && len(sub.Groups) == 0 {
+		return true
+	}
+	for _, id := range sub.VehicleIDs {
+		if id == vehicleID {
+			return true
+		}
+	}
+	for _, group := range sub.Groups {
+		for _, g := range groups {
+			if g == group {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// due ... a delivery is due to be sent when it is pending, or retrying and its backoff is over
+func (d *Delivery) due(now time.Time) bool {
+	switch d.Status {
+	case DELIVERY_PENDING:
+		return true
+	case DELIVERY_RETRYING:
+		return d.NextAttemptAt == nil || !now.Before(*d.NextAttemptAt)
+	default:
+		return false
+	}
+}
+
+func webhookError(err error, clientErr string) *shared.APIError {
+	switch {
+	case errors.Is(err, errSubscriptionNotFound):
+		return shared.NewAPIError(http.StatusNotFound, err, "Webhook subscription not found")
+	case errors.Is(err, errDeadLetterNotFound):
+		return shared.NewAPIError(http.StatusNotFound, err, "Dead letter not found")
+	default:
+		return shared.NewAPIError(http.StatusInternalServerError, err, clientErr)
+	}
+}
+
+func logError(err error, internalErr string) {
+	loghelper.LogErrorsNoCTX(shared.NewAPIError(http.StatusInternalServerError, err, "Internal Error").SetInternalErrorMessage(internalErr))
+}
@@ -0,0 +1,405 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"app_api/apis/events"
+	"app_api/apis/registry"
+	"app_api/shared/store/storetest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// receiver ... a local webhook receiver that records the deliveries it verified, and fails the first failures of them
+type receiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	failures int
+	received []events.Event
+	headers  []http.Header
+}
+
+func newReceiver(t *testing.T, failures int) *receiver {
+	rec := &receiver{failures: failures}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		if err := Verify(testSecret, r.Header.Get(HEADER_TIMESTAMP), r.Header.Get(HEADER_SIGNATURE), body, time.Minute, time.Now()); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		if rec.failures > 0 {
+			rec.failures--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		var event events.Event
+		assert.NoError(t, json.Unmarshal(body, &event))
+		rec.received = append(rec.received, event)
+		rec.headers = append(rec.headers, r.Header.Clone())
+	}))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+func (rec *receiver) events() []events.Event {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]events.Event{}, rec.received...)
+}
+
+type testService struct {
+	*service
+	bus      *events.Bus
+	registry registry.Service
+}
+
+// newTestService ... returns a running webhook service, stopped once the test completes
+func newTestService(t *testing.T, opts ...Option) *testService {
+	db := storetest.Open(t)
+	bus := events.NewBus()
+	registryService, err := registry.NewService(db)
+	assert.NoError(t, err)
+
+	// the receivers listen on loopback, which the default client refuses to connect to
+	defaults := []Option{WithRetryBackoff(time.Millisecond), WithHTTPClient(&http.Client{Timeout: DEFAULT_TIMEOUT})}
+	s, err := NewService(db, bus, registryService, append(defaults, opts...)...)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// wait for Run to subscribe to the bus, so no event published by the test is missed
+	assert.Eventually(t, func() bool {
+		s.(*service).mu.RLock()
+		defer s.(*service).mu.RUnlock()
+		return s.(*service).ctx != nil
+	}, time.Second, time.Millisecond)
+
+	return &testService{service: s.(*service), bus: bus, registry: registryService}
+}
+
+func (s *testService) subscribe(t *testing.T, req SubscriptionRequest) Subscription {
+	if req.Secret == "" {
+		req.Secret = testSecret
+	}
+	sub, err := s.CreateSubscription(req)
+	assert.Nil(t, err)
+	return sub
+}
+
+func TestSignature(t *testing.T) {
+	now := time.Unix(1604340000, 0)
+	body := []byte(`{"id":1}`)
+	signature := Sign(testSecret, now.Unix(), body)
+
+	assert.NoError(t, Verify(testSecret, "1604340000", signature, body, time.Minute, now))
+	assert.NoError(t, Verify(testSecret, "1604340000", signature, body, 0, now.Add(time.Hour)), "no tolerance skips the timestamp check")
+
+	assert.Equal(t, errInvalidSignature, Verify("another-secret-value", "1604340000", signature, body, time.Minute, now))
+	assert.Equal(t, errInvalidSignature, Verify(testSecret, "1604340000", signature, []byte(`{"id":2}`), time.Minute, now))
+	assert.Equal(t, errInvalidSignature, Verify(testSecret, "1604340001", signature, body, time.Minute, now), "the timestamp is signed")
+	assert.Equal(t, errStaleTimestamp, Verify(testSecret, "1604340000", signature, body, time.Minute, now.Add(2*time.Minute)))
+	assert.Equal(t, errStaleTimestamp, Verify(testSecret, "soon", signature, body, time.Minute, now))
+}
+
+func TestSubscriptions(t *testing.T) {
+	s := newTestService(t)
+
+	created := s.subscribe(t, SubscriptionRequest{URL: "https://example.com/hooks", VehicleIDs: []int64{1235, 1234}, EventTypes: []string{"fuel_low"}})
+	assert.Equal(t, testSecret, created.Secret, "the secret is returned on creation")
+	assert.Equal(t, []int64{1234, 1235}, created.VehicleIDs)
+
+	generated, err := s.CreateSubscription(SubscriptionRequest{URL: "https://example.com/other"})
+	assert.Nil(t, err)
+	assert.Len(t, generated.Secret, 64)
+
+	got, err := s.GetSubscription(created.ID)
+	assert.Nil(t, err)
+	assert.Empty(t, got.Secret)
+	assert.Equal(t, created.URL, got.URL)
+
+	list, err := s.ListSubscriptions()
+	assert.Nil(t, err)
+	assert.Len(t, list, 2)
+
+	updated, err := s.UpdateSubscription(created.ID, SubscriptionRequest{URL: "https://example.com/moved", Groups: []string{"depot-7"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/moved", updated.URL)
+	assert.Empty(t, updated.VehicleIDs)
+	assert.Equal(t, testSecret, s.subscriptions[created.ID].Secret, "the secret is kept when left out")
+
+	assert.Nil(t, s.DeleteSubscription(created.ID))
+
+	_, err = s.GetSubscription(created.ID)
+	assert.Equal(t, http.StatusNotFound, err.ErrorCode)
+	_, err = s.UpdateSubscription(created.ID, SubscriptionRequest{URL: "https://example.com/hooks"})
+	assert.Equal(t, http.StatusNotFound, err.ErrorCode)
+	assert.Equal(t, http.StatusNotFound, s.DeleteSubscription(created.ID).ErrorCode)
+	_, err = s.ListDeliveries(created.ID)
+	assert.Equal(t, http.StatusNotFound, err.ErrorCode)
+	_, err = s.RetryDeadLetter(generated.ID, "missing")
+	assert.Equal(t, http.StatusNotFound, err.ErrorCode)
+}
+
+func TestDelivery(t *testing.T) {
+	s := newTestService(t)
+	rec := newReceiver(t, 0)
+	sub := s.subscribe(t, SubscriptionRequest{URL: rec.URL})
+
+	published := s.bus.Publish(events.Event{Type: events.EVENT_FUEL_LOW, VehicleID: 1234, Time: time.Now().UTC()})
+
+	assert.Eventually(t, func() bool { return len(rec.events()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, published.ID, rec.events()[0].ID)
+
+	rec.mu.Lock()
+	headers := rec.headers[0]
+	rec.mu.Unlock()
+	assert.Equal(t, sub.ID, headers.Get(HEADER_SUBSCRIPTION))
+	assert.Equal(t, events.EVENT_FUEL_LOW, headers.Get(HEADER_EVENT))
+	assert.Equal(t, "application/json", headers.Get("Content-Type"))
+
+	assert.Eventually(t, func() bool {
+		deliveries, err := s.ListDeliveries(sub.ID)
+		return err == nil && len(deliveries) == 1 && deliveries[0].Status == DELIVERY_SUCCEEDED
+	}, time.Second, 5*time.Millisecond)
+
+	deliveries, _ := s.ListDeliveries(sub.ID)
+	assert.Equal(t, headers.Get(HEADER_DELIVERY), deliveries[0].ID)
+	assert.Len(t, deliveries[0].Attempts, 1)
+	assert.Equal(t, http.StatusOK, deliveries[0].Attempts[0].StatusCode)
+	assert.NotNil(t, deliveries[0].CompletedAt)
+}
+
+func TestDeliveryFilters(t *testing.T) {
+	s := newTestService(t)
+	rec := newReceiver(t, 0)
+
+	_, err := s.registry.CreateGroup(registry.GroupRequest{Name: "depot-7"})
+	assert.Nil(t, err)
+	_, err = s.registry.AddGroupVehicle("depot-7", 2000)
+	assert.Nil(t, err)
+
+	s.subscribe(t, SubscriptionRequest{URL: rec.URL, VehicleIDs: []int64{1234}, Groups: []string{"depot-7"}, EventTypes: []string{events.EVENT_DOOR_UNLOCKED}})
+
+	s.bus.Publish(events.Event{Type: events.EVENT_DOOR_UNLOCKED, VehicleID: 1234})
+	s.bus.Publish(events.Event{Type: events.EVENT_DOOR_LOCKED, VehicleID: 1234})
+	s.bus.Publish(events.Event{Type: events.EVENT_DOOR_UNLOCKED, VehicleID: 1235})
+	s.bus.Publish(events.Event{Type: events.EVENT_DOOR_UNLOCKED, VehicleID: 2000})
+	s.bus.Publish(events.Event{Type: events.EVENT_DOOR_UNLOCKED, VehicleID: 1234})
+
+	assert.Eventually(t, func() bool { return len(rec.events()) == 3 }, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	var vehicles []int64
+	for _, event := range rec.events() {
+		assert.Equal(t, events.EVENT_DOOR_UNLOCKED, event.Type)
+		vehicles = append(vehicles, event.VehicleID)
+	}
+	assert.ElementsMatch(t, []int64{1234, 2000, 1234}, vehicles)
+}
+
+func TestDeliveryRetries(t *testing.T) {
+	s := newTestService(t, WithMaxAttempts(3))
+	rec := newReceiver(t, 2)
+	sub := s.subscribe(t, SubscriptionRequest{URL: rec.URL})
+
+	s.bus.Publish(events.Event{Type: events.EVENT_ENGINE_STARTED, VehicleID: 1234})
+
+	assert.Eventually(t, func() bool { return len(rec.events()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		deliveries, _ := s.ListDeliveries(sub.ID)
+		return len(deliveries) == 1 && deliveries[0].Status == DELIVERY_SUCCEEDED
+	}, time.Second, 5*time.Millisecond)
+
+	deliveries, _ := s.ListDeliveries(sub.ID)
+	assert.Len(t, deliveries[0].Attempts, 3)
+	assert.Equal(t, http.StatusBadGateway, deliveries[0].Attempts[0].StatusCode)
+	assert.Equal(t, "receiver responded with 502", deliveries[0].Attempts[0].Error)
+	assert.Empty(t, deliveries[0].Attempts[2].Error)
+
+	deadLetters, err := s.ListDeadLetters(sub.ID)
+	assert.Nil(t, err)
+	assert.Empty(t, deadLetters)
+}
+
+func TestDefaultClientRefusesLocalDestinations(t *testing.T) {
+	s := newTestService(t, WithHTTPClient(newClient()), WithMaxAttempts(1))
+	rec := newReceiver(t, 0)
+	sub := s.subscribe(t, SubscriptionRequest{URL: rec.URL})
+
+	s.bus.Publish(events.Event{Type: events.EVENT_ENGINE_STARTED, VehicleID: 1234})
+
+	assert.Eventually(t, func() bool {
+		deliveries, _ := s.ListDeliveries(sub.ID)
+		return len(deliveries) == 1 && deliveries[0].Status == DELIVERY_DEAD
+	}, time.Second, 5*time.Millisecond)
+
+	deliveries, _ := s.ListDeliveries(sub.ID)
+	assert.Contains(t, deliveries[0].Attempts[0].Error, "is not a public address")
+	assert.Empty(t, rec.events())
+}
+
+func TestCheckDestination(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:8006", "[::1]:443", "169.254.169.254:80", "10.1.2.3:443", "172.20.0.1:443", "192.168.1.1:80", "0.0.0.0:80", "[fd00::1]:443", "[fe80::1]:443", "[::ffff:127.0.0.1]:80"} {
+		assert.Error(t, checkDestination(address), address)
+	}
+	for _, address := range []string{"93.184.216.34:443", "[2606:2800:220:1:248:1893:25c8:1946]:443", "172.32.0.1:443"} {
+		assert.NoError(t, checkDestination(address), address)
+	}
+}
+
+func TestDefaultClientDoesNotFollowRedirects(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "https://example.com/hooks", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.ErrUseLastResponse, newClient().CheckRedirect(req, []*http.Request{req}))
+}
+
+func TestDeadLetters(t *testing.T) {
+	s := newTestService(t, WithMaxAttempts(2))
+	rec := newReceiver(t, 2)
+	sub := s.subscribe(t, SubscriptionRequest{URL: rec.URL})
+
+	published := s.bus.Publish(events.Event{Type: events.EVENT_ENGINE_STOPPED, VehicleID: 1234})
+
+	var deadLetters []Delivery
+	assert.Eventually(t, func() bool {
+		deadLetters, _ = s.ListDeadLetters(sub.ID)
+		return len(deadLetters) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, DELIVERY_DEAD, deadLetters[0].Status)
+	assert.Len(t, deadLetters[0].Attempts, 2)
+	assert.Empty(t, rec.events())
+
+	retried, err := s.RetryDeadLetter(sub.ID, deadLetters[0].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, deadLetters[0].ID, retried.ID, "the retry keeps the delivery ID")
+	assert.Equal(t, DELIVERY_PENDING, retried.Status)
+
+	assert.Eventually(t, func() bool { return len(rec.events()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, published.ID, rec.events()[0].ID)
+
+	deadLetters, err = s.ListDeadLetters(sub.ID)
+	assert.Nil(t, err)
+	assert.Empty(t, deadLetters)
+
+	_, err = s.RetryDeadLetter(sub.ID, retried.ID)
+	assert.Equal(t, http.StatusNotFound, err.ErrorCode)
+}
+
+func TestDeliveryLogSize(t *testing.T) {
+	s := newTestService(t, WithDeliveryLogSize(2))
+	rec := newReceiver(t, 0)
+	sub := s.subscribe(t, SubscriptionRequest{URL: rec.URL})
+
+	for i := 0; i < 4; i++ {
+		s.bus.Publish(events.Event{Type: events.EVENT_FUEL_LOW, VehicleID: 1234})
+	}
+	assert.Eventually(t, func() bool { return len(rec.events()) == 4 }, time.Second, 5*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		deliveries, _ := s.ListDeliveries(sub.ID)
+		return len(deliveries) == 2 && deliveries[0].Status == DELIVERY_SUCCEEDED && deliveries[1].Status == DELIVERY_SUCCEEDED
+	}, time.Second, 5*time.Millisecond)
+
+	deliveries, _ := s.ListDeliveries(sub.ID)
+	assert.True(t, deliveries[0].Event.ID > deliveries[1].Event.ID, "newest first")
+}
+
+func TestDispatchDoesNotWaitForWorkers(t *testing.T) {
+	s := newTestService(t, WithWorkers(1), WithPickUpInterval(10*time.Millisecond))
+
+	// the receiver holds every delivery until released, so the queue of the only worker fills up
+	release := make(chan struct{})
+	var mu sync.Mutex
+	received := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		received++
+	}))
+	t.Cleanup(server.Close)
+	sub := s.subscribe(t, SubscriptionRequest{URL: server.URL})
+
+	total := cap(s.queue) + 20
+	for i := 0; i < total; i++ {
+		s.bus.Publish(events.Event{Type: events.EVENT_FUEL_LOW, VehicleID: 1234})
+	}
+	assert.Eventually(t, func() bool {
+		deliveries, _ := s.ListDeliveries(sub.ID)
+		return len(deliveries) == total
+	}, time.Second, 5*time.Millisecond, "every delivery is saved while the worker is busy")
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return received == total
+	}, 5*time.Second, 10*time.Millisecond, "the deliveries left out of the queue are picked up from the log")
+}
+
+func TestPickUpWaitsForRetries(t *testing.T) {
+	s := newTestService(t, WithPickUpInterval(10*time.Millisecond))
+	rec := newReceiver(t, 0)
+	sub := s.subscribe(t, SubscriptionRequest{URL: rec.URL})
+
+	// deliveries left by a previous run: one pending, and one retrying after a backoff which isn't over yet
+	now := time.Now().UTC()
+	next := now.Add(300 * time.Millisecond)
+	assert.NoError(t, s.saveDelivery(&Delivery{
+		ID:             "retrying",
+		SubscriptionID: sub.ID,
+		Status:         DELIVERY_RETRYING,
+		Event:          events.Event{ID: 1, Type: events.EVENT_FUEL_LOW, VehicleID: 1234},
+		Attempts:       []Attempt{{At: now, StatusCode: http.StatusBadGateway, Error: "receiver responded with 502"}},
+		CreatedAt:      now,
+		NextAttemptAt:  &next,
+	}))
+	assert.NoError(t, s.saveDelivery(&Delivery{
+		ID:             "pending",
+		SubscriptionID: sub.ID,
+		Status:         DELIVERY_PENDING,
+		Event:          events.Event{ID: 2, Type: events.EVENT_FUEL_LOW, VehicleID: 1234},
+		Attempts:       []Attempt{},
+		CreatedAt:      now,
+	}))
+
+	assert.Eventually(t, func() bool { return len(rec.events()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int64(2), rec.events()[0].ID, "the pending delivery is sent first")
+
+	assert.Eventually(t, func() bool { return len(rec.events()) == 2 }, 2*time.Second, 5*time.Millisecond)
+	assert.False(t, time.Now().Before(next), "the retry waits for its backoff")
+	assert.Equal(t, int64(1), rec.events()[1].ID)
+
+	deliveries, _ := s.ListDeliveries(sub.ID)
+	assert.Eventually(t, func() bool {
+		deliveries, _ = s.ListDeliveries(sub.ID)
+		return len(deliveries) == 2 && deliveries[0].Status == DELIVERY_SUCCEEDED && deliveries[1].Status == DELIVERY_SUCCEEDED
+	}, time.Second, 5*time.Millisecond)
+	assert.Len(t, deliveries[1].Attempts, 2)
+}
@@ -0,0 +1,322 @@
+// Package client ... a typed Go client for the SmartCar API. Requests and responses use the model types of the apis
+// packages, failed requests return an *Error decoded from the API's error response, and idempotent requests are retried
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HEADER_API_KEY     = "X-API-Key"
+	HEADER_RETRY_AFTER = "Retry-After"
+
+	// DEFAULT_TIMEOUT ... how long a single attempt of a request may take. Streams aren't limited
+	DEFAULT_TIMEOUT = 30 * time.Second
+
+	DEFAULT_USER_AGENT = "app_api-client"
+)
+
+// RetryPolicy ... how failed requests are retried. Requests are only retried when the API couldn't have acted on them:
+// on a network error, a 429, or a 502, 503 or 504 to an idempotent request
+type RetryPolicy struct {
+	// MaxRetries ... the retries after the first attempt. Zero disables retries
+	MaxRetries int
+
+	// Backoff ... the wait before the first retry, doubled for every retry after it
+	Backoff time.Duration
+
+	// MaxBackoff ... the longest wait between attempts, including one asked for by a Retry-After header
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy ... retries twice, after about 200ms and 400ms
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 2, Backoff: 200 * time.Millisecond, MaxBackoff: 5 * time.Second}
+
+// Client ... calls the API at a base URL. It is safe for concurrent use
+type Client struct {
+	baseURL    string
+	adminURL   string
+	httpClient *http.Client
+	apiKey     string
+	userAgent  string
+	timeout    time.Duration
+	retry      RetryPolicy
+}
+
+// Option ... configures optional behaviour of the client
+type Option func(*Client)
+
+// WithHTTPClient ... sends requests with the given client instead of http.DefaultClient. Its Timeout also applies to
+// streams, so it should be left unset when streaming
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAPIKey ... sends the key in the X-API-Key header of every request
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithAdminURL ... sends the /admin requests to the admin API at adminURL, e.g. http://localhost:8006, instead of the
+// base URL
+func WithAdminURL(adminURL string) Option {
+	return func(c *Client) {
+		c.adminURL = adminURL
+	}
+}
+
+// WithUserAgent ... sets the User-Agent header of every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithTimeout ... sets how long a single attempt of a request may take. Zero leaves it to the context
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithRetryPolicy ... replaces DefaultRetryPolicy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// New ... returns a client of the API at baseURL, e.g. http://localhost:8003
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    strings.TrimRight(u.String(), "/"),
+		httpClient: http.DefaultClient,
+		userAgent:  DEFAULT_USER_AGENT,
+		timeout:    DEFAULT_TIMEOUT,
+		retry:      DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.adminURL == "" {
+		c.adminURL = c.baseURL
+	}
+	u, err = url.Parse(c.adminURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid admin URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid admin URL %q: scheme must be http or https", c.adminURL)
+	}
+	c.adminURL = strings.TrimRight(u.String(), "/")
+	return c, nil
+}
+
+// request ... a call to the API. Idempotent requests are retried on more failures, as repeating them is harmless
+type request struct {
+	method     string
+	path       string
+	query      url.Values
+	body       interface{}
+	idempotent bool
+}
+
+func get(path string, query url.Values) request {
+	return request{method: http.MethodGet, path: path, query: query, idempotent: true}
+}
+
+func post(path string, body interface{}) request {
+	return request{method: http.MethodPost, path: path, body: body}
+}
+
+func put(path string, body interface{}) request {
+	return request{method: http.MethodPut, path: path, body: body, idempotent: true}
+}
+
+func del(path string) request {
+	return request{method: http.MethodDelete, path: path, idempotent: true}
+}
+
+// do ... sends the request, retrying it as the retry policy allows, and decodes the response into out unless it is nil
+func (c *Client) do(ctx context.Context, req request, out interface{}) error {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return fmt.Errorf("failed to encode request body: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		wait, err := c.attempt(ctx, req, body, out)
+		if err == nil {
+			return nil
+		}
+		if wait < 0 || attempt >= c.retry.MaxRetries {
+			return err
+		}
+
+		if wait == 0 {
+			wait = c.backoff(attempt)
+		}
+		if wait > c.retry.MaxBackoff && c.retry.MaxBackoff > 0 {
+			wait = c.retry.MaxBackoff
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt ... sends the request once. A failed attempt returns how long to wait before it can be retried: zero for the
+// policy's backoff, or negative when it must not be retried
+func (c *Client) attempt(ctx context.Context, req request, body []byte, out interface{}) (time.Duration, error) {
+	attemptCtx := ctx
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	httpReq, err := c.newRequest(attemptCtx, req.method, req.path, req.query, body)
+	if err != nil {
+		return -1, err
+	}
+
+	res, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		// the request may have reached the API before the connection failed or the attempt timed out
+		if !req.idempotent || ctx.Err() != nil {
+			return -1, err
+		}
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		apiErr := decodeError(res)
+		if !retryable(res.StatusCode, req.idempotent) {
+			return -1, apiErr
+		}
+		return retryAfter(res.Header.Get(HEADER_RETRY_AFTER)), apiErr
+	}
+
+	if out == nil {
+		_, _ = io.Copy(ioutil.Discard, res.Body)
+		return 0, nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return -1, fmt.Errorf("failed to decode %s %s response: %w", req.method, req.path, err)
+	}
+	return 0, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body []byte) (*http.Request, error) {
+	target := c.baseURL + path
+	if strings.HasPrefix(path, "/admin/") || path == "/admin" {
+		target = c.adminURL + path
+	}
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	if c.apiKey != "" {
+		httpReq.Header.Set(HEADER_API_KEY, c.apiKey)
+	}
+	return httpReq, nil
+}
+
+// backoff ... doubles the policy's backoff for every retry, with up to 20% jitter so clients failing together don't
+// retry together
+func (c *Client) backoff(attempt int) time.Duration {
+	wait := c.retry.Backoff << uint(attempt)
+	if wait <= 0 {
+		return 0
+	}
+	return wait - time.Duration(rand.Int63n(int64(wait)/5+1))
+}
+
+// retryable ... a 429 was rejected before the API acted on it. A gateway error or an unavailable GM might have come after
+func retryable(statusCode int, idempotent bool) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent
+	default:
+		return false
+	}
+}
+
+// retryAfter ... reads a Retry-After header in seconds or as an HTTP date. Zero when absent or invalid
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// errMissingID ... returned instead of sending a request for a resource without an ID, which would hit another route
+var errMissingID = errors.New("id is required")
+
+// join ... joins the segments into a path, escaping each
+func join(segments ...interface{}) string {
+	var b strings.Builder
+	for _, segment := range segments {
+		b.WriteString("/")
+		b.WriteString(url.PathEscape(fmt.Sprint(segment)))
+	}
+	return b.String()
+}
@@ -0,0 +1,229 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"app_api/apis/registry"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRetryPolicy = RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+
+// newFakeServer ... answers every request with handler, counting the requests
+func newFakeServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, n int32)) (*Client, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r, atomic.AddInt32(&requests, 1))
+	}))
+	t.Cleanup(server.Close)
+
+	c, err := New(server.URL+"/", WithAPIKey("k3y"), WithRetryPolicy(testRetryPolicy))
+	require.NoError(t, err)
+	return c, &requests
+}
+
+func TestNewInvalidBaseURL(t *testing.T) {
+	_, err := New("localhost:8003")
+	assert.Error(t, err)
+}
+
+func TestAdminURL(t *testing.T) {
+	admin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/admin/poller", r.URL.Path)
+		w.Write([]byte(`{"status":"running"}`))
+	}))
+	defer admin.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/vehicles/1234", r.URL.Path)
+		w.Write([]byte(`{"vin":"123123412412"}`))
+	}))
+	defer api.Close()
+
+	c, err := New(api.URL, WithAdminURL(admin.URL+"/"))
+	require.NoError(t, err)
+	status, err := c.GetPollerStatus(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "running", status.Status)
+	_, err = c.GetVehicle(context.Background(), 1234)
+	assert.NoError(t, err)
+
+	_, err = New(api.URL, WithAdminURL("localhost:8006"))
+	assert.Error(t, err)
+}
+
+func TestRequestHeaders(t *testing.T) {
+	c, _ := newFakeServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		assert.Equal(t, "/vehicles/1234/engine", r.URL.Path)
+		assert.Equal(t, "k3y", r.Header.Get(HEADER_API_KEY))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, DEFAULT_USER_AGENT, r.Header.Get("User-Agent"))
+		w.Write([]byte(`{"status":"success"}`))
+	})
+
+	res, err := c.SendEngineAction(context.Background(), 1234, "START")
+	assert.NoError(t, err)
+	assert.Equal(t, "success", res.Action)
+}
+
+func TestRetryIdempotentRequest(t *testing.T) {
+	c, requests := newFakeServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		if n < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":1,"message":"GM is unavailable"}`))
+			return
+		}
+		w.Write([]byte(`{"vin":"123123412412"}`))
+	})
+
+	res, err := c.GetVehicle(context.Background(), 1234)
+	assert.NoError(t, err)
+	assert.Equal(t, "123123412412", res.Vin)
+	assert.Equal(t, int32(3), atomic.LoadInt32(requests))
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	c, requests := newFakeServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		w.WriteHeader(http.StatusGatewayTimeout)
+	})
+
+	_, err := c.GetVehicle(context.Background(), 1234)
+	assert.Equal(t, http.StatusGatewayTimeout, StatusCode(err))
+	assert.Equal(t, int32(1+testRetryPolicy.MaxRetries), atomic.LoadInt32(requests))
+}
+
+func TestNoRetryForCommands(t *testing.T) {
+	c, requests := newFakeServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	_, err := c.SendEngineAction(context.Background(), 1234, "START")
+	assert.Equal(t, http.StatusServiceUnavailable, StatusCode(err))
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+}
+
+func TestRetryTooManyRequests(t *testing.T) {
+	c, requests := newFakeServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		if n == 1 {
+			w.Header().Set(HEADER_RETRY_AFTER, "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"status":"success"}`))
+	})
+
+	// the Retry-After of a second is capped by the policy's MaxBackoff
+	start := time.Now()
+	_, err := c.SendEngineAction(context.Background(), 1234, "START")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestRetryStopsWhenContextDone(t *testing.T) {
+	c, requests := newFakeServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	c.retry = RetryPolicy{MaxRetries: 5, Backoff: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := c.GetVehicle(ctx, 1234)
+	assert.Equal(t, http.StatusServiceUnavailable, StatusCode(err))
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+}
+
+func TestDecodeError(t *testing.T) {
+	c, _ := newFakeServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		switch r.URL.Path {
+		case "/groups":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":1,"message":"Request body failed validation","result":{"validation_errors":[{"field":"name","rule":"required","message":"name is required"}]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("404 page not found\n"))
+		}
+	})
+
+	_, err := c.CreateGroup(context.Background(), registry.GroupRequest{})
+	assert.True(t, IsValidation(err))
+	assert.EqualError(t, err, "400 Bad Request: Request body failed validation (name is required)")
+
+	_, err = c.GetGroup(context.Background(), "depot-7")
+	assert.True(t, IsNotFound(err))
+	assert.EqualError(t, err, "404 Not Found: 404 page not found")
+}
+
+func TestPathEscaping(t *testing.T) {
+	c, _ := newFakeServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		assert.Equal(t, "/groups/a%2Fb", r.URL.RawPath)
+		w.Write([]byte(`{}`))
+	})
+
+	_, err := c.GetGroup(context.Background(), "a/b")
+	assert.NoError(t, err)
+
+	_, err = c.GetGroup(context.Background(), "")
+	assert.Equal(t, errMissingID, err)
+}
+
+func TestPage(t *testing.T) {
+	assert.True(t, Page{Count: 2, Offset: 0, Limit: 2, Total: 5}.HasMore())
+	assert.Equal(t, int64(4), Page{Count: 2, Offset: 2, Limit: 2, Total: 5}.NextOffset())
+	assert.False(t, Page{Count: 1, Offset: 4, Limit: 2, Total: 5}.HasMore())
+	// an empty page ends the pagination even if the total grew meanwhile
+	assert.False(t, Page{Count: 0, Offset: 6, Limit: 2, Total: 8}.HasMore())
+}
+
+func TestStream(t *testing.T) {
+	c, _ := newFakeServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		assert.Equal(t, "7", r.Header.Get(HEADER_LAST_EVENT_ID))
+		assert.Equal(t, "depot-7", r.URL.Query().Get("group"))
+		assert.Equal(t, "200", r.URL.Query().Get("offset"))
+		assert.Equal(t, "", r.URL.Query().Get("limit"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, strings.Join([]string{
+			"retry: 3000",
+			"",
+			": heartbeat",
+			"",
+			"id: 8",
+			"event: engine_started",
+			`data: {"id":8,"type":"engine_started","vehicleId":1234}`,
+			"",
+			"event: error",
+			`data: {"message":"Failed to get vehicle snapshot"}`,
+			"",
+		}, "\n")+"\n")
+	})
+
+	stream, err := c.StreamFleet(context.Background(), "depot-7", StreamOptions{LastEventID: "7", Offset: 200})
+	require.NoError(t, err)
+	defer stream.Close()
+
+	event, err := stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "engine_started", event.Name)
+	decoded, err := event.Event()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1234), decoded.VehicleID)
+	assert.NoError(t, event.Err())
+	assert.Equal(t, "8", stream.LastEventID())
+
+	event, err = stream.Next()
+	require.NoError(t, err)
+	assert.EqualError(t, event.Err(), "Failed to get vehicle snapshot")
+	assert.Equal(t, "8", stream.LastEventID())
+
+	_, err = stream.Next()
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+
+	"app_api/apis/command"
+)
+
+// SubmitBulkCommand ... sends an engine action to many vehicles at once, and returns the job tracking it. It is never
+// retried, as the job may already have been submitted
+func (c *Client) SubmitBulkCommand(ctx context.Context, req command.BulkCommandRequest) (res command.Job, err error) {
+	err = c.do(ctx, post("/fleet/commands", req), &res)
+	return
+}
+
+// ListBulkCommands ... returns the summaries of the latest jobs
+func (c *Client) ListBulkCommands(ctx context.Context) (res []command.Job, err error) {
+	err = c.do(ctx, get("/fleet/commands", nil), &res)
+	return
+}
+
+// GetBulkCommand ... returns a job with the outcome of the command sent to every vehicle
+func (c *Client) GetBulkCommand(ctx context.Context, jobID string) (res command.Job, err error) {
+	if jobID == "" {
+		return res, errMissingID
+	}
+
+	err = c.do(ctx, get(join("fleet", "commands", jobID), nil), &res)
+	return
+}
+
+// CancelBulkCommand ... stops sending the commands of a job that haven't been sent yet
+func (c *Client) CancelBulkCommand(ctx context.Context, jobID string) (res command.Job, err error) {
+	if jobID == "" {
+		return res, errMissingID
+	}
+
+	// cancelling a cancelled job changes nothing, so it can be retried
+	cancel := post(join("fleet", "commands", jobID, "cancel"), nil)
+	cancel.idempotent = true
+
+	err = c.do(ctx, cancel, &res)
+	return
+}
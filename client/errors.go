@@ -0,0 +1,99 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"app_api/shared"
+)
+
+// maxErrorBody ... how much of an error response is read. The API's own errors are far shorter
+const maxErrorBody = 64 * 1024
+
+// Error ... a request the API answered with an error status
+type Error struct {
+	// StatusCode ... the HTTP status of the response
+	StatusCode int
+
+	// Message ... the message of the API's error response, or the body of a response that isn't one
+	Message string
+
+	// ValidationErrors ... the fields of the request body that failed validation, for a 400
+	ValidationErrors []shared.FieldError
+}
+
+func (e *Error) Error() string {
+	if len(e.ValidationErrors) == 0 {
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+	}
+
+	fields := make([]string, len(e.ValidationErrors))
+	for i, fieldErr := range e.ValidationErrors {
+		fields[i] = fieldErr.Message
+	}
+	return fmt.Sprintf("%d %s: %s (%s)", e.StatusCode, http.StatusText(e.StatusCode), e.Message, strings.Join(fields, "; "))
+}
+
+// errorResponse ... the body the API responds with on error
+type errorResponse struct {
+	Error   int    `json:"error"`
+	Message string `json:"message"`
+	Result  struct {
+		ValidationErrors []shared.FieldError `json:"validation_errors"`
+	} `json:"result"`
+}
+
+// decodeError ... reads the error of a response. A body that isn't the API's error response, e.g. from a proxy in
+// front of it, becomes the message as is
+func decodeError(res *http.Response) *Error {
+	apiErr := &Error{StatusCode: res.StatusCode}
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+	if err != nil && len(body) == 0 {
+		apiErr.Message = http.StatusText(res.StatusCode)
+		return apiErr
+	}
+
+	var decoded errorResponse
+	if json.Unmarshal(body, &decoded) == nil && decoded.Error != 0 {
+		apiErr.Message = decoded.Message
+		apiErr.ValidationErrors = decoded.Result.ValidationErrors
+		return apiErr
+	}
+
+	apiErr.Message = strings.TrimSpace(string(body))
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(res.StatusCode)
+	}
+	return apiErr
+}
+
+// StatusCode ... returns the HTTP status of an *Error, or 0 for any other error, such as a network failure
+func StatusCode(err error) int {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound ... reports whether the API answered 404, e.g. for an unknown vehicle, group or webhook
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// IsUnauthorized ... reports whether the API key was missing or invalid (401), or not granted the scope of the route (403)
+func IsUnauthorized(err error) bool {
+	code := StatusCode(err)
+	return code == http.StatusUnauthorized || code == http.StatusForbidden
+}
+
+// IsValidation ... reports whether the API rejected the request as invalid (400)
+func IsValidation(err error) bool {
+	return StatusCode(err) == http.StatusBadRequest
+}
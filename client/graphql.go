@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"app_api/apis/graphql"
+)
+
+// GraphQL ... runs a GraphQL query or mutation and decodes its data into data, unless it is nil. Fields that failed are
+// returned as GraphQL errors alongside the data of the rest, a rejected request as an *Error. It is never retried, as
+// it may be a mutation
+func (c *Client) GraphQL(ctx context.Context, req graphql.Request, data interface{}) ([]graphql.ResponseError, error) {
+	var res struct {
+		Data   json.RawMessage         `json:"data"`
+		Errors []graphql.ResponseError `json:"errors"`
+	}
+	if err := c.do(ctx, post("/graphql", req), &res); err != nil {
+		return nil, err
+	}
+
+	if data != nil && len(res.Data) > 0 {
+		if err := json.Unmarshal(res.Data, data); err != nil {
+			return res.Errors, fmt.Errorf("failed to decode GraphQL data: %w", err)
+		}
+	}
+	return res.Errors, nil
+}
+
+// PersistedQuery ... returns a request running the persisted query with the given text by its hash alone
+func PersistedQuery(query string, variables map[string]interface{}) graphql.Request {
+	return graphql.Request{
+		Variables: variables,
+		Extensions: &graphql.RequestExtensions{
+			PersistedQuery: &graphql.PersistedQuery{Version: 1, SHA256Hash: graphql.QueryHash(query)},
+		},
+	}
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// Page ... the position of a page of results within all of them
+type Page struct {
+	// Count ... the results in this page
+	Count int64 `json:"count"`
+
+	// Offset ... the index of the first result in this page
+	Offset int64 `json:"offset"`
+
+	// Limit ... the most results a page holds
+	Limit int64 `json:"limit"`
+
+	// Total ... the results across every page
+	Total int64 `json:"total"`
+}
+
+// HasMore ... reports whether there are results after this page
+func (p Page) HasMore() bool {
+	return p.Count > 0 && p.Offset+p.Count < p.Total
+}
+
+// NextOffset ... the offset of the page after this one
+func (p Page) NextOffset() int64 {
+	return p.Offset + p.Count
+}
+
+// PageOptions ... selects a page of a list. Zero values use the API's defaults
+type PageOptions struct {
+	Offset int64
+	Limit  int64
+}
+
+func (o PageOptions) values(query url.Values) url.Values {
+	if o.Offset > 0 {
+		query.Set("offset", strconv.FormatInt(o.Offset, 10))
+	}
+	if o.Limit > 0 {
+		query.Set("limit", strconv.FormatInt(o.Limit, 10))
+	}
+	return query
+}
+
+// paginate ... fetches every page from offset on, until a page is the last. fetch returns the page it fetched
+func paginate(ctx context.Context, offset int64, fetch func(offset int64) (Page, error)) error {
+	for {
+		page, err := fetch(offset)
+		if err != nil {
+			return err
+		}
+		if !page.HasMore() {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		offset = page.NextOffset()
+	}
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+
+	"app_api/apis/poller"
+)
+
+// The poller routes need an API key granted the admin scope. Pausing and resuming change nothing when repeated, so
+// they are retried like a PUT would be
+
+// GetPollerStatus ... returns the state of the background poller
+func (c *Client) GetPollerStatus(ctx context.Context) (res poller.Status, err error) {
+	err = c.do(ctx, get("/admin/poller", nil), &res)
+	return
+}
+
+// PausePoller ... stops polling every vehicle until ResumePoller
+func (c *Client) PausePoller(ctx context.Context) (res poller.Status, err error) {
+	err = c.do(ctx, idempotentPost(join("admin", "poller", "pause")), &res)
+	return
+}
+
+// ResumePoller ... resumes polling after PausePoller
+func (c *Client) ResumePoller(ctx context.Context) (res poller.Status, err error) {
+	err = c.do(ctx, idempotentPost(join("admin", "poller", "resume")), &res)
+	return
+}
+
+// ListPollerSchedules ... returns the polling schedule of every registered vehicle
+func (c *Client) ListPollerSchedules(ctx context.Context) (res []poller.Schedule, err error) {
+	err = c.do(ctx, get("/admin/poller/schedules", nil), &res)
+	return
+}
+
+// GetPollerSchedule ... returns the polling schedule of a vehicle
+func (c *Client) GetPollerSchedule(ctx context.Context, vehicleID int64) (res poller.Schedule, err error) {
+	err = c.do(ctx, get(join("admin", "poller", "schedules", vehicleID), nil), &res)
+	return
+}
+
+// SetPollerSchedule ... overrides the polling intervals of a vehicle
+func (c *Client) SetPollerSchedule(ctx context.Context, vehicleID int64, req poller.ScheduleRequest) (res poller.Schedule, err error) {
+	err = c.do(ctx, put(join("admin", "poller", "schedules", vehicleID), req), &res)
+	return
+}
+
+// ResetPollerSchedule ... returns a vehicle to the default polling intervals
+func (c *Client) ResetPollerSchedule(ctx context.Context, vehicleID int64) (res poller.Schedule, err error) {
+	err = c.do(ctx, del(join("admin", "poller", "schedules", vehicleID)), &res)
+	return
+}
+
+// PausePollerSchedule ... stops polling a vehicle until ResumePollerSchedule
+func (c *Client) PausePollerSchedule(ctx context.Context, vehicleID int64) (res poller.Schedule, err error) {
+	err = c.do(ctx, idempotentPost(join("admin", "poller", "schedules", vehicleID, "pause")), &res)
+	return
+}
+
+// ResumePollerSchedule ... resumes polling a vehicle after PausePollerSchedule
+func (c *Client) ResumePollerSchedule(ctx context.Context, vehicleID int64) (res poller.Schedule, err error) {
+	err = c.do(ctx, idempotentPost(join("admin", "poller", "schedules", vehicleID, "resume")), &res)
+	return
+}
+
+func idempotentPost(path string) request {
+	req := post(path, nil)
+	req.idempotent = true
+	return req
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"app_api/apis/registry"
+)
+
+// VehicleFilter ... selects the registered vehicles listed by ListVehicles
+type VehicleFilter struct {
+	PageOptions
+
+	// Group ... only vehicles in the group
+	Group string
+
+	// Tag ... only vehicles with the tag
+	Tag string
+}
+
+// VehiclePage ... a page of the vehicles listed by ListVehicles
+type VehiclePage struct {
+	Page
+	Registrations []registry.Registration `json:"result"`
+}
+
+// ListVehicles ... returns a page of the registered vehicles matching the filter
+func (c *Client) ListVehicles(ctx context.Context, filter VehicleFilter) (res VehiclePage, err error) {
+	query := url.Values{}
+	if filter.Group != "" {
+		query.Set("group", filter.Group)
+	}
+	if filter.Tag != "" {
+		query.Set("tag", filter.Tag)
+	}
+
+	err = c.do(ctx, get("/vehicles", filter.values(query)), &res)
+	return
+}
+
+// ListAllVehicles ... returns every registered vehicle matching the filter from its offset on, a page at a time
+func (c *Client) ListAllVehicles(ctx context.Context, filter VehicleFilter) (res []registry.Registration, err error) {
+	err = paginate(ctx, filter.Offset, func(offset int64) (Page, error) {
+		filter.Offset = offset
+		page, err := c.ListVehicles(ctx, filter)
+		res = append(res, page.Registrations...)
+		return page.Page, err
+	})
+	return
+}
+
+// GetRegistration ... returns the registration of a vehicle
+func (c *Client) GetRegistration(ctx context.Context, vehicleID int64) (res registry.Registration, err error) {
+	err = c.do(ctx, get(join("vehicles", vehicleID, "registration"), nil), &res)
+	return
+}
+
+// RegisterVehicle ... registers a vehicle, or replaces its registration
+func (c *Client) RegisterVehicle(ctx context.Context, vehicleID int64, req registry.RegistrationRequest) (res registry.Registration, err error) {
+	err = c.do(ctx, put(join("vehicles", vehicleID, "registration"), req), &res)
+	return
+}
+
+// DeleteRegistration ... removes a vehicle from the registry, and from its groups
+func (c *Client) DeleteRegistration(ctx context.Context, vehicleID int64) error {
+	return c.do(ctx, del(join("vehicles", vehicleID, "registration")), nil)
+}
+
+// GetTags ... returns the tags of a registered vehicle
+func (c *Client) GetTags(ctx context.Context, vehicleID int64) (res []string, err error) {
+	err = c.do(ctx, get(join("vehicles", vehicleID, "tags"), nil), &res)
+	return
+}
+
+// AddTags ... adds tags to a registered vehicle, and returns all of its tags
+func (c *Client) AddTags(ctx context.Context, vehicleID int64, tags ...string) (res []string, err error) {
+	// adding a tag twice keeps one, so it can be retried
+	add := post(join("vehicles", vehicleID, "tags"), registry.TagsRequest{Tags: tags})
+	add.idempotent = true
+
+	err = c.do(ctx, add, &res)
+	return
+}
+
+// RemoveTag ... removes a tag from a registered vehicle, and returns the tags left
+func (c *Client) RemoveTag(ctx context.Context, vehicleID int64, tag string) (res []string, err error) {
+	if tag == "" {
+		return nil, errMissingID
+	}
+
+	err = c.do(ctx, del(join("vehicles", vehicleID, "tags", tag)), &res)
+	return
+}
+
+// ListGroups ... returns every group
+func (c *Client) ListGroups(ctx context.Context) (res []registry.Group, err error) {
+	err = c.do(ctx, get("/groups", nil), &res)
+	return
+}
+
+// CreateGroup ... creates an empty group
+func (c *Client) CreateGroup(ctx context.Context, req registry.GroupRequest) (res registry.Group, err error) {
+	err = c.do(ctx, post("/groups", req), &res)
+	return
+}
+
+// GetGroup ... returns a group and its vehicles
+func (c *Client) GetGroup(ctx context.Context, name string) (res registry.Group, err error) {
+	if name == "" {
+		return res, errMissingID
+	}
+
+	err = c.do(ctx, get(join("groups", name), nil), &res)
+	return
+}
+
+// UpdateGroup ... replaces the description of a group
+func (c *Client) UpdateGroup(ctx context.Context, name string, req registry.GroupUpdateRequest) (res registry.Group, err error) {
+	if name == "" {
+		return res, errMissingID
+	}
+
+	err = c.do(ctx, put(join("groups", name), req), &res)
+	return
+}
+
+// DeleteGroup ... deletes a group. Its vehicles stay registered
+func (c *Client) DeleteGroup(ctx context.Context, name string) error {
+	if name == "" {
+		return errMissingID
+	}
+
+	return c.do(ctx, del(join("groups", name)), nil)
+}
+
+// AddGroupVehicle ... adds a registered vehicle to a group
+func (c *Client) AddGroupVehicle(ctx context.Context, name string, vehicleID int64) (res registry.Group, err error) {
+	if name == "" {
+		return res, errMissingID
+	}
+
+	err = c.do(ctx, put(join("groups", name, "vehicles", vehicleID), nil), &res)
+	return
+}
+
+// RemoveGroupVehicle ... removes a vehicle from a group
+func (c *Client) RemoveGroupVehicle(ctx context.Context, name string, vehicleID int64) (res registry.Group, err error) {
+	if name == "" {
+		return res, errMissingID
+	}
+
+	err = c.do(ctx, del(join("groups", name, "vehicles", vehicleID)), &res)
+	return
+}
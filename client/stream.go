@@ -0,0 +1,190 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"app_api/apis/events"
+)
+
+const (
+	// STREAM_EVENT_SNAPSHOT ... the first events of a fresh stream, a vehicle.Snapshot from StreamVehicle and a
+	// vehicle.BatchResult per vehicle from StreamFleet
+	STREAM_EVENT_SNAPSHOT = "snapshot"
+
+	// STREAM_EVENT_ERROR ... sent instead of the snapshot when it failed
+	STREAM_EVENT_ERROR = "error"
+
+	HEADER_LAST_EVENT_ID = "Last-Event-ID"
+)
+
+// StreamOptions ... selects what a stream sends
+type StreamOptions struct {
+	// Sections ... the snapshot sections of each vehicle. Defaults to every section
+	Sections []string
+
+	// LastEventID ... resumes a stream after the event with this ID, from Stream.LastEventID, instead of starting with
+	// a snapshot. A stream that can no longer be resumed starts with a snapshot again
+	LastEventID string
+
+	// Offset, Limit ... the page of the vehicles of StreamFleet which get a snapshot. Limit defaults to 100
+	Offset int64
+	Limit  int64
+}
+
+// StreamEvent ... a server-sent event. Any event but a snapshot or error is an events.Event, named by its type
+type StreamEvent struct {
+	ID   string
+	Name string
+	Data json.RawMessage
+}
+
+// Decode ... decodes the data of the event into v
+func (e StreamEvent) Decode(v interface{}) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// Event ... decodes a vehicle event, e.g. door_unlocked or fuel_low
+func (e StreamEvent) Event() (res events.Event, err error) {
+	err = e.Decode(&res)
+	return
+}
+
+// Err ... returns the failure an error event carries, or nil for any other event
+func (e StreamEvent) Err() error {
+	if e.Name != STREAM_EVENT_ERROR {
+		return nil
+	}
+
+	var data struct {
+		Message string `json:"message"`
+	}
+	if err := e.Decode(&data); err != nil || data.Message == "" {
+		return errors.New("stream failed")
+	}
+	return errors.New(data.Message)
+}
+
+// Stream ... reads the events of a stream, until it is closed or its context is done. Streams aren't reconnected
+// automatically: to resume one, open it again with its LastEventID
+type Stream struct {
+	body        io.ReadCloser
+	reader      *bufio.Reader
+	lastEventID string
+}
+
+// StreamVehicle ... streams a snapshot of a vehicle followed by its events
+func (c *Client) StreamVehicle(ctx context.Context, vehicleID int64, opts StreamOptions) (*Stream, error) {
+	return c.stream(ctx, join("vehicles", vehicleID, "stream"), url.Values{}, opts)
+}
+
+// StreamFleet ... streams a snapshot of a page of the vehicles in the group followed by the events of the group, or of
+// the fleet without a group
+func (c *Client) StreamFleet(ctx context.Context, group string, opts StreamOptions) (*Stream, error) {
+	query := url.Values{}
+	if group != "" {
+		query.Set("group", group)
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.FormatInt(opts.Offset, 10))
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.FormatInt(opts.Limit, 10))
+	}
+	return c.stream(ctx, "/fleet/stream", query, opts)
+}
+
+// stream ... opens a stream once. A stream outlives any request timeout, so only the context ends it
+func (c *Client) stream(ctx context.Context, path string, query url.Values, opts StreamOptions) (*Stream, error) {
+	if len(opts.Sections) > 0 {
+		query.Set("fields", strings.Join(opts.Sections, ","))
+	}
+
+	httpReq, err := c.newRequest(ctx, http.MethodGet, path, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+	if opts.LastEventID != "" {
+		httpReq.Header.Set(HEADER_LAST_EVENT_ID, opts.LastEventID)
+	}
+
+	res, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		defer res.Body.Close()
+		return nil, decodeError(res)
+	}
+
+	return &Stream{body: res.Body, reader: bufio.NewReader(res.Body), lastEventID: opts.LastEventID}, nil
+}
+
+// Next ... blocks until the next event. Returns io.EOF once the server closes the stream, or the error of the context
+// once it is done
+func (s *Stream) Next() (StreamEvent, error) {
+	var event StreamEvent
+	var data bytes.Buffer
+	hasData := false
+
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return StreamEvent{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		// a blank line dispatches the event. Comments, such as heartbeats, and retry fields have no data
+		if line == "" {
+			if !hasData {
+				event = StreamEvent{}
+				continue
+			}
+			event.Data = json.RawMessage(data.Bytes())
+			if event.ID != "" {
+				s.lastEventID = event.ID
+			}
+			return event, nil
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch field {
+		case "id":
+			event.ID = value
+		case "event":
+			event.Name = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		}
+	}
+}
+
+// LastEventID ... the ID of the last event read, to resume the stream with after it is closed
+func (s *Stream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Close ... closes the stream
+func (s *Stream) Close() error {
+	return s.body.Close()
+}
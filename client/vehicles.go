@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"app_api/apis/telemetry"
+	"app_api/apis/vehicle"
+)
+
+// GetVehicle ... returns the info of a vehicle
+func (c *Client) GetVehicle(ctx context.Context, vehicleID int64) (res vehicle.Vehicle, err error) {
+	err = c.do(ctx, get(join("vehicles", vehicleID), nil), &res)
+	return
+}
+
+// GetDoors ... returns the lock status of every door of a vehicle
+func (c *Client) GetDoors(ctx context.Context, vehicleID int64) (res []vehicle.Door, err error) {
+	err = c.do(ctx, get(join("vehicles", vehicleID, "doors"), nil), &res)
+	return
+}
+
+// GetFuel ... returns the fuel level of a vehicle. The percentage is nil for an electric vehicle
+func (c *Client) GetFuel(ctx context.Context, vehicleID int64) (res vehicle.Fuel, err error) {
+	err = c.do(ctx, get(join("vehicles", vehicleID, "fuel"), nil), &res)
+	return
+}
+
+// GetBattery ... returns the battery level of a vehicle. The percentage is nil for a vehicle without one
+func (c *Client) GetBattery(ctx context.Context, vehicleID int64) (res vehicle.Battery, err error) {
+	err = c.do(ctx, get(join("vehicles", vehicleID, "battery"), nil), &res)
+	return
+}
+
+// SendEngineAction ... starts or stops the engine of a vehicle, with vehicle.ENGINE_START or vehicle.ENGINE_STOP. It is
+// never retried, as the engine may already have been started or stopped
+func (c *Client) SendEngineAction(ctx context.Context, vehicleID int64, action string) (res vehicle.EngineActionResponse, err error) {
+	err = c.do(ctx, post(join("vehicles", vehicleID, "engine"), vehicle.EngineActionRequest{Action: action}), &res)
+	return
+}
+
+// GetSnapshot ... returns the given sections of a vehicle, or every section when none are given. A section GM failed
+// to return has an error status instead of failing the request
+func (c *Client) GetSnapshot(ctx context.Context, vehicleID int64, sections ...string) (res vehicle.Snapshot, err error) {
+	query := url.Values{}
+	if len(sections) > 0 {
+		query.Set("fields", strings.Join(sections, ","))
+	}
+
+	err = c.do(ctx, get(join("vehicles", vehicleID, "snapshot"), query), &res)
+	return
+}
+
+// BatchPage ... a page of the vehicles fetched by BatchVehicles
+type BatchPage struct {
+	Page
+	Results []vehicle.BatchResult `json:"result"`
+}
+
+// BatchVehicles ... fetches a page of the vehicles of the request at once. A vehicle GM failed to return has an error
+// status instead of failing the request
+func (c *Client) BatchVehicles(ctx context.Context, req vehicle.BatchRequest) (res BatchPage, err error) {
+	// fetching vehicles changes nothing, so the batch can be retried as a GET would be
+	batch := post("/vehicles/batch", req)
+	batch.idempotent = true
+
+	err = c.do(ctx, batch, &res)
+	return
+}
+
+// BatchAllVehicles ... fetches every vehicle of the request from its offset on, a page at a time
+func (c *Client) BatchAllVehicles(ctx context.Context, req vehicle.BatchRequest) (res []vehicle.BatchResult, err error) {
+	err = paginate(ctx, req.Offset, func(offset int64) (Page, error) {
+		req.Offset = offset
+		page, err := c.BatchVehicles(ctx, req)
+		res = append(res, page.Results...)
+		return page.Page, err
+	})
+	return
+}
+
+// HistoryOptions ... selects the readings of a history. Zero values use the API's defaults: the last 24 hours, raw
+type HistoryOptions struct {
+	From time.Time
+	To   time.Time
+
+	// Interval ... downsamples the readings into points this far apart, e.g. 15 * time.Minute
+	Interval time.Duration
+
+	// Aggregation ... how the readings within an interval are combined, one of telemetry.Aggregations
+	Aggregation string
+}
+
+func (o HistoryOptions) values() url.Values {
+	query := url.Values{}
+	if !o.From.IsZero() {
+		query.Set("from", o.From.Format(time.RFC3339))
+	}
+	if !o.To.IsZero() {
+		query.Set("to", o.To.Format(time.RFC3339))
+	}
+	if o.Interval > 0 {
+		query.Set("interval", o.Interval.String())
+	}
+	if o.Aggregation != "" {
+		query.Set("aggregation", o.Aggregation)
+	}
+	return query
+}
+
+// GetFuelHistory ... returns the fuel readings of a vehicle over a time range
+func (c *Client) GetFuelHistory(ctx context.Context, vehicleID int64, opts HistoryOptions) (res telemetry.History, err error) {
+	err = c.do(ctx, get(join("vehicles", vehicleID, "fuel", "history"), opts.values()), &res)
+	return
+}
+
+// GetBatteryHistory ... returns the battery readings of a vehicle over a time range
+func (c *Client) GetBatteryHistory(ctx context.Context, vehicleID int64, opts HistoryOptions) (res telemetry.History, err error) {
+	err = c.do(ctx, get(join("vehicles", vehicleID, "battery", "history"), opts.values()), &res)
+	return
+}
+
+// GetDoorsHistory ... returns the number of unlocked doors of a vehicle over a time range
+func (c *Client) GetDoorsHistory(ctx context.Context, vehicleID int64, opts HistoryOptions) (res telemetry.History, err error) {
+	err = c.do(ctx, get(join("vehicles", vehicleID, "doors", "history"), opts.values()), &res)
+	return
+}
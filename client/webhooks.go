@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+
+	"app_api/apis/webhook"
+)
+
+// CreateWebhook ... subscribes a URL to vehicle events. The secret is only returned here, keep it to verify deliveries
+// with webhook.Verify
+func (c *Client) CreateWebhook(ctx context.Context, req webhook.SubscriptionRequest) (res webhook.Subscription, err error) {
+	err = c.do(ctx, post("/webhooks", req), &res)
+	return
+}
+
+// ListWebhooks ... returns every webhook subscription
+func (c *Client) ListWebhooks(ctx context.Context) (res []webhook.Subscription, err error) {
+	err = c.do(ctx, get("/webhooks", nil), &res)
+	return
+}
+
+// GetWebhook ... returns a webhook subscription
+func (c *Client) GetWebhook(ctx context.Context, webhookID string) (res webhook.Subscription, err error) {
+	if webhookID == "" {
+		return res, errMissingID
+	}
+
+	err = c.do(ctx, get(join("webhooks", webhookID), nil), &res)
+	return
+}
+
+// UpdateWebhook ... replaces a webhook subscription
+func (c *Client) UpdateWebhook(ctx context.Context, webhookID string, req webhook.SubscriptionRequest) (res webhook.Subscription, err error) {
+	if webhookID == "" {
+		return res, errMissingID
+	}
+
+	err = c.do(ctx, put(join("webhooks", webhookID), req), &res)
+	return
+}
+
+// DeleteWebhook ... removes a webhook subscription. Deliveries in flight are dropped
+func (c *Client) DeleteWebhook(ctx context.Context, webhookID string) error {
+	if webhookID == "" {
+		return errMissingID
+	}
+
+	return c.do(ctx, del(join("webhooks", webhookID)), nil)
+}
+
+// ListWebhookDeliveries ... returns the latest deliveries of a webhook subscription, newest first
+func (c *Client) ListWebhookDeliveries(ctx context.Context, webhookID string) (res []webhook.Delivery, err error) {
+	if webhookID == "" {
+		return nil, errMissingID
+	}
+
+	err = c.do(ctx, get(join("webhooks", webhookID, "deliveries"), nil), &res)
+	return
+}
+
+// ListWebhookDeadLetters ... returns the deliveries of a webhook subscription that failed every attempt
+func (c *Client) ListWebhookDeadLetters(ctx context.Context, webhookID string) (res []webhook.Delivery, err error) {
+	if webhookID == "" {
+		return nil, errMissingID
+	}
+
+	err = c.do(ctx, get(join("webhooks", webhookID, "dead-letters"), nil), &res)
+	return
+}
+
+// RetryWebhookDeadLetter ... queues a dead letter for delivery again. It isn't retried: once an attempt has taken the
+// dead letter, the next would fail with a 404
+func (c *Client) RetryWebhookDeadLetter(ctx context.Context, webhookID, deliveryID string) (res webhook.Delivery, err error) {
+	if webhookID == "" || deliveryID == "" {
+		return res, errMissingID
+	}
+
+	err = c.do(ctx, post(join("webhooks", webhookID, "dead-letters", deliveryID, "retry"), nil), &res)
+	return
+}
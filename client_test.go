@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"app_api/apis/command"
+	"app_api/apis/events"
+	apigraphql "app_api/apis/graphql"
+	"app_api/apis/poller"
+	"app_api/apis/realtime"
+	"app_api/apis/registry"
+	"app_api/apis/telemetry"
+	"app_api/apis/vehicle"
+	"app_api/apis/webhook"
+	"app_api/client"
+	"app_api/shared/audit"
+	"app_api/shared/auth"
+	"app_api/shared/config"
+	gmConnector "app_api/shared/gm"
+	"app_api/shared/store/storetest"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testReadKey  = "r3ad"
+	testAdminKey = "adm1n"
+)
+
+// newTestEnv ... builds every service over the GM connector and a database of its own.
+// The background poller and webhook deliveries aren't started
+func newTestEnv(t *testing.T, gm gmConnector.GMAPIConnector) *Env {
+	log.SetOutput(ioutil.Discard)
+
+	db := storetest.Open(t)
+
+	keys, err := auth.ParseKeys(testReadKey + "=vehicles:read;" + testAdminKey + "=*")
+	require.NoError(t, err)
+
+	telemetryService, err := telemetry.NewService(db)
+	require.NoError(t, err)
+	t.Cleanup(telemetryService.Close)
+	eventBus := events.NewBus()
+	detector := events.NewDetector(eventBus)
+	vehicleService := vehicle.NewService(gm, vehicle.WithObserver(telemetryService), vehicle.WithObserver(detector))
+	registryService, err := registry.NewService(db)
+	require.NoError(t, err)
+	pollerService, err := poller.NewService(db, vehicleService, registryService)
+	require.NoError(t, err)
+	webhookService, err := webhook.NewService(db, eventBus, registryService)
+	require.NoError(t, err)
+	graphqlService, err := apigraphql.NewService(vehicleService, registryService)
+	require.NoError(t, err)
+	cfg := defaultConfig()
+
+	return &Env{
+		Services: Services{
+			VehicleService:   vehicleService,
+			CommandService:   command.NewService(vehicleService),
+			RegistryService:  registryService,
+			TelemetryService: telemetryService,
+			PollerService:    pollerService,
+			EventBus:         eventBus,
+			Detector:         detector,
+			WebhookService:   webhookService,
+			RealtimeService:  realtime.NewService(vehicleService, eventBus, registryService),
+			GraphQLService:   graphqlService,
+			GM:               gm,
+			Auth:             auth.New(keys),
+			Config:           config.NewWatcher(&cfg, nil, defaultConfig()),
+			Audit:            audit.New(ioutil.Discard, audit.DEFAULT_SIZE),
+		},
+	}
+}
+
+// testServer ... serves the API, and the admin API on a server of its own
+type testServer struct {
+	*httptest.Server
+	admin *httptest.Server
+}
+
+// newTestServer ... serves every route over the mock GM connector, with faults injected by /admin/gm/faults
+func newTestServer(t *testing.T) *testServer {
+	gmFaults := gmConnector.NewFaultInjector(nil)
+	env := newTestEnv(t, gmConnector.NewFaultConnector(gmConnector.NewMockGMAPIConnector(), gmFaults))
+	env.Services.GMFaults = gmFaults
+
+	server := &testServer{httptest.NewServer(NewRouter(env)), httptest.NewServer(NewAdminRouter(env))}
+	t.Cleanup(server.Close)
+	t.Cleanup(server.admin.Close)
+	return server
+}
+
+func newTestClient(t *testing.T, server *testServer, key string) *client.Client {
+	c, err := client.New(server.URL, client.WithAdminURL(server.admin.URL), client.WithAPIKey(key), client.WithRetryPolicy(client.RetryPolicy{}))
+	require.NoError(t, err)
+	return c
+}
+
+func TestClientVehicles(t *testing.T) {
+	server := newTestServer(t)
+	c := newTestClient(t, server, testAdminKey)
+	ctx := context.Background()
+
+	res, err := c.GetVehicle(ctx, 1234)
+	assert.NoError(t, err)
+	assert.Equal(t, vehicle.Vehicle{Vin: "123123412412", Color: "Metallic Silver", DoorCount: 4, DriveTrain: "v8"}, res)
+
+	doors, err := c.GetDoors(ctx, 1234)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, doors)
+
+	fuel, err := c.GetFuel(ctx, 1234)
+	assert.NoError(t, err)
+	assert.NotNil(t, fuel.Percentage)
+
+	battery, err := c.GetBattery(ctx, 1235)
+	assert.NoError(t, err)
+	assert.NotNil(t, battery.Percentage)
+
+	engine, err := c.SendEngineAction(ctx, 1234, vehicle.ENGINE_START)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, engine.Action)
+
+	snapshot, err := c.GetSnapshot(ctx, 1234, vehicle.SECTION_DOORS)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1234), snapshot.VehicleID)
+	assert.NotNil(t, snapshot.Doors)
+	assert.Nil(t, snapshot.Fuel)
+
+	history, err := c.GetFuelHistory(ctx, 1234, client.HistoryOptions{From: time.Now().Add(-time.Hour)})
+	assert.NoError(t, err)
+	assert.Equal(t, "fuel", history.Metric)
+	assert.NotEmpty(t, history.Points)
+
+	history, err = c.GetDoorsHistory(ctx, 1234, client.HistoryOptions{From: time.Now().Add(-time.Hour)})
+	assert.NoError(t, err)
+	assert.Equal(t, "doors_unlocked", history.Metric)
+	assert.NotEmpty(t, history.Points)
+}
+
+func TestClientErrors(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	_, err := newTestClient(t, server, testAdminKey).GetVehicle(ctx, 1)
+	assert.Equal(t, http.StatusInternalServerError, client.StatusCode(err))
+	assert.EqualError(t, err, "500 Internal Server Error: Failed to get vehicle")
+
+	_, err = newTestClient(t, server, testAdminKey).GetRegistration(ctx, 1234)
+	assert.True(t, client.IsNotFound(err))
+
+	_, err = newTestClient(t, server, testAdminKey).SendEngineAction(ctx, 1234, "JUMP")
+	assert.True(t, client.IsValidation(err))
+	if apiErr, ok := err.(*client.Error); assert.True(t, ok) {
+		assert.Equal(t, "Request body failed validation", apiErr.Message)
+		if assert.Len(t, apiErr.ValidationErrors, 1) {
+			assert.Equal(t, "action", apiErr.ValidationErrors[0].Field)
+		}
+	}
+
+	_, err = newTestClient(t, server, "").GetVehicle(ctx, 1234)
+	assert.Equal(t, http.StatusUnauthorized, client.StatusCode(err))
+
+	_, err = newTestClient(t, server, testReadKey).GetPollerStatus(ctx)
+	assert.Equal(t, http.StatusForbidden, client.StatusCode(err))
+	assert.True(t, client.IsUnauthorized(err))
+}
+
+func TestClientRegistryPagination(t *testing.T) {
+	server := newTestServer(t)
+	c := newTestClient(t, server, testAdminKey)
+	ctx := context.Background()
+
+	_, err := c.CreateGroup(ctx, registry.GroupRequest{Name: "depot-7"})
+	assert.NoError(t, err)
+	for _, vehicleID := range []int64{1234, 1235, 1236, 1237, 1238} {
+		_, err := c.RegisterVehicle(ctx, vehicleID, registry.RegistrationRequest{Tags: []string{"fleet"}})
+		assert.NoError(t, err)
+	}
+	for _, vehicleID := range []int64{1234, 1235} {
+		_, err := c.AddGroupVehicle(ctx, "depot-7", vehicleID)
+		assert.NoError(t, err)
+	}
+
+	page, err := c.ListVehicles(ctx, client.VehicleFilter{PageOptions: client.PageOptions{Limit: 2}, Tag: "fleet"})
+	assert.NoError(t, err)
+	assert.Len(t, page.Registrations, 2)
+	assert.Equal(t, client.Page{Count: 2, Offset: 0, Limit: 2, Total: 5}, page.Page)
+	assert.True(t, page.HasMore())
+
+	all, err := c.ListAllVehicles(ctx, client.VehicleFilter{PageOptions: client.PageOptions{Limit: 2}})
+	assert.NoError(t, err)
+	assert.Len(t, all, 5)
+
+	limit := int64(1)
+	results, err := c.BatchAllVehicles(ctx, vehicle.BatchRequest{Group: "depot-7", Limit: &limit})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	tags, err := c.AddTags(ctx, 1234, "priority")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"fleet", "priority"}, tags)
+
+	tags, err = c.RemoveTag(ctx, 1234, "fleet")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"priority"}, tags)
+
+	assert.NoError(t, c.DeleteGroup(ctx, "depot-7"))
+	_, err = c.GetGroup(ctx, "depot-7")
+	assert.True(t, client.IsNotFound(err))
+}
+
+func TestClientCommandsPollerAndWebhooks(t *testing.T) {
+	server := newTestServer(t)
+	c := newTestClient(t, server, testAdminKey)
+	ctx := context.Background()
+
+	job, err := c.SubmitBulkCommand(ctx, command.BulkCommandRequest{VehicleIDs: []int64{1234}, Action: vehicle.ENGINE_STOP})
+	assert.NoError(t, err)
+	cancelled, err := c.CancelBulkCommand(ctx, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, job.ID, cancelled.ID)
+	assert.NotEqual(t, command.JOB_RUNNING, cancelled.Status)
+
+	status, err := c.PausePoller(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, poller.POLLER_PAUSED, status.Status)
+
+	subscription, err := c.CreateWebhook(ctx, webhook.SubscriptionRequest{URL: "https://example.com/hooks"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, subscription.Secret)
+
+	deliveries, err := c.ListWebhookDeliveries(ctx, subscription.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, deliveries)
+
+	assert.NoError(t, c.DeleteWebhook(ctx, subscription.ID))
+	_, err = c.GetWebhook(ctx, subscription.ID)
+	assert.True(t, client.IsNotFound(err))
+}
+
+func TestClientGraphQL(t *testing.T) {
+	server := newTestServer(t)
+	c := newTestClient(t, server, testReadKey)
+
+	var data struct {
+		Vehicle struct {
+			Vin  string `json:"vin"`
+			Fuel struct {
+				Percentage *float64 `json:"percentage"`
+			} `json:"fuel"`
+		} `json:"vehicle"`
+	}
+	gqlErrs, err := c.GraphQL(context.Background(), apigraphql.Request{Query: `{ vehicle(id: "1234") { vin fuel { percentage } } }`}, &data)
+	assert.NoError(t, err)
+	assert.Empty(t, gqlErrs)
+	assert.Equal(t, "123123412412", data.Vehicle.Vin)
+	assert.NotNil(t, data.Vehicle.Fuel.Percentage)
+
+	gqlErrs, err = c.GraphQL(context.Background(), apigraphql.Request{Query: `{ vehicle(id: "1") { vin } }`}, &data)
+	assert.NoError(t, err)
+	assert.Len(t, gqlErrs, 1)
+}
+
+func TestClientStream(t *testing.T) {
+	server := newTestServer(t)
+	c := newTestClient(t, server, testAdminKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream, err := c.StreamVehicle(ctx, 1234, client.StreamOptions{Sections: []string{vehicle.SECTION_DOORS}})
+	require.NoError(t, err)
+	defer stream.Close()
+
+	first, err := stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, client.STREAM_EVENT_SNAPSHOT, first.Name)
+	var snapshot vehicle.Snapshot
+	assert.NoError(t, first.Decode(&snapshot))
+	assert.Equal(t, int64(1234), snapshot.VehicleID)
+
+	_, err = c.SendEngineAction(ctx, 1234, vehicle.ENGINE_START)
+	require.NoError(t, err)
+
+	next, err := stream.Next()
+	require.NoError(t, err)
+	event, err := next.Event()
+	assert.NoError(t, err)
+	assert.Equal(t, events.EVENT_ENGINE_STARTED, event.Type)
+	assert.Equal(t, next.ID, stream.LastEventID())
+
+	_, err = c.StreamVehicle(ctx, 1234, client.StreamOptions{Sections: []string{"wheels"}})
+	assert.True(t, client.IsValidation(err))
+}
+
+func TestGMFaults(t *testing.T) {
+	server := newTestServer(t)
+	c := newTestClient(t, server, testAdminKey)
+	ctx := context.Background()
+
+	send := func(method, body string) (int, string) {
+		req, err := http.NewRequest(method, server.admin.URL+"/admin/gm/faults", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("X-API-Key", testAdminKey)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(res.Body)
+		return res.StatusCode, string(b)
+	}
+
+	status, body := send("PUT", `{"rules": [{"endpoint": "getEnergyService", "vehicleId": 1234, "fault": "malformed"}]}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"rules": [{"endpoint": "getEnergyService", "vehicleId": 1234, "fault": "malformed"}]}`, body)
+
+	_, err := c.GetFuel(ctx, 1234)
+	assert.Equal(t, http.StatusInternalServerError, client.StatusCode(err))
+	_, err = c.GetBattery(ctx, 1235)
+	assert.NoError(t, err, "the rule only applies to vehicle 1234")
+	_, err = c.GetVehicle(ctx, 1234)
+	assert.NoError(t, err, "the rule only applies to the energy service")
+
+	status, body = send("PUT", `{"rules": [{"fault": "sideways"}, {"endpoint": "getVehicleInfoService"}]}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, "rules[0].fault must be one of [error, gm_error, malformed, drop, timeout]")
+
+	status, body = send("DELETE", ``)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"rules": []}`, body)
+	_, err = c.GetFuel(ctx, 1234)
+	assert.NoError(t, err)
+}
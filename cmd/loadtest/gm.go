@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	gmConnector "app_api/shared/gm"
+
+	"github.com/spf13/cobra"
+)
+
+func newGMCommand(stderr io.Writer) *cobra.Command {
+	var listen string
+	var errorRate float64
+	var latency, jitter time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "gm",
+		Short: "Serve a simulated GM API, to load test the API without calling GM",
+		Long: "Serve a simulated GM API, to load test the API without calling GM.\n\n" +
+			"It answers for vehicles 1234 and 1235 like GM's sandbox, after the given latency. Point the API at it with " +
+			"GM_API_URL, and raise GM_RATE_LIMIT so the limit of GM's own API doesn't cap the load.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if errorRate < 0 || errorRate > 1 {
+				return errors.New("--error-rate must be between 0 and 1")
+			}
+
+			lis, err := net.Listen("tcp", listen)
+			if err != nil {
+				return err
+			}
+			sim := gmConnector.NewSimulator(gmConnector.WithLatency(latency, jitter), gmConnector.WithErrorRate(errorRate))
+			server := &http.Server{Handler: sim}
+
+			go func() {
+				<-cmd.Context().Done()
+				server.Shutdown(context.Background())
+			}()
+
+			fmt.Fprintf(stderr, "Simulating GM on http://%s\n", lis.Addr())
+			if err := server.Serve(lis); err != http.ErrServerClosed {
+				return err
+			}
+			fmt.Fprintf(stderr, "Served %d requests\n", sim.Requests())
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&listen, "listen", "localhost:8010", "address to serve the simulated GM API on")
+	flags.DurationVar(&latency, "latency", 50*time.Millisecond, "how long GM takes to answer")
+	flags.DurationVar(&jitter, "jitter", 50*time.Millisecond, "up to how much longer GM takes to answer, at random")
+	flags.Float64Var(&errorRate, "error-rate", 0, "fraction of requests GM fails with a 500, from 0 to 1")
+
+	return cmd
+}
@@ -0,0 +1,54 @@
+// loadtest ... replays scenarios against the SmartCar API and reports the latency, throughput and errors of each endpoint
+//
+//	loadtest gm --listen :8010 --latency 80ms --jitter 40ms
+//	GM_API_URL=http://localhost:8010 GM_RATE_LIMIT=1000 ./app_api
+//	loadtest run cmd/loadtest/scenarios/sandbox.jsonl --concurrency 20 --duration 1m --out run.json
+//	loadtest run cmd/loadtest/scenarios/sandbox.jsonl --rps 200 --compare run.json
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// an interrupt ends the run early, still reporting the requests made so far
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	if err := newRootCommand(os.Stdout, os.Stderr).ExecuteContext(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func newRootCommand(stdout, stderr io.Writer) *cobra.Command {
+	root := &cobra.Command{
+		Use:           "loadtest",
+		Short:         "Load test the SmartCar API",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	root.SetOut(stdout)
+	root.SetErr(stderr)
+
+	root.AddCommand(
+		newRunCommand(stdout, stderr),
+		newCompareCommand(stdout),
+		newGMCommand(stderr),
+	)
+	return root
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "loadtest")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func writeScenario(t *testing.T, lines ...string) string {
+	path := filepath.Join(tempDir(t), "scenario.jsonl")
+	require.NoError(t, ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644))
+	return path
+}
+
+func execute(t *testing.T, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	cmd := newRootCommand(&stdout, ioutil.Discard)
+	cmd.SetArgs(args)
+	err := cmd.ExecuteContext(context.Background())
+	return stdout.String(), err
+}
+
+func TestLoadScenario(t *testing.T) {
+	steps, err := loadScenario(writeScenario(t,
+		`{"path": "/vehicles/1234"}`,
+		``,
+		`{"name": "engine", "method": "post", "path": "/vehicles/1234/engine", "body": {"action": "START"}, "weight": 3, "expect": 200}`,
+	))
+	require.NoError(t, err)
+	require.Len(t, steps, 2)
+	assert.Equal(t, Step{Name: "GET /vehicles/1234", Method: "GET", Path: "/vehicles/1234", Weight: 1}, steps[0])
+	assert.Equal(t, "engine", steps[1].Name)
+	assert.Equal(t, "POST", steps[1].Method)
+	assert.JSONEq(t, `{"action": "START"}`, string(steps[1].Body))
+	assert.Equal(t, 3, steps[1].Weight)
+
+	assert.True(t, steps[0].ok(204))
+	assert.False(t, steps[0].ok(404))
+	assert.False(t, steps[1].ok(201))
+
+	path := writeScenario(t, `{"path": "/vehicles/1234"}`, `{"path": "vehicles"}`)
+	_, err = loadScenario(path)
+	assert.EqualError(t, err, path+":2: path must start with /")
+
+	path = writeScenario(t, `{"path": "/vehicles/1234"`)
+	_, err = loadScenario(path)
+	assert.EqualError(t, err, path+":1: unexpected end of JSON input")
+
+	path = writeScenario(t, ``)
+	_, err = loadScenario(path)
+	assert.EqualError(t, err, path+": no steps")
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 200; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, 100*time.Millisecond, percentile(latencies, 50))
+	assert.Equal(t, 190*time.Millisecond, percentile(latencies, 95))
+	assert.Equal(t, 198*time.Millisecond, percentile(latencies, 99))
+	assert.Equal(t, 7*time.Millisecond, percentile([]time.Duration{7 * time.Millisecond}, 99))
+}
+
+func TestNewReport(t *testing.T) {
+	samples := []sample{
+		{endpoint: "fuel", latency: 10 * time.Millisecond},
+		{endpoint: "fuel", latency: 30 * time.Millisecond, kind: "500"},
+		{endpoint: "doors", latency: 20 * time.Millisecond, kind: "timeout"},
+		{endpoint: "fuel", latency: 20 * time.Millisecond, kind: "500"},
+	}
+	report := newReport(samples, 2*time.Second)
+
+	require.Len(t, report.Endpoints, 2)
+	assert.Equal(t, "doors", report.Endpoints[0].Name)
+
+	fuel := report.Endpoints[1]
+	assert.Equal(t, 3, fuel.Requests)
+	assert.Equal(t, 2, fuel.Errors)
+	assert.Equal(t, 1.5, fuel.Throughput)
+	assert.Equal(t, Latency{Mean: 20, P50: 20, P95: 30, P99: 30, Max: 30}, fuel.Latency)
+	assert.Equal(t, map[string]int{"500": 2}, fuel.ErrorKinds)
+
+	assert.Equal(t, 4, report.Total.Requests)
+	assert.Equal(t, 0.75, report.Total.ErrorRate())
+	assert.Equal(t, map[string]int{"500": 2, "timeout": 1}, report.Total.ErrorKinds)
+
+	var out bytes.Buffer
+	require.NoError(t, report.write(&out))
+	assert.Contains(t, out.String(), "fuel      3         2       1.5  20.0ms  20.0ms  30.0ms  30.0ms  30.0ms\n")
+	assert.Contains(t, out.String(), "ENDPOINT  ERROR    COUNT\ndoors     timeout  1\nfuel      500      2\n")
+}
+
+func TestRun(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		assert.Equal(t, "k3y", r.Header.Get("X-API-Key"))
+		if r.URL.Path == "/vehicles/1236" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Method == "POST" {
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.JSONEq(t, `{"action": "START"}`, string(body))
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	scenario := writeScenario(t,
+		`{"name": "vehicle", "path": "/vehicles/1234", "weight": 2}`,
+		`{"name": "engine", "method": "POST", "path": "/vehicles/1234/engine", "body": {"action": "START"}}`,
+		`{"name": "unknown vehicle", "path": "/vehicles/1236"}`,
+	)
+	out := filepath.Join(tempDir(t), "run.json")
+
+	stdout, err := execute(t, "run", scenario, "--target", server.URL, "--api-key", "k3y", "-c", "1", "-n", "8", "-d", "0", "--out", out)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"GET /vehicles/1234", "GET /vehicles/1234", "POST /vehicles/1234/engine", "GET /vehicles/1236",
+		"GET /vehicles/1234", "GET /vehicles/1234", "POST /vehicles/1234/engine", "GET /vehicles/1236",
+	}, paths)
+	assert.Contains(t, stdout, "unknown vehicle  500    2\n")
+
+	report, err := loadReport(out)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL, report.Target)
+	assert.Equal(t, 8, report.Total.Requests)
+	assert.Equal(t, 2, report.Total.Errors)
+	vehicle, ok := report.endpoint("vehicle")
+	require.True(t, ok)
+	assert.Equal(t, 4, vehicle.Requests)
+	assert.Equal(t, 0, vehicle.Errors)
+
+	// the server going away is reported as an error of every request, rather than failing the run
+	server.Close()
+	stdout, err = execute(t, "run", scenario, "--target", server.URL, "-n", "3", "-d", "0")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "vehicle   connection refused  2\n")
+}
+
+func TestRunAtRate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	r := &runner{
+		client:      server.Client(),
+		target:      server.URL,
+		steps:       []Step{{Name: "vehicle", Method: "GET", Path: "/vehicles/1234", Weight: 1}},
+		concurrency: 5,
+		rps:         50,
+		requests:    11,
+	}
+	start := time.Now()
+	samples, _ := r.run(context.Background())
+	assert.Len(t, samples, 11)
+	// the first request starts right away, the next ten 20ms apart
+	assert.True(t, time.Since(start) >= 190*time.Millisecond, time.Since(start))
+}
+
+func TestRunStopsWhenInterrupted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	r := &runner{
+		client:      server.Client(),
+		target:      server.URL,
+		steps:       []Step{{Name: "vehicle", Method: "GET", Path: "/vehicles/1234", Weight: 1}},
+		concurrency: 2,
+		duration:    time.Minute,
+	}
+	samples, elapsed := r.run(ctx)
+	assert.Empty(t, samples, "interrupted requests aren't failures of the API")
+	assert.True(t, elapsed < time.Second)
+}
+
+func TestCompare(t *testing.T) {
+	before := Report{
+		Started:   time.Date(2020, 11, 20, 10, 0, 0, 0, time.UTC),
+		Total:     EndpointReport{Name: TOTAL, Requests: 100, Throughput: 50, Latency: Latency{P50: 10, P95: 20, P99: 40}},
+		Endpoints: []EndpointReport{{Name: "fuel", Requests: 100, Throughput: 50, Latency: Latency{P50: 10, P95: 20, P99: 40}}},
+	}
+	after := Report{
+		Total: EndpointReport{Name: TOTAL, Requests: 100, Errors: 1, Throughput: 40, Latency: Latency{P50: 10, P95: 30, P99: 42}},
+		Endpoints: []EndpointReport{
+			{Name: "doors", Requests: 50, Throughput: 20, Latency: Latency{P50: 5, P95: 6, P99: 7}},
+			{Name: "fuel", Requests: 50, Errors: 1, Throughput: 20, Latency: Latency{P50: 10, P95: 30, P99: 42}},
+		},
+	}
+
+	var out bytes.Buffer
+	regressions, err := compare(&out, before, after, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "ENDPOINT     P50                      P95                        P99                       RPS                    ERRORS\n"+
+		"doors (new)  5.0ms                    6.0ms                      7.0ms                     20.0                   0.00%\n"+
+		"fuel         10.0ms -> 10.0ms (0.0%)  20.0ms -> 30.0ms (+50.0%)  40.0ms -> 42.0ms (+5.0%)  50.0 -> 20.0 (-60.0%)  0.00% -> 2.00%\n"+
+		"total        10.0ms -> 10.0ms (0.0%)  20.0ms -> 30.0ms (+50.0%)  40.0ms -> 42.0ms (+5.0%)  50.0 -> 40.0 (-20.0%)  0.00% -> 1.00%\n",
+		out.String())
+	require.Len(t, regressions, 2)
+	assert.Equal(t, "fuel p95 went from 20.0ms to 30.0ms (+50.0%)", regressions[0].String())
+
+	regressions, err = compare(ioutil.Discard, before, after, 0)
+	require.NoError(t, err)
+	assert.Empty(t, regressions, "regressions aren't checked without a limit")
+
+	dir := tempDir(t)
+	require.NoError(t, before.save(filepath.Join(dir, "before.json")))
+	require.NoError(t, after.save(filepath.Join(dir, "after.json")))
+	_, err = execute(t, "compare", filepath.Join(dir, "before.json"), filepath.Join(dir, "after.json"), "--max-regression", "60")
+	assert.NoError(t, err)
+	_, err = execute(t, "compare", filepath.Join(dir, "before.json"), filepath.Join(dir, "after.json"), "--max-regression", "25")
+	assert.EqualError(t, err, "latency regressed:\n  fuel p95 went from 20.0ms to 30.0ms (+50.0%)\n  total p95 went from 20.0ms to 30.0ms (+50.0%)")
+
+	var saved map[string]interface{}
+	b, _ := ioutil.ReadFile(filepath.Join(dir, "after.json"))
+	require.NoError(t, json.Unmarshal(b, &saved))
+	assert.Contains(t, saved, "endpoints")
+}
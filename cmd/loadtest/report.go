@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// TOTAL ... the name the requests of every endpoint are reported under together
+const TOTAL = "total"
+
+// Report ... the results of a run, as saved with --out and read back with --compare
+type Report struct {
+	Started     time.Time `json:"started"`
+	Target      string    `json:"target"`
+	Scenario    string    `json:"scenario"`
+	Concurrency int       `json:"concurrency"`
+	RPS         float64   `json:"rps,omitempty"`
+
+	// Seconds ... how long the run took
+	Seconds float64 `json:"seconds"`
+
+	Total     EndpointReport   `json:"total"`
+	Endpoints []EndpointReport `json:"endpoints"`
+}
+
+// EndpointReport ... the results of the requests of an endpoint
+type EndpointReport struct {
+	Name     string `json:"name"`
+	Requests int    `json:"requests"`
+	Errors   int    `json:"errors"`
+
+	// Throughput ... the requests completed per second, failed or not
+	Throughput float64 `json:"throughput"`
+
+	Latency Latency `json:"latency"`
+
+	// ErrorKinds ... the number of errors by the status the requests got, or the network error
+	ErrorKinds map[string]int `json:"errorKinds,omitempty"`
+}
+
+// ErrorRate ... the fraction of the requests that failed
+func (e EndpointReport) ErrorRate() float64 {
+	if e.Requests == 0 {
+		return 0
+	}
+	return float64(e.Errors) / float64(e.Requests)
+}
+
+// Latency ... the distribution of the response times of an endpoint, in milliseconds
+type Latency struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// newReport ... summarizes the samples of a run, per endpoint and in total
+func newReport(samples []sample, elapsed time.Duration) Report {
+	byEndpoint := map[string][]sample{}
+	for _, s := range samples {
+		byEndpoint[s.endpoint] = append(byEndpoint[s.endpoint], s)
+	}
+
+	report := Report{
+		Seconds: elapsed.Seconds(),
+		Total:   summarize(TOTAL, samples, elapsed),
+	}
+	for name, endpointSamples := range byEndpoint {
+		report.Endpoints = append(report.Endpoints, summarize(name, endpointSamples, elapsed))
+	}
+	sort.Slice(report.Endpoints, func(i, j int) bool {
+		return report.Endpoints[i].Name < report.Endpoints[j].Name
+	})
+	return report
+}
+
+func summarize(name string, samples []sample, elapsed time.Duration) EndpointReport {
+	e := EndpointReport{Name: name, Requests: len(samples)}
+	if len(samples) == 0 {
+		return e
+	}
+	if elapsed > 0 {
+		e.Throughput = float64(len(samples)) / elapsed.Seconds()
+	}
+
+	latencies := make([]time.Duration, len(samples))
+	var sum time.Duration
+	for i, s := range samples {
+		latencies[i] = s.latency
+		sum += s.latency
+
+		if s.kind != "" {
+			e.Errors++
+			if e.ErrorKinds == nil {
+				e.ErrorKinds = map[string]int{}
+			}
+			e.ErrorKinds[s.kind]++
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	e.Latency = Latency{
+		Mean: milliseconds(sum / time.Duration(len(latencies))),
+		P50:  milliseconds(percentile(latencies, 50)),
+		P95:  milliseconds(percentile(latencies, 95)),
+		P99:  milliseconds(percentile(latencies, 99)),
+		Max:  milliseconds(latencies[len(latencies)-1]),
+	}
+	return e
+}
+
+// percentile ... the nearest-rank percentile p of the sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*100) / 100
+}
+
+// write ... writes the report as a table of the endpoints, followed by the errors of each
+func (r Report) write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENDPOINT\tREQUESTS\tERRORS\tRPS\tMEAN\tP50\tP95\tP99\tMAX")
+	for _, e := range append(r.Endpoints, r.Total) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t%s\n",
+			e.Name, e.Requests, e.Errors, e.Throughput,
+			formatMs(e.Latency.Mean), formatMs(e.Latency.P50), formatMs(e.Latency.P95), formatMs(e.Latency.P99), formatMs(e.Latency.Max))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if r.Total.Errors == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENDPOINT\tERROR\tCOUNT")
+	for _, e := range r.Endpoints {
+		kinds := make([]string, 0, len(e.ErrorKinds))
+		for kind := range e.ErrorKinds {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			fmt.Fprintf(tw, "%s\t%s\t%d\n", e.Name, kind, e.ErrorKinds[kind])
+		}
+	}
+	return tw.Flush()
+}
+
+func formatMs(ms float64) string {
+	return fmt.Sprintf("%.1fms", ms)
+}
+
+// save ... writes the report as JSON, to be compared with a later run
+func (r Report) save(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+func loadReport(path string) (Report, error) {
+	var r Report
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return r, fmt.Errorf("%s: %v", path, err)
+	}
+	return r, nil
+}
+
+// endpoint ... the results of the named endpoint, or of every endpoint for TOTAL
+func (r Report) endpoint(name string) (EndpointReport, bool) {
+	if name == TOTAL {
+		return r.Total, true
+	}
+	for _, e := range r.Endpoints {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return EndpointReport{}, false
+}
+
+// Regression ... a latency percentile of an endpoint that grew by more than the allowed percentage
+type Regression struct {
+	Endpoint   string
+	Percentile string
+	Before     float64
+	After      float64
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%s %s went from %s to %s (%s)", r.Endpoint, r.Percentile, formatMs(r.Before), formatMs(r.After), change(r.Before, r.After))
+}
+
+// compare ... writes how the latency, throughput and error rate of each endpoint changed since the previous run, and
+// returns the p95 and p99 latencies that grew by more than maxRegression percent. A maxRegression of zero or less
+// reports none
+func compare(w io.Writer, previous, current Report, maxRegression float64) ([]Regression, error) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENDPOINT\tP50\tP95\tP99\tRPS\tERRORS")
+
+	var regressions []Regression
+	for _, after := range append(current.Endpoints, current.Total) {
+		before, ok := previous.endpoint(after.Name)
+		if !ok {
+			fmt.Fprintf(tw, "%s (new)\t%s\t%s\t%s\t%.1f\t%.2f%%\n", after.Name,
+				formatMs(after.Latency.P50), formatMs(after.Latency.P95), formatMs(after.Latency.P99), after.Throughput, after.ErrorRate()*100)
+			continue
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", after.Name,
+			delta(formatMs(before.Latency.P50), formatMs(after.Latency.P50), change(before.Latency.P50, after.Latency.P50)),
+			delta(formatMs(before.Latency.P95), formatMs(after.Latency.P95), change(before.Latency.P95, after.Latency.P95)),
+			delta(formatMs(before.Latency.P99), formatMs(after.Latency.P99), change(before.Latency.P99, after.Latency.P99)),
+			delta(fmt.Sprintf("%.1f", before.Throughput), fmt.Sprintf("%.1f", after.Throughput), change(before.Throughput, after.Throughput)),
+			delta(fmt.Sprintf("%.2f%%", before.ErrorRate()*100), fmt.Sprintf("%.2f%%", after.ErrorRate()*100), ""),
+		)
+
+		if maxRegression <= 0 {
+			continue
+		}
+		for _, p := range []struct {
+			name          string
+			before, after float64
+		}{{"p95", before.Latency.P95, after.Latency.P95}, {"p99", before.Latency.P99, after.Latency.P99}} {
+			if p.before > 0 && (p.after-p.before)/p.before*100 > maxRegression {
+				regressions = append(regressions, Regression{Endpoint: after.Name, Percentile: p.name, Before: p.before, After: p.after})
+			}
+		}
+	}
+	return regressions, tw.Flush()
+}
+
+// delta ... e.g. 12.0ms -> 15.0ms (+25.0%)
+func delta(before, after, change string) string {
+	s := before + " -> " + after
+	if change != "" {
+		s += " (" + change + ")"
+	}
+	return s
+}
+
+// change ... the relative change from before to after, e.g. +25.0%
+func change(before, after float64) string {
+	if before == 0 {
+		return "n/a"
+	}
+	pct := (after - before) / before * 100
+	if math.Abs(pct) < 0.05 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%+.1f%%", pct)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newRunCommand(stdout, stderr io.Writer) *cobra.Command {
+	r := &runner{}
+	var timeout time.Duration
+	var out, previous string
+	var maxRegression float64
+
+	cmd := &cobra.Command{
+		Use:   "run SCENARIO",
+		Short: "Replay a scenario against the API and report the latency, throughput and errors of each endpoint",
+		Long: "Replay a scenario against the API and report the latency, throughput and errors of each endpoint.\n\n" +
+			"A scenario is a file of JSON requests, one per line, replayed in order over and over:\n\n" +
+			`  {"name": "vehicle", "path": "/vehicles/1234"}` + "\n" +
+			`  {"name": "engine", "method": "POST", "path": "/vehicles/1234/engine", "body": {"action": "START"}, "weight": 2}` + "\n" +
+			`  {"name": "unknown vehicle", "path": "/vehicles/1236", "expect": 500}` + "\n\n" +
+			"A request fails when it doesn't get the status it expects, by default any status below 400.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if r.concurrency < 1 {
+				return errors.New("--concurrency must be at least 1")
+			}
+			if r.duration <= 0 && r.requests <= 0 {
+				return errors.New("--duration or --requests is required")
+			}
+			if !strings.HasPrefix(r.target, "http://") && !strings.HasPrefix(r.target, "https://") {
+				return fmt.Errorf("--target must be an http or https URL, got %q", r.target)
+			}
+
+			steps, err := loadScenario(args[0])
+			if err != nil {
+				return err
+			}
+			r.steps = steps
+
+			// read the previous run first, rather than finding out it is missing after the run
+			var before Report
+			if previous != "" {
+				if before, err = loadReport(previous); err != nil {
+					return err
+				}
+			}
+
+			r.client = &http.Client{
+				Timeout:   timeout,
+				Transport: &http.Transport{MaxIdleConnsPerHost: r.concurrency},
+			}
+
+			fmt.Fprintf(stderr, "Running %s against %s with %d workers\n", args[0], r.target, r.concurrency)
+			started := time.Now()
+			samples, elapsed := r.run(cmd.Context())
+
+			report := newReport(samples, elapsed)
+			report.Started = started
+			report.Target = r.target
+			report.Scenario = args[0]
+			report.Concurrency = r.concurrency
+			report.RPS = r.rps
+
+			if err := report.write(stdout); err != nil {
+				return err
+			}
+			if out != "" {
+				if err := report.save(out); err != nil {
+					return err
+				}
+			}
+
+			if previous == "" {
+				return nil
+			}
+			fmt.Fprintf(stdout, "\nCompared with %s, run %s\n", previous, before.Started.Format(time.RFC3339))
+			regressions, err := compare(stdout, before, report, maxRegression)
+			if err != nil {
+				return err
+			}
+			return regressionError(regressions)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&r.target, "target", "t", "http://localhost:8003", "base URL of the API")
+	flags.StringVar(&r.apiKey, "api-key", "", "API key sent with every request")
+	flags.IntVarP(&r.concurrency, "concurrency", "c", 10, "number of requests in flight at once")
+	flags.Float64Var(&r.rps, "rps", 0, "most requests started per second. Defaults to as many as the workers can make")
+	flags.DurationVarP(&r.duration, "duration", "d", 30*time.Second, "how long to run for, 0 to run until --requests are made")
+	flags.IntVarP(&r.requests, "requests", "n", 0, "number of requests to make, 0 to run for --duration")
+	flags.DurationVar(&timeout, "timeout", 10*time.Second, "how long a request may take")
+	flags.StringVar(&out, "out", "", "file to save the results to as JSON, to compare later runs with")
+	flags.StringVar(&previous, "compare", "", "results of a previous run to compare with, as saved with --out")
+	flags.Float64Var(&maxRegression, "max-regression", 0, "fail if the p95 or p99 of an endpoint grew by more than this percentage since --compare")
+
+	return cmd
+}
+
+func newCompareCommand(stdout io.Writer) *cobra.Command {
+	var maxRegression float64
+
+	cmd := &cobra.Command{
+		Use:   "compare BEFORE AFTER",
+		Short: "Compare the results of two runs, as saved with run --out",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			before, err := loadReport(args[0])
+			if err != nil {
+				return err
+			}
+			after, err := loadReport(args[1])
+			if err != nil {
+				return err
+			}
+
+			regressions, err := compare(stdout, before, after, maxRegression)
+			if err != nil {
+				return err
+			}
+			return regressionError(regressions)
+		},
+	}
+	cmd.Flags().Float64Var(&maxRegression, "max-regression", 0, "fail if the p95 or p99 of an endpoint grew by more than this percentage")
+
+	return cmd
+}
+
+// regressionError ... fails the command when the latency regressed, so CI can catch it
+func regressionError(regressions []Regression) error {
+	if len(regressions) == 0 {
+		return nil
+	}
+	lines := make([]string, len(regressions))
+	for i, r := range regressions {
+		lines[i] = r.String()
+	}
+	return fmt.Errorf("latency regressed:\n  %s", strings.Join(lines, "\n  "))
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"app_api/shared/ratelimit"
+)
+
+// runner ... replays the steps of a scenario in order, over and over, from concurrent workers
+type runner struct {
+	client *http.Client
+	target string
+	apiKey string
+	steps  []Step
+
+	// concurrency ... the number of workers, each with at most one request in flight
+	concurrency int
+
+	// rps ... the most requests started per second, across workers. Zero starts them as fast as the workers finish them
+	rps float64
+
+	// duration and requests ... the run ends at whichever comes first. Zero is no limit
+	duration time.Duration
+	requests int
+}
+
+// sample ... the outcome of a request
+type sample struct {
+	endpoint string
+	latency  time.Duration
+
+	// kind ... why the request failed: the status it got, or the network error. Empty when it succeeded
+	kind string
+}
+
+// run ... sends requests until the duration or number of requests is reached, or the context is done, and returns the
+// outcome of every request that completed. Requests in flight when the run ends are waited for, unless the context is done
+func (r *runner) run(ctx context.Context) (samples []sample, elapsed time.Duration) {
+	stop := ctx
+	if r.duration > 0 {
+		var cancel context.CancelFunc
+		stop, cancel = context.WithTimeout(ctx, r.duration)
+		defer cancel()
+	}
+
+	jobs := make(chan Step)
+	go r.schedule(stop, jobs)
+
+	results := make(chan []sample, r.concurrency)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < r.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var done []sample
+			for step := range jobs {
+				s := r.send(ctx, step)
+				if ctx.Err() != nil {
+					// interrupted, not a failure of the API
+					break
+				}
+				done = append(done, s)
+			}
+			results <- done
+		}()
+	}
+	wg.Wait()
+	elapsed = time.Since(start)
+
+	close(results)
+	for done := range results {
+		samples = append(samples, done...)
+	}
+	return samples, elapsed
+}
+
+// schedule ... hands the steps out to the workers, at the rate of the run, until it ends
+func (r *runner) schedule(ctx context.Context, jobs chan<- Step) {
+	defer close(jobs)
+
+	limiter := ratelimit.NewLimiter(r.rps, 1)
+	for sent := 0; ; {
+		for _, step := range r.steps {
+			for i := 0; i < step.Weight; i++ {
+				if r.requests > 0 && sent >= r.requests {
+					return
+				}
+				if err := limiter.Wait(ctx); err != nil {
+					return
+				}
+
+				select {
+				case jobs <- step:
+					sent++
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
+
+// send ... makes the request of a step, reading the whole response so its latency includes the body
+func (r *runner) send(ctx context.Context, step Step) sample {
+	s := sample{endpoint: step.Name}
+
+	var body io.Reader
+	if len(step.Body) > 0 {
+		body = bytes.NewReader(step.Body)
+	}
+	req, err := http.NewRequest(step.Method, strings.TrimSuffix(r.target, "/")+step.Path, body)
+	if err != nil {
+		s.kind = "invalid request"
+		return s
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if r.apiKey != "" {
+		req.Header.Set("X-API-Key", r.apiKey)
+	}
+	for name, value := range step.Headers {
+		req.Header.Set(name, value)
+	}
+
+	start := time.Now()
+	res, err := r.client.Do(req)
+	if err == nil {
+		_, err = io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+	}
+	s.latency = time.Since(start)
+
+	switch {
+	case err != nil:
+		s.kind = errorKind(err)
+	case !step.ok(res.StatusCode):
+		s.kind = strconv.Itoa(res.StatusCode)
+	}
+	return s
+}
+
+// errorKind ... groups network errors by their cause, as their messages vary with the address and port
+func errorKind(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case strings.Contains(err.Error(), "connection refused"):
+		return "connection refused"
+	case strings.Contains(err.Error(), "connection reset"):
+		return "connection reset"
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		return "connection closed"
+	default:
+		return "network error"
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Step ... a request of a scenario, one per line of the scenario file
+//
+//	{"name": "engine", "method": "POST", "path": "/vehicles/1234/engine", "body": {"action": "START"}}
+type Step struct {
+	// Name ... the endpoint the request is reported under. Defaults to the method and path
+	Name string `json:"name"`
+
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+
+	// Expect ... the status the request should get. Defaults to any status below 400
+	Expect int `json:"expect"`
+
+	// Weight ... how many times the step is repeated each time the scenario is replayed. Defaults to 1
+	Weight int `json:"weight"`
+}
+
+// ok ... whether the status is the one the step expects
+func (s Step) ok(status int) bool {
+	if s.Expect != 0 {
+		return status == s.Expect
+	}
+	return status < 400
+}
+
+// loadScenario ... reads the steps of a scenario file, skipping blank lines
+func loadScenario(path string) ([]Step, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var steps []Step
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var step Step
+		if err := json.Unmarshal(text, &step); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		if step.Path == "" || !strings.HasPrefix(step.Path, "/") {
+			return nil, fmt.Errorf("%s:%d: path must start with /", path, line)
+		}
+		if step.Weight < 0 {
+			return nil, fmt.Errorf("%s:%d: weight must not be negative", path, line)
+		}
+
+		if step.Method == "" {
+			step.Method = "GET"
+		}
+		step.Method = strings.ToUpper(step.Method)
+		if step.Name == "" {
+			step.Name = step.Method + " " + step.Path
+		}
+		if step.Weight == 0 {
+			step.Weight = 1
+		}
+		steps = append(steps, step)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("%s: no steps", path)
+	}
+	return steps, nil
+}
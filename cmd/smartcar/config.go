@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	ENV_CONFIG   = "SMARTCAR_CONFIG"
+	ENV_PROFILE  = "SMARTCAR_PROFILE"
+	ENV_ENDPOINT = "SMARTCAR_ENDPOINT"
+	ENV_API_KEY  = "SMARTCAR_API_KEY"
+
+	DEFAULT_ENDPOINT = "http://localhost:8003"
+	DEFAULT_PROFILE  = "default"
+)
+
+// Config ... the profiles saved in the config file
+type Config struct {
+	// CurrentProfile ... the profile used when none is given
+	CurrentProfile string `yaml:"current_profile"`
+
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Profile ... an API endpoint and the credentials to call it with
+type Profile struct {
+	Endpoint string `yaml:"endpoint"`
+	APIKey   string `yaml:"api_key,omitempty"`
+}
+
+// defaultConfigPath ... $SMARTCAR_CONFIG, or smartcar/config.yaml in the user's config directory
+func defaultConfigPath() string {
+	if path := os.Getenv(ENV_CONFIG); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return filepath.Join(".smartcar", "config.yaml")
+	}
+	return filepath.Join(dir, "smartcar", "config.yaml")
+}
+
+// loadConfig ... reads the config file. A missing file is an empty config
+func loadConfig(path string) (*Config, error) {
+	config := &Config{Profiles: map[string]Profile{}}
+
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if config.Profiles == nil {
+		config.Profiles = map[string]Profile{}
+	}
+	return config, nil
+}
+
+// save ... writes the config file, readable only by the user as it holds API keys
+func (c *Config) save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// profileNames ... the names of the saved profiles, sorted
+func (c *Config) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolve ... returns the profile to call the API with. The profile is the one named, or $SMARTCAR_PROFILE, or the
+// current profile. $SMARTCAR_ENDPOINT and $SMARTCAR_API_KEY override it, and the endpoint and API key flags override those
+func (c *Config) resolve(name, endpoint, apiKey string) (Profile, error) {
+	if name == "" {
+		name = os.Getenv(ENV_PROFILE)
+	}
+	explicit := name != ""
+	if name == "" {
+		name = c.CurrentProfile
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok && explicit {
+		return Profile{}, fmt.Errorf("unknown profile %q, see smartcar profile list", name)
+	}
+
+	if v := os.Getenv(ENV_ENDPOINT); v != "" {
+		profile.Endpoint = v
+	}
+	if v := os.Getenv(ENV_API_KEY); v != "" {
+		profile.APIKey = v
+	}
+	if endpoint != "" {
+		profile.Endpoint = endpoint
+	}
+	if apiKey != "" {
+		profile.APIKey = apiKey
+	}
+
+	if profile.Endpoint == "" {
+		profile.Endpoint = DEFAULT_ENDPOINT
+	}
+	return profile, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+
+	"app_api/apis/vehicle"
+
+	"github.com/spf13/cobra"
+)
+
+func newEngineCommand(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "engine",
+		Short: "Start or stop the engine of a vehicle",
+	}
+
+	for _, action := range []string{vehicle.ENGINE_START, vehicle.ENGINE_STOP} {
+		action := action
+		cmd.AddCommand(&cobra.Command{
+			Use:   strings.ToLower(action) + " VEHICLE_ID",
+			Short: strings.Title(strings.ToLower(action)) + " the engine of a vehicle",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				vehicleID, p, c, err := a.vehicleCommand(args[0])
+				if err != nil {
+					return err
+				}
+
+				res, err := c.SendEngineAction(cmd.Context(), vehicleID, action)
+				if err != nil {
+					return err
+				}
+
+				return p.print(res, table{
+					header: []string{"VEHICLE", "ACTION", "STATUS"},
+					rows:   [][]string{{args[0], action, res.Action}},
+				})
+			},
+		})
+	}
+
+	return cmd
+}
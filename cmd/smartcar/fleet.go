@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	"app_api/apis/vehicle"
+
+	"github.com/spf13/cobra"
+)
+
+func newFleetCommand(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fleet",
+		Short: "Read the status of many vehicles at once",
+	}
+
+	var group string
+	var vehicleIDs []string
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "Show the doors, fuel and battery of every vehicle in a group",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := vehicle.BatchRequest{
+				Group:    group,
+				Sections: []string{vehicle.SECTION_DOORS, vehicle.SECTION_FUEL, vehicle.SECTION_BATTERY},
+			}
+			for _, arg := range vehicleIDs {
+				vehicleID, err := parseVehicleID(arg)
+				if err != nil {
+					return err
+				}
+				req.VehicleIDs = append(req.VehicleIDs, vehicleID)
+			}
+			if req.Group == "" && len(req.VehicleIDs) == 0 {
+				return errors.New("--group or --vehicles is required")
+			}
+
+			p, err := a.printer()
+			if err != nil {
+				return err
+			}
+			c, err := a.client()
+			if err != nil {
+				return err
+			}
+
+			results, err := c.BatchAllVehicles(cmd.Context(), req)
+			if err != nil {
+				return err
+			}
+
+			t := table{header: []string{"VEHICLE", "STATUS", "DOORS", "FUEL", "BATTERY", "ERROR"}}
+			for _, result := range results {
+				row := []string{strconv.FormatInt(result.VehicleID, 10), result.Status, "-", "-", "-", result.Error}
+				if result.Snapshot != nil {
+					row[2] = lockedDoors(result.Snapshot.Doors)
+					row[3] = fuelLevel(result.Snapshot.Fuel)
+					row[4] = batteryLevel(result.Snapshot.Battery)
+				}
+				t.rows = append(t.rows, row)
+			}
+			return p.print(results, t)
+		},
+	}
+	status.Flags().StringVarP(&group, "group", "g", "", "the group of the vehicles")
+	status.Flags().StringSliceVar(&vehicleIDs, "vehicles", nil, "vehicle IDs, in addition to the group, e.g. 1234,1235")
+	cmd.AddCommand(status)
+
+	return cmd
+}
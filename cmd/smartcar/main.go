@@ -0,0 +1,119 @@
+// smartcar ... a command line tool for operators of the SmartCar API
+//
+//	smartcar profile set prod --endpoint https://smartcar.example.com --api-key k3y
+//	smartcar vehicle get 1234
+//	smartcar fleet status --group depot-7 -o csv
+//	smartcar watch --group depot-7
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"app_api/client"
+
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the first interrupt stops a watch or a request in flight, the second exits right away
+	interrupt := make(chan os.Signal, 2)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		cancel()
+		<-interrupt
+		os.Exit(130)
+	}()
+
+	if err := newRootCommand(os.Stdout, os.Stderr).ExecuteContext(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// app ... the options and config shared by every command
+type app struct {
+	stdout io.Writer
+	stderr io.Writer
+
+	configPath string
+	profile    string
+	endpoint   string
+	apiKey     string
+	output     string
+	timeout    time.Duration
+
+	config *Config
+}
+
+func newRootCommand(stdout, stderr io.Writer) *cobra.Command {
+	a := &app{stdout: stdout, stderr: stderr}
+
+	root := &cobra.Command{
+		Use:   "smartcar",
+		Short: "Operate vehicles through the SmartCar API",
+		Long: "Operate vehicles through the SmartCar API.\n\n" +
+			"The endpoint and API key come from the --endpoint and --api-key flags, $SMARTCAR_ENDPOINT and $SMARTCAR_API_KEY, " +
+			"or a profile saved with smartcar profile set, in that order.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) (err error) {
+			a.config, err = loadConfig(a.configPath)
+			return
+		},
+	}
+	root.SetOut(stdout)
+	root.SetErr(stderr)
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&a.configPath, "config", defaultConfigPath(), "config file of the profiles ($"+ENV_CONFIG+")")
+	flags.StringVarP(&a.profile, "profile", "p", "", "profile to use instead of the current one ($"+ENV_PROFILE+")")
+	flags.StringVar(&a.endpoint, "endpoint", "", "base URL of the API ($"+ENV_ENDPOINT+")")
+	flags.StringVar(&a.apiKey, "api-key", "", "API key ($"+ENV_API_KEY+")")
+	flags.StringVarP(&a.output, "output", "o", OUTPUT_TABLE, "output format: table, json or csv")
+	flags.DurationVar(&a.timeout, "timeout", client.DEFAULT_TIMEOUT, "how long a request may take")
+
+	root.AddCommand(
+		newVehicleCommand(a),
+		newEngineCommand(a),
+		newFleetCommand(a),
+		newWatchCommand(a),
+		newProfileCommand(a),
+	)
+	return root
+}
+
+// client ... returns a client of the API of the resolved profile
+func (a *app) client() (*client.Client, error) {
+	profile, err := a.config.resolve(a.profile, a.endpoint, a.apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return client.New(profile.Endpoint,
+		client.WithAPIKey(profile.APIKey),
+		client.WithTimeout(a.timeout),
+		client.WithUserAgent("smartcar-cli"),
+	)
+}
+
+func (a *app) printer() (*printer, error) {
+	return newPrinter(a.stdout, a.output)
+}
+
+func parseVehicleID(arg string) (int64, error) {
+	vehicleID, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || vehicleID <= 0 {
+		return 0, fmt.Errorf("vehicle ID must be a positive integer, got %q", arg)
+	}
+	return vehicleID, nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI ... answers each path with its canned response body, and records the last request
+type fakeAPI struct {
+	responses   map[string]string
+	lastRequest *http.Request
+	lastBody    string
+}
+
+func newFakeAPI(t *testing.T, responses map[string]string) (*fakeAPI, *httptest.Server) {
+	api := &fakeAPI{responses: responses}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		api.lastRequest, api.lastBody = r, string(body)
+
+		res, ok := api.responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":1,"message":"Not found"}`))
+			return
+		}
+		w.Write([]byte(res))
+	}))
+	t.Cleanup(server.Close)
+	return api, server
+}
+
+// execute ... runs the command line against the endpoint, with a config file of its own
+func execute(t *testing.T, ctx context.Context, configPath string, args ...string) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := newRootCommand(&stdout, &stderr)
+	cmd.SetArgs(append([]string{"--config", configPath}, args...))
+	err := cmd.ExecuteContext(ctx)
+	return stdout.String(), stderr.String(), err
+}
+
+func newConfigPath(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "smartcar")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "config.yaml")
+}
+
+func TestVehicleGetOutputs(t *testing.T) {
+	_, server := newFakeAPI(t, map[string]string{
+		"GET /vehicles/1234": `{"vin":"123123412412","color":"Metallic Silver","doorCount":4,"driveTrain":"v8"}`,
+	})
+	config := newConfigPath(t)
+
+	stdout, _, err := execute(t, context.Background(), config, "--endpoint", server.URL, "vehicle", "get", "1234")
+	assert.NoError(t, err)
+	assert.Equal(t, "VEHICLE  VIN           COLOR            DOORS  DRIVETRAIN\n1234     123123412412  Metallic Silver  4      v8\n", stdout)
+
+	stdout, _, err = execute(t, context.Background(), config, "--endpoint", server.URL, "vehicle", "get", "1234", "-o", "csv")
+	assert.NoError(t, err)
+	assert.Equal(t, "VEHICLE,VIN,COLOR,DOORS,DRIVETRAIN\n1234,123123412412,Metallic Silver,4,v8\n", stdout)
+
+	stdout, _, err = execute(t, context.Background(), config, "--endpoint", server.URL, "vehicle", "get", "1234", "-o", "json")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"vin":"123123412412","color":"Metallic Silver","doorCount":4,"driveTrain":"v8"}`, stdout)
+
+	_, _, err = execute(t, context.Background(), config, "--endpoint", server.URL, "vehicle", "get", "1234", "-o", "xml")
+	assert.EqualError(t, err, `unsupported output "xml", must be one of [table, json, csv]`)
+
+	_, _, err = execute(t, context.Background(), config, "--endpoint", server.URL, "vehicle", "get", "1235")
+	assert.EqualError(t, err, "404 Not Found: Not found")
+}
+
+func TestVehicleDoorsAndEnergy(t *testing.T) {
+	_, server := newFakeAPI(t, map[string]string{
+		"GET /vehicles/1235/doors": `[{"location":"frontLeft","locked":true},{"location":"frontRight","locked":false}]`,
+	})
+	config := newConfigPath(t)
+
+	stdout, _, err := execute(t, context.Background(), config, "--endpoint", server.URL, "vehicle", "doors", "1235", "-o", "csv")
+	assert.NoError(t, err)
+	assert.Equal(t, "DOOR,LOCKED\nfrontLeft,yes\nfrontRight,no\n", stdout)
+
+	api, server := newFakeAPI(t, map[string]string{
+		"GET /vehicles/1235/snapshot": `{"vehicleId":1235,"fuel":{"status":"ok","data":{"percentage":null}},"battery":{"status":"ok","data":{"percentage":42.5}}}`,
+	})
+	stdout, _, err = execute(t, context.Background(), config, "--endpoint", server.URL, "vehicle", "energy", "1235", "-o", "csv")
+	assert.NoError(t, err)
+	assert.Equal(t, "VEHICLE,FUEL,BATTERY\n1235,-,42.5%\n", stdout)
+	assert.Equal(t, "fuel,battery", api.lastRequest.URL.Query().Get("fields"))
+}
+
+func TestEngine(t *testing.T) {
+	api, server := newFakeAPI(t, map[string]string{
+		"POST /vehicles/1234/engine": `{"status":"success"}`,
+	})
+	config := newConfigPath(t)
+
+	stdout, _, err := execute(t, context.Background(), config, "--endpoint", server.URL, "--api-key", "k3y", "engine", "stop", "1234", "-o", "csv")
+	assert.NoError(t, err)
+	assert.Equal(t, "VEHICLE,ACTION,STATUS\n1234,STOP,success\n", stdout)
+	assert.JSONEq(t, `{"action":"STOP"}`, api.lastBody)
+	assert.Equal(t, "k3y", api.lastRequest.Header.Get("X-API-Key"))
+
+	_, _, err = execute(t, context.Background(), config, "--endpoint", server.URL, "engine", "start", "abc")
+	assert.EqualError(t, err, `vehicle ID must be a positive integer, got "abc"`)
+}
+
+func TestFleetStatus(t *testing.T) {
+	api, server := newFakeAPI(t, map[string]string{
+		"POST /vehicles/batch": `{"result":[` +
+			`{"vehicleId":1234,"status":"ok","snapshot":{"vehicleId":1234,"doors":{"status":"ok","data":[{"location":"frontLeft","locked":true},{"location":"frontRight","locked":false}]},"fuel":{"status":"ok","data":{"percentage":30}},"battery":{"status":"ok","data":{"percentage":null}}}},` +
+			`{"vehicleId":1236,"status":"error","error":"Failed to get vehicle snapshot"}` +
+			`],"count":2,"offset":0,"limit":100,"total":2}`,
+	})
+	config := newConfigPath(t)
+
+	stdout, _, err := execute(t, context.Background(), config, "--endpoint", server.URL, "fleet", "status", "--group", "depot-7", "-o", "csv")
+	assert.NoError(t, err)
+	assert.Equal(t, "VEHICLE,STATUS,DOORS,FUEL,BATTERY,ERROR\n"+
+		"1234,ok,1/2 locked,30%,-,\n"+
+		"1236,error,-,-,-,Failed to get vehicle snapshot\n", stdout)
+
+	var req map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(api.lastBody), &req))
+	assert.Equal(t, "depot-7", req["group"])
+
+	_, _, err = execute(t, context.Background(), config, "--endpoint", server.URL, "fleet", "status")
+	assert.EqualError(t, err, "--group or --vehicles is required")
+}
+
+func TestProfiles(t *testing.T) {
+	api, server := newFakeAPI(t, map[string]string{
+		"GET /vehicles/1234": `{"vin":"123123412412"}`,
+	})
+	config := newConfigPath(t)
+	ctx := context.Background()
+
+	_, _, err := execute(t, ctx, config, "profile", "set", "staging", "--endpoint", server.URL, "--api-key", "s3cret")
+	assert.NoError(t, err)
+	_, _, err = execute(t, ctx, config, "profile", "set", "prod", "--endpoint", "https://smartcar.example.com")
+	assert.NoError(t, err)
+
+	stdout, _, err := execute(t, ctx, config, "profile", "list", "-o", "csv")
+	assert.NoError(t, err)
+	assert.Equal(t, "NAME,CURRENT,ENDPOINT,API KEY\nprod,no,https://smartcar.example.com,no\nstaging,yes,"+server.URL+",yes\n", stdout)
+	assert.NotContains(t, stdout, "s3cret")
+
+	// the first profile saved is the current one
+	_, _, err = execute(t, ctx, config, "vehicle", "get", "1234")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", api.lastRequest.Header.Get("X-API-Key"))
+
+	_, _, err = execute(t, ctx, config, "profile", "use", "prod")
+	assert.NoError(t, err)
+	_, _, err = execute(t, ctx, config, "--profile", "staging", "--api-key", "0ther", "vehicle", "get", "1234")
+	assert.NoError(t, err)
+	assert.Equal(t, "0ther", api.lastRequest.Header.Get("X-API-Key"))
+
+	_, _, err = execute(t, ctx, config, "--profile", "dev", "vehicle", "get", "1234")
+	assert.EqualError(t, err, `unknown profile "dev", see smartcar profile list`)
+
+	info, err := os.Stat(config)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/fleet/stream", r.URL.Path)
+		assert.Equal(t, "depot-7", r.URL.Query().Get("group"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, strings.Join([]string{
+			"id: 6",
+			"event: snapshot",
+			`data: {"vehicleId":1234,"status":"ok","snapshot":{"vehicleId":1234,"fuel":{"status":"ok","data":{"percentage":16}}}}`,
+			"",
+			"id: 7",
+			"event: fuel_low",
+			`data: {"id":7,"type":"fuel_low","vehicleId":1234,"time":"2020-11-20T10:00:00Z","data":{"percentage":12,"previous":16,"threshold":15}}`,
+			"",
+			"",
+		}, "\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	// the watch ends once interrupted after the last event, rather than reconnecting
+	stdout := &interruptingWriter{cancel: cancel, after: "fuel_low"}
+	cmd := newRootCommand(stdout, ioutil.Discard)
+	cmd.SetArgs([]string{"--config", newConfigPath(t), "--endpoint", server.URL, "watch", "--group", "depot-7", "-o", "csv"})
+	assert.NoError(t, cmd.ExecuteContext(ctx))
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "TIME,ID,VEHICLE,EVENT,DETAIL", lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], ",6,1234,snapshot,fuel 16%"), lines[1])
+	assert.Equal(t, `2020-11-20T10:00:00Z,7,1234,fuel_low,"12% (was 16%, threshold 15%)"`, lines[2])
+}
+
+// interruptingWriter ... cancels once the output contains after
+type interruptingWriter struct {
+	bytes.Buffer
+	cancel context.CancelFunc
+	after  string
+}
+
+func (w *interruptingWriter) Write(p []byte) (int, error) {
+	n, err := w.Buffer.Write(p)
+	if strings.Contains(w.String(), w.after) {
+		w.cancel()
+	}
+	return n, err
+}
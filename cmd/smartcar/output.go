@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	OUTPUT_TABLE = "table"
+	OUTPUT_JSON  = "json"
+	OUTPUT_CSV   = "csv"
+)
+
+// Outputs ... the supported output formats
+var Outputs = []string{OUTPUT_TABLE, OUTPUT_JSON, OUTPUT_CSV}
+
+// table ... the rows a result is shown as in the table and CSV formats. JSON shows the result as the API returned it
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// printer ... writes results in the output format
+type printer struct {
+	w      io.Writer
+	format string
+
+	// wroteHeader ... a stream of rows only has its header written once
+	wroteHeader bool
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	for _, output := range Outputs {
+		if format == output {
+			return &printer{w: w, format: format}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported output %q, must be one of [%s]", format, strings.Join(Outputs, ", "))
+}
+
+// print ... writes a result, as value in JSON and as t otherwise
+func (p *printer) print(value interface{}, t table) error {
+	switch p.format {
+	case OUTPUT_JSON:
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(value)
+	case OUTPUT_CSV:
+		w := csv.NewWriter(p.w)
+		w.Write(t.header)
+		w.WriteAll(t.rows)
+		return w.Error()
+	default:
+		w := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, strings.Join(t.header, "\t"))
+		for _, row := range t.rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		return w.Flush()
+	}
+}
+
+// stream ... writes one result of a stream as soon as it arrives: a line of JSON, or a row after the header of the
+// first. Table columns can't be aligned with rows yet to come, so they are separated by tabs
+func (p *printer) stream(value interface{}, header, row []string) error {
+	switch p.format {
+	case OUTPUT_JSON:
+		return json.NewEncoder(p.w).Encode(value)
+	case OUTPUT_CSV:
+		w := csv.NewWriter(p.w)
+		if !p.wroteHeader {
+			w.Write(header)
+			p.wroteHeader = true
+		}
+		w.Write(row)
+		w.Flush()
+		return w.Error()
+	default:
+		if !p.wroteHeader {
+			fmt.Fprintln(p.w, strings.Join(header, "\t"))
+			p.wroteHeader = true
+		}
+		_, err := fmt.Fprintln(p.w, strings.Join(row, "\t"))
+		return err
+	}
+}
+
+// percentage ... formats a fuel or battery level, which is nil when the vehicle has none
+func percentage(v *float64) string {
+	if v == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64) + "%"
+}
+
+func boolean(v bool) string {
+	if v {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newProfileCommand(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage the saved endpoints and API keys",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the saved profiles",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := a.printer()
+			if err != nil {
+				return err
+			}
+
+			// API keys are never printed, only whether one is saved
+			type profileLine struct {
+				Name    string `json:"name"`
+				Current bool   `json:"current"`
+				Profile
+			}
+			var lines []profileLine
+			t := table{header: []string{"NAME", "CURRENT", "ENDPOINT", "API KEY"}}
+			for _, name := range a.config.profileNames() {
+				profile := a.config.Profiles[name]
+				current := name == a.config.CurrentProfile
+				lines = append(lines, profileLine{Name: name, Current: current, Profile: Profile{Endpoint: profile.Endpoint}})
+				t.rows = append(t.rows, []string{name, boolean(current), profile.Endpoint, boolean(profile.APIKey != "")})
+			}
+			return p.print(lines, t)
+		},
+	})
+
+	// the --endpoint and --api-key flags of profile set are what it saves
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set NAME --endpoint URL [--api-key KEY]",
+		Short: "Save a profile, or change the endpoint or API key of one",
+		Long:  "Save a profile, or change the endpoint or API key of one. The first profile saved becomes the current one.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			profile := a.config.Profiles[name]
+			if cmd.Flags().Changed("endpoint") {
+				profile.Endpoint = a.endpoint
+			}
+			if cmd.Flags().Changed("api-key") {
+				profile.APIKey = a.apiKey
+			}
+			if profile.Endpoint == "" {
+				profile.Endpoint = DEFAULT_ENDPOINT
+			}
+
+			a.config.Profiles[name] = profile
+			if a.config.CurrentProfile == "" {
+				a.config.CurrentProfile = name
+			}
+			if err := a.config.save(a.configPath); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(a.stdout, "Saved profile %s to %s\n", name, a.configPath)
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "use NAME",
+		Short: "Make a profile the current one",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, ok := a.config.Profiles[args[0]]; !ok {
+				return fmt.Errorf("unknown profile %q", args[0])
+			}
+
+			a.config.CurrentProfile = args[0]
+			if err := a.config.save(a.configPath); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(a.stdout, "Using profile %s\n", args[0])
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a saved profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, ok := a.config.Profiles[args[0]]; !ok {
+				return fmt.Errorf("unknown profile %q", args[0])
+			}
+
+			delete(a.config.Profiles, args[0])
+			if a.config.CurrentProfile == args[0] {
+				a.config.CurrentProfile = ""
+			}
+			if err := a.config.save(a.configPath); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(a.stdout, "Deleted profile %s\n", args[0])
+			return nil
+		},
+	})
+
+	return cmd
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"strconv"
+
+	"app_api/apis/vehicle"
+	"app_api/client"
+
+	"github.com/spf13/cobra"
+)
+
+func newVehicleCommand(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "vehicle",
+		Short: "Read the status of a vehicle",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get VEHICLE_ID",
+		Short: "Show the info of a vehicle",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vehicleID, p, c, err := a.vehicleCommand(args[0])
+			if err != nil {
+				return err
+			}
+
+			res, err := c.GetVehicle(cmd.Context(), vehicleID)
+			if err != nil {
+				return err
+			}
+
+			return p.print(res, table{
+				header: []string{"VEHICLE", "VIN", "COLOR", "DOORS", "DRIVETRAIN"},
+				rows:   [][]string{{args[0], res.Vin, res.Color, strconv.FormatInt(res.DoorCount, 10), res.DriveTrain}},
+			})
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "doors VEHICLE_ID",
+		Short: "Show whether each door of a vehicle is locked",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vehicleID, p, c, err := a.vehicleCommand(args[0])
+			if err != nil {
+				return err
+			}
+
+			doors, err := c.GetDoors(cmd.Context(), vehicleID)
+			if err != nil {
+				return err
+			}
+
+			t := table{header: []string{"DOOR", "LOCKED"}}
+			for _, door := range doors {
+				t.rows = append(t.rows, []string{door.Location, boolean(door.Locked)})
+			}
+			return p.print(doors, t)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "energy VEHICLE_ID",
+		Short: "Show the fuel and battery levels of a vehicle",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vehicleID, p, c, err := a.vehicleCommand(args[0])
+			if err != nil {
+				return err
+			}
+
+			// one snapshot reads both levels with a single request to GM
+			snapshot, err := c.GetSnapshot(cmd.Context(), vehicleID, vehicle.SECTION_FUEL, vehicle.SECTION_BATTERY)
+			if err != nil {
+				return err
+			}
+
+			return p.print(snapshot, table{
+				header: []string{"VEHICLE", "FUEL", "BATTERY"},
+				rows:   [][]string{{args[0], fuelLevel(snapshot.Fuel), batteryLevel(snapshot.Battery)}},
+			})
+		},
+	})
+
+	return cmd
+}
+
+// vehicleCommand ... parses the vehicle ID argument, and returns the printer and client every vehicle command needs
+func (a *app) vehicleCommand(arg string) (vehicleID int64, p *printer, c *client.Client, err error) {
+	if vehicleID, err = parseVehicleID(arg); err != nil {
+		return
+	}
+	if p, err = a.printer(); err != nil {
+		return
+	}
+	c, err = a.client()
+	return
+}
+
+// fuelLevel ... the level of a snapshot section, or why it couldn't be read
+func fuelLevel(section *vehicle.FuelSection) string {
+	switch {
+	case section == nil:
+		return "-"
+	case section.Status != vehicle.SECTION_STATUS_OK:
+		return "error: " + section.Error
+	case section.Data == nil:
+		return "-"
+	default:
+		return percentage(section.Data.Percentage)
+	}
+}
+
+func batteryLevel(section *vehicle.BatterySection) string {
+	switch {
+	case section == nil:
+		return "-"
+	case section.Status != vehicle.SECTION_STATUS_OK:
+		return "error: " + section.Error
+	case section.Data == nil:
+		return "-"
+	default:
+		return percentage(section.Data.Percentage)
+	}
+}
+
+// lockedDoors ... e.g. 3/4 locked
+func lockedDoors(section *vehicle.DoorsSection) string {
+	switch {
+	case section == nil:
+		return "-"
+	case section.Status != vehicle.SECTION_STATUS_OK:
+		return "error: " + section.Error
+	}
+
+	locked := 0
+	for _, door := range section.Data {
+		if door.Locked {
+			locked++
+		}
+	}
+	return strconv.Itoa(locked) + "/" + strconv.Itoa(len(section.Data)) + " locked"
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"app_api/apis/events"
+	"app_api/apis/vehicle"
+	"app_api/client"
+
+	"github.com/spf13/cobra"
+)
+
+// watchReconnect ... how long watch waits before reopening a stream that closed, as the API asks browsers to
+const watchReconnect = 3 * time.Second
+
+// watchLine ... an event as written in the JSON output, one per line
+type watchLine struct {
+	ID    string          `json:"id,omitempty"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+var watchHeader = []string{"TIME", "ID", "VEHICLE", "EVENT", "DETAIL"}
+
+func newWatchCommand(a *app) *cobra.Command {
+	var group string
+	var sections []string
+
+	cmd := &cobra.Command{
+		Use:   "watch [VEHICLE_ID]",
+		Short: "Stream live updates of a vehicle, a group, or the whole fleet",
+		Long: "Stream live updates of a vehicle, a group, or the whole fleet, until interrupted.\n\n" +
+			"A snapshot of each vehicle is shown first, then every event as it happens. A stream that closes is reopened " +
+			"from the last event shown, so no event is missed.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var vehicleID int64
+			if len(args) == 1 {
+				var err error
+				if vehicleID, err = parseVehicleID(args[0]); err != nil {
+					return err
+				}
+				if group != "" {
+					return fmt.Errorf("--group can't be combined with a vehicle ID")
+				}
+			}
+
+			p, err := a.printer()
+			if err != nil {
+				return err
+			}
+			c, err := a.client()
+			if err != nil {
+				return err
+			}
+
+			open := func(ctx context.Context, opts client.StreamOptions) (*client.Stream, error) {
+				if vehicleID > 0 {
+					return c.StreamVehicle(ctx, vehicleID, opts)
+				}
+				return c.StreamFleet(ctx, group, opts)
+			}
+			return a.watch(cmd.Context(), p, open, client.StreamOptions{Sections: sections})
+		},
+	}
+	cmd.Flags().StringVarP(&group, "group", "g", "", "only the vehicles of the group")
+	cmd.Flags().StringSliceVar(&sections, "sections", nil, "snapshot sections to show: vehicle, doors, fuel, battery. Defaults to all")
+
+	return cmd
+}
+
+// watch ... writes the events of the stream until the context is done, reopening it from the last event when it closes.
+// The API rejecting the stream ends the watch, as reopening it would be rejected too
+func (a *app) watch(ctx context.Context, p *printer, open func(context.Context, client.StreamOptions) (*client.Stream, error), opts client.StreamOptions) error {
+	for {
+		stream, err := open(ctx, opts)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil && client.StatusCode(err) >= 400 && client.StatusCode(err) < 500 {
+			return err
+		}
+
+		if err == nil {
+			err = a.readStream(p, stream)
+			opts.LastEventID = stream.LastEventID()
+			stream.Close()
+			if ctx.Err() != nil {
+				return nil
+			}
+		}
+
+		fmt.Fprintf(a.stderr, "Stream closed (%v), reconnecting in %s\n", err, watchReconnect)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watchReconnect):
+		}
+	}
+}
+
+// readStream ... writes every event of the stream, until it fails
+func (a *app) readStream(p *printer, stream *client.Stream) error {
+	for {
+		event, err := stream.Next()
+		if err != nil {
+			return err
+		}
+
+		row, err := watchRow(event)
+		if err != nil {
+			return err
+		}
+		if err := p.stream(watchLine{ID: event.ID, Event: event.Name, Data: event.Data}, watchHeader, row); err != nil {
+			return err
+		}
+	}
+}
+
+// watchRow ... the table row of an event: a snapshot of a vehicle, the reason it failed, or a change of the vehicle
+func watchRow(event client.StreamEvent) ([]string, error) {
+	now := time.Now().Format(time.RFC3339)
+
+	switch event.Name {
+	case client.STREAM_EVENT_ERROR:
+		return []string{now, event.ID, "-", event.Name, event.Err().Error()}, nil
+
+	case client.STREAM_EVENT_SNAPSHOT:
+		// a fleet stream sends a batch result per vehicle, a vehicle stream the snapshot alone
+		var result vehicle.BatchResult
+		if err := event.Decode(&result); err != nil {
+			return nil, err
+		}
+		if result.Snapshot == nil && result.Status == "" {
+			var snapshot vehicle.Snapshot
+			if err := event.Decode(&snapshot); err != nil {
+				return nil, err
+			}
+			result.Snapshot = &snapshot
+		}
+
+		vehicleID := strconv.FormatInt(result.VehicleID, 10)
+		if result.Snapshot == nil {
+			return []string{now, event.ID, vehicleID, event.Name, "error: " + result.Error}, nil
+		}
+		return []string{now, event.ID, strconv.FormatInt(result.Snapshot.VehicleID, 10), event.Name, snapshotDetail(*result.Snapshot)}, nil
+
+	default:
+		e, err := event.Event()
+		if err != nil {
+			return nil, err
+		}
+		return []string{e.Time.Format(time.RFC3339), event.ID, strconv.FormatInt(e.VehicleID, 10), e.Type, eventDetail(e)}, nil
+	}
+}
+
+// snapshotDetail ... e.g. doors 4/4 locked, fuel 42%, battery -
+func snapshotDetail(snapshot vehicle.Snapshot) string {
+	var parts []string
+	if snapshot.Vehicle != nil && snapshot.Vehicle.Data != nil {
+		parts = append(parts, "vin "+snapshot.Vehicle.Data.Vin)
+	}
+	if snapshot.Doors != nil {
+		parts = append(parts, "doors "+lockedDoors(snapshot.Doors))
+	}
+	if snapshot.Fuel != nil {
+		parts = append(parts, "fuel "+fuelLevel(snapshot.Fuel))
+	}
+	if snapshot.Battery != nil {
+		parts = append(parts, "battery "+batteryLevel(snapshot.Battery))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// eventDetail ... e.g. frontLeft, or 12% (was 16%, threshold 15%)
+func eventDetail(e events.Event) string {
+	if e.Data.Location != "" {
+		return e.Data.Location
+	}
+	if e.Data.Percentage == nil {
+		return ""
+	}
+
+	detail := percentage(e.Data.Percentage)
+	var notes []string
+	if e.Data.Previous != nil {
+		notes = append(notes, "was "+percentage(e.Data.Previous))
+	}
+	if e.Data.Threshold != nil {
+		notes = append(notes, "threshold "+percentage(e.Data.Threshold))
+	}
+	if len(notes) > 0 {
+		detail += " (" + strings.Join(notes, ", ") + ")"
+	}
+	return detail
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"net/http"
+
+	"app_api/apis/command"
+	"app_api/shared/httphelper"
+
+	"github.com/gorilla/mux"
+)
+
+// submitBulkCommand ... /fleet/commands POST
+//
+// swagger:operation POST /fleet/commands Fleet submitBulkCommand
+//
+// Sends an engine action to many vehicles at once
+//
+// ---
+// summary: Sends an engine action to many vehicles at once. Commands are sent in the background with bounded concurrency and a per vehicle rate limit, use the returned job to track their outcome
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: body
+//   in: body
+//   description: body parameters
+//   schema:
+//     "$ref": "#/definitions/BulkCommandRequest"
+//   required: true
+// responses:
+//   '200':
+//     description: >
+//       The job that was started.
+//     schema:
+//       $ref: "#/definitions/CommandJob"
+//   '400':
+//     description: "Bad request e.g. a body that fails validation"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Request body failed validation"
+//         result:
+//           type: "object"
+//           properties:
+//             validation_errors:
+//               type: "array"
+//               items:
+//                 $ref: "#/definitions/FieldError"
+//   '404':
+//     description: "Group not found"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Group not found"
+func (env *Env) submitBulkCommand(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	bulkCommand := command.BulkCommandRequest{}
+
+	// validate json body
+	err := httphelper.DecodeJSONBody(w, r, &bulkCommand)
+	if err != nil {
+		httphelper.NewResponse(ctx, w, nil, err)
+		return
+	}
+
+	groupVehicleIDs, apiErr := env.resolveGroup(bulkCommand.Group)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+	bulkCommand.VehicleIDs = append(bulkCommand.VehicleIDs, groupVehicleIDs...)
+
+	job, apiErr := env.Services.CommandService.Submit(bulkCommand)
+
+	httphelper.NewResponse(ctx, w, job, apiErr)
+	return
+}
+
+// listBulkCommands ... /fleet/commands GET
+//
+// swagger:operation GET /fleet/commands Fleet listBulkCommands
+//
+// Returns the summary of every tracked bulk command, newest first
+//
+// ---
+// summary: Returns the summary of every tracked bulk command, newest first
+// produces:
+// - application/json
+// schemes:
+// - https
+// responses:
+//   '200':
+//     description: >
+//       List of jobs, without per vehicle results.
+//     schema:
+//       type: "array"
+//       items:
+//         $ref: "#/definitions/CommandJob"
+func (env *Env) listBulkCommands(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	jobs, apiErr := env.Services.CommandService.ListJobs()
+
+	httphelper.NewResponse(ctx, w, jobs, apiErr)
+	return
+}
+
+// getBulkCommand ... /fleet/commands/{command_id} GET
+//
+// swagger:operation GET /fleet/commands/{command_id} Fleet getBulkCommand
+//
+// Returns the summary report and per vehicle outcome of a bulk command
+//
+// ---
+// summary: Returns the summary report and per vehicle outcome of a bulk command
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: command_id
+//   in: path
+//   description: The ID of the bulk command job
+//   required: true
+//   type: string
+// responses:
+//   '200':
+//     description: >
+//       Job object.
+//     schema:
+//       $ref: "#/definitions/CommandJob"
+//   '404':
+//     description: "Not found"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Command job not found"
+func (env *Env) getBulkCommand(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	job, apiErr := env.Services.CommandService.GetJob(mux.Vars(r)["command_id"])
+
+	httphelper.NewResponse(ctx, w, job, apiErr)
+	return
+}
+
+// cancelBulkCommand ... /fleet/commands/{command_id}/cancel POST
+//
+// swagger:operation POST /fleet/commands/{command_id}/cancel Fleet cancelBulkCommand
+//
+// Cancels the commands of a bulk command that haven't been sent yet
+//
+// ---
+// summary: Cancels the commands of a bulk command that haven't been sent yet. Commands already sent to GM can't be recalled
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: command_id
+//   in: path
+//   description: The ID of the bulk command job
+//   required: true
+//   type: string
+// responses:
+//   '200':
+//     description: >
+//       Job object, after cancellation.
+//     schema:
+//       $ref: "#/definitions/CommandJob"
+//   '404':
+//     description: "Not found"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Command job not found"
+func (env *Env) cancelBulkCommand(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	job, apiErr := env.Services.CommandService.CancelJob(mux.Vars(r)["command_id"])
+
+	httphelper.NewResponse(ctx, w, job, apiErr)
+	return
+}
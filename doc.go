@@ -14,5 +14,15 @@
 //     Produces:
 //     - application/json
 //
+//     Security:
+//     - api_key:
+//
+//     SecurityDefinitions:
+//     api_key:
+//          type: apiKey
+//          name: X-API-Key
+//          in: header
+//          description: Required once API_KEYS is configured. Every route also needs its scope, see the README
+//
 // swagger:meta
 package main
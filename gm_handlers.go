@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"app_api/shared"
+	gmConnector "app_api/shared/gm"
+	"app_api/shared/httphelper"
+)
+
+// getGMCassette ... /admin/gm/cassette GET
+//
+// swagger:operation GET /admin/gm/cassette Admin getGMCassette
+//
+// Returns the latest requests to GM and their responses, with the sensitive fields redacted, when GM_RECORD_CASSETTE is set.
+// Saved to a file, the cassette replays them with GM_REPLAY_CASSETTE or cassette.Replay
+//
+// ---
+// summary: Returns the latest requests to GM and their responses
+// produces:
+// - application/json
+// schemes:
+// - https
+// responses:
+//   '200':
+//     description: >
+//       The recorded interactions, oldest first.
+//   '404':
+//     description: >
+//       GM traffic isn't being recorded.
+func (env *Env) getGMCassette(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	recorder := env.Services.GMRecorder
+	if recorder == nil {
+		err := errors.New("GM_RECORD_CASSETTE is not set")
+		httphelper.NewResponse(ctx, w, nil, shared.NewAPIError(http.StatusNotFound, err, "GM traffic isn't being recorded"))
+		return
+	}
+
+	httphelper.NewResponse(ctx, w, recorder.Cassette(), nil)
+	return
+}
+
+// getGMFaults ... /admin/gm/faults GET
+//
+// swagger:operation GET /admin/gm/faults Admin getGMFaults
+//
+// Returns the faults injected into the requests to GM. Only available in the development and testing environments
+//
+// ---
+// summary: Returns the faults injected into the requests to GM
+// produces:
+// - application/json
+// schemes:
+// - https
+// responses:
+//   '200':
+//     description: >
+//       The fault rules.
+//     schema:
+//       $ref: "#/definitions/FaultRulesRequest"
+func (env *Env) getGMFaults(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	httphelper.NewResponse(ctx, w, gmConnector.FaultRulesRequest{Rules: env.Services.GMFaults.Rules()}, nil)
+	return
+}
+
+// setGMFaults ... /admin/gm/faults PUT
+//
+// swagger:operation PUT /admin/gm/faults Admin setGMFaults
+//
+// Replaces the faults injected into the requests to GM, to see how the API behaves when GM is slow or failing. Only available in the development and testing environments
+//
+// ---
+// summary: Replaces the faults injected into the requests to GM
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: body
+//   in: body
+//   description: The fault rules, the first matching a request applies to it
+//   required: true
+//   schema:
+//     $ref: "#/definitions/FaultRulesRequest"
+// responses:
+//   '200':
+//     description: >
+//       The fault rules.
+//     schema:
+//       $ref: "#/definitions/FaultRulesRequest"
+//   '400':
+//     description: "Bad request e.g. a body that fails validation"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Request body failed validation"
+func (env *Env) setGMFaults(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req := gmConnector.FaultRulesRequest{}
+
+	// validate json body
+	if err := httphelper.DecodeJSONBody(w, r, &req); err != nil {
+		httphelper.NewResponse(ctx, w, nil, err)
+		return
+	}
+
+	env.Services.GMFaults.SetRules(req.Rules)
+
+	httphelper.NewResponse(ctx, w, gmConnector.FaultRulesRequest{Rules: env.Services.GMFaults.Rules()}, nil)
+	return
+}
+
+// clearGMFaults ... /admin/gm/faults DELETE
+//
+// swagger:operation DELETE /admin/gm/faults Admin clearGMFaults
+//
+// Stops injecting faults into the requests to GM. Only available in the development and testing environments
+//
+// ---
+// summary: Stops injecting faults into the requests to GM
+// produces:
+// - application/json
+// schemes:
+// - https
+// responses:
+//   '200':
+//     description: >
+//       The fault rules, now empty.
+//     schema:
+//       $ref: "#/definitions/FaultRulesRequest"
+func (env *Env) clearGMFaults(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	env.Services.GMFaults.SetRules(nil)
+
+	httphelper.NewResponse(ctx, w, gmConnector.FaultRulesRequest{Rules: env.Services.GMFaults.Rules()}, nil)
+	return
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+
+	"app_api/apis/graphql"
+	"app_api/shared/httphelper"
+)
+
+// graphql ... /graphql POST
+//
+// swagger:operation POST /graphql GraphQL graphql
+//
+// Runs a GraphQL query or mutation over vehicles
+//
+// ---
+// summary: Runs a GraphQL query or mutation over vehicles, fetching only the fields it selects
+// description: >
+//   The schema covers vehicles, their doors, fuel and battery, and engine actions as the engineAction mutation, which
+//   needs the vehicles:command scope. The fields every vehicle of a query selects are fetched from GM together, and each
+//   at most once per request. Queries nested too deeply or selecting too many fields are rejected before they run. A
+//   persisted query can be run by its hash alone through the persistedQuery extension, and in allowlist mode only
+//   persisted queries run. Errors of individual fields are reported in errors, with the HTTP status the error has over
+//   REST as extensions.code, next to the data that could be resolved
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: body
+//   in: body
+//   description: body parameters
+//   schema:
+//     "$ref": "#/definitions/GraphQLRequest"
+//   required: true
+// responses:
+//   '200':
+//     description: >
+//       The result of the operation.
+//     schema:
+//       "$ref": "#/definitions/GraphQLResponse"
+//   '400':
+//     description: "Bad request e.g. an unknown persisted query, or a query over the depth or complexity limit"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Query is nested 8 levels deep, at most 6 are allowed"
+//   '403':
+//     description: "Allowlist mode is on and the query isn't persisted"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Only persisted queries are allowed"
+func (env *Env) graphql(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req := graphql.Request{}
+
+	// validate json body
+	err := httphelper.DecodeJSONBody(w, r, &req)
+	if err != nil {
+		httphelper.NewResponse(ctx, w, nil, err)
+		return
+	}
+
+	res, err := env.Services.GraphQLService.Execute(ctx, req)
+	httphelper.NewResponse(ctx, w, res, err)
+}
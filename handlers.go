@@ -4,9 +4,11 @@ import (
 	"net/http"
 	"strconv"
 
+	"app_api/apis/registry"
 	"app_api/apis/vehicle"
 	"app_api/shared"
 	"app_api/shared/httphelper"
+	loghelper "app_api/shared/loghelpers"
 
 	"github.com/gorilla/mux"
 )
@@ -288,13 +290,20 @@ func (env *Env) getVehicleBatteryStatus(w http.ResponseWriter, r *http.Request)
 //     schema:
 //       $ref: "#/definitions/EngineActionResponse"
 //   '400':
-//     description: "Bad request e.g. Invalid vehicle_id"
+//     description: "Bad request e.g. Invalid vehicle_id, or a body that fails validation"
 //     schema:
 //       type: "object"
 //       properties:
 //         message:
 //           type: "string"
-//           example: "Vehicle ID must be an integer"
+//           example: "Request body failed validation"
+//         result:
+//           type: "object"
+//           properties:
+//             validation_errors:
+//               type: "array"
+//               items:
+//                 $ref: "#/definitions/FieldError"
 //   '503':
 //     description: "Service Unavailable"
 //     schema:
@@ -328,3 +337,222 @@ func (env *Env) actionEngine(w http.ResponseWriter, r *http.Request) {
 	httphelper.NewResponse(ctx, w, engineSubmissionStatus, apiErr)
 	return
 }
+
+// getVehicleSnapshot ... /vehicles/{vehicle_id}/snapshot GET
+//
+// swagger:operation GET /vehicles/{vehicle_id}/snapshot Vehicles getVehicleSnapshot
+//
+// Returns a merged overview, doors and energy document for the requested vehicle
+//
+// ---
+// summary: Returns a merged overview, doors and energy document for the requested vehicle. Sections are fetched concurrently and report their own status, so one failed section doesn't fail the response
+// consumes:
+// - application/x-www-form-urlencoded
+// - application/json
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// - name: fields
+//   in: query
+//   description: Comma separated list of sections to include. Defaults to every section
+//   required: false
+//   type: array
+//   collectionFormat: csv
+//   items:
+//     type: string
+//     enum: [vehicle, doors, fuel, battery]
+// responses:
+//   '200':
+//     description: >
+//       Snapshot object.
+//     schema:
+//       $ref: "#/definitions/Snapshot"
+//   '400':
+//     description: "Bad request e.g. Invalid vehicle_id or unsupported field"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Vehicle ID must be an integer"
+//   '503':
+//     description: "Service Unavailable"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Internal Error"
+func (env *Env) getVehicleSnapshot(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vehicleID, parseErr := strconv.ParseInt(mux.Vars(r)["vehicle_id"], 10, 64)
+	if parseErr != nil {
+		apiError := shared.NewAPIError(http.StatusBadRequest, parseErr, "Vehicle ID must be an integer").
+			SetInternalErrorMessage("Failed to parse vehicle ID")
+		httphelper.NewResponse(r.Context(), w, nil, apiError)
+		return
+	}
+
+	sections, apiErr := vehicle.ParseSnapshotSections(r.URL.Query().Get("fields"))
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	snapshot, apiErr := env.Services.VehicleService.GetVehicleSnapshot(vehicleID, sections)
+
+	// Failed sections don't fail the response, but should still be traceable in the logs
+	if apiErr == nil {
+		for _, sectionErr := range snapshot.Errors() {
+			loghelper.LogErrors(ctx, sectionErr)
+		}
+	}
+
+	httphelper.NewResponse(ctx, w, snapshot, apiErr)
+	return
+}
+
+// batchVehicles ... /vehicles/batch POST
+//
+// swagger:operation POST /vehicles/batch Vehicles batchVehicles
+//
+// Returns snapshots for many vehicles at once
+//
+// ---
+// summary: Returns snapshots for many vehicles at once. Vehicles are fetched with bounded concurrency, and a failed vehicle is reported in its own result rather than failing the batch
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: body
+//   in: body
+//   description: body parameters
+//   schema:
+//     "$ref": "#/definitions/BatchRequest"
+//   required: true
+// responses:
+//   '200':
+//     description: >
+//       Paginated list of per-vehicle results.
+//     schema:
+//       type: "object"
+//       properties:
+//         result:
+//           type: "array"
+//           items:
+//             $ref: "#/definitions/BatchResult"
+//         count:
+//           type: "integer"
+//           example: 2
+//         offset:
+//           type: "integer"
+//           example: 0
+//         limit:
+//           type: "integer"
+//           example: 100
+//         total:
+//           type: "integer"
+//           example: 2
+//   '400':
+//     description: "Bad request e.g. a body that fails validation"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Request body failed validation"
+//         result:
+//           type: "object"
+//           properties:
+//             validation_errors:
+//               type: "array"
+//               items:
+//                 $ref: "#/definitions/FieldError"
+//   '404':
+//     description: "Group not found"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Group not found"
+//   '503':
+//     description: "Service Unavailable"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Internal Error"
+func (env *Env) batchVehicles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	batchRequest := vehicle.BatchRequest{}
+
+	// validate json body
+	err := httphelper.DecodeJSONBody(w, r, &batchRequest)
+	if err != nil {
+		httphelper.NewResponse(ctx, w, nil, err)
+		return
+	}
+
+	groupVehicleIDs, apiErr := env.resolveGroup(batchRequest.Group)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+	batchRequest.VehicleIDs = append(batchRequest.VehicleIDs, groupVehicleIDs...)
+
+	vehicleIDs, total := batchRequest.Page()
+
+	results, apiErr := env.Services.VehicleService.GetVehicleSnapshots(vehicleIDs, batchRequest.Sections)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	// Failed vehicles don't fail the response, but should still be traceable in the logs
+	for _, result := range results {
+		if result.Err() != nil {
+			loghelper.LogErrors(ctx, result.Err())
+		}
+	}
+
+	httphelper.NewResponse(ctx, w, httphelper.BatchResponse{
+		Result: results,
+		Length: int64(len(results)),
+		Offset: batchRequest.Offset,
+		Limit:  batchRequest.PageLimit(),
+		Total:  total,
+	}, nil)
+	return
+}
+
+// vehicleIDFromRequest ... parses the vehicle_id path variable
+func vehicleIDFromRequest(r *http.Request) (int64, *shared.APIError) {
+	vehicleID, parseErr := strconv.ParseInt(mux.Vars(r)["vehicle_id"], 10, 64)
+	if parseErr != nil {
+		return 0, shared.NewAPIError(http.StatusBadRequest, parseErr, "Vehicle ID must be an integer").
+			SetInternalErrorMessage("Failed to parse vehicle ID")
+	}
+	return vehicleID, nil
+}
+
+// resolveGroup ... returns the vehicle IDs registered in the group, or none when no group is given
+func (env *Env) resolveGroup(group string) ([]int64, *shared.APIError) {
+	if group == "" {
+		return nil, nil
+	}
+	return env.Services.RegistryService.ResolveVehicleIDs(registry.Filter{Group: group})
+}
@@ -1,14 +1,36 @@
 package main
 
 import (
-	"io"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
+	"time"
 
+	"app_api/apis/command"
+	"app_api/apis/events"
+	"app_api/apis/graphql"
+	"app_api/apis/poller"
+	"app_api/apis/realtime"
+	"app_api/apis/registry"
+	"app_api/apis/rpc"
+	"app_api/apis/telemetry"
 	"app_api/apis/vehicle"
+	"app_api/apis/webhook"
+	"app_api/shared/audit"
+	"app_api/shared/auth"
+	"app_api/shared/cassette"
+	"app_api/shared/config"
 	gmConnector "app_api/shared/gm"
+	loghelper "app_api/shared/loghelpers"
+	"app_api/shared/store"
 
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
@@ -16,9 +38,6 @@ import (
 
 //go:generate swagger generate spec -m -o ./swagger/swagger.json
 
-var env *Env
-var r *mux.Router
-
 // Env ... export db and router with Env
 type Env struct {
 	// The struct for storing services with all necessary dependencies.
@@ -29,94 +48,546 @@ type Env struct {
 
 // struct for splitting services by versions
 type Services struct {
-	VehicleService vehicle.Service
+	VehicleService   vehicle.Service
+	CommandService   command.Service
+	RegistryService  registry.Service
+	TelemetryService telemetry.Service
+	PollerService    poller.Service
+	EventBus         *events.Bus
+	Detector         *events.Detector
+	WebhookService   webhook.Service
+	RealtimeService  realtime.Service
+	GraphQLService   graphql.Service
+	GM               gmConnector.GMAPIConnector
+	GMRecorder       *cassette.Recorder
+	GMFaults         *gmConnector.FaultInjector
+	Auth             *auth.Authenticator
+	Config           *config.Watcher
+	Audit            *audit.Log
+}
+
+// defaultConfig ... the settings of the API when neither the config file, the environment nor the flags set them
+func defaultConfig() config.Config {
+	return config.Config{
+		Port:      8003,
+		GRPCPort:  8004,
+		AdminPort: 8006,
+		AdminHost: "127.0.0.1",
+		DBFile:    "app_api.db",
+		Log: config.LogConfig{
+			Format: loghelper.FORMAT_LOGFMT,
+		},
+		GM: config.GMConfig{
+			RateLimit:        10,
+			RateBurst:        20,
+			BreakerThreshold: 5,
+			BreakerCooldown:  30 * time.Second,
+		},
+		Poller: config.PollerConfig{
+			Concurrency: poller.DEFAULT_CONCURRENCY,
+		},
+		Command: config.CommandConfig{
+			VehicleRateLimit: command.DEFAULT_VEHICLE_RATE,
+			VehicleRateBurst: command.DEFAULT_VEHICLE_BURST,
+		},
+		Telemetry: config.TelemetryConfig{
+			Retention: telemetry.DEFAULT_RETENTION,
+		},
+		Events: config.EventsConfig{
+			FuelLowThreshold:        events.DEFAULT_FUEL_LOW_THRESHOLD,
+			BatteryChargedThreshold: events.DEFAULT_BATTERY_CHARGED_THRESHOLD,
+		},
+		GraphQL: config.GraphQLConfig{
+			MaxDepth:      graphql.DEFAULT_MAX_DEPTH,
+			MaxComplexity: graphql.DEFAULT_MAX_COMPLEXITY,
+		},
+	}
 }
 
-// Initialize ... initialize the env so we can use it in testing
-func Initialize() {
+// Initialize ... builds every service from the config of the watcher, wired to GM, and reconfigures them when the config
+// is reloaded. The background services are started by runInBackground. Tests build the Env over fakes instead
+func Initialize(watcher *config.Watcher) *Env {
+	cfg := watcher.Config()
+
 	// init all services
-	gmAPIConnector := gmConnector.NewGMAPIConnector()
+	apiKeys, err := auth.ParseKeys(cfg.APIKeys)
+	if err != nil {
+		log.Fatal("invalid API_KEYS:", err)
+	}
+	authenticator := auth.New(apiKeys)
+	if !authenticator.Enabled() {
+		log.Warn("API_KEYS is not set, every route but the admin API's is open")
+	}
+
+	// The rate limit and circuit breaker protect GM from every caller, user requests and the background poller alike
+	gmOpts := []gmConnector.Option{
+		gmConnector.WithRateLimit(cfg.GM.RateLimit, cfg.GM.RateBurst),
+		gmConnector.WithCircuitBreaker(cfg.GM.BreakerThreshold, cfg.GM.BreakerCooldown),
+	}
+	// GM_API_URL ... points the API at another GM, such as the simulator of cmd/loadtest
+	if cfg.GM.URL != "" {
+		gmOpts = append(gmOpts, gmConnector.WithBaseURL(cfg.GM.URL))
+	}
+	// GM_RECORD_CASSETTE ... records the latest requests to GM, to reproduce an incident from the cassette saved on shutdown
+	// or fetched from /admin/gm/cassette. GM_REPLAY_CASSETTE ... answers them from a cassette instead of calling GM
+	var gmTransport http.RoundTripper
+	var gmRecorder *cassette.Recorder
+	switch record, replay := cfg.GM.RecordCassette, cfg.GM.ReplayCassette; {
+	case record != "":
+		gmRecorder = gmConnector.NewRecorder(nil)
+		gmTransport = gmRecorder
+		onShutdown(func() {
+			if err := gmRecorder.Save(record); err != nil {
+				log.Error("failed to save GM cassette:", err)
+			}
+		})
+	case replay != "":
+		player, err := cassette.Replay(replay)
+		if err != nil {
+			log.Fatal("invalid GM_REPLAY_CASSETTE:", err)
+		}
+		gmTransport = player
+	}
+	// GM_FAULTS ... injects the faults of a file of rules into the requests to GM, also set from /admin/gm/faults
+	gmFaults, err := newGMFaults(cfg)
+	if err != nil {
+		log.Fatal("invalid GM_FAULTS:", err)
+	}
+	if gmFaults != nil {
+		gmTransport = gmConnector.NewFaultTransport(gmTransport, gmFaults)
+	}
+	if gmTransport != nil {
+		gmOpts = append(gmOpts, gmConnector.WithTransport(gmTransport))
+	}
+	gmAPIConnector := gmConnector.NewGMAPIConnector(gmOpts...)
+	watcher.OnReload(func(cfg *config.Config) {
+		err := gmConnector.Reconfigure(gmAPIConnector, gmConnector.Settings{
+			BaseURL:          cfg.GM.URL,
+			RateLimit:        cfg.GM.RateLimit,
+			RateBurst:        cfg.GM.RateBurst,
+			BreakerThreshold: cfg.GM.BreakerThreshold,
+			BreakerCooldown:  cfg.GM.BreakerCooldown,
+		})
+		if err != nil {
+			log.Error("failed to reconfigure the GM connector:", err)
+		}
+	})
+
+	db, err := store.Open(cfg.DBFile)
+	if err != nil {
+		log.Fatal("failed to open database:", err)
+	}
+
+	// TelemetryService ... keeps the history of every fuel, battery and door reading observed by the vehicle service
+	var telemetryOpts []telemetry.Option
+	for _, metric := range telemetry.Metrics {
+		telemetryOpts = append(telemetryOpts, telemetry.WithRetention(metric, cfg.Telemetry.Retention))
+	}
+	telemetryService, err := telemetry.NewService(db, telemetryOpts...)
+	if err != nil {
+		log.Fatal("failed to initialize telemetry:", err)
+	}
+
+	// EventBus ... carries the state changes the detector notices in the readings of the vehicle service to every subscriber
+	eventBus := events.NewBus()
+	detector := events.NewDetector(eventBus,
+		events.WithFuelLowThreshold(cfg.Events.FuelLowThreshold),
+		events.WithBatteryChargedThreshold(cfg.Events.BatteryChargedThreshold),
+	)
 
 	// VehicleService ... represents a wrapper around all actions available around a vehicle
 
 	// TODO: As the API functionality increases, this should be broken out into more services. Such as by Vehicle parts: i.e. Overview, Wheels, Doors, Engine, Energy
-	vehicleService := vehicle.NewService(gmAPIConnector)
+	vehicleService := vehicle.NewService(gmAPIConnector, vehicle.WithObserver(telemetryService), vehicle.WithObserver(detector))
+
+	// CommandService ... fans out commands to many vehicles at once, through the vehicle service
+	commandService := command.NewService(vehicleService, command.WithVehicleRateLimit(cfg.Command.VehicleRateLimit, cfg.Command.VehicleRateBurst))
+
+	// RegistryService ... persists the vehicles known to the fleet, with their tags and groups
+	registryService, err := registry.NewService(db)
+	if err != nil {
+		log.Fatal("failed to initialize registry:", err)
+	}
 
-	r = mux.NewRouter()
+	// PollerService ... reads the status of every registered vehicle in the background, so the telemetry history fills up on its own
+	pollerService, err := poller.NewService(db, vehicleService, registryService, poller.WithConcurrency(cfg.Poller.Concurrency))
+	if err != nil {
+		log.Fatal("failed to initialize poller:", err)
+	}
+	watcher.OnReload(func(cfg *config.Config) {
+		detector.SetThresholds(cfg.Events.FuelLowThreshold, cfg.Events.BatteryChargedThreshold)
+		commandService.SetVehicleRateLimit(cfg.Command.VehicleRateLimit, cfg.Command.VehicleRateBurst)
+		pollerService.SetConcurrency(cfg.Poller.Concurrency)
+	})
+
+	// WebhookService ... pushes the events on the bus to the URLs integrators subscribed
+	webhookService, err := webhook.NewService(db, eventBus, registryService)
+	if err != nil {
+		log.Fatal("failed to initialize webhooks:", err)
+	}
 
-	env = &Env{
+	// RealtimeService ... serves vehicle events and commands over a single socket per client
+	realtimeService := realtime.NewService(vehicleService, eventBus, registryService)
+
+	// GraphQLService ... lets clients fetch exactly the vehicle fields they need in one request
+	graphqlOpts := []graphql.Option{
+		graphql.WithMaxDepth(cfg.GraphQL.MaxDepth),
+		graphql.WithMaxComplexity(cfg.GraphQL.MaxComplexity),
+	}
+	if path := cfg.GraphQL.PersistedQueries; path != "" {
+		queries, err := graphql.LoadPersistedQueries(path)
+		if err != nil {
+			log.Fatal("invalid GRAPHQL_PERSISTED_QUERIES:", err)
+		}
+		graphqlOpts = append(graphqlOpts, graphql.WithPersistedQueries(queries))
+	}
+	if cfg.GraphQL.Allowlist {
+		graphqlOpts = append(graphqlOpts, graphql.WithAllowlist())
+	}
+	graphqlService, err := graphql.NewService(vehicleService, registryService, graphqlOpts...)
+	if err != nil {
+		log.Fatal("failed to initialize graphql:", err)
+	}
+
+	return &Env{
 		// init services struct.
 		Services: Services{
-			VehicleService: vehicleService,
+			VehicleService:   vehicleService,
+			CommandService:   commandService,
+			RegistryService:  registryService,
+			TelemetryService: telemetryService,
+			PollerService:    pollerService,
+			EventBus:         eventBus,
+			Detector:         detector,
+			WebhookService:   webhookService,
+			RealtimeService:  realtimeService,
+			GraphQLService:   graphqlService,
+			GM:               gmAPIConnector,
+			GMRecorder:       gmRecorder,
+			GMFaults:         gmFaults,
+			Auth:             authenticator,
+			Config:           watcher,
+			// Audit ... records every request to the admin API, in the logs whatever their level
+			Audit: audit.New(log.StandardLogger().Out, audit.DEFAULT_SIZE),
 		},
 	}
-	env.initializeRoutes()
 }
 
-func (env *Env) initializeRoutes() {
-	r.HandleFunc("/vehicles/{vehicle_id}", env.getVehicle).Methods("GET")
-	r.HandleFunc("/vehicles/{vehicle_id}/doors", env.getVehicleDoors).Methods("GET")
-	r.HandleFunc("/vehicles/{vehicle_id}/fuel", env.getVehicleFuelStatus).Methods("GET")
-	r.HandleFunc("/vehicles/{vehicle_id}/battery", env.getVehicleBatteryStatus).Methods("GET")
-	r.HandleFunc("/vehicles/{vehicle_id}/engine", env.actionEngine).Methods("POST")
+// NewRouter ... serves every route of the API from the services of env, but those of NewAdminRouter
+func NewRouter(env *Env) http.Handler {
+	r := mux.NewRouter()
+	env.initializeRoutes(r)
+	return r
+}
+
+// NewAdminRouter ... serves the /admin routes operators run the API with from the services of env, on a port of their
+// own so it can be kept off the public network
+func NewAdminRouter(env *Env) http.Handler {
+	r := mux.NewRouter()
+	env.initializeAdminRoutes(r)
+	return r
+}
+
+func (env *Env) initializeAdminRoutes(r *mux.Router) {
+	// Every route requires an API key granted the admin scope, once keys are configured
+	authenticator := env.Services.Auth
+	admin := authenticator.Require(auth.SCOPE_ADMIN)
+
+	r.HandleFunc("/admin/log-level", admin(env.getLogLevel)).Methods("GET")
+	r.HandleFunc("/admin/log-level", admin(env.setLogLevel)).Methods("PUT")
+	r.HandleFunc("/admin/config", admin(env.getConfig)).Methods("GET")
+	r.HandleFunc("/admin/audit", admin(env.listAuditEntries)).Methods("GET")
+	r.HandleFunc("/admin/commands", admin(env.listInFlightCommands)).Methods("GET")
+	r.HandleFunc("/admin/vehicles/{vehicle_id}/cache", admin(env.purgeVehicle)).Methods("DELETE")
+
+	r.HandleFunc("/admin/gm/breaker", admin(env.getGMBreaker)).Methods("GET")
+	r.HandleFunc("/admin/gm/breaker/reset", admin(env.resetGMBreaker)).Methods("POST")
+	r.HandleFunc("/admin/gm/cassette", admin(env.getGMCassette)).Methods("GET")
+	if env.Services.GMFaults != nil {
+		r.HandleFunc("/admin/gm/faults", admin(env.getGMFaults)).Methods("GET")
+		r.HandleFunc("/admin/gm/faults", admin(env.setGMFaults)).Methods("PUT")
+		r.HandleFunc("/admin/gm/faults", admin(env.clearGMFaults)).Methods("DELETE")
+	}
+	r.HandleFunc("/admin/poller", admin(env.getPollerStatus)).Methods("GET")
+	r.HandleFunc("/admin/poller/pause", admin(env.pausePoller)).Methods("POST")
+	r.HandleFunc("/admin/poller/resume", admin(env.resumePoller)).Methods("POST")
+	r.HandleFunc("/admin/poller/schedules", admin(env.listPollerSchedules)).Methods("GET")
+	r.HandleFunc("/admin/poller/schedules/{vehicle_id}", admin(env.getPollerSchedule)).Methods("GET")
+	r.HandleFunc("/admin/poller/schedules/{vehicle_id}", admin(env.setPollerSchedule)).Methods("PUT")
+	r.HandleFunc("/admin/poller/schedules/{vehicle_id}", admin(env.resetPollerSchedule)).Methods("DELETE")
+	r.HandleFunc("/admin/poller/schedules/{vehicle_id}/pause", admin(env.pausePollerSchedule)).Methods("POST")
+	r.HandleFunc("/admin/poller/schedules/{vehicle_id}/resume", admin(env.resumePollerSchedule)).Methods("POST")
+
+	// Every request is logged, and audited with the key it was sent with, even when it is rejected
+	r.Use(Logger, env.Services.Audit.Middleware(func(r *http.Request) string {
+		principal, err := authenticator.Authenticate(r)
+		if err != nil {
+			return ""
+		}
+		return principal.KeyID
+	}))
+}
+
+func (env *Env) initializeRoutes(r *mux.Router) {
+	// Every route requires an API key granted its scope, once keys are configured
+	authenticator := env.Services.Auth
+	read := authenticator.Require(auth.SCOPE_VEHICLES_READ)
+	command := authenticator.Require(auth.SCOPE_VEHICLES_COMMAND)
+	registryWrite := authenticator.Require(auth.SCOPE_REGISTRY_WRITE)
+	webhooks := authenticator.Require(auth.SCOPE_WEBHOOKS_MANAGE)
+
+	r.HandleFunc("/vehicles", read(env.listVehicles)).Methods("GET")
+	r.HandleFunc("/vehicles/batch", read(env.batchVehicles)).Methods("POST")
+	r.HandleFunc("/vehicles/{vehicle_id}", read(env.getVehicle)).Methods("GET")
+	r.HandleFunc("/vehicles/{vehicle_id}/doors", read(env.getVehicleDoors)).Methods("GET")
+	r.HandleFunc("/vehicles/{vehicle_id}/doors/history", read(env.getVehicleDoorsHistory)).Methods("GET")
+	r.HandleFunc("/vehicles/{vehicle_id}/fuel", read(env.getVehicleFuelStatus)).Methods("GET")
+	r.HandleFunc("/vehicles/{vehicle_id}/fuel/history", read(env.getVehicleFuelHistory)).Methods("GET")
+	r.HandleFunc("/vehicles/{vehicle_id}/battery", read(env.getVehicleBatteryStatus)).Methods("GET")
+	r.HandleFunc("/vehicles/{vehicle_id}/battery/history", read(env.getVehicleBatteryHistory)).Methods("GET")
+	r.HandleFunc("/vehicles/{vehicle_id}/engine", command(env.actionEngine)).Methods("POST")
+	r.HandleFunc("/vehicles/{vehicle_id}/snapshot", read(env.getVehicleSnapshot)).Methods("GET")
+	r.HandleFunc("/vehicles/{vehicle_id}/stream", read(env.streamVehicle)).Methods("GET")
+	r.HandleFunc("/vehicles/{vehicle_id}/registration", read(env.getVehicleRegistration)).Methods("GET")
+	r.HandleFunc("/vehicles/{vehicle_id}/registration", registryWrite(env.registerVehicle)).Methods("PUT")
+	r.HandleFunc("/vehicles/{vehicle_id}/registration", registryWrite(env.deleteVehicleRegistration)).Methods("DELETE")
+	r.HandleFunc("/vehicles/{vehicle_id}/tags", read(env.getVehicleTags)).Methods("GET")
+	r.HandleFunc("/vehicles/{vehicle_id}/tags", registryWrite(env.addVehicleTags)).Methods("POST")
+	r.HandleFunc("/vehicles/{vehicle_id}/tags/{tag}", registryWrite(env.removeVehicleTag)).Methods("DELETE")
+
+	r.HandleFunc("/groups", read(env.listGroups)).Methods("GET")
+	r.HandleFunc("/groups", registryWrite(env.createGroup)).Methods("POST")
+	r.HandleFunc("/groups/{group}", read(env.getGroup)).Methods("GET")
+	r.HandleFunc("/groups/{group}", registryWrite(env.updateGroup)).Methods("PUT")
+	r.HandleFunc("/groups/{group}", registryWrite(env.deleteGroup)).Methods("DELETE")
+	r.HandleFunc("/groups/{group}/vehicles/{vehicle_id}", registryWrite(env.addGroupVehicle)).Methods("PUT")
+	r.HandleFunc("/groups/{group}/vehicles/{vehicle_id}", registryWrite(env.removeGroupVehicle)).Methods("DELETE")
+
+	r.HandleFunc("/fleet/commands", command(env.submitBulkCommand)).Methods("POST")
+	r.HandleFunc("/fleet/commands", read(env.listBulkCommands)).Methods("GET")
+	r.HandleFunc("/fleet/commands/{command_id}", read(env.getBulkCommand)).Methods("GET")
+	r.HandleFunc("/fleet/commands/{command_id}/cancel", command(env.cancelBulkCommand)).Methods("POST")
+	r.HandleFunc("/fleet/stream", read(env.streamFleet)).Methods("GET")
+
+	// the socket checks the scope of every message, so any valid key can connect
+	r.HandleFunc("/ws", authenticator.Require()(env.serveSocket)).Methods("GET")
+
+	// mutations also need the vehicles:command scope, checked by the graphql service
+	r.HandleFunc("/graphql", read(env.graphql)).Methods("POST")
+
+	r.HandleFunc("/webhooks", webhooks(env.listWebhooks)).Methods("GET")
+	r.HandleFunc("/webhooks", webhooks(env.createWebhook)).Methods("POST")
+	r.HandleFunc("/webhooks/{webhook_id}", webhooks(env.getWebhook)).Methods("GET")
+	r.HandleFunc("/webhooks/{webhook_id}", webhooks(env.updateWebhook)).Methods("PUT")
+	r.HandleFunc("/webhooks/{webhook_id}", webhooks(env.deleteWebhook)).Methods("DELETE")
+	r.HandleFunc("/webhooks/{webhook_id}/deliveries", webhooks(env.listWebhookDeliveries)).Methods("GET")
+	r.HandleFunc("/webhooks/{webhook_id}/dead-letters", webhooks(env.listWebhookDeadLetters)).Methods("GET")
+	r.HandleFunc("/webhooks/{webhook_id}/dead-letters/{delivery_id}/retry", webhooks(env.retryWebhookDeadLetter)).Methods("POST")
 
 	// Logger - attaches logging functionalities as middleware to all endpoints
 	/** Todo: This is also where additional checks that need to be applied against all endpoints would happen. For example:
-	- Authorization checks
 	- Resource availability, such as variations between what's available for the given vehicle's make/model
 	*/
 	r.Use(Logger)
 }
 
 func main() {
-	// set up logger
-	var logFile string = os.Getenv("LOG_FILE")
-	var level string = os.Getenv("ENVIRONMENT")
-	switch level {
-	case "development":
-		log.SetReportCaller(false)
-		log.SetLevel(log.TraceLevel)
-	case "testing":
-		log.SetReportCaller(false)
-		log.SetLevel(log.DebugLevel)
-	case "production":
-		log.SetReportCaller(false)
-		log.SetLevel(log.ErrorLevel)
-	default:
-		log.SetReportCaller(false)
-		log.SetLevel(log.InfoLevel)
+	cfg, err := config.Load(os.Args[1:], defaultConfig())
+	if errors.Is(err, flag.ErrHelp) {
+		return
 	}
-	log.SetOutput(os.Stdout)
-	file, err := os.OpenFile(logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0755)
 	if err != nil {
-		log.Fatal(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-	log.SetOutput(file)
-	if level == "development" {
-		mWriter := io.MultiWriter(os.Stdout, file)
-		log.SetOutput(mWriter)
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
-	defer file.Close()
 
-	Initialize()
-	port := os.Getenv("PORT")
-	if len(port) == 0 {
-		port = "8003"
+	// set up logger
+	logging, err := loghelper.Setup(logOptions(cfg))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to set up logging:", err)
+		os.Exit(1)
+	}
+	if cfg.File != "" {
+		log.Info("loaded config from ", cfg.File)
 	}
+
+	// Watcher ... reloads the config on a SIGHUP or a change of its file, applying the log level and GM settings right away
+	watcher := config.NewWatcher(cfg, os.Args[1:], defaultConfig())
+	watcher.OnReload(func(cfg *config.Config) {
+		loghelper.SetLevel(cfg.Level())
+		loghelper.SetPackageLevels(cfg.PackageLevels())
+	})
+
+	// every hook is registered by Initialize before the first reload
+	env := Initialize(watcher)
+	go watcher.Run(context.Background())
+	// the poller, webhook deliveries and telemetry retention run until shutdown
+	stopBackground := runInBackground(
+		env.Services.PollerService.Run,
+		env.Services.WebhookService.Run,
+		func(ctx context.Context) { env.Services.TelemetryService.RunRetention(ctx, time.Hour) },
+	)
+
+	server := &http.Server{Addr: ":" + strconv.Itoa(cfg.Port), Handler: NewRouter(env)}
+	servers := []*http.Server{server}
 	go func() {
 		log.Println("Starting Server")
-		if err := http.ListenAndServe(":"+port, r); err != nil {
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
 			log.Fatal("web-server error:", err)
 		}
 	}()
+
+	// the admin API takes a key granted the admin scope, so it isn't served at all until keys are configured
+	if env.Services.Auth.Enabled() {
+		adminServer := &http.Server{Addr: net.JoinHostPort(cfg.AdminHost, strconv.Itoa(cfg.AdminPort)), Handler: NewAdminRouter(env)}
+		servers = append(servers, adminServer)
+		go func() {
+			log.Println("Starting Admin Server")
+			if err := adminServer.ListenAndServe(); err != http.ErrServerClosed {
+				log.Fatal("admin-server error:", err)
+			}
+		}()
+	} else {
+		log.Warn("API_KEYS is not set, the admin API isn't served")
+	}
+
+	// grpcServer ... serves the vehicle API of proto/vehicle/v1 from the same services as the REST API
+	grpcServer := rpc.NewServer(env.Services.VehicleService, env.Services.EventBus, env.Services.Auth)
+	go func() {
+		lis, err := net.Listen("tcp", ":"+strconv.Itoa(cfg.GRPCPort))
+		if err != nil {
+			log.Fatal("grpc-server error:", err)
+		}
+		log.Println("Starting gRPC Server")
+		if err := grpcServer.Serve(lis); err != nil {
+			log.Fatal("grpc-server error:", err)
+		}
+	}()
 	// Graceful Shutdown
+	// the requests and gRPC calls in flight complete, though streams are cut once shutdownTimeout is over as they never end
+	onShutdown(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for _, server := range servers {
+			wg.Add(1)
+			go func(server *http.Server) {
+				defer wg.Done()
+				if err := server.Shutdown(ctx); err != nil {
+					server.Close()
+				}
+			}(server)
+		}
+
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+		wg.Wait()
+	})
+	// then the background services, and once nothing reads vehicles any more the readings still waiting for the
+	// telemetry writer are stored
+	onShutdown(stopBackground)
+	onShutdown(env.Services.TelemetryService.Close)
+	// the logs are flushed last, after the other hooks have logged
+	onShutdown(func() {
+		logging.Close()
+	})
 	waitForShutdown()
 }
 
+// newGMFaults ... the fault injector of the requests to GM, with the rules of GM_FAULTS. Faults are opt-in: only the
+// development and testing environments inject them, so a deployment which doesn't set ENVIRONMENT never does. Returns
+// nil otherwise
+func newGMFaults(cfg *config.Config) (*gmConnector.FaultInjector, error) {
+	switch cfg.Environment {
+	case config.ENVIRONMENT_DEVELOPMENT, config.ENVIRONMENT_TESTING:
+	default:
+		if cfg.GM.Faults != "" {
+			log.Warn("GM_FAULTS is ignored outside the development and testing environments")
+		}
+		return nil, nil
+	}
+
+	var rules []gmConnector.FaultRule
+	if path := cfg.GM.Faults; path != "" {
+		var err error
+		if rules, err = gmConnector.LoadFaultRules(path); err != nil {
+			return nil, err
+		}
+		log.Warnf("injecting %d fault rule(s) into the requests to GM", len(rules))
+	}
+	return gmConnector.NewFaultInjector(rules), nil
+}
+
+// logOptions ... the sinks, format and levels of the logs in the config
+func logOptions(cfg *config.Config) loghelper.Options {
+	return loghelper.Options{
+		Format: cfg.Log.Format,
+		Sinks:  cfg.LogSinks(),
+		File:   cfg.Log.File,
+		Rotation: loghelper.Rotation{
+			MaxSize:    int64(cfg.Log.MaxSize) << 20,
+			Every:      cfg.Log.RotateEvery,
+			MaxBackups: cfg.Log.MaxBackups,
+			MaxAge:     cfg.Log.MaxAge,
+			Compress:   cfg.Log.Compress,
+		},
+		Level:         cfg.Level(),
+		PackageLevels: cfg.PackageLevels(),
+	}
+}
+
+// shutdownTimeout ... how long the requests and gRPC calls in flight get to complete on shutdown
+const shutdownTimeout = 10 * time.Second
+
+// runInBackground ... runs every service until the returned func is called, which returns once they all have stopped
+func runInBackground(services ...func(ctx context.Context)) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	for _, run := range services {
+		wg.Add(1)
+		go func(run func(ctx context.Context)) {
+			defer wg.Done()
+			run(ctx)
+		}(run)
+	}
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// shutdownHooks ... run in order before the process exits
+var shutdownHooks []func()
+
+// onShutdown ... runs hook before the process exits on a signal
+func onShutdown(hook func()) {
+	shutdownHooks = append(shutdownHooks, hook)
+}
+
 func waitForShutdown() {
 	interruptChan := make(chan os.Signal, 1)
 	signal.Notify(interruptChan, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	// Block until we receive our signal.
 	<-interruptChan
+	for _, hook := range shutdownHooks {
+		hook()
+	}
 	os.Exit(0)
 }
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"net/http"
+
+	"app_api/apis/poller"
+	"app_api/shared/httphelper"
+)
+
+// getPollerStatus ... /admin/poller GET
+//
+// swagger:operation GET /admin/poller Admin getPollerStatus
+//
+// Returns the state of the background poller
+//
+// ---
+// summary: Returns the state of the background poller
+// produces:
+// - application/json
+// schemes:
+// - https
+// responses:
+//   '200':
+//     description: >
+//       Poller status.
+//     schema:
+//       $ref: "#/definitions/PollerStatus"
+func (env *Env) getPollerStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	httphelper.NewResponse(ctx, w, env.Services.PollerService.Status(), nil)
+	return
+}
+
+// pausePoller ... /admin/poller/pause POST
+//
+// swagger:operation POST /admin/poller/pause Admin pausePoller
+//
+// Stops polling every vehicle until the poller is resumed. Polls in flight complete
+//
+// ---
+// summary: Stops polling every vehicle until the poller is resumed. Polls in flight complete
+// produces:
+// - application/json
+// schemes:
+// - https
+// responses:
+//   '200':
+//     description: >
+//       Poller status.
+//     schema:
+//       $ref: "#/definitions/PollerStatus"
+func (env *Env) pausePoller(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	status, apiErr := env.Services.PollerService.Pause()
+
+	httphelper.NewResponse(ctx, w, status, apiErr)
+	return
+}
+
+// resumePoller ... /admin/poller/resume POST
+//
+// swagger:operation POST /admin/poller/resume Admin resumePoller
+//
+// Starts polling again, spreading the next polls of every vehicle over their intervals
+//
+// ---
+// summary: Starts polling again, spreading the next polls of every vehicle over their intervals
+// produces:
+// - application/json
+// schemes:
+// - https
+// responses:
+//   '200':
+//     description: >
+//       Poller status.
+//     schema:
+//       $ref: "#/definitions/PollerStatus"
+func (env *Env) resumePoller(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	status, apiErr := env.Services.PollerService.Resume()
+
+	httphelper.NewResponse(ctx, w, status, apiErr)
+	return
+}
+
+// listPollerSchedules ... /admin/poller/schedules GET
+//
+// swagger:operation GET /admin/poller/schedules Admin listPollerSchedules
+//
+// Returns the polling schedule of every registered vehicle
+//
+// ---
+// summary: Returns the polling schedule of every registered vehicle
+// produces:
+// - application/json
+// schemes:
+// - https
+// responses:
+//   '200':
+//     description: >
+//       List of schedules.
+//     schema:
+//       type: "array"
+//       items:
+//         $ref: "#/definitions/Schedule"
+func (env *Env) listPollerSchedules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	schedules, apiErr := env.Services.PollerService.ListSchedules()
+
+	httphelper.NewResponse(ctx, w, schedules, apiErr)
+	return
+}
+
+// getPollerSchedule ... /admin/poller/schedules/{vehicle_id} GET
+//
+// swagger:operation GET /admin/poller/schedules/{vehicle_id} Admin getPollerSchedule
+//
+// Returns the polling schedule of a registered vehicle
+//
+// ---
+// summary: Returns the polling schedule of a registered vehicle
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// responses:
+//   '200':
+//     description: >
+//       Schedule object.
+//     schema:
+//       $ref: "#/definitions/Schedule"
+//   '404':
+//     description: "Vehicle is not registered"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Vehicle is not registered"
+func (env *Env) getPollerSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vehicleID, apiErr := vehicleIDFromRequest(r)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	schedule, apiErr := env.Services.PollerService.GetSchedule(vehicleID)
+
+	httphelper.NewResponse(ctx, w, schedule, apiErr)
+	return
+}
+
+// setPollerSchedule ... /admin/poller/schedules/{vehicle_id} PUT
+//
+// swagger:operation PUT /admin/poller/schedules/{vehicle_id} Admin setPollerSchedule
+//
+// Sets how often each section of a registered vehicle is polled
+//
+// ---
+// summary: Sets how often each section of a registered vehicle is polled
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// - name: body
+//   in: body
+//   description: body parameters
+//   schema:
+//     "$ref": "#/definitions/ScheduleRequest"
+//   required: true
+// responses:
+//   '200':
+//     description: >
+//       Schedule object.
+//     schema:
+//       $ref: "#/definitions/Schedule"
+//   '400':
+//     description: "Bad request e.g. a body that fails validation"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Request body failed validation"
+//   '404':
+//     description: "Vehicle is not registered"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Vehicle is not registered"
+func (env *Env) setPollerSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vehicleID, apiErr := vehicleIDFromRequest(r)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	req := poller.ScheduleRequest{}
+
+	// validate json body
+	if err := httphelper.DecodeJSONBody(w, r, &req); err != nil {
+		httphelper.NewResponse(ctx, w, nil, err)
+		return
+	}
+
+	schedule, apiErr := env.Services.PollerService.SetSchedule(vehicleID, req)
+
+	httphelper.NewResponse(ctx, w, schedule, apiErr)
+	return
+}
+
+// resetPollerSchedule ... /admin/poller/schedules/{vehicle_id} DELETE
+//
+// swagger:operation DELETE /admin/poller/schedules/{vehicle_id} Admin resetPollerSchedule
+//
+// Returns a registered vehicle to the default polling intervals, resuming it if it was paused
+//
+// ---
+// summary: Returns a registered vehicle to the default polling intervals, resuming it if it was paused
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// responses:
+//   '200':
+//     description: >
+//       Schedule object.
+//     schema:
+//       $ref: "#/definitions/Schedule"
+//   '404':
+//     description: "Vehicle is not registered"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Vehicle is not registered"
+func (env *Env) resetPollerSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vehicleID, apiErr := vehicleIDFromRequest(r)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	schedule, apiErr := env.Services.PollerService.ResetSchedule(vehicleID)
+
+	httphelper.NewResponse(ctx, w, schedule, apiErr)
+	return
+}
+
+// pausePollerSchedule ... /admin/poller/schedules/{vehicle_id}/pause POST
+//
+// swagger:operation POST /admin/poller/schedules/{vehicle_id}/pause Admin pausePollerSchedule
+//
+// Stops polling a registered vehicle until it is resumed
+//
+// ---
+// summary: Stops polling a registered vehicle until it is resumed
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// responses:
+//   '200':
+//     description: >
+//       Schedule object.
+//     schema:
+//       $ref: "#/definitions/Schedule"
+//   '404':
+//     description: "Vehicle is not registered"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Vehicle is not registered"
+func (env *Env) pausePollerSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vehicleID, apiErr := vehicleIDFromRequest(r)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	schedule, apiErr := env.Services.PollerService.PauseSchedule(vehicleID)
+
+	httphelper.NewResponse(ctx, w, schedule, apiErr)
+	return
+}
+
+// resumePollerSchedule ... /admin/poller/schedules/{vehicle_id}/resume POST
+//
+// swagger:operation POST /admin/poller/schedules/{vehicle_id}/resume Admin resumePollerSchedule
+//
+// Starts polling a paused vehicle again
+//
+// ---
+// summary: Starts polling a paused vehicle again
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// responses:
+//   '200':
+//     description: >
+//       Schedule object.
+//     schema:
+//       $ref: "#/definitions/Schedule"
+//   '404':
+//     description: "Vehicle is not registered"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Vehicle is not registered"
+func (env *Env) resumePollerSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vehicleID, apiErr := vehicleIDFromRequest(r)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	schedule, apiErr := env.Services.PollerService.ResumeSchedule(vehicleID)
+
+	httphelper.NewResponse(ctx, w, schedule, apiErr)
+	return
+}
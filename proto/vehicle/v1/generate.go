@@ -0,0 +1,4 @@
+package vehiclev1
+
+// Requires protoc with protoc-gen-go v1.25.0 and protoc-gen-go-grpc v1.0.1 on the PATH
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative vehicle/v1/vehicle.proto
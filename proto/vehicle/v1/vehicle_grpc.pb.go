@@ -0,0 +1,317 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package vehiclev1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion7
+
+// VehicleServiceClient is the client API for VehicleService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type VehicleServiceClient interface {
+	// GetVehicle ... requires the vehicles:read scope
+	GetVehicle(ctx context.Context, in *VehicleRequest, opts ...grpc.CallOption) (*Vehicle, error)
+	// GetDoors ... requires the vehicles:read scope
+	GetDoors(ctx context.Context, in *VehicleRequest, opts ...grpc.CallOption) (*Doors, error)
+	// GetFuel ... requires the vehicles:read scope
+	GetFuel(ctx context.Context, in *VehicleRequest, opts ...grpc.CallOption) (*Fuel, error)
+	// GetBattery ... requires the vehicles:read scope
+	GetBattery(ctx context.Context, in *VehicleRequest, opts ...grpc.CallOption) (*Battery, error)
+	// SendEngineAction ... requires the vehicles:command scope
+	SendEngineAction(ctx context.Context, in *EngineActionRequest, opts ...grpc.CallOption) (*EngineActionResponse, error)
+	// StreamUpdates ... streams the changes of vehicles until the client cancels. Requires the vehicles:read scope
+	StreamUpdates(ctx context.Context, in *StreamUpdatesRequest, opts ...grpc.CallOption) (VehicleService_StreamUpdatesClient, error)
+}
+
+type vehicleServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewVehicleServiceClient(cc grpc.ClientConnInterface) VehicleServiceClient {
+	return &vehicleServiceClient{cc}
+}
+
+func (c *vehicleServiceClient) GetVehicle(ctx context.Context, in *VehicleRequest, opts ...grpc.CallOption) (*Vehicle, error) {
+	out := new(Vehicle)
+	err := c.cc.Invoke(ctx, "/smartcar.vehicle.v1.VehicleService/GetVehicle", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vehicleServiceClient) GetDoors(ctx context.Context, in *VehicleRequest, opts ...grpc.CallOption) (*Doors, error) {
+	out := new(Doors)
+	err := c.cc.Invoke(ctx, "/smartcar.vehicle.v1.VehicleService/GetDoors", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vehicleServiceClient) GetFuel(ctx context.Context, in *VehicleRequest, opts ...grpc.CallOption) (*Fuel, error) {
+	out := new(Fuel)
+	err := c.cc.Invoke(ctx, "/smartcar.vehicle.v1.VehicleService/GetFuel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vehicleServiceClient) GetBattery(ctx context.Context, in *VehicleRequest, opts ...grpc.CallOption) (*Battery, error) {
+	out := new(Battery)
+	err := c.cc.Invoke(ctx, "/smartcar.vehicle.v1.VehicleService/GetBattery", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vehicleServiceClient) SendEngineAction(ctx context.Context, in *EngineActionRequest, opts ...grpc.CallOption) (*EngineActionResponse, error) {
+	out := new(EngineActionResponse)
+	err := c.cc.Invoke(ctx, "/smartcar.vehicle.v1.VehicleService/SendEngineAction", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vehicleServiceClient) StreamUpdates(ctx context.Context, in *StreamUpdatesRequest, opts ...grpc.CallOption) (VehicleService_StreamUpdatesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_VehicleService_serviceDesc.Streams[0], "/smartcar.vehicle.v1.VehicleService/StreamUpdates", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &vehicleServiceStreamUpdatesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type VehicleService_StreamUpdatesClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type vehicleServiceStreamUpdatesClient struct {
+	grpc.ClientStream
+}
+
+func (x *vehicleServiceStreamUpdatesClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// VehicleServiceServer is the server API for VehicleService service.
+// All implementations must embed UnimplementedVehicleServiceServer
+// for forward compatibility
+type VehicleServiceServer interface {
+	// GetVehicle ... requires the vehicles:read scope
+	GetVehicle(context.Context, *VehicleRequest) (*Vehicle, error)
+	// GetDoors ... requires the vehicles:read scope
+	GetDoors(context.Context, *VehicleRequest) (*Doors, error)
+	// GetFuel ... requires the vehicles:read scope
+	GetFuel(context.Context, *VehicleRequest) (*Fuel, error)
+	// GetBattery ... requires the vehicles:read scope
+	GetBattery(context.Context, *VehicleRequest) (*Battery, error)
+	// SendEngineAction ... requires the vehicles:command scope
+	SendEngineAction(context.Context, *EngineActionRequest) (*EngineActionResponse, error)
+	// StreamUpdates ... streams the changes of vehicles until the client cancels. Requires the vehicles:read scope
+	StreamUpdates(*StreamUpdatesRequest, VehicleService_StreamUpdatesServer) error
+	mustEmbedUnimplementedVehicleServiceServer()
+}
+
+// UnimplementedVehicleServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedVehicleServiceServer struct {
+}
+
+func (UnimplementedVehicleServiceServer) GetVehicle(context.Context, *VehicleRequest) (*Vehicle, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVehicle not implemented")
+}
+func (UnimplementedVehicleServiceServer) GetDoors(context.Context, *VehicleRequest) (*Doors, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDoors not implemented")
+}
+func (UnimplementedVehicleServiceServer) GetFuel(context.Context, *VehicleRequest) (*Fuel, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFuel not implemented")
+}
+func (UnimplementedVehicleServiceServer) GetBattery(context.Context, *VehicleRequest) (*Battery, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBattery not implemented")
+}
+func (UnimplementedVehicleServiceServer) SendEngineAction(context.Context, *EngineActionRequest) (*EngineActionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendEngineAction not implemented")
+}
+func (UnimplementedVehicleServiceServer) StreamUpdates(*StreamUpdatesRequest, VehicleService_StreamUpdatesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamUpdates not implemented")
+}
+func (UnimplementedVehicleServiceServer) mustEmbedUnimplementedVehicleServiceServer() {}
+
+// UnsafeVehicleServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to VehicleServiceServer will
+// result in compilation errors.
+type UnsafeVehicleServiceServer interface {
+	mustEmbedUnimplementedVehicleServiceServer()
+}
+
+func RegisterVehicleServiceServer(s grpc.ServiceRegistrar, srv VehicleServiceServer) {
+	s.RegisterService(&_VehicleService_serviceDesc, srv)
+}
+
+func _VehicleService_GetVehicle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VehicleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VehicleServiceServer).GetVehicle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/smartcar.vehicle.v1.VehicleService/GetVehicle",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VehicleServiceServer).GetVehicle(ctx, req.(*VehicleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VehicleService_GetDoors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VehicleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VehicleServiceServer).GetDoors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/smartcar.vehicle.v1.VehicleService/GetDoors",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VehicleServiceServer).GetDoors(ctx, req.(*VehicleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VehicleService_GetFuel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VehicleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VehicleServiceServer).GetFuel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/smartcar.vehicle.v1.VehicleService/GetFuel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VehicleServiceServer).GetFuel(ctx, req.(*VehicleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VehicleService_GetBattery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VehicleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VehicleServiceServer).GetBattery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/smartcar.vehicle.v1.VehicleService/GetBattery",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VehicleServiceServer).GetBattery(ctx, req.(*VehicleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VehicleService_SendEngineAction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EngineActionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VehicleServiceServer).SendEngineAction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/smartcar.vehicle.v1.VehicleService/SendEngineAction",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VehicleServiceServer).SendEngineAction(ctx, req.(*EngineActionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VehicleService_StreamUpdates_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamUpdatesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VehicleServiceServer).StreamUpdates(m, &vehicleServiceStreamUpdatesServer{stream})
+}
+
+type VehicleService_StreamUpdatesServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type vehicleServiceStreamUpdatesServer struct {
+	grpc.ServerStream
+}
+
+func (x *vehicleServiceStreamUpdatesServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _VehicleService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "smartcar.vehicle.v1.VehicleService",
+	HandlerType: (*VehicleServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetVehicle",
+			Handler:    _VehicleService_GetVehicle_Handler,
+		},
+		{
+			MethodName: "GetDoors",
+			Handler:    _VehicleService_GetDoors_Handler,
+		},
+		{
+			MethodName: "GetFuel",
+			Handler:    _VehicleService_GetFuel_Handler,
+		},
+		{
+			MethodName: "GetBattery",
+			Handler:    _VehicleService_GetBattery_Handler,
+		},
+		{
+			MethodName: "SendEngineAction",
+			Handler:    _VehicleService_SendEngineAction_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamUpdates",
+			Handler:       _VehicleService_StreamUpdates_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "vehicle/v1/vehicle.proto",
+}
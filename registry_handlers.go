@@ -0,0 +1,766 @@
+package main
+
+import (
+	"net/http"
+
+	"app_api/apis/registry"
+	"app_api/shared/httphelper"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 500
+)
+
+// listVehicles ... /vehicles GET
+//
+// swagger:operation GET /vehicles Registry listVehicles
+//
+// Returns the registered vehicles, optionally filtered by group or tag
+//
+// ---
+// summary: Returns the registered vehicles, optionally filtered by group or tag
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: group
+//   in: query
+//   description: Only return vehicles in the group
+//   required: false
+//   type: string
+// - name: tag
+//   in: query
+//   description: Only return vehicles with the tag
+//   required: false
+//   type: string
+// - name: offset
+//   in: query
+//   description: The index of the first vehicle to return
+//   required: false
+//   type: integer
+// - name: limit
+//   in: query
+//   description: The maximum number of vehicles to return. Defaults to 100, at most 500
+//   required: false
+//   type: integer
+// responses:
+//   '200':
+//     description: >
+//       Paginated list of registrations.
+//     schema:
+//       type: "object"
+//       properties:
+//         result:
+//           type: "array"
+//           items:
+//             $ref: "#/definitions/Registration"
+//         count:
+//           type: "integer"
+//           example: 2
+//         offset:
+//           type: "integer"
+//           example: 0
+//         limit:
+//           type: "integer"
+//           example: 100
+//         total:
+//           type: "integer"
+//           example: 2
+//   '404':
+//     description: "Group not found"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Group not found"
+func (env *Env) listVehicles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	offset, limit, apiErr := httphelper.ParsePagination(r, defaultListLimit, maxListLimit)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	filter := registry.Filter{Group: r.URL.Query().Get("group"), Tag: r.URL.Query().Get("tag")}
+
+	registrations, apiErr := env.Services.RegistryService.ListVehicles(filter)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	items := make([]interface{}, len(registrations))
+	for i, registration := range registrations {
+		items[i] = registration
+	}
+
+	httphelper.NewResponse(ctx, w, httphelper.Paginate(items, offset, limit), nil)
+	return
+}
+
+// getVehicleRegistration ... /vehicles/{vehicle_id}/registration GET
+//
+// swagger:operation GET /vehicles/{vehicle_id}/registration Registry getVehicleRegistration
+//
+// Returns the registration of a vehicle
+//
+// ---
+// summary: Returns the nickname, tags and groups of a vehicle
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// responses:
+//   '200':
+//     description: >
+//       Registration object.
+//     schema:
+//       $ref: "#/definitions/Registration"
+//   '404':
+//     description: "Vehicle is not registered"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Vehicle is not registered"
+func (env *Env) getVehicleRegistration(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vehicleID, apiErr := vehicleIDFromRequest(r)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	registration, apiErr := env.Services.RegistryService.GetVehicle(vehicleID)
+
+	httphelper.NewResponse(ctx, w, registration, apiErr)
+	return
+}
+
+// registerVehicle ... /vehicles/{vehicle_id}/registration PUT
+//
+// swagger:operation PUT /vehicles/{vehicle_id}/registration Registry registerVehicle
+//
+// Registers a vehicle, replacing any previous registration
+//
+// ---
+// summary: Registers a vehicle with a nickname, tags and group membership, replacing any previous registration
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// - name: body
+//   in: body
+//   description: body parameters
+//   schema:
+//     "$ref": "#/definitions/RegistrationRequest"
+//   required: true
+// responses:
+//   '200':
+//     description: >
+//       Registration object.
+//     schema:
+//       $ref: "#/definitions/Registration"
+//   '400':
+//     description: "Bad request e.g. a body that fails validation"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Request body failed validation"
+//   '404':
+//     description: "Group not found"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Group not found"
+func (env *Env) registerVehicle(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vehicleID, apiErr := vehicleIDFromRequest(r)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	req := registry.RegistrationRequest{}
+
+	// validate json body
+	if err := httphelper.DecodeJSONBody(w, r, &req); err != nil {
+		httphelper.NewResponse(ctx, w, nil, err)
+		return
+	}
+
+	registration, apiErr := env.Services.RegistryService.RegisterVehicle(vehicleID, req)
+
+	httphelper.NewResponse(ctx, w, registration, apiErr)
+	return
+}
+
+// deleteVehicleRegistration ... /vehicles/{vehicle_id}/registration DELETE
+//
+// swagger:operation DELETE /vehicles/{vehicle_id}/registration Registry deleteVehicleRegistration
+//
+// Removes a vehicle from the registry, along with its tags and group memberships
+//
+// ---
+// summary: Removes a vehicle from the registry, along with its tags and group memberships
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// responses:
+//   '200':
+//     description: >
+//       The vehicle was removed.
+//   '404':
+//     description: "Vehicle is not registered"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Vehicle is not registered"
+func (env *Env) deleteVehicleRegistration(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vehicleID, apiErr := vehicleIDFromRequest(r)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	apiErr = env.Services.RegistryService.DeleteVehicle(vehicleID)
+
+	httphelper.NewResponse(ctx, w, nil, apiErr)
+	return
+}
+
+// getVehicleTags ... /vehicles/{vehicle_id}/tags GET
+//
+// swagger:operation GET /vehicles/{vehicle_id}/tags Registry getVehicleTags
+//
+// Returns the tags of a vehicle
+//
+// ---
+// summary: Returns the tags of a vehicle
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// responses:
+//   '200':
+//     description: >
+//       List of tags.
+//     schema:
+//       type: "array"
+//       items:
+//         type: "string"
+//   '404':
+//     description: "Vehicle is not registered"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Vehicle is not registered"
+func (env *Env) getVehicleTags(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vehicleID, apiErr := vehicleIDFromRequest(r)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	tags, apiErr := env.Services.RegistryService.GetTags(vehicleID)
+
+	httphelper.NewResponse(ctx, w, tags, apiErr)
+	return
+}
+
+// addVehicleTags ... /vehicles/{vehicle_id}/tags POST
+//
+// swagger:operation POST /vehicles/{vehicle_id}/tags Registry addVehicleTags
+//
+// Adds tags to a vehicle, registering the vehicle if it isn't already
+//
+// ---
+// summary: Adds tags to a vehicle, registering the vehicle if it isn't already
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// - name: body
+//   in: body
+//   description: body parameters
+//   schema:
+//     "$ref": "#/definitions/TagsRequest"
+//   required: true
+// responses:
+//   '200':
+//     description: >
+//       Every tag of the vehicle.
+//     schema:
+//       type: "array"
+//       items:
+//         type: "string"
+//   '400':
+//     description: "Bad request e.g. a body that fails validation"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Request body failed validation"
+func (env *Env) addVehicleTags(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vehicleID, apiErr := vehicleIDFromRequest(r)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	req := registry.TagsRequest{}
+
+	// validate json body
+	if err := httphelper.DecodeJSONBody(w, r, &req); err != nil {
+		httphelper.NewResponse(ctx, w, nil, err)
+		return
+	}
+
+	tags, apiErr := env.Services.RegistryService.AddTags(vehicleID, req.Tags)
+
+	httphelper.NewResponse(ctx, w, tags, apiErr)
+	return
+}
+
+// removeVehicleTag ... /vehicles/{vehicle_id}/tags/{tag} DELETE
+//
+// swagger:operation DELETE /vehicles/{vehicle_id}/tags/{tag} Registry removeVehicleTag
+//
+// Removes a tag from a vehicle
+//
+// ---
+// summary: Removes a tag from a vehicle
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// - name: tag
+//   in: path
+//   description: The tag to remove
+//   required: true
+//   type: string
+// responses:
+//   '200':
+//     description: >
+//       The remaining tags of the vehicle.
+//     schema:
+//       type: "array"
+//       items:
+//         type: "string"
+//   '404':
+//     description: "Vehicle is not registered"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Vehicle is not registered"
+func (env *Env) removeVehicleTag(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vehicleID, apiErr := vehicleIDFromRequest(r)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	tags, apiErr := env.Services.RegistryService.RemoveTag(vehicleID, mux.Vars(r)["tag"])
+
+	httphelper.NewResponse(ctx, w, tags, apiErr)
+	return
+}
+
+// listGroups ... /groups GET
+//
+// swagger:operation GET /groups Registry listGroups
+//
+// Returns every group with its members
+//
+// ---
+// summary: Returns every group with its members
+// produces:
+// - application/json
+// schemes:
+// - https
+// responses:
+//   '200':
+//     description: >
+//       List of groups.
+//     schema:
+//       type: "array"
+//       items:
+//         $ref: "#/definitions/Group"
+func (env *Env) listGroups(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	groups, apiErr := env.Services.RegistryService.ListGroups()
+
+	httphelper.NewResponse(ctx, w, groups, apiErr)
+	return
+}
+
+// createGroup ... /groups POST
+//
+// swagger:operation POST /groups Registry createGroup
+//
+// Creates an empty group
+//
+// ---
+// summary: Creates an empty group
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: body
+//   in: body
+//   description: body parameters
+//   schema:
+//     "$ref": "#/definitions/GroupRequest"
+//   required: true
+// responses:
+//   '200':
+//     description: >
+//       Group object.
+//     schema:
+//       $ref: "#/definitions/Group"
+//   '400':
+//     description: "Bad request e.g. a body that fails validation"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Request body failed validation"
+//   '409':
+//     description: "Group already exists"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Group already exists"
+func (env *Env) createGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req := registry.GroupRequest{}
+
+	// validate json body
+	if err := httphelper.DecodeJSONBody(w, r, &req); err != nil {
+		httphelper.NewResponse(ctx, w, nil, err)
+		return
+	}
+
+	group, apiErr := env.Services.RegistryService.CreateGroup(req)
+
+	httphelper.NewResponse(ctx, w, group, apiErr)
+	return
+}
+
+// getGroup ... /groups/{group} GET
+//
+// swagger:operation GET /groups/{group} Registry getGroup
+//
+// Returns a group with its members
+//
+// ---
+// summary: Returns a group with its members
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: group
+//   in: path
+//   description: The name of the group
+//   required: true
+//   type: string
+// responses:
+//   '200':
+//     description: >
+//       Group object.
+//     schema:
+//       $ref: "#/definitions/Group"
+//   '404':
+//     description: "Group not found"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Group not found"
+func (env *Env) getGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	group, apiErr := env.Services.RegistryService.GetGroup(mux.Vars(r)["group"])
+
+	httphelper.NewResponse(ctx, w, group, apiErr)
+	return
+}
+
+// updateGroup ... /groups/{group} PUT
+//
+// swagger:operation PUT /groups/{group} Registry updateGroup
+//
+// Updates the description of a group
+//
+// ---
+// summary: Updates the description of a group
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: group
+//   in: path
+//   description: The name of the group
+//   required: true
+//   type: string
+// - name: body
+//   in: body
+//   description: body parameters
+//   schema:
+//     "$ref": "#/definitions/GroupUpdateRequest"
+//   required: true
+// responses:
+//   '200':
+//     description: >
+//       Group object.
+//     schema:
+//       $ref: "#/definitions/Group"
+//   '404':
+//     description: "Group not found"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Group not found"
+func (env *Env) updateGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req := registry.GroupUpdateRequest{}
+
+	// validate json body
+	if err := httphelper.DecodeJSONBody(w, r, &req); err != nil {
+		httphelper.NewResponse(ctx, w, nil, err)
+		return
+	}
+
+	group, apiErr := env.Services.RegistryService.UpdateGroup(mux.Vars(r)["group"], req)
+
+	httphelper.NewResponse(ctx, w, group, apiErr)
+	return
+}
+
+// deleteGroup ... /groups/{group} DELETE
+//
+// swagger:operation DELETE /groups/{group} Registry deleteGroup
+//
+// Deletes a group. Its vehicles stay registered
+//
+// ---
+// summary: Deletes a group. Its vehicles stay registered
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: group
+//   in: path
+//   description: The name of the group
+//   required: true
+//   type: string
+// responses:
+//   '200':
+//     description: >
+//       The group was deleted.
+//   '404':
+//     description: "Group not found"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Group not found"
+func (env *Env) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	apiErr := env.Services.RegistryService.DeleteGroup(mux.Vars(r)["group"])
+
+	httphelper.NewResponse(ctx, w, nil, apiErr)
+	return
+}
+
+// addGroupVehicle ... /groups/{group}/vehicles/{vehicle_id} PUT
+//
+// swagger:operation PUT /groups/{group}/vehicles/{vehicle_id} Registry addGroupVehicle
+//
+// Adds a vehicle to a group, registering the vehicle if it isn't already
+//
+// ---
+// summary: Adds a vehicle to a group, registering the vehicle if it isn't already
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: group
+//   in: path
+//   description: The name of the group
+//   required: true
+//   type: string
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// responses:
+//   '200':
+//     description: >
+//       Group object.
+//     schema:
+//       $ref: "#/definitions/Group"
+//   '404':
+//     description: "Group not found"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Group not found"
+func (env *Env) addGroupVehicle(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vehicleID, apiErr := vehicleIDFromRequest(r)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	group, apiErr := env.Services.RegistryService.AddGroupVehicle(mux.Vars(r)["group"], vehicleID)
+
+	httphelper.NewResponse(ctx, w, group, apiErr)
+	return
+}
+
+// removeGroupVehicle ... /groups/{group}/vehicles/{vehicle_id} DELETE
+//
+// swagger:operation DELETE /groups/{group}/vehicles/{vehicle_id} Registry removeGroupVehicle
+//
+// Removes a vehicle from a group. The vehicle stays registered
+//
+// ---
+// summary: Removes a vehicle from a group. The vehicle stays registered
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: group
+//   in: path
+//   description: The name of the group
+//   required: true
+//   type: string
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// responses:
+//   '200':
+//     description: >
+//       Group object.
+//     schema:
+//       $ref: "#/definitions/Group"
+//   '404':
+//     description: "Group not found, or the vehicle isn't a member"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Vehicle is not a member of the group"
+func (env *Env) removeGroupVehicle(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vehicleID, apiErr := vehicleIDFromRequest(r)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	group, apiErr := env.Services.RegistryService.RemoveGroupVehicle(mux.Vars(r)["group"], vehicleID)
+
+	httphelper.NewResponse(ctx, w, group, apiErr)
+	return
+}
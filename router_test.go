@@ -0,0 +1,403 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"app_api/shared/auth"
+	"app_api/shared/config"
+	gmConnector "app_api/shared/gm"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// integrationServer ... the API and the admin API, served from the same services
+type integrationServer struct {
+	api         *httptest.Server
+	apiRouter   *mux.Router
+	admin       *httptest.Server
+	adminRouter *mux.Router
+}
+
+// route ... the server and router of a path, those of the admin API for /admin
+func (s integrationServer) route(path string) (*httptest.Server, *mux.Router) {
+	if strings.HasPrefix(path, "/admin/") {
+		return s.admin, s.adminRouter
+	}
+	return s.api, s.apiRouter
+}
+
+// newIntegrationServer ... serves every route over the GM connector, sending its requests to the GM simulator through a
+// cassette recorder and the fault transport, as Initialize wires them
+func newIntegrationServer(t *testing.T, gmOpts ...gmConnector.Option) integrationServer {
+	gm := httptest.NewServer(gmConnector.NewSimulator())
+	t.Cleanup(gm.Close)
+
+	gmRecorder := gmConnector.NewRecorder(nil)
+	gmFaults := gmConnector.NewFaultInjector(nil)
+	gmOpts = append(gmOpts, gmConnector.WithBaseURL(gm.URL), gmConnector.WithTransport(gmConnector.NewFaultTransport(gmRecorder, gmFaults)))
+
+	env := newTestEnv(t, gmConnector.NewGMAPIConnector(gmOpts...))
+	env.Services.GMRecorder = gmRecorder
+	env.Services.GMFaults = gmFaults
+
+	s := integrationServer{apiRouter: NewRouter(env).(*mux.Router), adminRouter: NewAdminRouter(env).(*mux.Router)}
+	s.api = httptest.NewServer(s.apiRouter)
+	t.Cleanup(s.api.Close)
+	s.admin = httptest.NewServer(s.adminRouter)
+	t.Cleanup(s.admin.Close)
+	return s
+}
+
+// routeCase ... a request through the router, and the response it gets
+type routeCase struct {
+	method string
+	// path ... {name} is replaced with a value captured from an earlier response
+	path string
+	// key ... the API key sent, testAdminKey if left out, none if "-"
+	key         string
+	body        string
+	contentType string
+
+	status int
+	// contains ... a substring of the response body
+	contains string
+	// capture ... saves the string fields of the response under names for later paths
+	capture map[string]string
+	// stream ... the response is an event stream, closed once its headers arrive
+	stream bool
+}
+
+func (c routeCase) do(t *testing.T, s integrationServer, captured map[string]string) (*http.Request, *http.Response, string) {
+	path := c.path
+	for name, value := range captured {
+		path = strings.Replace(path, "{"+name+"}", value, -1)
+	}
+	server, _ := s.route(path)
+
+	req, err := http.NewRequest(c.method, server.URL+path, strings.NewReader(c.body))
+	require.NoError(t, err)
+	switch c.key {
+	case "":
+		req.Header.Set("X-API-Key", testAdminKey)
+	case "-":
+	default:
+		req.Header.Set("X-API-Key", c.key)
+	}
+	if c.contentType != "" {
+		req.Header.Set("Content-Type", c.contentType)
+	} else if c.body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	if c.stream {
+		return req, res, ""
+	}
+	b, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	return req, res, string(b)
+}
+
+// routeCases ... run in order, each building on the state the previous ones left
+var routeCases = []routeCase{
+	// vehicles, read from the simulator
+	{method: "GET", path: "/vehicles/1234", status: 200, contains: `"doorCount":4`},
+	{method: "GET", path: "/vehicles/1235", key: testReadKey, status: 200, contains: `"vin":"123123412412"`},
+	{method: "GET", path: "/vehicles/abc", status: 400, contains: "Vehicle ID must be an integer"},
+	{method: "GET", path: "/vehicles/0", status: 400, contains: "Vehicle ID must be a positive integer"},
+	{method: "POST", path: "/vehicles/-1/engine", body: `{"action": "START"}`, status: 400, contains: "Vehicle ID must be a positive integer"},
+	{method: "GET", path: "/vehicles/4321", status: 500, contains: "Failed to get vehicle"},
+	{method: "GET", path: "/vehicles/1234", key: "-", status: 401},
+	{method: "GET", path: "/vehicles/1234", key: "wr0ng", status: 401},
+	{method: "DELETE", path: "/vehicles/1234", status: 405},
+	{method: "GET", path: "/vehicles/1234/doors", status: 200, contains: `"location":"frontLeft"`},
+	{method: "GET", path: "/vehicles/x/doors", status: 400},
+	{method: "GET", path: "/vehicles/4321/doors", status: 500, contains: "Failed to get vehicle doors"},
+	{method: "GET", path: "/vehicles/1234/fuel", status: 200, contains: `"percentage":33.5`},
+	{method: "GET", path: "/vehicles/1235/fuel", status: 200, contains: `"percentage":null`},
+	{method: "GET", path: "/vehicles/1.5/fuel", status: 400},
+	{method: "GET", path: "/vehicles/1235/battery", status: 200, contains: `"percentage":88.55`},
+	{method: "GET", path: "/vehicles/4321/battery", status: 500, contains: "Failed to get vehicle energy status"},
+	{method: "GET", path: "/vehicles/1234/fuel/history", status: 200, contains: `"metric":"fuel"`},
+	{method: "GET", path: "/vehicles/1234/fuel/history?from=yesterday", status: 400},
+	{method: "GET", path: "/vehicles/1235/battery/history?interval=1h&aggregation=avg", status: 200, contains: `"metric":"battery"`},
+	{method: "GET", path: "/vehicles/1235/battery/history?aggregation=median", status: 400},
+	{method: "GET", path: "/vehicles/1234/doors/history?interval=1h&aggregation=max", status: 200, contains: `"metric":"doors_unlocked"`},
+	{method: "POST", path: "/vehicles/1234/engine", body: `{"action": "START"}`, status: 200, contains: `"status":"success"`},
+	{method: "POST", path: "/vehicles/1234/engine", key: testReadKey, body: `{"action": "START"}`, status: 403},
+	{method: "POST", path: "/vehicles/1234/engine", body: `{"action": "JUMP"}`, status: 400, contains: `"field":"action"`},
+	{method: "POST", path: "/vehicles/1234/engine", body: `{"action": "START"`, status: 400, contains: "badly-formed JSON"},
+	{method: "POST", path: "/vehicles/1234/engine", body: `{"action": "START", "speed": 88}`, status: 400, contains: "unknown field"},
+	{method: "POST", path: "/vehicles/1234/engine", body: `action=START`, contentType: "application/x-www-form-urlencoded", status: 415},
+	{method: "POST", path: "/vehicles/1234/engine", status: 400, contains: "must not be empty"},
+	{method: "POST", path: "/vehicles/4321/engine", body: `{"action": "STOP"}`, status: 500, contains: "Failed to send engine action"},
+	{method: "GET", path: "/vehicles/1234/engine", status: 405},
+	{method: "GET", path: "/vehicles/1234/snapshot?fields=doors,fuel", status: 200, contains: `"vehicleId":1234`},
+	{method: "GET", path: "/vehicles/1234/snapshot?fields=wheels", status: 400},
+	{method: "POST", path: "/vehicles/batch", body: `{"vehicleIds": [1234, 4321], "sections": ["vehicle"]}`, status: 200, contains: `"vehicleId":4321`},
+	{method: "POST", path: "/vehicles/batch", body: `{"vehicleIds": [1234], "limit": 1000}`, status: 400, contains: `"field":"limit"`},
+	{method: "POST", path: "/vehicles/batch", body: `{"vehicleIds": [1234], "limit": 0}`, status: 400, contains: `"field":"limit"`},
+	{method: "POST", path: "/vehicles/batch", body: `{"group": "nowhere"}`, status: 404},
+	{method: "GET", path: "/vehicles/1234/stream?fields=doors", status: 200, stream: true},
+	{method: "GET", path: "/vehicles/1234/stream?fields=wheels", status: 400},
+	{method: "GET", path: "/vehicles/abc/stream", status: 400},
+	{method: "GET", path: "/nowhere", status: 404},
+
+	// registry
+	{method: "GET", path: "/vehicles/1234/registration", status: 404},
+	{method: "PUT", path: "/vehicles/1234/registration", body: `{"nickname": "Blue van", "tags": ["van"]}`, status: 200, contains: `"nickname":"Blue van"`},
+	{method: "PUT", path: "/vehicles/1234/registration", key: testReadKey, body: `{}`, status: 403},
+	{method: "PUT", path: "/vehicles/1234/registration", body: `{"tags": ["Not A Tag!"]}`, status: 400},
+	{method: "PUT", path: "/vehicles/1235/registration", body: `{}`, status: 200},
+	{method: "GET", path: "/vehicles/1234/registration", key: testReadKey, status: 200, contains: `"vehicleId":1234`},
+	{method: "GET", path: "/vehicles?tag=van", status: 200, contains: `"total":1`},
+	{method: "GET", path: "/vehicles?limit=0", status: 400},
+	{method: "GET", path: "/vehicles/1234/tags", status: 200, contains: `"van"`},
+	{method: "GET", path: "/vehicles/1236/tags", status: 404},
+	{method: "POST", path: "/vehicles/1234/tags", body: `{"tags": ["electric"]}`, status: 200, contains: `"electric"`},
+	{method: "POST", path: "/vehicles/1234/tags", body: `{}`, status: 400, contains: `"field":"tags"`},
+	{method: "DELETE", path: "/vehicles/1234/tags/electric", status: 200},
+	{method: "DELETE", path: "/vehicles/1236/tags/electric", status: 404},
+	{method: "POST", path: "/groups", body: `{"name": "depot-7", "description": "Vehicles parked at depot 7"}`, status: 200, contains: `"name":"depot-7"`},
+	{method: "POST", path: "/groups", body: `{"name": "depot-7"}`, status: 409},
+	{method: "POST", path: "/groups", body: `{"name": "Depot 7"}`, status: 400, contains: `"field":"name"`},
+	{method: "GET", path: "/groups", status: 200, contains: `"depot-7"`},
+	{method: "GET", path: "/groups/depot-7", status: 200},
+	{method: "GET", path: "/groups/depot-8", status: 404},
+	{method: "PUT", path: "/groups/depot-7", body: `{"description": "Depot 7"}`, status: 200, contains: `"description":"Depot 7"`},
+	{method: "PUT", path: "/groups/depot-8", body: `{"description": "Depot 8"}`, status: 404},
+	{method: "PUT", path: "/groups/depot-7/vehicles/1234", status: 200},
+	{method: "PUT", path: "/groups/depot-8/vehicles/1234", status: 404},
+	{method: "PUT", path: "/groups/depot-7/vehicles/abc", status: 400},
+	{method: "POST", path: "/vehicles/batch", body: `{"group": "depot-7"}`, status: 200, contains: `"vehicleId":1234`},
+	{method: "GET", path: "/fleet/stream?group=depot-7", status: 200, stream: true},
+	{method: "GET", path: "/fleet/stream?group=depot-8", status: 404},
+	{method: "GET", path: "/fleet/stream?offset=1&limit=1", status: 200, stream: true},
+	{method: "GET", path: "/fleet/stream?limit=501", status: 400},
+	{method: "DELETE", path: "/groups/depot-7/vehicles/1234", status: 200},
+	{method: "DELETE", path: "/groups/depot-7", status: 200},
+	{method: "DELETE", path: "/groups/depot-7", status: 404},
+	{method: "DELETE", path: "/vehicles/1235/registration", status: 200},
+	{method: "DELETE", path: "/vehicles/1235/registration", status: 404},
+
+	// fleet commands
+	{method: "POST", path: "/fleet/commands", body: `{"vehicleIds": [1234, 4321], "action": "STOP"}`, status: 200, capture: map[string]string{"command": "id"}},
+	{method: "POST", path: "/fleet/commands", body: `{"vehicleIds": [1234]}`, status: 400, contains: `"field":"action"`},
+	{method: "POST", path: "/fleet/commands", body: `{"group": "depot-8", "action": "STOP"}`, status: 404},
+	{method: "POST", path: "/fleet/commands", key: testReadKey, body: `{"vehicleIds": [1234], "action": "STOP"}`, status: 403},
+	{method: "GET", path: "/fleet/commands", status: 200, contains: `"failed":1`},
+	{method: "GET", path: "/fleet/commands/{command}", status: 200, contains: `"action":"STOP"`},
+	{method: "GET", path: "/fleet/commands/unknown", status: 404},
+	{method: "POST", path: "/fleet/commands/{command}/cancel", status: 200},
+	{method: "POST", path: "/fleet/commands/unknown/cancel", status: 404},
+
+	// webhooks
+	{method: "POST", path: "/webhooks", body: `{"url": "https://example.com/hooks", "eventTypes": ["fuel_low"]}`, status: 200, contains: `"secret"`, capture: map[string]string{"webhook": "id"}},
+	{method: "POST", path: "/webhooks", body: `{"url": "ftp://example.com/hooks"}`, status: 400, contains: `"field":"url"`},
+	{method: "POST", path: "/webhooks", key: testReadKey, body: `{"url": "https://example.com/hooks"}`, status: 403},
+	{method: "GET", path: "/webhooks", status: 200, contains: `"url":"https://example.com/hooks"`},
+	{method: "GET", path: "/webhooks/{webhook}", status: 200, contains: `"eventTypes":["fuel_low"]`},
+	{method: "GET", path: "/webhooks/unknown", status: 404},
+	{method: "PUT", path: "/webhooks/{webhook}", body: `{"url": "https://example.com/hooks/v2"}`, status: 200, contains: `"url":"https://example.com/hooks/v2"`},
+	{method: "PUT", path: "/webhooks/unknown", body: `{"url": "https://example.com/hooks"}`, status: 404},
+	{method: "GET", path: "/webhooks/{webhook}/deliveries", status: 200},
+	{method: "GET", path: "/webhooks/unknown/deliveries", status: 404},
+	{method: "GET", path: "/webhooks/{webhook}/dead-letters", status: 200},
+	{method: "GET", path: "/webhooks/unknown/dead-letters", status: 404},
+	{method: "POST", path: "/webhooks/{webhook}/dead-letters/unknown/retry", status: 404},
+	{method: "DELETE", path: "/webhooks/{webhook}", status: 200},
+	{method: "DELETE", path: "/webhooks/{webhook}", status: 404},
+
+	// graphql and the socket
+	{method: "POST", path: "/graphql", key: testReadKey, body: `{"query": "{ vehicle(id: \"1234\") { vin } }"}`, status: 200, contains: `"vin":"123123412412"`},
+	{method: "POST", path: "/graphql", key: testReadKey, body: `{"query": "mutation { engineAction(vehicleId: \"1234\", action: START) { status } }"}`, status: 200, contains: `"errors"`},
+	{method: "POST", path: "/graphql", body: `{"query": "{ vehicle("}`, status: 200, contains: "Syntax Error"},
+	{method: "POST", path: "/graphql", body: `{"query": 1}`, status: 400},
+	{method: "GET", path: "/ws", status: 400},
+	{method: "GET", path: "/ws", key: "-", status: 401},
+
+	// admin
+	{method: "GET", path: "/admin/poller", status: 200, contains: `"status":"running"`},
+	{method: "GET", path: "/admin/poller", key: testReadKey, status: 403},
+	{method: "POST", path: "/admin/poller/pause", status: 200, contains: `"status":"paused"`},
+	{method: "POST", path: "/admin/poller/resume", status: 200, contains: `"status":"running"`},
+	{method: "PUT", path: "/vehicles/1234/registration", body: `{}`, status: 200},
+	{method: "GET", path: "/admin/poller/schedules", status: 200},
+	{method: "GET", path: "/admin/poller/schedules/1234", status: 200},
+	{method: "GET", path: "/admin/poller/schedules/9999", status: 404},
+	{method: "PUT", path: "/admin/poller/schedules/1234", body: `{"intervals": {"doors": 60, "fuel": 0}}`, status: 200},
+	{method: "PUT", path: "/admin/poller/schedules/1234", body: `{"intervals": {"wheels": 60}}`, status: 400},
+	{method: "PUT", path: "/admin/poller/schedules/9999", body: `{"intervals": {"doors": 60}}`, status: 404},
+	{method: "POST", path: "/admin/poller/schedules/1234/pause", status: 200},
+	{method: "POST", path: "/admin/poller/schedules/9999/pause", status: 404},
+	{method: "POST", path: "/admin/poller/schedules/1234/resume", status: 200},
+	{method: "POST", path: "/admin/poller/schedules/9999/resume", status: 404},
+	{method: "DELETE", path: "/admin/poller/schedules/1234", status: 200},
+	{method: "DELETE", path: "/admin/poller/schedules/9999", status: 404},
+	{method: "GET", path: "/admin/gm/cassette", status: 200, contains: `/getVehicleInfoService`},
+	{method: "GET", path: "/admin/gm/faults", status: 200, contains: `"rules":[]`},
+	{method: "PUT", path: "/admin/gm/faults", body: `{"rules": [{"vehicleId": 1235, "fault": "gm_error"}]}`, status: 200},
+	{method: "GET", path: "/vehicles/1235", status: 500, contains: "Failed to get vehicle"},
+	{method: "PUT", path: "/admin/gm/faults", body: `{"rules": [{"vehicleId": 0, "fault": "error"}]}`, status: 400, contains: `"field":"rules[0].vehicleId"`},
+	{method: "DELETE", path: "/admin/gm/faults", status: 200, contains: `"rules":[]`},
+	{method: "GET", path: "/vehicles/1235", status: 200},
+	{method: "GET", path: "/admin/gm/breaker", status: 200, contains: `"state":"closed"`},
+	{method: "POST", path: "/admin/gm/breaker/reset", status: 200, contains: `"state":"closed"`},
+	{method: "POST", path: "/admin/gm/breaker/reset", key: testReadKey, status: 403},
+	{method: "GET", path: "/admin/log-level", status: 200, contains: `"level":"info"`},
+	{method: "PUT", path: "/admin/log-level", body: `{"level": "debug"}`, status: 200, contains: `"level":"debug"`},
+	{method: "PUT", path: "/admin/log-level", body: `{"level": "loud"}`, status: 400, contains: `"field":"level"`},
+	{method: "PUT", path: "/admin/log-level", body: `{"level": "info"}`, status: 200, contains: `"level":"info"`},
+	{method: "GET", path: "/admin/config", status: 200, contains: `"admin_port":8006`},
+	{method: "GET", path: "/admin/commands", status: 200},
+	{method: "DELETE", path: "/admin/vehicles/1234/cache", status: 200, contains: `"vehicleId":1234,"telemetryReadings":`},
+	{method: "GET", path: "/vehicles/1234/fuel/history", status: 200, contains: `"points":[]`},
+	{method: "DELETE", path: "/admin/vehicles/4321/cache", status: 200, contains: `"telemetryReadings":0,"scheduled":false`},
+	{method: "DELETE", path: "/admin/vehicles/1234/cache", key: testReadKey, status: 403},
+	{method: "GET", path: "/admin/audit", status: 200, contains: `"path":"/admin/log-level","body":"{\"level\": \"loud\"}","status":400`},
+	{method: "GET", path: "/admin/audit", key: "-", status: 401},
+}
+
+func TestRouter(t *testing.T) {
+	s := newIntegrationServer(t)
+
+	// every route is exercised by at least one request
+	exercised := map[string]bool{}
+	captured := map[string]string{}
+
+	for _, c := range routeCases {
+		req, res, body := c.do(t, s, captured)
+		name := c.method + " " + req.URL.RequestURI()
+		assert.Equal(t, c.status, res.StatusCode, "%s: %s", name, body)
+		assert.Contains(t, body, c.contains, name)
+		if c.status >= 400 && res.Header.Get("Content-Type") == "application/json" {
+			// errors of the API, rather than of the router or the socket upgrade, are in the error envelope
+			assert.Contains(t, body, `"error":1`, name)
+		}
+
+		for name, field := range c.capture {
+			var fields map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(body), &fields))
+			captured[name], _ = fields[field].(string)
+		}
+
+		var match mux.RouteMatch
+		if _, router := s.route(req.URL.Path); router.Match(req, &match) && match.MatchErr == nil {
+			template, _ := match.Route.GetPathTemplate()
+			exercised[c.method+" "+template] = true
+		}
+	}
+
+	var missed []string
+	for _, router := range []*mux.Router{s.apiRouter, s.adminRouter} {
+		router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+			template, _ := route.GetPathTemplate()
+			methods, _ := route.GetMethods()
+			for _, method := range methods {
+				if !exercised[method+" "+template] {
+					missed = append(missed, method+" "+template)
+				}
+			}
+			return nil
+		})
+	}
+	sort.Strings(missed)
+	assert.Empty(t, missed, "routes without a test case")
+
+	// the admin routes are only served on the admin port, and the API's only on the API port
+	for server, path := range map[*httptest.Server]string{s.api: "/admin/poller", s.admin: "/vehicles/1234"} {
+		req, err := http.NewRequest("GET", server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("X-API-Key", testAdminKey)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusNotFound, res.StatusCode, path)
+	}
+}
+
+// GM failing is mapped to a 500 for the request that failed, then to a 503 once 3 failures in a row open the circuit
+// breaker. A malformed response doesn't count against the breaker, GM answered
+func TestRouterGMErrors(t *testing.T) {
+	s := newIntegrationServer(t, gmConnector.WithCircuitBreaker(3, time.Minute))
+
+	cases := []routeCase{
+		{method: "PUT", path: "/admin/gm/faults", body: `{"rules": [{"endpoint": "getEnergyService", "fault": "error"}]}`, status: 200},
+		{method: "GET", path: "/vehicles/1234/fuel", status: 500, contains: "Failed to get vehicle energy status"},
+		{method: "PUT", path: "/admin/gm/faults", body: `{"rules": [{"endpoint": "getEnergyService", "fault": "timeout", "latencyMs": 1}]}`, status: 200},
+		{method: "GET", path: "/vehicles/1234/fuel", status: 500, contains: `"message":"Internal Error"`},
+		{method: "PUT", path: "/admin/gm/faults", body: `{"rules": [{"endpoint": "getEnergyService", "fault": "malformed"}]}`, status: 200},
+		{method: "GET", path: "/vehicles/1234/fuel", status: 500, contains: "Failed to get vehicle energy levels"},
+		{method: "PUT", path: "/admin/gm/faults", body: `{"rules": [{"fault": "error"}]}`, status: 200},
+		{method: "GET", path: "/vehicles/1234", status: 500},
+		{method: "GET", path: "/vehicles/1234", status: 500},
+		{method: "GET", path: "/vehicles/1234", status: 500},
+		{method: "GET", path: "/vehicles/1234", status: 503, contains: "GM API is unavailable"},
+		{method: "DELETE", path: "/admin/gm/faults", status: 200},
+		{method: "GET", path: "/vehicles/1234/doors", status: 503, contains: "GM API is unavailable"},
+		{method: "POST", path: "/vehicles/batch", body: `{"vehicleIds": [1234, 1235], "sections": ["doors"]}`, status: 200, contains: `"status":"error"`},
+		{method: "GET", path: "/admin/gm/breaker", status: 200, contains: `"state":"open"`},
+		{method: "POST", path: "/admin/gm/breaker/reset", status: 200, contains: `"state":"closed"`},
+		{method: "GET", path: "/vehicles/1234/doors", status: 200},
+	}
+	for _, c := range cases {
+		req, res, body := c.do(t, s, nil)
+		assert.Equal(t, c.status, res.StatusCode, "%s %s: %s", c.method, req.URL.RequestURI(), body)
+		assert.Contains(t, body, c.contains)
+	}
+}
+
+func TestRouterGMFaultsOptIn(t *testing.T) {
+	for environment, status := range map[string]int{
+		"":                             http.StatusNotFound,
+		config.ENVIRONMENT_PRODUCTION:  http.StatusNotFound,
+		config.ENVIRONMENT_TESTING:     http.StatusOK,
+		config.ENVIRONMENT_DEVELOPMENT: http.StatusOK,
+	} {
+		cfg := defaultConfig()
+		cfg.Environment = environment
+		gmFaults, err := newGMFaults(&cfg)
+		require.NoError(t, err)
+
+		env := newTestEnv(t, gmConnector.NewMockGMAPIConnector())
+		env.Services.GMFaults = gmFaults
+		router := NewAdminRouter(env)
+
+		for _, method := range []string{"GET", "PUT", "DELETE"} {
+			req := httptest.NewRequest(method, "/admin/gm/faults", strings.NewReader(`{"rules": []}`))
+			req.Header.Set("X-API-Key", testAdminKey)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, status, w.Code, "%s /admin/gm/faults with ENVIRONMENT=%q", method, environment)
+		}
+	}
+}
+
+func TestRouterAdminTakesAKey(t *testing.T) {
+	env := newTestEnv(t, gmConnector.NewMockGMAPIConnector())
+	env.Services.Auth = auth.New(nil)
+	api, admin := NewRouter(env), NewAdminRouter(env)
+
+	// without keys the API is open, but the admin API refuses every request
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest("GET", "/vehicles/1234", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	for _, path := range []string{"/admin/config", "/admin/log-level", "/admin/poller"} {
+		w := httptest.NewRecorder()
+		admin.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+	}
+}
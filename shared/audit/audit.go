@@ -0,0 +1,144 @@
+package audit
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	loghelper "app_api/shared/loghelpers"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DEFAULT_SIZE ... the entries kept in memory
+	DEFAULT_SIZE = 1000
+	// MAX_BODY ... the most of a request body recorded
+	MAX_BODY = 4096
+)
+
+// Entry ... a request to the admin API, who sent it and how it ended
+//
+// swagger:model AuditEntry
+type Entry struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"requestId"`
+	// KeyID ... identifies the API key of the request without revealing it, empty if it had none
+	KeyID         string `json:"keyId"`
+	RemoteAddress string `json:"remoteAddress"`
+	Method        string `json:"method"`
+	Path          string `json:"path"`
+	// Body ... the start of the request body, e.g. the new log level
+	Body   string `json:"body,omitempty"`
+	Status int    `json:"status"`
+}
+
+// Log ... keeps the latest entries, and writes every entry to its logger whatever the level of the API's logs, so
+// lowering the log level never hides who did what
+type Log struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	size    int
+	logger  *log.Logger
+}
+
+// New ... returns a log writing entries to out, in the format and to the other sinks of the API's logs, and keeping the
+// latest size of them
+func New(out io.Writer, size int) *Log {
+	logger := loghelper.NewLogger()
+	logger.SetOutput(out)
+	return &Log{size: size, logger: logger}
+}
+
+// Record ... writes the entry, and keeps it in place of the oldest one once the log is full
+func (l *Log) Record(entry Entry) {
+	l.logger.WithFields(log.Fields{
+		"Audit":         true,
+		"RequestID":     entry.RequestID,
+		"KeyID":         entry.KeyID,
+		"RemoteAddress": entry.RemoteAddress,
+		"Method":        entry.Method,
+		"URL":           entry.Path,
+		"Body":          entry.Body,
+		"Status":        entry.Status,
+	}).Info("admin request")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) < l.size {
+		l.entries = append(l.entries, entry)
+		return
+	}
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % l.size
+}
+
+// Entries ... the entries kept, oldest first
+func (l *Log) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	res := make([]Entry, 0, len(l.entries))
+	res = append(res, l.entries[l.next:]...)
+	return append(res, l.entries[:l.next]...)
+}
+
+// statusRecorder ... remembers the status code of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Middleware ... records every request once it is served. identify returns the key ID of the request, empty if it
+// has none
+func (l *Log) Middleware(identify func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			entry := Entry{
+				Time:          time.Now().UTC(),
+				RequestID:     loghelper.GetRequestID(r.Context()),
+				KeyID:         identify(r),
+				RemoteAddress: r.RemoteAddr,
+				Method:        r.Method,
+				Path:          r.URL.RequestURI(),
+			}
+
+			// the start of the body is read for the entry, and put back for the handler
+			if r.Body != nil && r.Method != http.MethodGet {
+				body, _ := ioutil.ReadAll(io.LimitReader(r.Body, MAX_BODY))
+				entry.Body = string(body)
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+			}
+
+			recorder := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, r)
+
+			entry.Status = recorder.status
+			if entry.Status == 0 {
+				entry.Status = http.StatusOK
+			}
+			l.Record(entry)
+		})
+	}
+}
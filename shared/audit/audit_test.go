@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogKeepsLatestEntries(t *testing.T) {
+	l := New(ioutil.Discard, 2)
+	assert.Empty(t, l.Entries())
+
+	for _, path := range []string{"/admin/a", "/admin/b", "/admin/c"} {
+		l.Record(Entry{Path: path})
+	}
+
+	entries := l.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "/admin/b", entries[0].Path)
+	assert.Equal(t, "/admin/c", entries[1].Path)
+}
+
+func TestMiddleware(t *testing.T) {
+	var out bytes.Buffer
+	l := New(&out, DEFAULT_SIZE)
+
+	handler := l.Middleware(func(r *http.Request) string {
+		return r.Header.Get("X-Key-ID")
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != `{"level": "debug"}` {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"level":"debug"}`))
+	}))
+
+	r := httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{"level": "debug"}`))
+	r.Header.Set("X-Key-ID", "0f3a9b2c")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, "the handler reads the whole body")
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{}`)))
+
+	entries := l.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "0f3a9b2c", entries[0].KeyID)
+	assert.Equal(t, http.MethodPut, entries[0].Method)
+	assert.Equal(t, "/admin/log-level", entries[0].Path)
+	assert.Equal(t, `{"level": "debug"}`, entries[0].Body)
+	assert.Equal(t, http.StatusOK, entries[0].Status)
+	assert.Equal(t, "", entries[1].KeyID)
+	assert.Equal(t, http.StatusBadRequest, entries[1].Status)
+
+	assert.Contains(t, out.String(), "KeyID=0f3a9b2c")
+	assert.Contains(t, out.String(), "Status=400")
+}
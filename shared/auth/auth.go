@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"app_api/shared"
+	"app_api/shared/httphelper"
+)
+
+const (
+	// SCOPE_VEHICLES_READ ... read vehicle data, history and registrations, and subscribe to vehicle events
+	SCOPE_VEHICLES_READ = "vehicles:read"
+	// SCOPE_VEHICLES_COMMAND ... send engine commands, to one vehicle or the fleet
+	SCOPE_VEHICLES_COMMAND = "vehicles:command"
+	// SCOPE_REGISTRY_WRITE ... change registrations, tags and groups
+	SCOPE_REGISTRY_WRITE = "registry:write"
+	// SCOPE_WEBHOOKS_MANAGE ... manage webhook subscriptions and their deliveries
+	SCOPE_WEBHOOKS_MANAGE = "webhooks:manage"
+	// SCOPE_ADMIN ... operate the service, e.g. the background poller
+	SCOPE_ADMIN = "admin"
+	// SCOPE_ALL ... grants every scope
+	SCOPE_ALL = "*"
+
+	HEADER_API_KEY = "X-API-Key"
+)
+
+// Scopes ... every scope a key can be granted, besides SCOPE_ALL
+var Scopes = []string{SCOPE_VEHICLES_READ, SCOPE_VEHICLES_COMMAND, SCOPE_REGISTRY_WRITE, SCOPE_WEBHOOKS_MANAGE, SCOPE_ADMIN}
+
+type key string
+
+// ContextKeyPrincipal ... key for the authenticated principal in context
+const ContextKeyPrincipal key = "principal"
+
+var (
+	errMissingKey = errors.New("no API key in the request")
+	errInvalidKey = errors.New("unknown API key")
+)
+
+// Principal ... the caller behind an API key
+type Principal struct {
+	// KeyID ... identifies the key in logs without revealing it
+	KeyID  string
+	Scopes []string
+}
+
+// Has ... returns true if the principal was granted the scope
+func (p Principal) Has(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == SCOPE_ALL {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize ... returns a 403 unless the principal was granted every scope
+func (p Principal) Authorize(scopes ...string) *shared.APIError {
+	for _, scope := range scopes {
+		if !p.Has(scope) {
+			clientErr := fmt.Sprintf("API key lacks the %s scope", scope)
+			return shared.NewAPIError(http.StatusForbidden, fmt.Errorf("key %s lacks the %s scope", p.KeyID, scope), clientErr)
+		}
+	}
+	return nil
+}
+
+// Anonymous ... the principal of every request while authentication is disabled. It is granted every scope but
+// SCOPE_ADMIN, so operating the service always takes a key
+var Anonymous = Principal{KeyID: "anonymous", Scopes: []string{SCOPE_VEHICLES_READ, SCOPE_VEHICLES_COMMAND, SCOPE_REGISTRY_WRITE, SCOPE_WEBHOOKS_MANAGE}}
+
+// Authenticator ... checks the API key of requests against the configured keys. Without any keys authentication is disabled
+// and every request acts as Anonymous, so the API stays open until keys are configured, except for the admin routes
+type Authenticator struct {
+	// principals ... keyed by the SHA-256 of the API key, so keys aren't kept in memory in the clear
+	principals map[[sha256.Size]byte]Principal
+}
+
+// New ... returns an authenticator for the keys, each mapped to the scopes it is granted
+func New(keys map[string][]string) *Authenticator {
+	a := &Authenticator{principals: make(map[[sha256.Size]byte]Principal)}
+	for apiKey, scopes := range keys {
+		sum := sha256.Sum256([]byte(apiKey))
+		a.principals[sum] = Principal{KeyID: hex.EncodeToString(sum[:4]), Scopes: append([]string{}, scopes...)}
+	}
+	return a
+}
+
+// ParseKeys ... parses keys in the form "key1=vehicles:read vehicles:command;key2=*"
+func ParseKeys(spec string) (map[string][]string, error) {
+	keys := make(map[string][]string)
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		idx := strings.Index(entry, "=")
+		if idx <= 0 {
+			return nil, errors.New("API keys must be in the form key=scope scope, separated by semicolons")
+		}
+
+		apiKey, scopes := strings.TrimSpace(entry[:idx]), strings.Fields(entry[idx+1:])
+		if apiKey == "" {
+			return nil, errors.New("API keys must not be empty")
+		}
+		if len(scopes) == 0 {
+			return nil, errors.New("every API key must be granted at least one scope")
+		}
+		for _, scope := range scopes {
+			if !isScope(scope) {
+				return nil, fmt.Errorf("unknown scope %q, must be one of [%s]", scope, strings.Join(append(Scopes, SCOPE_ALL), ", "))
+			}
+		}
+		sort.Strings(scopes)
+		keys[apiKey] = scopes
+	}
+	return keys, nil
+}
+
+// Enabled ... returns true if any key is configured
+func (a *Authenticator) Enabled() bool {
+	return a != nil && len(a.principals) > 0
+}
+
+// Authenticate ... returns the principal of the API key in the X-API-Key header, or an Authorization header with a bearer token
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, *shared.APIError) {
+	return a.AuthenticateKey(KeyFromHeaders(r.Header.Get(HEADER_API_KEY), r.Header.Get("Authorization")))
+}
+
+// AuthenticateKey ... returns the principal of an API key, for transports other than HTTP
+func (a *Authenticator) AuthenticateKey(apiKey string) (Principal, *shared.APIError) {
+	if !a.Enabled() {
+		return Anonymous, nil
+	}
+
+	if apiKey == "" {
+		return Principal{}, shared.NewAPIError(http.StatusUnauthorized, errMissingKey, "API key required")
+	}
+
+	// the lookup is by hash, so the time it takes doesn't depend on how much of a key matches
+	principal, ok := a.principals[sha256.Sum256([]byte(apiKey))]
+	if !ok {
+		return Principal{}, shared.NewAPIError(http.StatusUnauthorized, errInvalidKey, "Invalid API key")
+	}
+	return principal, nil
+}
+
+// KeyFromHeaders ... returns the API key from the value of an X-API-Key header, or else an Authorization header with a bearer token
+func KeyFromHeaders(apiKey, authorization string) string {
+	if apiKey != "" {
+		return apiKey
+	}
+	if strings.HasPrefix(authorization, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer "))
+	}
+	return ""
+}
+
+// Require ... wraps a handler so it is only called for requests with an API key granted every scope. With no scopes any
+// valid key is enough. The principal is added to the request context
+func (a *Authenticator) Require(scopes ...string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			principal, apiErr := a.Authenticate(r)
+			if apiErr == nil {
+				apiErr = principal.Authorize(scopes...)
+			}
+			if apiErr != nil {
+				httphelper.NewResponse(r.Context(), w, nil, apiErr)
+				return
+			}
+
+			next(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		}
+	}
+}
+
+// WithPrincipal ... adds the principal to the context
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, ContextKeyPrincipal, principal)
+}
+
+// PrincipalFromContext ... returns the principal added by Require, or false if the request wasn't authenticated
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(ContextKeyPrincipal).(Principal)
+	return principal, ok
+}
+
+func isScope(scope string) bool {
+	if scope == SCOPE_ALL {
+		return true
+	}
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys(" reader = vehicles:read ; operator=vehicles:read vehicles:command;root=*;")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"reader":   {"vehicles:read"},
+		"operator": {"vehicles:command", "vehicles:read"},
+		"root":     {"*"},
+	}, keys)
+
+	keys, err = ParseKeys("")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	for _, spec := range []string{"reader", "=vehicles:read", "reader=", "reader=vehicles:write"} {
+		_, err = ParseKeys(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	a := New(map[string][]string{"operator": {SCOPE_VEHICLES_READ, SCOPE_VEHICLES_COMMAND}})
+	assert.True(t, a.Enabled())
+
+	r := httptest.NewRequest(http.MethodGet, "/vehicles/1234", nil)
+	_, err := a.Authenticate(r)
+	assert.Equal(t, http.StatusUnauthorized, err.ErrorCode)
+	assert.Equal(t, "API key required", err.ClientErrorMessage)
+
+	r.Header.Set(HEADER_API_KEY, "guess")
+	_, err = a.Authenticate(r)
+	assert.Equal(t, http.StatusUnauthorized, err.ErrorCode)
+	assert.Equal(t, "Invalid API key", err.ClientErrorMessage)
+
+	r.Header.Set(HEADER_API_KEY, "operator")
+	principal, err := a.Authenticate(r)
+	assert.Nil(t, err)
+	assert.True(t, principal.Has(SCOPE_VEHICLES_COMMAND))
+	assert.False(t, principal.Has(SCOPE_ADMIN))
+	assert.Len(t, principal.KeyID, 8)
+	assert.NotContains(t, principal.KeyID, "operator")
+
+	r = httptest.NewRequest(http.MethodGet, "/vehicles/1234", nil)
+	r.Header.Set("Authorization", "Bearer operator")
+	_, err = a.Authenticate(r)
+	assert.Nil(t, err)
+}
+
+func TestKeyFromHeaders(t *testing.T) {
+	assert.Equal(t, "k1", KeyFromHeaders("k1", "Bearer k2"), "The API key header wins")
+	assert.Equal(t, "k2", KeyFromHeaders("", "Bearer k2"))
+	assert.Equal(t, "", KeyFromHeaders("", "Basic dXNlcjpwYXNz"))
+	assert.Equal(t, "", KeyFromHeaders("", ""))
+}
+
+func TestAuthenticateDisabled(t *testing.T) {
+	for _, a := range []*Authenticator{nil, New(nil)} {
+		assert.False(t, a.Enabled())
+
+		principal, err := a.Authenticate(httptest.NewRequest(http.MethodGet, "/vehicles/1234", nil))
+		assert.Nil(t, err)
+		assert.Equal(t, Anonymous, principal)
+		assert.Nil(t, principal.Authorize(SCOPE_VEHICLES_READ, SCOPE_VEHICLES_COMMAND, SCOPE_REGISTRY_WRITE, SCOPE_WEBHOOKS_MANAGE))
+		assert.NotNil(t, principal.Authorize(SCOPE_ADMIN), "the admin scope always takes a key")
+
+		rec := httptest.NewRecorder()
+		a.Require(SCOPE_ADMIN)(func(w http.ResponseWriter, r *http.Request) {})(rec, httptest.NewRequest(http.MethodPost, "/admin/poller/pause", nil))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	a := New(map[string][]string{"reader": {SCOPE_VEHICLES_READ}})
+
+	var got Principal
+	handler := a.Require(SCOPE_VEHICLES_READ)(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = PrincipalFromContext(r.Context())
+	})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/vehicles/1234", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	r := httptest.NewRequest(http.MethodGet, "/vehicles/1234", nil)
+	r.Header.Set(HEADER_API_KEY, "reader")
+	rec = httptest.NewRecorder()
+	handler(rec, r)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{SCOPE_VEHICLES_READ}, got.Scopes)
+
+	rec = httptest.NewRecorder()
+	a.Require(SCOPE_VEHICLES_COMMAND)(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler called without the scope")
+	})(rec, r)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "API key lacks the vehicles:command scope")
+}
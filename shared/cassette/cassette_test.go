@@ -0,0 +1,164 @@
+package cassette
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func post(t *testing.T, rt http.RoundTripper, url, body string) (*http.Response, string, error) {
+	req, err := http.NewRequest("POST", url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer s3cret")
+
+	res, err := (&http.Client{Transport: rt}).Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res, string(b), nil
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, "Bearer s3cret", r.Header.Get("Authorization"), "the upstream gets the credentials")
+
+		w.Header().Set("Set-Cookie", "session=abc")
+		switch {
+		case r.URL.Path == "/text":
+			w.Write([]byte("not json & <b>plain</b>"))
+		case strings.Contains(string(body), "1235"):
+			w.Write([]byte(`{"status": "404", "reason": "Vehicle id: 1235 not found."}`))
+		default:
+			w.Write([]byte(`{"status": "200", "data": {"vin": {"type": "String", "value": "1HGCM82633A004352"}, "call": ` + string(rune('0'+calls)) + `}}`))
+		}
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder(nil, WithRedactor(RedactJSON("data.vin.value")))
+	recorder.now = func() time.Time { return time.Date(2020, 11, 20, 10, 0, 0, 0, time.UTC) }
+
+	_, body, err := post(t, recorder, server.URL+"/getVehicleInfoService", `{"id": "1234"}`)
+	require.NoError(t, err)
+	assert.Contains(t, body, "1HGCM82633A004352", "the caller gets the response as it was sent")
+	_, _, err = post(t, recorder, server.URL+"/getVehicleInfoService", `{"id": "1234"}`)
+	require.NoError(t, err)
+	_, _, err = post(t, recorder, server.URL+"/getVehicleInfoService", `{"id": "1235"}`)
+	require.NoError(t, err)
+	_, _, err = post(t, recorder, server.URL+"/text", ``)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "cassette")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "gm.json")
+	require.NoError(t, recorder.Save(path))
+	saved, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(saved), "1HGCM82633A004352")
+	assert.NotContains(t, string(saved), "s3cret")
+	assert.NotContains(t, string(saved), "session=abc")
+	assert.Contains(t, string(saved), `"body": {
+          "data": {
+            "call": 1,
+            "vin": {
+              "type": "String",
+              "value": "REDACTED"
+            }
+          },
+          "status": "200"
+        }`, "JSON bodies are saved as JSON")
+	assert.Contains(t, string(saved), `"body": "not json & <b>plain</b>"`)
+
+	player, err := Replay(path)
+	require.NoError(t, err)
+
+	// from another host, with the fields in another order
+	other := "http://gm.example.com"
+	res, body, err := post(t, player, other+"/getVehicleInfoService", `{ "id":"1234" }`)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.JSONEq(t, `{"status": "200", "data": {"vin": {"type": "String", "value": "REDACTED"}, "call": 1}}`, body)
+	assert.Equal(t, []string{REDACTED}, res.Header["Set-Cookie"])
+
+	// matching interactions are replayed in order, the last one over and over
+	for i := 0; i < 2; i++ {
+		_, body, err = post(t, player, other+"/getVehicleInfoService", `{"id": "1234"}`)
+		require.NoError(t, err)
+		assert.Contains(t, body, `"call":2`)
+	}
+
+	_, body, err = post(t, player, other+"/getVehicleInfoService", `{"id": "1235"}`)
+	require.NoError(t, err)
+	assert.Contains(t, body, "not found")
+
+	_, body, err = post(t, player, other+"/text", ``)
+	require.NoError(t, err)
+	assert.Equal(t, "not json & <b>plain</b>", body)
+
+	_, _, err = post(t, player, other+"/getVehicleInfoService", `{"id": "1236"}`)
+	assert.True(t, errors.Is(err, ErrNoInteraction), err)
+	assert.Equal(t, 4, calls, "a player never sends requests")
+}
+
+func TestRedactedRequestFieldsMatchAnyValue(t *testing.T) {
+	player := NewPlayer(&Cassette{Interactions: []Interaction{{
+		Request:  Request{Method: "POST", URL: "http://gm/actionEngineService", Body: Body(`{"id": "1234", "token": "REDACTED"}`)},
+		Response: Response{Status: http.StatusOK, Body: Body(`{"status": "200"}`)},
+	}}})
+
+	_, body, err := post(t, player, "http://localhost/actionEngineService", `{"id": "1234", "token": "t0ken"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"status": "200"}`, body)
+
+	_, _, err = post(t, player, "http://localhost/actionEngineService", `{"id": "1235", "token": "t0ken"}`)
+	assert.True(t, errors.Is(err, ErrNoInteraction))
+}
+
+func TestRedactJSONWildcards(t *testing.T) {
+	i := Interaction{Response: Response{Body: Body(`{"data": {"doors": {"values": [{"location": {"value": "frontLeft"}}, {"location": {"value": "frontRight"}}]}}}`)}}
+	RedactJSON("data.doors.values.*.location.value", "data.missing")(&i)
+	assert.JSONEq(t, `{"data": {"doors": {"values": [{"location": {"value": "REDACTED"}}, {"location": {"value": "REDACTED"}}]}}}`, string(i.Response.Body))
+
+	// bodies without any of the paths are kept byte for byte
+	i = Interaction{Response: Response{Body: Body(`{ "status":"200" }`)}}
+	RedactJSON("data.vin.value")(&i)
+	assert.Equal(t, `{ "status":"200" }`, string(i.Response.Body))
+}
+
+func TestRecorderKeepsTheLatest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder(server.Client().Transport, WithLimit(2))
+	for _, id := range []string{"1", "2", "3"} {
+		_, _, err := post(t, recorder, server.URL, `{"id": "`+id+`"}`)
+		require.NoError(t, err)
+	}
+
+	c := recorder.Cassette()
+	require.Len(t, c.Interactions, 2)
+	assert.JSONEq(t, `{"id": "2"}`, string(c.Interactions[0].Response.Body))
+	assert.JSONEq(t, `{"id": "3"}`, string(c.Interactions[1].Response.Body))
+
+	_, err := Load(filepath.Join(os.TempDir(), "missing-cassette.json"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,148 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// ErrNoInteraction ... returned by a player for a request the cassette has no recording of
+var ErrNoInteraction = errors.New("cassette: no recorded interaction matches the request")
+
+// Player ... an http.RoundTripper that answers requests with the responses of a cassette, without sending them.
+// A request matches an interaction with the same method, path, query and body, whatever the host, and a redacted value
+// of a recorded body matches any value. Matching interactions are replayed in the order they were recorded, the last
+// one again once they have all been replayed, so the same requests always get the same responses
+type Player struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	replayed     []bool
+}
+
+// NewPlayer ... replays the interactions of the cassette
+func NewPlayer(c *Cassette) *Player {
+	return &Player{
+		interactions: c.Interactions,
+		replayed:     make([]bool, len(c.Interactions)),
+	}
+}
+
+// Replay ... loads the cassette file and replays its interactions
+func Replay(path string) (*Player, error) {
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewPlayer(c), nil
+}
+
+// RoundTrip ... answers the request with the response of the next matching interaction
+func (p *Player) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.mu.Lock()
+	last := -1
+	next := -1
+	for i, interaction := range p.interactions {
+		if !matches(interaction.Request, req, body) {
+			continue
+		}
+		last = i
+		if !p.replayed[i] {
+			next = i
+			break
+		}
+	}
+	if next == -1 {
+		next = last
+	}
+	if next != -1 {
+		p.replayed[next] = true
+	}
+	p.mu.Unlock()
+
+	if next == -1 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, req.URL)
+	}
+
+	recorded := p.interactions[next].Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Headers.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+func matches(recorded Request, req *http.Request, body []byte) bool {
+	if recorded.Method != req.Method {
+		return false
+	}
+
+	u, err := url.Parse(recorded.URL)
+	if err != nil || u.Path != req.URL.Path || u.Query().Encode() != req.URL.Query().Encode() {
+		return false
+	}
+
+	return matchBody(recorded.Body, body)
+}
+
+// matchBody ... compares JSON bodies by value, so the order of their fields and their spacing don't matter
+func matchBody(recorded, body []byte) bool {
+	var want, got interface{}
+	if json.Unmarshal(recorded, &want) != nil || json.Unmarshal(body, &got) != nil {
+		return bytes.Equal(recorded, body)
+	}
+	return matchJSON(want, got)
+}
+
+func matchJSON(want, got interface{}) bool {
+	if want == REDACTED {
+		return true
+	}
+
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok || len(w) != len(g) {
+			return false
+		}
+		for key, value := range w {
+			if _, ok := g[key]; !ok || !matchJSON(value, g[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(w) != len(g) {
+			return false
+		}
+		for i := range w {
+			if !matchJSON(w[i], g[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return want == got
+	}
+}
@@ -0,0 +1,129 @@
+package cassette
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DEFAULT_LIMIT ... the number of interactions a recorder keeps by default
+const DEFAULT_LIMIT = 1000
+
+// Recorder ... an http.RoundTripper that sends requests through another, recording each request and its response
+// with the sensitive values redacted. Only the latest interactions are kept, so it can be left recording in production
+type Recorder struct {
+	transport http.RoundTripper
+	redactors []Redactor
+	limit     int
+
+	mu           sync.Mutex
+	interactions []Interaction
+	now          func() time.Time
+}
+
+// Option ... configures what a recorder keeps
+type Option func(*Recorder)
+
+// WithRedactor ... applies the redactor to every interaction before it is recorded, after the credential headers
+// every recorder redacts
+func WithRedactor(redactor Redactor) Option {
+	return func(r *Recorder) {
+		r.redactors = append(r.redactors, redactor)
+	}
+}
+
+// WithLimit ... keeps the latest limit interactions, dropping the oldest. Zero or less keeps every interaction
+func WithLimit(limit int) Option {
+	return func(r *Recorder) {
+		r.limit = limit
+	}
+}
+
+// NewRecorder ... records the requests sent through transport, or http.DefaultTransport if it is nil
+func NewRecorder(transport http.RoundTripper, opts ...Option) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	r := &Recorder{
+		transport: transport,
+		redactors: []Redactor{RedactHeaders(sensitiveHeaders...)},
+		limit:     DEFAULT_LIMIT,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// RoundTrip ... sends the request, and records it with its response. Requests that fail to get a response aren't
+// recorded, as there is nothing to replay
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		// a round tripper mustn't modify the request, so the body read is sent on a copy
+		req = req.Clone(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	res, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(resBody))
+
+	// the redactors work on copies, so the caller still gets the response as it was sent
+	interaction := Interaction{
+		RecordedAt: r.now().UTC(),
+		Request: Request{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: req.Header.Clone(),
+			Body:    append(Body(nil), reqBody...),
+		},
+		Response: Response{
+			Status:  res.StatusCode,
+			Headers: res.Header.Clone(),
+			Body:    append(Body(nil), resBody...),
+		},
+	}
+	for _, redact := range r.redactors {
+		redact(&interaction)
+	}
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, interaction)
+	if r.limit > 0 && len(r.interactions) > r.limit {
+		r.interactions = r.interactions[len(r.interactions)-r.limit:]
+	}
+	r.mu.Unlock()
+
+	return res, nil
+}
+
+// Cassette ... the interactions recorded so far
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Cassette{Interactions: append([]Interaction(nil), r.interactions...)}
+}
+
+// Save ... writes the interactions recorded so far to a cassette file
+func (r *Recorder) Save(path string) error {
+	return r.Cassette().Save(path)
+}
@@ -0,0 +1,106 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// REDACTED ... replaces the sensitive values of a recorded interaction
+const REDACTED = "REDACTED"
+
+// Redactor ... removes sensitive values from an interaction before it is recorded
+type Redactor func(*Interaction)
+
+// sensitiveHeaders ... credentials that are never recorded
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// RedactHeaders ... replaces the values of the named request and response headers
+func RedactHeaders(names ...string) Redactor {
+	return func(i *Interaction) {
+		for _, name := range names {
+			redactHeader(i.Request.Headers, name)
+			redactHeader(i.Response.Headers, name)
+		}
+	}
+}
+
+func redactHeader(h http.Header, name string) {
+	if values, ok := h[http.CanonicalHeaderKey(name)]; ok {
+		for i := range values {
+			values[i] = REDACTED
+		}
+	}
+}
+
+// RedactJSON ... replaces the values at the given paths of JSON request and response bodies, such as data.vin.value.
+// A * segment matches every element of an array or every field of an object, e.g. data.doors.values.*.location.value
+func RedactJSON(paths ...string) Redactor {
+	split := make([][]string, len(paths))
+	for i, path := range paths {
+		split[i] = strings.Split(path, ".")
+	}
+
+	return func(i *Interaction) {
+		i.Request.Body = redactBody(i.Request.Body, split)
+		i.Response.Body = redactBody(i.Response.Body, split)
+	}
+}
+
+// redactBody ... returns the body with the paths redacted, or as it was if it isn't JSON or has none of the paths
+func redactBody(body Body, paths [][]string) Body {
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return body
+	}
+
+	redacted := false
+	for _, path := range paths {
+		if redactPath(doc, path) {
+			redacted = true
+		}
+	}
+	if !redacted {
+		return body
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return body
+	}
+	return b
+}
+
+func redactPath(doc interface{}, path []string) bool {
+	redacted := false
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if path[0] != "*" && path[0] != key {
+				continue
+			}
+			if len(path) == 1 {
+				v[key] = REDACTED
+				redacted = true
+			} else if redactPath(child, path[1:]) {
+				redacted = true
+			}
+		}
+	case []interface{}:
+		if path[0] != "*" {
+			return false
+		}
+		for i, child := range v {
+			if len(path) == 1 {
+				v[i] = REDACTED
+				redacted = true
+			} else if redactPath(child, path[1:]) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,141 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	STATE_CLOSED    = "closed"
+	STATE_OPEN      = "open"
+	STATE_HALF_OPEN = "half-open"
+)
+
+// ErrOpen ... returned by Allow while the breaker is rejecting calls
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Breaker ... stops calls to an upstream after `threshold` consecutive failures. Once `cooldown` has passed
+// a single trial call is let through: success closes the breaker again, failure re-opens it for another cooldown.
+// A threshold of zero or less disables the breaker
+type Breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     string
+	failures  int
+	openedAt  time.Time
+	trial     bool
+	now       func() time.Time
+}
+
+// New ... returns a closed breaker
+func New(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     STATE_CLOSED,
+		now:       time.Now,
+	}
+}
+
+// Allow ... returns ErrOpen if the call should not be made. Every allowed call must be followed by Success or Failure
+func (b *Breaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState() {
+	case STATE_OPEN:
+		return ErrOpen
+	case STATE_HALF_OPEN:
+		if b.trial {
+			return ErrOpen
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+// Success ... records a successful call, closing the breaker
+func (b *Breaker) Success() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = STATE_CLOSED
+	b.failures = 0
+	b.trial = false
+}
+
+// Failure ... records a failed call, opening the breaker once the threshold is reached or the trial call failed
+func (b *Breaker) Failure() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.currentState() == STATE_HALF_OPEN || (b.threshold > 0 && b.failures >= b.threshold) {
+		b.state = STATE_OPEN
+		b.openedAt = b.now()
+	}
+	b.trial = false
+}
+
+// Reset ... closes the breaker and forgets the failures so far, as if GM had just succeeded
+func (b *Breaker) Reset() {
+	b.Success()
+}
+
+// SetLimits ... changes the threshold and cooldown of the breaker, keeping its state. An open breaker stays open until
+// the new cooldown has passed since it opened
+func (b *Breaker) SetLimits(threshold int, cooldown time.Duration) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.threshold = threshold
+	b.cooldown = cooldown
+}
+
+// Limits ... returns the current threshold and cooldown of the breaker
+func (b *Breaker) Limits() (threshold int, cooldown time.Duration) {
+	if b == nil {
+		return 0, 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.threshold, b.cooldown
+}
+
+// State ... returns whether the breaker is closed, open or waiting on a trial call
+func (b *Breaker) State() string {
+	if b == nil {
+		return STATE_CLOSED
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentState()
+}
+
+// currentState ... an open breaker becomes half-open once the cooldown has passed. Callers must hold the lock
+func (b *Breaker) currentState() string {
+	if b.state == STATE_OPEN && b.now().Sub(b.openedAt) >= b.cooldown {
+		b.state = STATE_HALF_OPEN
+	}
+	return b.state
+}
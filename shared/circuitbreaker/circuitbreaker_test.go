@@ -0,0 +1,114 @@
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBreaker(threshold int, cooldown time.Duration) (*Breaker, *time.Time) {
+	now := time.Date(2020, 11, 2, 18, 0, 0, 0, time.UTC)
+	b := New(threshold, cooldown)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	b, _ := newTestBreaker(3, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, b.Allow())
+		b.Failure()
+	}
+	assert.Equal(t, STATE_CLOSED, b.State())
+
+	// a success resets the consecutive failures
+	assert.NoError(t, b.Allow())
+	b.Success()
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, b.Allow())
+		b.Failure()
+	}
+	assert.Equal(t, STATE_CLOSED, b.State())
+
+	assert.NoError(t, b.Allow())
+	b.Failure()
+	assert.Equal(t, STATE_OPEN, b.State())
+	assert.Equal(t, ErrOpen, b.Allow())
+}
+
+func TestBreakerHalfOpenTrial(t *testing.T) {
+	b, now := newTestBreaker(1, time.Minute)
+
+	assert.NoError(t, b.Allow())
+	b.Failure()
+	assert.Equal(t, ErrOpen, b.Allow())
+
+	*now = now.Add(time.Minute)
+	assert.Equal(t, STATE_HALF_OPEN, b.State())
+
+	// only one trial call is let through
+	assert.NoError(t, b.Allow())
+	assert.Equal(t, ErrOpen, b.Allow())
+
+	// a failed trial re-opens the breaker for another cooldown
+	b.Failure()
+	assert.Equal(t, STATE_OPEN, b.State())
+	*now = now.Add(30 * time.Second)
+	assert.Equal(t, ErrOpen, b.Allow())
+
+	*now = now.Add(30 * time.Second)
+	assert.NoError(t, b.Allow())
+	b.Success()
+	assert.Equal(t, STATE_CLOSED, b.State())
+	assert.NoError(t, b.Allow())
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b, _ := newTestBreaker(0, time.Minute)
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, b.Allow())
+		b.Failure()
+	}
+	assert.Equal(t, STATE_CLOSED, b.State())
+
+	var nilBreaker *Breaker
+	assert.NoError(t, nilBreaker.Allow())
+	nilBreaker.Failure()
+	assert.Equal(t, STATE_CLOSED, nilBreaker.State())
+}
+
+func TestBreakerSetLimits(t *testing.T) {
+	b, now := newTestBreaker(3, time.Minute)
+
+	assert.NoError(t, b.Allow())
+	b.Failure()
+	b.SetLimits(2, 10*time.Second)
+	threshold, cooldown := b.Limits()
+	assert.Equal(t, 2, threshold)
+	assert.Equal(t, 10*time.Second, cooldown)
+
+	// the failures so far count against the new threshold
+	assert.NoError(t, b.Allow())
+	b.Failure()
+	assert.Equal(t, STATE_OPEN, b.State())
+
+	*now = now.Add(10 * time.Second)
+	assert.Equal(t, STATE_HALF_OPEN, b.State())
+}
+
+func TestBreakerReset(t *testing.T) {
+	b, _ := newTestBreaker(1, time.Minute)
+
+	assert.NoError(t, b.Allow())
+	b.Failure()
+	assert.Equal(t, STATE_OPEN, b.State())
+
+	b.Reset()
+	assert.Equal(t, STATE_CLOSED, b.State())
+	assert.NoError(t, b.Allow())
+
+	var nilBreaker *Breaker
+	nilBreaker.Reset()
+}
@@ -0,0 +1,530 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	loghelper "app_api/shared/loghelpers"
+
+	"github.com/pelletier/go-toml"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	ENVIRONMENT_DEVELOPMENT = "development"
+	ENVIRONMENT_TESTING     = "testing"
+	ENVIRONMENT_PRODUCTION  = "production"
+
+	// ENV_CONFIG_FILE ... the config file, when the --config flag isn't given
+	ENV_CONFIG_FILE = "CONFIG_FILE"
+
+	// REDACTED ... replaces the value of secrets in the printed config
+	REDACTED = "[REDACTED]"
+)
+
+// Config ... the settings of the API. Each setting is read from, lowest precedence first: its default, its key in the
+// config file, its environment variable and its flag. The flag is the environment variable in lower case with dashes,
+// e.g. GM_RATE_LIMIT is gm.rate_limit in the file and --gm-rate-limit on the command line.
+//
+// A secret can also be read from a file, named by the setting with a _file suffix, e.g. API_KEYS_FILE, api_keys_file
+// and --api-keys-file. The settings tagged reload apply without a restart, see Watcher
+type Config struct {
+	Environment string `config:"environment" env:"ENVIRONMENT" usage:"development, testing or production, which sets the default log level"`
+	Port        int    `config:"port" env:"PORT" usage:"port of the REST API"`
+	GRPCPort    int    `config:"grpc_port" env:"GRPC_PORT" usage:"port of the gRPC API"`
+	AdminPort   int    `config:"admin_port" env:"ADMIN_PORT" usage:"port of the admin API"`
+	AdminHost   string `config:"admin_host" env:"ADMIN_HOST" usage:"address the admin API listens on, every interface when empty"`
+	DBFile      string `config:"db_file" env:"DB_FILE" usage:"database of the registry, telemetry history, polling schedules and webhooks"`
+	APIKeys     string `config:"api_keys" env:"API_KEYS" secret:"true" usage:"API keys and their scopes, e.g. k3y1=vehicles:read;k3y2=*. Every route is open when empty"`
+
+	Log       LogConfig       `config:"log"`
+	GM        GMConfig        `config:"gm"`
+	Poller    PollerConfig    `config:"poller"`
+	Command   CommandConfig   `config:"command"`
+	Telemetry TelemetryConfig `config:"telemetry"`
+	Events    EventsConfig    `config:"events"`
+	GraphQL   GraphQLConfig   `config:"graphql"`
+
+	// File ... the config file the settings were read from, if any
+	File string `config:"-"`
+	// PrintConfig ... set by --print-config, to print the settings instead of starting the API
+	PrintConfig bool `config:"-"`
+}
+
+// LogConfig ... where and how the API logs
+type LogConfig struct {
+	Level       string        `config:"level" env:"LOG_LEVEL" reload:"true" usage:"trace, debug, info, warning or error. The level of the environment when empty"`
+	Levels      string        `config:"levels" env:"LOG_LEVELS" reload:"true" usage:"levels of packages logging from another level, e.g. gm=debug,apis/poller=warning"`
+	Format      string        `config:"format" env:"LOG_FORMAT" usage:"logfmt or json"`
+	Sinks       string        `config:"sinks" env:"LOG_SINKS" usage:"comma separated file, stdout and syslog. The file, besides stdout in development, when empty; stdout without a file"`
+	File        string        `config:"file" env:"LOG_FILE" usage:"file the logs are appended to"`
+	MaxSize     int           `config:"max_size" env:"LOG_MAX_SIZE" usage:"megabytes from which the log file is rotated, never when 0"`
+	RotateEvery time.Duration `config:"rotate_every" env:"LOG_ROTATE_EVERY" usage:"interval the log file is rotated at from midnight UTC, e.g. 24h, never when 0"`
+	MaxBackups  int           `config:"max_backups" env:"LOG_MAX_BACKUPS" usage:"rotated log files kept, all when 0"`
+	MaxAge      time.Duration `config:"max_age" env:"LOG_MAX_AGE" usage:"how long rotated log files are kept, forever when 0"`
+	Compress    bool          `config:"compress" env:"LOG_COMPRESS" usage:"gzip the rotated log files"`
+}
+
+// GMConfig ... the connection to GM's API
+type GMConfig struct {
+	URL              string        `config:"url" env:"GM_API_URL" reload:"true" usage:"GM API to call instead of GM's own, such as the simulator of cmd/loadtest"`
+	RateLimit        float64       `config:"rate_limit" env:"GM_RATE_LIMIT" reload:"true" usage:"requests per second to GM"`
+	RateBurst        int           `config:"rate_burst" env:"GM_RATE_BURST" reload:"true" usage:"requests to GM allowed in a burst over the rate limit"`
+	BreakerThreshold int           `config:"breaker_threshold" env:"GM_BREAKER_THRESHOLD" reload:"true" usage:"consecutive failures of GM before requests fail fast"`
+	BreakerCooldown  time.Duration `config:"breaker_cooldown" env:"GM_BREAKER_COOLDOWN" reload:"true" usage:"how long requests to GM fail fast for"`
+	RecordCassette   string        `config:"record_cassette" env:"GM_RECORD_CASSETTE" usage:"file the latest requests to GM are saved to on shutdown"`
+	ReplayCassette   string        `config:"replay_cassette" env:"GM_REPLAY_CASSETTE" usage:"cassette answering the requests to GM instead of GM"`
+	Faults           string        `config:"faults" env:"GM_FAULTS" usage:"file of fault rules injected into the requests to GM, in the development and testing environments only"`
+}
+
+// PollerConfig ... the background polling of registered vehicles
+type PollerConfig struct {
+	Concurrency int `config:"concurrency" env:"POLLER_CONCURRENCY" reload:"true" usage:"most polls in flight at once"`
+}
+
+// CommandConfig ... the pace of the engine commands of bulk jobs
+type CommandConfig struct {
+	VehicleRateLimit float64 `config:"vehicle_rate_limit" env:"COMMAND_VEHICLE_RATE_LIMIT" reload:"true" usage:"commands per second sent to the same vehicle"`
+	VehicleRateBurst int     `config:"vehicle_rate_burst" env:"COMMAND_VEHICLE_RATE_BURST" reload:"true" usage:"commands sent to the same vehicle in a burst over its rate limit"`
+}
+
+// TelemetryConfig ... the history of the vehicle readings
+type TelemetryConfig struct {
+	Retention time.Duration `config:"retention" env:"TELEMETRY_RETENTION" usage:"how long readings are kept, e.g. 720h"`
+}
+
+// EventsConfig ... the thresholds of the vehicle events
+type EventsConfig struct {
+	FuelLowThreshold        float64 `config:"fuel_low_threshold" env:"FUEL_LOW_THRESHOLD" reload:"true" usage:"fuel percentage below which fuel_low is emitted"`
+	BatteryChargedThreshold float64 `config:"battery_charged_threshold" env:"BATTERY_CHARGED_THRESHOLD" reload:"true" usage:"battery percentage from which battery_charged is emitted"`
+}
+
+// GraphQLConfig ... the limits of GraphQL queries
+type GraphQLConfig struct {
+	MaxDepth         int    `config:"max_depth" env:"GRAPHQL_MAX_DEPTH" usage:"deepest query nesting allowed"`
+	MaxComplexity    int    `config:"max_complexity" env:"GRAPHQL_MAX_COMPLEXITY" usage:"highest query complexity allowed"`
+	PersistedQueries string `config:"persisted_queries" env:"GRAPHQL_PERSISTED_QUERIES" usage:"JSON file of queries keyed by their hex SHA-256"`
+	Allowlist        bool   `config:"allowlist" env:"GRAPHQL_ALLOWLIST" usage:"only run the persisted queries"`
+}
+
+// setting ... a field of the config, with its names in each source
+type setting struct {
+	key    string
+	env    string
+	flag   string
+	usage  string
+	secret bool
+	reload bool
+	value  reflect.Value
+}
+
+// settings ... every setting of the config, in the order of its fields
+func settings(v reflect.Value, prefix string) []setting {
+	var res []setting
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("config")
+		if key == "-" {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			res = append(res, settings(v.Field(i), prefix+key+".")...)
+			continue
+		}
+		env := field.Tag.Get("env")
+		res = append(res, setting{
+			key:    prefix + key,
+			env:    env,
+			flag:   strings.ReplaceAll(strings.ToLower(env), "_", "-"),
+			usage:  field.Tag.Get("usage"),
+			secret: field.Tag.Get("secret") == "true",
+			reload: field.Tag.Get("reload") == "true",
+			value:  v.Field(i),
+		})
+	}
+	return res
+}
+
+// set ... parses the raw value of a setting into it
+func (s setting) set(raw string) error {
+	switch s.value.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, e.g. 30s", raw)
+		}
+		s.value.SetInt(int64(d))
+		return nil
+	}
+
+	switch s.value.Kind() {
+	case reflect.String:
+		s.value.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		s.value.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		s.value.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q, true or false", raw)
+		}
+		s.value.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported setting type %s", s.value.Type())
+	}
+	return nil
+}
+
+// source ... where settings are read from, such as the environment
+type source struct {
+	// names ... the name of a setting in the source, and of the file holding it if it is a secret
+	names func(s setting) (name, file string)
+	// label ... how errors refer to the named setting
+	label  func(name string) string
+	lookup func(name string) (string, bool)
+}
+
+// flagValue ... records the value of a flag, applied once every other source was
+type flagValue struct {
+	values map[string]string
+	name   string
+	isBool bool
+}
+
+func (f *flagValue) String() string { return "" }
+
+func (f *flagValue) Set(raw string) error {
+	f.values[f.name] = raw
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool { return f.isBool }
+
+// Load ... reads the config from defaults, the config file of the --config flag or $CONFIG_FILE, the environment and
+// the command line args. Every invalid setting is reported at once. -h returns flag.ErrHelp
+func Load(args []string, defaults Config) (*Config, error) {
+	return load(args, defaults, os.LookupEnv)
+}
+
+func load(args []string, defaults Config, lookupEnv func(string) (string, bool)) (*Config, error) {
+	c := defaults
+	all := settings(reflect.ValueOf(&c).Elem(), "")
+
+	flags := map[string]string{}
+	fs := flag.NewFlagSet("app_api", flag.ContinueOnError)
+	fs.StringVar(&c.File, "config", "", "YAML or TOML config file (env "+ENV_CONFIG_FILE+")")
+	fs.BoolVar(&c.PrintConfig, "print-config", false, "print the config, with secrets redacted, and exit")
+	for _, s := range all {
+		fs.Var(&flagValue{values: flags, name: s.flag, isBool: s.value.Kind() == reflect.Bool}, s.flag, fmt.Sprintf("%s (env %s)", s.usage, s.env))
+		if s.secret {
+			fs.Var(&flagValue{values: flags, name: s.flag + "-file"}, s.flag+"-file", fmt.Sprintf("file holding --%s (env %s_FILE)", s.flag, s.env))
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	var sources []source
+	if c.File == "" {
+		c.File, _ = lookupEnv(ENV_CONFIG_FILE)
+	}
+	var errs []string
+	if c.File != "" {
+		values, err := readFile(c.File)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range unknownKeys(values, all) {
+			errs = append(errs, fmt.Sprintf("%s: unknown setting %s", c.File, key))
+		}
+		sources = append(sources, source{
+			names: func(s setting) (string, string) { return s.key, s.key + "_file" },
+			label: func(name string) string { return c.File + ": " + name },
+			lookup: func(name string) (string, bool) {
+				v, ok := values[name]
+				return v, ok
+			},
+		})
+	}
+	sources = append(sources,
+		source{
+			names:  func(s setting) (string, string) { return s.env, s.env + "_FILE" },
+			label:  func(name string) string { return name },
+			lookup: lookupEnv,
+		},
+		source{
+			names: func(s setting) (string, string) { return s.flag, s.flag + "-file" },
+			label: func(name string) string { return "--" + name },
+			lookup: func(name string) (string, bool) {
+				v, ok := flags[name]
+				return v, ok
+			},
+		},
+	)
+
+	for _, src := range sources {
+		for _, s := range all {
+			if err := src.apply(s); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	errs = append(errs, c.validate()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid config:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return &c, nil
+}
+
+// apply ... sets the setting from the source, if the source has it
+func (src source) apply(s setting) error {
+	name, file := src.names(s)
+	raw, ok := src.lookup(name)
+	if s.secret {
+		path, fromFile := src.lookup(file)
+		if ok && fromFile {
+			return fmt.Errorf("%s and %s can't both be set", src.label(name), src.label(file))
+		}
+		if fromFile {
+			secret, err := ioutil.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("%s: %v", src.label(file), err)
+			}
+			raw, ok = strings.TrimRight(string(secret), "\r\n"), true
+		}
+	}
+	if !ok {
+		return nil
+	}
+	if err := s.set(raw); err != nil {
+		return fmt.Errorf("%s: %v", src.label(name), err)
+	}
+	return nil
+}
+
+// readFile ... the settings of a YAML or TOML config file, keyed by their dotted keys
+func readFile(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var tree map[string]interface{}
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		var t *toml.Tree
+		if t, err = toml.LoadBytes(data); err == nil {
+			tree = t.ToMap()
+		}
+	default:
+		return nil, fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	if err := flatten(values, "", tree); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// flatten ... adds the values of the tree to values, keyed by their path joined with dots
+func flatten(values map[string]string, prefix string, tree interface{}) error {
+	switch t := tree.(type) {
+	case map[string]interface{}:
+		for k, v := range t {
+			if err := flatten(values, prefix+k+".", v); err != nil {
+				return err
+			}
+		}
+	case map[interface{}]interface{}:
+		for k, v := range t {
+			if err := flatten(values, fmt.Sprintf("%s%v.", prefix, k), v); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		return fmt.Errorf("%s must be a single value", strings.TrimSuffix(prefix, "."))
+	case nil:
+		values[strings.TrimSuffix(prefix, ".")] = ""
+	default:
+		values[strings.TrimSuffix(prefix, ".")] = fmt.Sprint(t)
+	}
+	return nil
+}
+
+// unknownKeys ... the keys of the file which aren't settings, sorted
+func unknownKeys(values map[string]string, all []setting) []string {
+	known := map[string]bool{}
+	for _, s := range all {
+		known[s.key] = true
+		if s.secret {
+			known[s.key+"_file"] = true
+		}
+	}
+
+	var unknown []string
+	for key := range values {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// validate ... the ways the settings are invalid, none if they are valid
+func (c *Config) validate() []string {
+	var errs []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Sprintf(format, args...))
+		}
+	}
+
+	switch c.Environment {
+	case "", ENVIRONMENT_DEVELOPMENT, ENVIRONMENT_TESTING, ENVIRONMENT_PRODUCTION:
+	default:
+		errs = append(errs, fmt.Sprintf("ENVIRONMENT must be development, testing or production, not %q", c.Environment))
+	}
+	if c.Log.Level != "" {
+		_, err := log.ParseLevel(c.Log.Level)
+		check(err == nil, "LOG_LEVEL must be trace, debug, info, warning or error, not %q", c.Log.Level)
+	}
+	if _, err := loghelper.ParseLevels(c.Log.Levels); err != nil {
+		errs = append(errs, "LOG_LEVELS: "+err.Error())
+	}
+	switch c.Log.Format {
+	case "", loghelper.FORMAT_LOGFMT, loghelper.FORMAT_JSON:
+	default:
+		errs = append(errs, fmt.Sprintf("LOG_FORMAT must be logfmt or json, not %q", c.Log.Format))
+	}
+	for _, sink := range c.LogSinks() {
+		switch sink {
+		case loghelper.SINK_FILE:
+			check(c.Log.File != "", "LOG_SINKS has file, which needs LOG_FILE")
+		case loghelper.SINK_STDOUT, loghelper.SINK_SYSLOG:
+		default:
+			errs = append(errs, fmt.Sprintf("LOG_SINKS must list file, stdout or syslog, not %q", sink))
+		}
+	}
+	check(c.Log.MaxSize >= 0, "LOG_MAX_SIZE can't be negative")
+	check(c.Log.RotateEvery >= 0, "LOG_ROTATE_EVERY can't be negative")
+	check(c.Log.MaxBackups >= 0, "LOG_MAX_BACKUPS can't be negative")
+	check(c.Log.MaxAge >= 0, "LOG_MAX_AGE can't be negative")
+	check(c.Port > 0 && c.Port <= 65535, "PORT must be between 1 and 65535")
+	check(c.GRPCPort > 0 && c.GRPCPort <= 65535, "GRPC_PORT must be between 1 and 65535")
+	check(c.AdminPort > 0 && c.AdminPort <= 65535, "ADMIN_PORT must be between 1 and 65535")
+	check(c.Port != c.GRPCPort && c.Port != c.AdminPort && c.GRPCPort != c.AdminPort, "PORT, GRPC_PORT and ADMIN_PORT must differ")
+	check(c.DBFile != "", "DB_FILE is required")
+
+	if c.GM.URL != "" {
+		u, err := url.Parse(c.GM.URL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "GM_API_URL must be an http or https URL")
+	}
+	check(c.GM.RateLimit > 0, "GM_RATE_LIMIT must be above 0")
+	check(c.GM.RateBurst >= 1, "GM_RATE_BURST must be at least 1")
+	check(c.GM.BreakerThreshold >= 1, "GM_BREAKER_THRESHOLD must be at least 1")
+	check(c.GM.BreakerCooldown > 0, "GM_BREAKER_COOLDOWN must be above 0")
+	check(c.GM.RecordCassette == "" || c.GM.ReplayCassette == "", "GM_RECORD_CASSETTE and GM_REPLAY_CASSETTE can't both be set")
+
+	check(c.Poller.Concurrency >= 1, "POLLER_CONCURRENCY must be at least 1")
+
+	check(c.Command.VehicleRateLimit > 0, "COMMAND_VEHICLE_RATE_LIMIT must be above 0")
+	check(c.Command.VehicleRateBurst >= 1, "COMMAND_VEHICLE_RATE_BURST must be at least 1")
+
+	check(c.Telemetry.Retention > 0, "TELEMETRY_RETENTION must be above 0")
+
+	check(c.Events.FuelLowThreshold >= 0 && c.Events.FuelLowThreshold <= 100, "FUEL_LOW_THRESHOLD must be a percentage")
+	check(c.Events.BatteryChargedThreshold >= 0 && c.Events.BatteryChargedThreshold <= 100, "BATTERY_CHARGED_THRESHOLD must be a percentage")
+
+	check(c.GraphQL.MaxDepth >= 1, "GRAPHQL_MAX_DEPTH must be at least 1")
+	check(c.GraphQL.MaxComplexity >= 1, "GRAPHQL_MAX_COMPLEXITY must be at least 1")
+	check(!c.GraphQL.Allowlist || c.GraphQL.PersistedQueries != "", "GRAPHQL_ALLOWLIST needs GRAPHQL_PERSISTED_QUERIES, or no query would run")
+	return errs
+}
+
+// Print ... writes the settings as a YAML config file, with the value of secrets redacted
+func (c *Config) Print(w io.Writer) error {
+	out, err := yaml.Marshal(printable(reflect.ValueOf(c).Elem()))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// Redacted ... the settings keyed as in the config file, with the value of secrets redacted
+func (c *Config) Redacted() map[string]interface{} {
+	return redacted(printable(reflect.ValueOf(c).Elem()))
+}
+
+func redacted(section yaml.MapSlice) map[string]interface{} {
+	res := make(map[string]interface{}, len(section))
+	for _, item := range section {
+		if nested, ok := item.Value.(yaml.MapSlice); ok {
+			res[item.Key.(string)] = redacted(nested)
+		} else {
+			res[item.Key.(string)] = item.Value
+		}
+	}
+	return res
+}
+
+// printable ... the settings of a config section, in the order of its fields
+func printable(v reflect.Value) yaml.MapSlice {
+	var res yaml.MapSlice
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("config")
+		if key == "-" {
+			continue
+		}
+
+		var value interface{}
+		switch fv := v.Field(i); {
+		case field.Tag.Get("secret") == "true":
+			value = ""
+			if fv.String() != "" {
+				value = REDACTED
+			}
+		case field.Type == reflect.TypeOf(time.Duration(0)):
+			value = fv.Interface().(time.Duration).String()
+		case field.Type.Kind() == reflect.Struct:
+			value = printable(fv)
+		default:
+			value = fv.Interface()
+		}
+		res = append(res, yaml.MapItem{Key: key, Value: value})
+	}
+	return res
+}
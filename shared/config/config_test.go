@@ -0,0 +1,214 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDefaults() Config {
+	return Config{
+		Port:      8003,
+		GRPCPort:  8004,
+		AdminPort: 8006,
+		DBFile:    "app_api.db",
+		GM:        GMConfig{RateLimit: 10, RateBurst: 20, BreakerThreshold: 5, BreakerCooldown: 30 * time.Second},
+		Poller:    PollerConfig{Concurrency: 5},
+		Command:   CommandConfig{VehicleRateLimit: 0.2, VehicleRateBurst: 1},
+		Telemetry: TelemetryConfig{Retention: 30 * 24 * time.Hour},
+		Events:    EventsConfig{FuelLowThreshold: 15, BatteryChargedThreshold: 95},
+		GraphQL:   GraphQLConfig{MaxDepth: 6, MaxComplexity: 1000},
+	}
+}
+
+func testEnv(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	c, err := load(nil, testDefaults(), testEnv(nil))
+	require.NoError(t, err)
+	assert.Equal(t, testDefaults(), *c)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+environment: testing
+port: 9000
+grpc_port: 9001
+gm:
+  rate_limit: 2.5
+  breaker_cooldown: 1m
+poller:
+  concurrency: 2
+`)
+	env := testEnv(map[string]string{
+		"PORT":          "9100",
+		"GM_RATE_LIMIT": "4",
+	})
+
+	c, err := load([]string{"--config", path, "--port", "9200", "--graphql-max-depth=3"}, testDefaults(), env)
+	require.NoError(t, err)
+	assert.Equal(t, path, c.File)
+	assert.Equal(t, 9200, c.Port, "the flag wins over the environment and the file")
+	assert.Equal(t, 9001, c.GRPCPort, "the file wins over the default")
+	assert.Equal(t, 4.0, c.GM.RateLimit, "the environment wins over the file")
+	assert.Equal(t, 20, c.GM.RateBurst)
+	assert.Equal(t, time.Minute, c.GM.BreakerCooldown)
+	assert.Equal(t, 2, c.Poller.Concurrency)
+	assert.Equal(t, 3, c.GraphQL.MaxDepth)
+	assert.Equal(t, ENVIRONMENT_TESTING, c.Environment)
+}
+
+func TestLoadFile(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[log]
+file = "app_api.log"
+max_size = 100
+rotate_every = "24h"
+
+[gm]
+url = "http://localhost:8005"
+rate_burst = 40
+
+[graphql]
+persisted_queries = "queries.json"
+allowlist = true
+`)
+
+	c, err := load(nil, testDefaults(), testEnv(map[string]string{ENV_CONFIG_FILE: path}))
+	require.NoError(t, err)
+	assert.Equal(t, "app_api.log", c.Log.File)
+	assert.Equal(t, 100, c.Log.MaxSize)
+	assert.Equal(t, 24*time.Hour, c.Log.RotateEvery)
+	assert.Equal(t, "http://localhost:8005", c.GM.URL)
+	assert.Equal(t, 40, c.GM.RateBurst)
+	assert.True(t, c.GraphQL.Allowlist)
+
+	_, err = load([]string{"--config", writeFile(t, "config.json", `{}`)}, testDefaults(), testEnv(nil))
+	assert.Error(t, err)
+
+	_, err = load([]string{"--config", "missing.yaml"}, testDefaults(), testEnv(nil))
+	assert.Error(t, err)
+}
+
+func TestLoadBoolFlag(t *testing.T) {
+	c, err := load([]string{"--graphql-allowlist", "--graphql-persisted-queries", "queries.json"}, testDefaults(), testEnv(nil))
+	require.NoError(t, err)
+	assert.True(t, c.GraphQL.Allowlist)
+
+	c, err = load([]string{"--graphql-allowlist=false"}, testDefaults(), testEnv(map[string]string{"GRAPHQL_ALLOWLIST": "true"}))
+	require.NoError(t, err)
+	assert.False(t, c.GraphQL.Allowlist)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+port: 8003
+gm:
+  rate_limt: 5
+  breaker_cooldown: 30
+`)
+	env := testEnv(map[string]string{
+		"ENVIRONMENT":        "staging",
+		"POLLER_CONCURRENCY": "many",
+		"GM_RECORD_CASSETTE": "gm.json",
+		"GM_REPLAY_CASSETTE": "gm.json",
+	})
+
+	_, err := load([]string{"--config", path, "--grpc-port", "8003"}, testDefaults(), env)
+	require.Error(t, err)
+	assert.Equal(t, `invalid config:
+  `+path+`: unknown setting gm.rate_limt
+  `+path+`: gm.breaker_cooldown: invalid duration "30", e.g. 30s
+  POLLER_CONCURRENCY: invalid integer "many"
+  ENVIRONMENT must be development, testing or production, not "staging"
+  PORT, GRPC_PORT and ADMIN_PORT must differ
+  GM_RECORD_CASSETTE and GM_REPLAY_CASSETTE can't both be set`, err.Error())
+
+	_, err = load([]string{"--gm-api-url", "localhost:8005", "--fuel-low-threshold", "150"}, testDefaults(), testEnv(nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "GM_API_URL must be an http or https URL")
+	assert.Contains(t, err.Error(), "FUEL_LOW_THRESHOLD must be a percentage")
+
+	_, err = load([]string{"--unknown"}, testDefaults(), testEnv(nil))
+	assert.Error(t, err)
+
+	_, err = load([]string{"--log-format", "xml", "--log-sinks", "file,kafka", "--log-levels", "gm", "--log-max-backups", "-1"}, testDefaults(), testEnv(nil))
+	require.Error(t, err)
+	assert.Equal(t, `invalid config:
+  LOG_LEVELS: invalid package level "gm", e.g. gm=debug
+  LOG_FORMAT must be logfmt or json, not "xml"
+  LOG_SINKS has file, which needs LOG_FILE
+  LOG_SINKS must list file, stdout or syslog, not "kafka"
+  LOG_MAX_BACKUPS can't be negative`, err.Error())
+}
+
+func TestLoadSecretFromFile(t *testing.T) {
+	keys := writeFile(t, "api_keys", "k3y=*\n")
+
+	c, err := load(nil, testDefaults(), testEnv(map[string]string{"API_KEYS_FILE": keys}))
+	require.NoError(t, err)
+	assert.Equal(t, "k3y=*", c.APIKeys)
+
+	c, err = load([]string{"--api-keys", "flag=*"}, testDefaults(), testEnv(map[string]string{"API_KEYS_FILE": keys}))
+	require.NoError(t, err)
+	assert.Equal(t, "flag=*", c.APIKeys, "the flag wins over the file of the environment")
+
+	path := writeFile(t, "config.yaml", "api_keys_file: "+keys+"\n")
+	c, err = load([]string{"--config", path}, testDefaults(), testEnv(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "k3y=*", c.APIKeys)
+
+	_, err = load(nil, testDefaults(), testEnv(map[string]string{"API_KEYS": "k3y=*", "API_KEYS_FILE": keys}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "API_KEYS and API_KEYS_FILE can't both be set")
+
+	_, err = load([]string{"--api-keys-file", "missing"}, testDefaults(), testEnv(nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--api-keys-file: open missing")
+}
+
+func TestPrint(t *testing.T) {
+	c, err := load([]string{"--api-keys", "k3y=*", "--print-config"}, testDefaults(), testEnv(nil))
+	require.NoError(t, err)
+	assert.True(t, c.PrintConfig)
+
+	var out bytes.Buffer
+	require.NoError(t, c.Print(&out))
+	assert.NotContains(t, out.String(), "k3y")
+	assert.Contains(t, out.String(), "api_keys: '[REDACTED]'")
+	assert.Contains(t, out.String(), "gm:\n  url: \"\"\n  rate_limit: 10\n")
+	assert.Contains(t, out.String(), "breaker_cooldown: 30s")
+
+	settings := c.Redacted()
+	assert.Equal(t, REDACTED, settings["api_keys"])
+	assert.Equal(t, 8006, settings["admin_port"])
+	assert.Equal(t, "30s", settings["gm"].(map[string]interface{})["breaker_cooldown"])
+
+	// the printed config is a valid config file
+	path := writeFile(t, "config.yaml", out.String())
+	printed, err := load([]string{"--config", path}, Config{}, testEnv(nil))
+	require.NoError(t, err)
+	printed.File, printed.APIKeys, c.APIKeys, c.PrintConfig = "", "", "", false
+	assert.Equal(t, *c, *printed)
+}
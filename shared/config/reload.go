@@ -0,0 +1,246 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	loghelper "app_api/shared/loghelpers"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// reloadDebounce ... how long the config file must be left alone before it is reloaded, as editors write it in steps
+var reloadDebounce = 200 * time.Millisecond
+
+// Change ... a setting which differs between two configs
+type Change struct {
+	Key string
+	Old string
+	New string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Key, c.Old, c.New)
+}
+
+// Diff ... the settings which differ between two configs, with the value of secrets redacted
+func Diff(old, new *Config) []Change {
+	olds := settings(reflect.ValueOf(old).Elem(), "")
+	news := settings(reflect.ValueOf(new).Elem(), "")
+
+	var changes []Change
+	for i, s := range olds {
+		if reflect.DeepEqual(s.value.Interface(), news[i].value.Interface()) {
+			continue
+		}
+		changes = append(changes, Change{Key: s.key, Old: s.format(), New: news[i].format()})
+	}
+	return changes
+}
+
+// format ... the value of the setting as it is logged
+func (s setting) format() string {
+	switch v := s.value.Interface().(type) {
+	case string:
+		if s.secret && v != "" {
+			return REDACTED
+		}
+		return fmt.Sprintf("%q", v)
+	case time.Duration:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Level ... the log level, LOG_LEVEL or the level of the environment
+func (c *Config) Level() log.Level {
+	if level, err := log.ParseLevel(c.Log.Level); err == nil {
+		return level
+	}
+	switch c.Environment {
+	case ENVIRONMENT_DEVELOPMENT:
+		return log.TraceLevel
+	case ENVIRONMENT_TESTING:
+		return log.DebugLevel
+	case ENVIRONMENT_PRODUCTION:
+		return log.ErrorLevel
+	default:
+		return log.InfoLevel
+	}
+}
+
+// PackageLevels ... the levels of the packages logging from another level, LOG_LEVELS
+func (c *Config) PackageLevels() map[string]log.Level {
+	levels, _ := loghelper.ParseLevels(c.Log.Levels)
+	return levels
+}
+
+// LogSinks ... the sinks of LOG_SINKS. When it is empty the file, besides stdout in development, and stdout without a
+// file
+func (c *Config) LogSinks() []string {
+	var sinks []string
+	for _, sink := range strings.Split(c.Log.Sinks, ",") {
+		if sink = strings.TrimSpace(sink); sink != "" {
+			sinks = append(sinks, sink)
+		}
+	}
+	switch {
+	case len(sinks) > 0:
+		return sinks
+	case c.Log.File == "":
+		return []string{loghelper.SINK_STDOUT}
+	case c.Environment == ENVIRONMENT_DEVELOPMENT:
+		return []string{loghelper.SINK_FILE, loghelper.SINK_STDOUT}
+	default:
+		return []string{loghelper.SINK_FILE}
+	}
+}
+
+// Watcher ... reloads the config on a SIGHUP or a change of its file. The settings tagged reload apply right away,
+// through the hooks of OnReload. The others are logged and kept as they are until the API restarts
+type Watcher struct {
+	args      []string
+	defaults  Config
+	lookupEnv func(string) (string, bool)
+
+	// reloading ... one reload at a time, so the hooks see the configs in the order they were loaded
+	reloading sync.Mutex
+
+	mu      sync.Mutex
+	current Config
+	hooks   []func(*Config)
+}
+
+// NewWatcher ... watches the config loaded by Load from args and defaults
+func NewWatcher(current *Config, args []string, defaults Config) *Watcher {
+	return &Watcher{
+		args:      args,
+		defaults:  defaults,
+		lookupEnv: os.LookupEnv,
+		current:   *current,
+	}
+}
+
+// OnReload ... calls hook with the config after every reload which changed a setting tagged reload. Hooks are called
+// without the lock of the watcher, so they may read its Config. Register them before Run
+func (w *Watcher) OnReload(hook func(*Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.hooks = append(w.hooks, hook)
+}
+
+// Config ... the config the API runs with
+func (w *Watcher) Config() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	c := w.current
+	return &c
+}
+
+// Reload ... loads the config again and applies the settings tagged reload, returning those which changed. The others
+// which changed are returned as pending, until a restart. An invalid config is rejected as a whole, and the API keeps
+// running with the current one
+func (w *Watcher) Reload() (applied, pending []Change, err error) {
+	w.reloading.Lock()
+	defer w.reloading.Unlock()
+
+	loaded, err := load(w.args, w.defaults, w.lookupEnv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w.mu.Lock()
+	next := w.current
+	nextSettings := settings(reflect.ValueOf(&next).Elem(), "")
+	loadedSettings := settings(reflect.ValueOf(loaded).Elem(), "")
+	for i, s := range nextSettings {
+		if s.reload {
+			s.value.Set(loadedSettings[i].value)
+		}
+	}
+
+	applied = Diff(&w.current, &next)
+	pending = Diff(&next, loaded)
+	w.current = next
+	hooks := append([]func(*Config){}, w.hooks...)
+	w.mu.Unlock()
+
+	if len(applied) > 0 {
+		for _, hook := range hooks {
+			c := next
+			hook(&c)
+		}
+	}
+	return applied, pending, nil
+}
+
+// reload ... reloads the config, logging what changed
+func (w *Watcher) reload(reason string) {
+	applied, pending, err := w.Reload()
+	if err != nil {
+		log.Errorf("config reload on %s failed, keeping the current config: %v", reason, err)
+		return
+	}
+	for _, change := range applied {
+		log.WithField("setting", change.Key).Infof("config reloaded on %s: %s", reason, change)
+	}
+	for _, change := range pending {
+		log.WithField("setting", change.Key).Warnf("config changed, restart to apply: %s", change)
+	}
+	if len(applied) == 0 && len(pending) == 0 {
+		log.Infof("config reloaded on %s, nothing changed", reason)
+	}
+}
+
+// Run ... reloads the config on every SIGHUP, and every change of its file, until ctx is done
+func (w *Watcher) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	// the directory is watched rather than the file, so the file is still watched after an editor replaces it
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	file := filepath.Clean(w.Config().File)
+	if file != "." {
+		watcher, err := fsnotify.NewWatcher()
+		if err == nil {
+			defer watcher.Close()
+			err = watcher.Add(filepath.Dir(file))
+		}
+		if err != nil {
+			log.Error("failed to watch the config file, reload it with a SIGHUP instead: ", err)
+		} else {
+			events, errs = watcher.Events, watcher.Errors
+		}
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			w.reload("SIGHUP")
+		case event := <-events:
+			if filepath.Clean(event.Name) == file && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				debounce = time.After(reloadDebounce)
+			}
+		case err := <-errs:
+			log.Error("failed to watch the config file: ", err)
+		case <-debounce:
+			debounce = nil
+			w.reload("a change of " + file)
+		}
+	}
+}
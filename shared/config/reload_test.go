@@ -0,0 +1,131 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWatcher(t *testing.T, path string) *Watcher {
+	args := []string{"--config", path}
+	c, err := load(args, testDefaults(), testEnv(nil))
+	require.NoError(t, err)
+
+	w := NewWatcher(c, args, testDefaults())
+	w.lookupEnv = testEnv(nil)
+	return w
+}
+
+func TestDiff(t *testing.T) {
+	old := testDefaults()
+	new := testDefaults()
+	new.APIKeys = "k3y=*"
+	new.GM.URL = "http://localhost:8005"
+	new.GM.BreakerCooldown = time.Minute
+
+	assert.Equal(t, []Change{
+		{Key: "api_keys", Old: `""`, New: REDACTED},
+		{Key: "gm.url", Old: `""`, New: `"http://localhost:8005"`},
+		{Key: "gm.breaker_cooldown", Old: "30s", New: "1m0s"},
+	}, Diff(&old, &new))
+	assert.Empty(t, Diff(&old, &old))
+	assert.Equal(t, "gm.breaker_cooldown: 30s -> 1m0s", Diff(&old, &new)[2].String())
+}
+
+func TestLevel(t *testing.T) {
+	c := testDefaults()
+	assert.Equal(t, log.InfoLevel, c.Level())
+	c.Environment = ENVIRONMENT_PRODUCTION
+	assert.Equal(t, log.ErrorLevel, c.Level())
+	c.Log.Level = "debug"
+	assert.Equal(t, log.DebugLevel, c.Level())
+
+	_, err := load([]string{"--log-level", "loud"}, testDefaults(), testEnv(nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `LOG_LEVEL must be trace, debug, info, warning or error, not "loud"`)
+}
+
+func TestLogSinks(t *testing.T) {
+	c := testDefaults()
+	assert.Equal(t, []string{"stdout"}, c.LogSinks())
+	c.Log.File = "app_api.log"
+	assert.Equal(t, []string{"file"}, c.LogSinks())
+	c.Environment = ENVIRONMENT_DEVELOPMENT
+	assert.Equal(t, []string{"file", "stdout"}, c.LogSinks())
+	c.Log.Sinks = "syslog, file"
+	assert.Equal(t, []string{"syslog", "file"}, c.LogSinks())
+
+	assert.Empty(t, c.PackageLevels())
+	c.Log.Levels = "gm=debug,apis/poller=warning"
+	assert.Equal(t, map[string]log.Level{"gm": log.DebugLevel, "apis/poller": log.WarnLevel}, c.PackageLevels())
+}
+
+func TestWatcherReload(t *testing.T) {
+	path := writeFile(t, "config.yaml", "port: 9000\ngm:\n  rate_limit: 10\n")
+	w := newTestWatcher(t, path)
+
+	var reloaded []*Config
+	w.OnReload(func(c *Config) { reloaded = append(reloaded, c) })
+	w.OnReload(func(c *Config) {
+		assert.Equal(t, c.GM.RateLimit, w.Config().GM.RateLimit, "hooks may read the config they were called with")
+	})
+
+	applied, pending, err := w.Reload()
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+	assert.Empty(t, pending)
+	assert.Empty(t, reloaded, "hooks only run when a setting changed")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("port: 9100\nlog:\n  level: debug\ngm:\n  rate_limit: 20\n"), 0600))
+	applied, pending, err = w.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Key: "log.level", Old: `""`, New: `"debug"`}, {Key: "gm.rate_limit", Old: "10", New: "20"}}, applied)
+	assert.Equal(t, []Change{{Key: "port", Old: "9000", New: "9100"}}, pending)
+
+	// the port needs a restart, so the API keeps running on the old one
+	require.Len(t, reloaded, 1)
+	assert.Equal(t, 20.0, reloaded[0].GM.RateLimit)
+	assert.Equal(t, 9000, reloaded[0].Port)
+	assert.Equal(t, 9000, w.Config().Port)
+	assert.Equal(t, "debug", w.Config().Log.Level)
+
+	// an invalid config is rejected as a whole
+	require.NoError(t, ioutil.WriteFile(path, []byte("gm:\n  rate_limit: 0\n  rate_burst: 40\n"), 0600))
+	_, _, err = w.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, 20.0, w.Config().GM.RateLimit)
+	assert.Equal(t, 20, w.Config().GM.RateBurst)
+	assert.Len(t, reloaded, 1)
+}
+
+func TestWatcherRunOnFileChange(t *testing.T) {
+	reloadDebounce = 10 * time.Millisecond
+	path := writeFile(t, "config.toml", "[gm]\nrate_burst = 20\n")
+	w := newTestWatcher(t, path)
+
+	reloaded := make(chan *Config, 1)
+	w.OnReload(func(c *Config) { reloaded <- c })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	// the watch starts in the background, so the file is rewritten until it is noticed
+	deadline := time.After(5 * time.Second)
+	for {
+		require.NoError(t, ioutil.WriteFile(path, []byte("[gm]\nrate_burst = 40\n"), 0600))
+		select {
+		case c := <-reloaded:
+			assert.Equal(t, 40, c.GM.RateBurst)
+			return
+		case <-time.After(100 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("the change of the config file wasn't reloaded")
+		}
+	}
+}
@@ -8,15 +8,38 @@ import (
 
 // APIError ... error wrapper for more RESTful errors
 type APIError struct {
-	ErrorCode              int
-	ErrorMessage           error
-	InternalErrorMessage   string
-	ClientErrorMessage     string
-	ValidationErrorMessage string
-	file                   string
-	line                   int
-	funcName               string
-	withCallerInf          bool
+	ErrorCode            int
+	ErrorMessage         error
+	InternalErrorMessage string
+	ClientErrorMessage   string
+	ValidationErrors     []FieldError
+	file                 string
+	line                 int
+	funcName             string
+	withCallerInf        bool
+}
+
+// FieldError ... a single failed validation rule for a field in the request
+//
+// swagger:model FieldError
+type FieldError struct {
+	// Field ... the JSON path of the field that failed validation
+	//
+	// required: true
+	// example: action
+	Field string `json:"field"`
+
+	// Rule ... the name of the validation rule that failed
+	//
+	// required: true
+	// example: oneof
+	Rule string `json:"rule"`
+
+	// Message ... a human readable description of the failure
+	//
+	// required: true
+	// example: action must be one of [START, STOP]
+	Message string `json:"message"`
 }
 
 // NewAPIError return error wrapper
@@ -43,8 +66,8 @@ func (e *APIError) SetClientErrorMessage(msg string) *APIError {
 	return e
 }
 
-func (e *APIError) SetValidationErrorMessage(msg string) *APIError {
-	e.ValidationErrorMessage = msg
+func (e *APIError) SetValidationErrors(errs []FieldError) *APIError {
+	e.ValidationErrors = errs
 	return e
 }
 
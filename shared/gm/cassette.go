@@ -0,0 +1,17 @@
+package gmapiconnector
+
+import (
+	"net/http"
+
+	"app_api/shared/cassette"
+)
+
+// SensitiveFields ... the fields of GM's requests and responses that identify a vehicle, redacted from recorded cassettes
+var SensitiveFields = []string{"data.vin.value"}
+
+// NewRecorder ... records the traffic to GM through transport with the sensitive fields redacted, for
+// WithTransport. Replay the saved cassettes with cassette.Replay
+func NewRecorder(transport http.RoundTripper, opts ...cassette.Option) *cassette.Recorder {
+	opts = append([]cassette.Option{cassette.WithRedactor(cassette.RedactJSON(SensitiveFields...))}, opts...)
+	return cassette.NewRecorder(transport, opts...)
+}
@@ -0,0 +1,73 @@
+package gmapiconnector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"app_api/shared/cassette"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaySandboxCassette(t *testing.T) {
+	player, err := cassette.Replay("testdata/cassettes/sandbox.json")
+	require.NoError(t, err)
+	gm := NewGMAPIConnector(WithTransport(player))
+
+	info, apiErr := gm.GetVehicle(1234)
+	assert.Nil(t, apiErr)
+	assert.Equal(t, gmVehicleData{cassette.REDACTED, "Metallic Silver", true, false, "v8"}, info)
+
+	doors, apiErr := gm.GetVehicleDoors(1235)
+	assert.Nil(t, apiErr)
+	assert.Equal(t, []GMVehicleDoorData{{"frontLeft", true}, {"frontRight", true}}, doors)
+
+	_, battery, apiErr := gm.GetVehicleEnergyStatus(1235)
+	assert.Nil(t, apiErr)
+	assert.Equal(t, 88.55, *battery)
+
+	res, apiErr := gm.SendVehicleEngineAction(1234, ENGINE_START)
+	assert.Nil(t, apiErr)
+	assert.Equal(t, EXECUTED, res.Status)
+
+	_, apiErr = gm.GetVehicle(1236)
+	require.NotNil(t, apiErr)
+	assert.Contains(t, apiErr.ErrorMessage.Error(), "Vehicle id: 1236 not found.")
+
+	// a request the cassette has no recording of fails like GM being unreachable
+	_, apiErr = gm.SendVehicleEngineAction(1235, ENGINE_STOP)
+	require.NotNil(t, apiErr)
+	assert.Equal(t, http.StatusInternalServerError, apiErr.ErrorCode)
+}
+
+// GM once sent the locked flag of a door as a String, which the doors can't be read from
+func TestReplayDoorsLockedAsString(t *testing.T) {
+	player, err := cassette.Replay("testdata/cassettes/doors_locked_as_string.json")
+	require.NoError(t, err)
+	gm := NewGMAPIConnector(WithTransport(player))
+
+	_, apiErr := gm.GetVehicleDoors(1234)
+	require.NotNil(t, apiErr)
+	assert.Equal(t, "Failed to get vehicle doors", apiErr.ClientErrorMessage)
+	assert.Contains(t, apiErr.InternalErrorMessage, `"locked":{"type":"String","value":"True"}`)
+}
+
+func TestRecorderRedactsTheVIN(t *testing.T) {
+	server := httptest.NewServer(NewSimulator())
+	defer server.Close()
+
+	recorder := NewRecorder(nil)
+	gm := NewGMAPIConnector(WithBaseURL(server.URL), WithTransport(recorder))
+
+	info, apiErr := gm.GetVehicle(1234)
+	assert.Nil(t, apiErr)
+	assert.Equal(t, "123123412412", info.Vin, "the connector reads the response as GM sent it")
+
+	recorded := recorder.Cassette().Interactions
+	require.Len(t, recorded, 1)
+	assert.JSONEq(t, `{"id": "1234", "responseType": "JSON"}`, string(recorded[0].Request.Body))
+	assert.Contains(t, string(recorded[0].Response.Body), `"vin":{"type":"String","value":"REDACTED"}`)
+	assert.NotContains(t, string(recorded[0].Response.Body), "123123412412")
+}
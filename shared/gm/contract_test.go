@@ -0,0 +1,227 @@
+package gmapiconnector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"app_api/shared"
+	"app_api/shared/cassette"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// The contract of GM's API is the JSON schema in testdata/contract, with a definition per service. Each service has a
+// file of responses GM may send, whether they honour the contract and what the connector makes of them. A response
+// breaking the contract must fail, unless the fixture says why the connector tolerates it.
+//
+// TestContractLive checks the responses of a running GM against the contract: the simulator by default, or the GM API
+// at GM_CONTRACT_URL, e.g. GM_CONTRACT_URL=http://localhost:8005 go test ./shared/gm -run Contract
+
+const contractDir = "testdata/contract"
+
+var contractServices = []string{getVehicle, getVehicleDoors, getVehicleEnergyLevel, postVehicleEngineAction}
+
+// contractCase ... a response GM may send, and what the connector makes of it
+type contractCase struct {
+	Description string `json:"description"`
+	// Valid ... whether the response honours the contract
+	Valid bool `json:"valid"`
+	// Response ... the body GM answers with, sent as is if it is a JSON string
+	Response json.RawMessage `json:"response"`
+	// Result ... the connector's result as JSON, if it succeeds
+	Result json.RawMessage `json:"result"`
+	// Error ... the client error message of the connector, if it fails
+	Error string `json:"error"`
+	// Tolerated ... why the connector succeeds with a response breaking the contract
+	Tolerated string `json:"tolerated"`
+}
+
+func (c contractCase) body() []byte {
+	var raw string
+	if json.Unmarshal(c.Response, &raw) == nil {
+		return []byte(raw)
+	}
+	return c.Response
+}
+
+// contractSchema ... the schema of a definition of the contract
+func contractSchema(t *testing.T, definition string) *gojsonschema.Schema {
+	file, err := filepath.Abs(filepath.Join(contractDir, "gm.schema.json"))
+	require.NoError(t, err)
+
+	ref := fmt.Sprintf("file://%s#/definitions/%s", filepath.ToSlash(file), definition)
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(map[string]string{"$ref": ref}))
+	require.NoError(t, err)
+	return schema
+}
+
+// validate ... the ways the body breaks the contract, none if it honours it
+func validate(schema *gojsonschema.Schema, body []byte) []string {
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(body))
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	var violations []string
+	for _, e := range result.Errors() {
+		violations = append(violations, e.String())
+	}
+	return violations
+}
+
+// callService ... calls the connector method of the service for the vehicle, returning its result as JSON
+func callService(gm GMAPIConnector, service string, vehicleID int64) (result []byte, err *shared.APIError) {
+	var res interface{}
+	switch service {
+	case getVehicle:
+		res, err = gm.GetVehicle(vehicleID)
+	case getVehicleDoors:
+		res, err = gm.GetVehicleDoors(vehicleID)
+	case getVehicleEnergyLevel:
+		var fuel, battery *float64
+		fuel, battery, err = gm.GetVehicleEnergyStatus(vehicleID)
+		res = GMVehicleEnergyData{fuel, battery}
+	case postVehicleEngineAction:
+		res, err = gm.SendVehicleEngineAction(vehicleID, ENGINE_START)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result, _ = json.Marshal(res)
+	return result, nil
+}
+
+func requestDefinition(service string) string {
+	if service == postVehicleEngineAction {
+		return "actionEngineRequest"
+	}
+	return "request"
+}
+
+func TestContractFixtures(t *testing.T) {
+	for _, service := range contractServices {
+		service := service
+		t.Run(service, func(t *testing.T) {
+			b, err := ioutil.ReadFile(filepath.Join(contractDir, service+".json"))
+			require.NoError(t, err)
+			var cases []contractCase
+			require.NoError(t, json.Unmarshal(b, &cases))
+
+			requestSchema := contractSchema(t, requestDefinition(service))
+			responseSchema := contractSchema(t, service)
+
+			for _, c := range cases {
+				c := c
+				t.Run(c.Description, func(t *testing.T) {
+					require.True(t, (c.Result == nil) != (c.Error == ""), "a fixture either has a result or an error")
+					if !c.Valid && c.Result != nil {
+						require.NotEmpty(t, c.Tolerated, "the connector must fail with a response breaking the contract, unless the fixture says why it tolerates it")
+					}
+
+					violations := validate(responseSchema, c.body())
+					assert.Equal(t, c.Valid, len(violations) == 0, "the response breaks the contract: %v", violations)
+
+					server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						assert.Equal(t, "/"+service, r.URL.Path)
+						req, _ := ioutil.ReadAll(r.Body)
+						assert.Empty(t, validate(requestSchema, req), "the connector's request breaks the contract")
+
+						w.Header().Set("Content-Type", "application/json")
+						w.Write(c.body())
+					}))
+					defer server.Close()
+
+					result, apiErr := callService(NewGMAPIConnector(WithBaseURL(server.URL)), service, 1234)
+					if c.Error != "" {
+						require.NotNil(t, apiErr, "the connector returned %s", result)
+						assert.Equal(t, http.StatusInternalServerError, apiErr.ErrorCode)
+						assert.Equal(t, c.Error, apiErr.ClientErrorMessage)
+						return
+					}
+					require.Nil(t, apiErr)
+					assert.JSONEq(t, string(c.Result), string(result))
+				})
+			}
+		})
+	}
+}
+
+func TestContractLive(t *testing.T) {
+	baseURL := os.Getenv("GM_CONTRACT_URL")
+	if baseURL == "" {
+		server := httptest.NewServer(NewSimulator())
+		defer server.Close()
+		baseURL = server.URL
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	gm := NewGMAPIConnector(WithBaseURL(baseURL))
+
+	for _, service := range contractServices {
+		schema := contractSchema(t, service)
+
+		// 1234 and 1235 are GM's sandbox vehicles, 4321 doesn't exist
+		for _, vehicleID := range []int64{1234, 1235, 4321} {
+			t.Run(fmt.Sprintf("%s/%d", service, vehicleID), func(t *testing.T) {
+				req := map[string]string{"id": fmt.Sprintf("%d", vehicleID), "responseType": jsonResponseType}
+				if service == postVehicleEngineAction {
+					req["command"] = ENGINE_START
+				}
+				reqBody, _ := json.Marshal(req)
+
+				res, err := http.Post(baseURL+"/"+service, "application/json", strings.NewReader(string(reqBody)))
+				require.NoError(t, err)
+				defer res.Body.Close()
+				require.Equal(t, http.StatusOK, res.StatusCode)
+				body, err := ioutil.ReadAll(res.Body)
+				require.NoError(t, err)
+
+				violations := validate(schema, body)
+				require.Empty(t, violations, "GM's response breaks the contract: %s", body)
+
+				var envelope struct {
+					Status string `json:"status"`
+				}
+				require.NoError(t, json.Unmarshal(body, &envelope))
+
+				_, apiErr := callService(gm, service, vehicleID)
+				if envelope.Status == "200" {
+					assert.Nil(t, apiErr, "the connector fails with a response honouring the contract: %s", body)
+				} else {
+					assert.NotNil(t, apiErr, "the connector succeeds with a failed request: %s", body)
+				}
+			})
+		}
+	}
+}
+
+// the recorded responses of the cassettes are GM's, so they keep the contract honest
+func TestContractCassettes(t *testing.T) {
+	tests := []struct {
+		cassette string
+		valid    bool
+	}{
+		{"testdata/cassettes/sandbox.json", true},
+		{"testdata/cassettes/doors_locked_as_string.json", false},
+	}
+	for _, test := range tests {
+		c, err := cassette.Load(test.cassette)
+		require.NoError(t, err)
+
+		for i, interaction := range c.Interactions {
+			service := path.Base(interaction.Request.URL)
+			violations := validate(contractSchema(t, service), interaction.Response.Body)
+			assert.Equal(t, test.valid, len(violations) == 0, "interaction %d of %s: %v", i, test.cassette, violations)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package gmapiconnector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"app_api/shared"
+	"app_api/shared/validator"
+)
+
+const (
+	// FAULT_ERROR ... GM answers with a 500
+	FAULT_ERROR = "error"
+	// FAULT_GM_ERROR ... GM answers with a 200, but status "500" in the body
+	FAULT_GM_ERROR = "gm_error"
+	// FAULT_MALFORMED ... GM answers with values of a type it never sends
+	FAULT_MALFORMED = "malformed"
+	// FAULT_DROP ... GM receives the request, but the connection drops before the response
+	FAULT_DROP = "drop"
+	// FAULT_TIMEOUT ... GM doesn't answer before the request times out, after the latency of the rule or DEFAULT_FAULT_TIMEOUT
+	FAULT_TIMEOUT = "timeout"
+
+	DEFAULT_FAULT_TIMEOUT = 30 * time.Second
+)
+
+// FaultRule ... a fault injected into the requests to a GM service for a vehicle
+//
+// swagger:model FaultRule
+type FaultRule struct {
+	// Endpoint ... the GM service the rule applies to, every service if left out
+	//
+	// example: getEnergyService
+	Endpoint string `json:"endpoint,omitempty" validate:"oneof=getVehicleInfoService getSecurityStatusService getEnergyService actionEngineService"`
+
+	// VehicleID ... the vehicle the rule applies to, every vehicle if left out
+	//
+	// example: 1234
+	VehicleID *int64 `json:"vehicleId,omitempty" validate:"min=1"`
+
+	// LatencyMs ... how long GM takes to answer, or to time out with the timeout fault
+	//
+	// example: 2000
+	LatencyMs int64 `json:"latencyMs,omitempty" validate:"min=0,max=300000"`
+
+	// Fault ... how GM fails, if it does
+	//
+	// example: malformed
+	Fault string `json:"fault,omitempty" validate:"oneof=error gm_error malformed drop timeout"`
+
+	// Rate ... the fraction of the matching requests the rule applies to, 1 if left out
+	//
+	// example: 0.25
+	Rate *float64 `json:"rate,omitempty" validate:"min=0,max=1"`
+}
+
+// FaultRulesRequest ... the fault rules of the GM connector, as set through /admin/gm/faults or the GM_FAULTS file
+//
+// swagger:model FaultRulesRequest
+type FaultRulesRequest struct {
+	// Rules ... the first rule matching a request applies to it, if any
+	Rules []FaultRule `json:"rules" validate:"max=100"`
+}
+
+// Validate ... a rule without a fault must at least slow GM down
+func (req FaultRulesRequest) Validate() (errs []shared.FieldError) {
+	for i, rule := range req.Rules {
+		if rule.Fault == "" && rule.LatencyMs == 0 {
+			field := fmt.Sprintf("rules[%d]", i)
+			errs = append(errs, shared.FieldError{Field: field, Rule: "required", Message: fmt.Sprintf("%s needs a fault or a latencyMs", field)})
+		}
+	}
+	return
+}
+
+// LoadFaultRules ... reads the rules of a GM_FAULTS file, in the format of FaultRulesRequest
+func LoadFaultRules(path string) ([]FaultRule, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var req FaultRulesRequest
+	if err := json.Unmarshal(b, &req); err != nil {
+		return nil, err
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return nil, fmt.Errorf("%s: %s", path, errs[0].Message)
+	}
+	return req.Rules, nil
+}
+
+// FaultInjector ... decides which requests to GM fail and how, from rules that can be changed at any time
+type FaultInjector struct {
+	mu    sync.RWMutex
+	rules []FaultRule
+	rand  func() float64
+}
+
+// NewFaultInjector ... returns an injector applying the rules, none if empty
+func NewFaultInjector(rules []FaultRule) *FaultInjector {
+	return &FaultInjector{rules: rules, rand: rand.Float64}
+}
+
+// Rules ... the rules being applied
+func (f *FaultInjector) Rules() []FaultRule {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return append([]FaultRule{}, f.rules...)
+}
+
+// SetRules ... replaces the rules, for the requests sent from now on
+func (f *FaultInjector) SetRules(rules []FaultRule) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append([]FaultRule(nil), rules...)
+}
+
+// decide ... the first rule matching a request to the endpoint for the vehicle, if its rate lets it apply
+func (f *FaultInjector) decide(endpoint string, vehicleID int64) (FaultRule, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, rule := range f.rules {
+		if (rule.Endpoint != "" && rule.Endpoint != endpoint) || (rule.VehicleID != nil && *rule.VehicleID != vehicleID) {
+			continue
+		}
+		if rule.Rate != nil && f.rand() >= *rule.Rate {
+			return FaultRule{}, false
+		}
+		return rule, true
+	}
+	return FaultRule{}, false
+}
+
+// delay ... waits for the latency of the rule, or the timeout it ends with. False if the context is done first
+func (rule FaultRule) delay(ctx context.Context) bool {
+	d := time.Duration(rule.LatencyMs) * time.Millisecond
+	if rule.Fault == FAULT_TIMEOUT && d == 0 {
+		d = DEFAULT_FAULT_TIMEOUT
+	}
+	if d == 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// faultTimeout ... the error of a request that timed out, as http.Client reports it
+type faultTimeout struct{}
+
+func (faultTimeout) Error() string   { return "fault injection: timed out awaiting response from GM" }
+func (faultTimeout) Timeout() bool   { return true }
+func (faultTimeout) Temporary() bool { return true }
+
+// faultTransport ... injects faults into the HTTP requests to GM, so the connector's own handling of them is exercised
+type faultTransport struct {
+	next     http.RoundTripper
+	injector *FaultInjector
+}
+
+// NewFaultTransport ... injects the faults of the injector into the requests sent through next, or
+// http.DefaultTransport if it is nil, for WithTransport
+func NewFaultTransport(next http.RoundTripper, injector *FaultInjector) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &faultTransport{next: next, injector: injector}
+}
+
+func (t *faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	var gmReq gmRequest
+	json.Unmarshal(body, &gmReq)
+	vehicleID, _ := strconv.ParseInt(gmReq.ID, 10, 64)
+
+	rule, ok := t.injector.decide(path.Base(req.URL.Path), vehicleID)
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+	if !rule.delay(req.Context()) {
+		return nil, req.Context().Err()
+	}
+
+	switch rule.Fault {
+	case FAULT_ERROR:
+		return faultResponse(req, http.StatusInternalServerError, []byte("Internal Server Error")), nil
+	case FAULT_GM_ERROR:
+		return faultResponse(req, http.StatusOK, []byte(`{"status": "500", "reason": "Internal Server Error"}`)), nil
+	case FAULT_TIMEOUT:
+		return nil, faultTimeout{}
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch rule.Fault {
+	case FAULT_DROP:
+		res.Body.Close()
+		return nil, fmt.Errorf("fault injection: connection to GM dropped: %w", io.ErrUnexpectedEOF)
+	case FAULT_MALFORMED:
+		resBody, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		return faultResponse(req, res.StatusCode, malform(resBody)), nil
+	}
+	return res, nil
+}
+
+func faultResponse(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// malform ... changes the type of every DataValue in a GM response to one GM never sends
+func malform(body []byte) []byte {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return body
+	}
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if _, ok := v["type"].(string); ok {
+				v["type"] = "Malformed"
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return body
+	}
+	return b
+}
+
+// faultConnector ... injects faults in front of another connector, failing the way the GM connector fails for each fault.
+// Unlike NewFaultTransport, it works with connectors that don't make HTTP requests, such as the mock
+type faultConnector struct {
+	next     GMAPIConnector
+	injector *FaultInjector
+}
+
+// NewFaultConnector ... injects the faults of the injector into the calls to next
+func NewFaultConnector(next GMAPIConnector, injector *FaultInjector) GMAPIConnector {
+	return &faultConnector{next: next, injector: injector}
+}
+
+// fault ... the error the GM connector returns for the fault a rule injects, after the latency of the rule
+func (c *faultConnector) fault(endpoint string, vehicleID int64, clientErr string) *shared.APIError {
+	rule, ok := c.injector.decide(endpoint, vehicleID)
+	if !ok {
+		return nil
+	}
+	rule.delay(context.Background())
+
+	switch rule.Fault {
+	case FAULT_ERROR, FAULT_GM_ERROR:
+		return shared.NewAPIError(http.StatusInternalServerError, fmt.Errorf("fault injection: %s for vehicle %d", rule.Fault, vehicleID), clientErr)
+	case FAULT_MALFORMED:
+		return shared.NewAPIError(http.StatusInternalServerError, fmt.Errorf("Unsupported data type: Malformed"), clientErr)
+	case FAULT_DROP:
+		return requestError(fmt.Errorf("fault injection: connection to GM dropped: %w", io.ErrUnexpectedEOF), endpoint+": Failed to send request")
+	case FAULT_TIMEOUT:
+		return requestError(faultTimeout{}, endpoint+": Failed to send request")
+	}
+	return nil
+}
+
+func (c *faultConnector) GetVehicle(vehicleID int64) (res gmVehicleData, err *shared.APIError) {
+	if err = c.fault(getVehicle, vehicleID, "Failed to get vehicle"); err != nil {
+		return
+	}
+	return c.next.GetVehicle(vehicleID)
+}
+
+func (c *faultConnector) GetVehicleDoors(vehicleID int64) (res []GMVehicleDoorData, err *shared.APIError) {
+	if err = c.fault(getVehicleDoors, vehicleID, "Failed to get vehicle doors"); err != nil {
+		return
+	}
+	return c.next.GetVehicleDoors(vehicleID)
+}
+
+func (c *faultConnector) GetVehicleEnergyStatus(vehicleID int64) (fuelLevel, batteryLevel *float64, err *shared.APIError) {
+	if err = c.fault(getVehicleEnergyLevel, vehicleID, "Failed to get vehicle energy status"); err != nil {
+		return
+	}
+	return c.next.GetVehicleEnergyStatus(vehicleID)
+}
+
+func (c *faultConnector) SendVehicleEngineAction(vehicleID int64, action string) (res ActionResult, err *shared.APIError) {
+	if err = c.fault(postVehicleEngineAction, vehicleID, "Failed to send engine action"); err != nil {
+		return
+	}
+	return c.next.SendVehicleEngineAction(vehicleID, action)
+}
@@ -0,0 +1,146 @@
+package gmapiconnector
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFaultyConnector(t *testing.T, rules ...FaultRule) (GMAPIConnector, *Simulator) {
+	sim := NewSimulator()
+	server := httptest.NewServer(sim)
+	t.Cleanup(server.Close)
+
+	injector := NewFaultInjector(rules)
+	return NewGMAPIConnector(WithBaseURL(server.URL), WithTransport(NewFaultTransport(nil, injector))), sim
+}
+
+func TestFaultTransport(t *testing.T) {
+	tests := []struct {
+		fault    string
+		internal string
+		sent     int64
+	}{
+		{FAULT_ERROR, "non-200 response: Internal Server Error", 0},
+		{FAULT_GM_ERROR, "non-200 response: Internal Server Error Response code: 500", 0},
+		{FAULT_MALFORMED, "Unsupported data type: Malformed", 1},
+		{FAULT_DROP, "connection to GM dropped", 1},
+		{FAULT_TIMEOUT, "timed out awaiting response from GM", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.fault, func(t *testing.T) {
+			gm, sim := newFaultyConnector(t, FaultRule{Endpoint: getVehicle, Fault: test.fault, LatencyMs: 10})
+
+			start := time.Now()
+			_, err := gm.GetVehicle(1234)
+			require.NotNil(t, err)
+			assert.Equal(t, http.StatusInternalServerError, err.ErrorCode)
+			assert.Contains(t, err.ErrorMessage.Error(), test.internal)
+			assert.True(t, time.Since(start) >= 10*time.Millisecond)
+			assert.Equal(t, test.sent, sim.Requests(), "whether GM received the request")
+
+			_, _, err = gm.GetVehicleEnergyStatus(1234)
+			assert.Nil(t, err, "the rule only applies to its endpoint")
+		})
+	}
+}
+
+func TestFaultRulesMatch(t *testing.T) {
+	never, always := 0.0, 1.0
+	vehicleID := int64(1235)
+	gm, _ := newFaultyConnector(t,
+		FaultRule{VehicleID: &vehicleID, Fault: FAULT_ERROR},
+		FaultRule{Endpoint: getVehicleDoors, Fault: FAULT_ERROR, Rate: &never},
+		FaultRule{Endpoint: getVehicleEnergyLevel, Fault: FAULT_GM_ERROR, Rate: &always},
+	)
+
+	_, err := gm.GetVehicle(1235)
+	assert.NotNil(t, err)
+	_, err = gm.GetVehicle(1234)
+	assert.Nil(t, err)
+
+	_, err = gm.GetVehicleDoors(1234)
+	assert.Nil(t, err, "a rule with a rate of 0 never applies")
+
+	_, _, err = gm.GetVehicleEnergyStatus(1234)
+	assert.NotNil(t, err)
+}
+
+func TestFaultRate(t *testing.T) {
+	half := 0.5
+	injector := NewFaultInjector([]FaultRule{{Fault: FAULT_ERROR, Rate: &half}})
+
+	draws := []float64{0.2, 0.7}
+	injector.rand = func() float64 {
+		draw := draws[0]
+		draws = draws[1:]
+		return draw
+	}
+
+	_, ok := injector.decide(getVehicle, 1234)
+	assert.True(t, ok)
+	_, ok = injector.decide(getVehicle, 1234)
+	assert.False(t, ok)
+}
+
+func TestFaultLatencyOnly(t *testing.T) {
+	gm, sim := newFaultyConnector(t, FaultRule{LatencyMs: 30})
+
+	start := time.Now()
+	_, err := gm.GetVehicleDoors(1234)
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) >= 30*time.Millisecond)
+	assert.Equal(t, int64(1), sim.Requests())
+}
+
+func TestFaultConnector(t *testing.T) {
+	injector := NewFaultInjector(nil)
+	gm := NewFaultConnector(NewMockGMAPIConnector(), injector)
+
+	_, err := gm.SendVehicleEngineAction(1234, ENGINE_START)
+	assert.Nil(t, err)
+
+	injector.SetRules([]FaultRule{{Endpoint: postVehicleEngineAction, Fault: FAULT_TIMEOUT, LatencyMs: 1}})
+	_, err = gm.SendVehicleEngineAction(1234, ENGINE_START)
+	require.NotNil(t, err)
+	assert.Equal(t, "Internal Error", err.ClientErrorMessage)
+
+	injector.SetRules([]FaultRule{{Fault: FAULT_MALFORMED}})
+	_, err = gm.GetVehicleDoors(1234)
+	require.NotNil(t, err)
+	assert.Equal(t, "Failed to get vehicle doors", err.ClientErrorMessage)
+
+	injector.SetRules(nil)
+	assert.Empty(t, injector.Rules())
+	_, err = gm.GetVehicleDoors(1234)
+	assert.Nil(t, err)
+}
+
+func TestLoadFaultRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faults")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "faults.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"rules": [{"endpoint": "getEnergyService", "latencyMs": 2000, "rate": 0.1}]}`), 0644))
+	rules, err := LoadFaultRules(path)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, int64(2000), rules[0].LatencyMs)
+	assert.Equal(t, 0.1, *rules[0].Rate)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"rules": [{"endpoint": "getEnergyService"}]}`), 0644))
+	_, err = LoadFaultRules(path)
+	assert.EqualError(t, err, path+": rules[0] needs a fault or a latencyMs")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"rules": [{"fault": "error", "rate": 2}]}`), 0644))
+	_, err = LoadFaultRules(path)
+	assert.EqualError(t, err, path+": rules[0].rate must be at most 1")
+}
@@ -0,0 +1,160 @@
+package gmapiconnector
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"app_api/shared/cassette"
+)
+
+// Run a target with e.g. go test ./shared/gm -run '^$' -fuzz FuzzGMResponse -fuzztime 1m. Crashers are saved under
+// testdata/fuzz, and are committed so they run with the other tests from then on.
+
+// fuzzSeeds ... the GM responses of the contract fixtures and the cassettes, for each service
+func fuzzSeeds(f *testing.F) map[string][][]byte {
+	seeds := map[string][][]byte{}
+	for _, service := range contractServices {
+		b, err := ioutil.ReadFile(filepath.Join(contractDir, service+".json"))
+		if err != nil {
+			f.Fatal(err)
+		}
+		var cases []contractCase
+		if err := json.Unmarshal(b, &cases); err != nil {
+			f.Fatal(err)
+		}
+		for _, c := range cases {
+			seeds[service] = append(seeds[service], c.body())
+		}
+	}
+
+	for _, path := range []string{"testdata/cassettes/sandbox.json", "testdata/cassettes/doors_locked_as_string.json"} {
+		c, err := cassette.Load(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, interaction := range c.Interactions {
+			service := filepath.Base(interaction.Request.URL)
+			seeds[service] = append(seeds[service], interaction.Response.Body)
+		}
+	}
+	return seeds
+}
+
+// wellFormed ... whether a DataValue MapToStruct accepted is one of GM's types, with a value of that type
+func wellFormed(val DataValue, number func(string) error) bool {
+	switch val.Type {
+	case "String", "Null":
+		return true
+	case "Boolean":
+		_, err := strconv.ParseBool(val.Value)
+		return err == nil
+	case "Number":
+		return number(val.Value) == nil
+	}
+	return false
+}
+
+func parseInt(s string) error {
+	_, err := strconv.ParseInt(s, 10, 64)
+	return err
+}
+
+func parseFloat(s string) error {
+	_, err := strconv.ParseFloat(s, 64)
+	return err
+}
+
+func FuzzMapToStruct(f *testing.F) {
+	for _, service := range fuzzSeeds(f) {
+		for _, body := range service {
+			f.Add(body)
+		}
+	}
+	f.Add([]byte(`{"vin": {"type": "Number", "value": "1e3"}}`))
+	f.Add([]byte(`{"tankLevel": {"type": "Number", "value": "NaN"}}`))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		// the data of a response, or the response itself
+		var res struct {
+			Data map[string]DataValue `json:"data"`
+		}
+		var data map[string]DataValue
+		if json.Unmarshal(body, &res) == nil && res.Data != nil {
+			data = res.Data
+		} else if json.Unmarshal(body, &data) != nil {
+			return
+		}
+
+		// a struct is only returned from data of GM's types, with values of those types
+		check := func(err error, number func(string) error) {
+			if err != nil {
+				return
+			}
+			for key, val := range data {
+				if !wellFormed(val, number) {
+					t.Fatalf("%s %+v was accepted", key, val)
+				}
+			}
+		}
+
+		var vehicle gmVehicleData
+		check(vehicle.MapToStruct(data), parseInt)
+		var door GMVehicleDoorData
+		check(door.MapToStruct(data), parseInt)
+		var energy GMVehicleEnergyData
+		check(energy.MapToStruct(data), parseFloat)
+	})
+}
+
+// fuzzTransport ... answers every request with the same body, without sending it
+type fuzzTransport []byte
+
+func (body fuzzTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func FuzzGMResponse(f *testing.F) {
+	seeds := fuzzSeeds(f)
+	for i, service := range contractServices {
+		for _, body := range seeds[service] {
+			f.Add(uint8(i), body)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, i uint8, body []byte) {
+		service := contractServices[int(i)%len(contractServices)]
+		gm := NewGMAPIConnector(WithTransport(fuzzTransport(body)))
+
+		result, err := callService(gm, service, 1234)
+		if err != nil {
+			if err.ErrorCode != http.StatusInternalServerError || err.ClientErrorMessage == "" || err.ErrorMessage == nil {
+				t.Fatalf("malformed error %+v", err)
+			}
+			return
+		}
+
+		// GM reports failed requests in the status of the body, which the connector must never take for a success
+		var envelope struct {
+			Status string `json:"status"`
+		}
+		if json.Unmarshal(body, &envelope) != nil {
+			t.Fatalf("%s was accepted", body)
+		}
+		if status, parseErr := strconv.ParseInt(envelope.Status, 10, 64); parseErr != nil || status != 200 {
+			t.Fatalf("status %q was accepted", envelope.Status)
+		}
+		if !json.Valid(result) {
+			t.Fatalf("invalid result %s", result)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package gmapiconnector
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,8 +10,13 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"app_api/shared"
+	"app_api/shared/circuitbreaker"
+	"app_api/shared/ratelimit"
 )
 
 // GMAPIConnector ... is an interface of appapi methods called
@@ -22,7 +28,13 @@ type GMAPIConnector interface {
 	SendVehicleEngineAction(vehicleID int64, action string) (res ActionResult, err *shared.APIError)
 }
 
-type gmAPIConnector struct{}
+type gmAPIConnector struct {
+	// baseURL ... a string, swapped by Reconfigure while requests are in flight
+	baseURL atomic.Value
+	client  *http.Client
+	limiter *ratelimit.Limiter
+	breaker *circuitbreaker.Breaker
+}
 
 const (
 	gmAPIURL         = "http://gmapi.azurewebsites.net"
@@ -39,9 +51,97 @@ const (
 	FAILED       = "FAILED"
 )
 
+// Option ... configures how the connector protects the GM API
+type Option func(*gmAPIConnector)
+
+// WithBaseURL ... sends the requests to another GM API, such as the simulator, instead of GM's own
+func WithBaseURL(baseURL string) Option {
+	return func(gm *gmAPIConnector) {
+		gm.baseURL.Store(strings.TrimSuffix(baseURL, "/"))
+	}
+}
+
+// WithTransport ... sends the requests to GM through transport, such as a cassette recorder or player
+func WithTransport(transport http.RoundTripper) Option {
+	return func(gm *gmAPIConnector) {
+		gm.client = &http.Client{Transport: transport}
+	}
+}
+
+// WithRateLimit ... limits the requests sent to GM to `rate` per second, with bursts. Requests over the limit wait for their turn
+func WithRateLimit(rate float64, burst int) Option {
+	return func(gm *gmAPIConnector) {
+		gm.limiter = ratelimit.NewLimiter(rate, burst)
+	}
+}
+
+// WithCircuitBreaker ... stops sending requests to GM for `cooldown` after `threshold` consecutive failures,
+// failing fast with a 503 instead of piling more load onto an unhealthy upstream
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(gm *gmAPIConnector) {
+		gm.breaker = circuitbreaker.New(threshold, cooldown)
+	}
+}
+
 // NewGMAPIConnector ... returns an interface of GMAPIConnector
-func NewGMAPIConnector() GMAPIConnector {
-	return &gmAPIConnector{}
+func NewGMAPIConnector(opts ...Option) GMAPIConnector {
+	gm := &gmAPIConnector{client: &http.Client{}}
+	gm.baseURL.Store(gmAPIURL)
+	for _, opt := range opts {
+		opt(gm)
+	}
+	return gm
+}
+
+// Settings ... the settings of a connector which can change while it serves requests
+type Settings struct {
+	// BaseURL ... GM's own API when empty
+	BaseURL          string
+	RateLimit        float64
+	RateBurst        int
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// Reconfigure ... swaps the settings of a connector built by NewGMAPIConnector while it serves requests. Requests in
+// flight complete with the settings they started with. The rate limit and circuit breaker only change on a connector
+// built with them
+func Reconfigure(connector GMAPIConnector, settings Settings) error {
+	gm, ok := connector.(*gmAPIConnector)
+	if !ok {
+		return fmt.Errorf("%T can't be reconfigured", connector)
+	}
+
+	baseURL := gmAPIURL
+	if settings.BaseURL != "" {
+		baseURL = strings.TrimSuffix(settings.BaseURL, "/")
+	}
+	gm.baseURL.Store(baseURL)
+	if gm.limiter != nil {
+		gm.limiter.SetLimit(settings.RateLimit, settings.RateBurst)
+	}
+	gm.breaker.SetLimits(settings.BreakerThreshold, settings.BreakerCooldown)
+	return nil
+}
+
+// BreakerState ... the state of the circuit breaker of a connector built by NewGMAPIConnector, closed without one
+func BreakerState(connector GMAPIConnector) (string, error) {
+	gm, ok := connector.(*gmAPIConnector)
+	if !ok {
+		return "", fmt.Errorf("%T has no circuit breaker", connector)
+	}
+	return gm.breaker.State(), nil
+}
+
+// ResetBreaker ... closes the circuit breaker of a connector built by NewGMAPIConnector, so requests go to GM again
+// without waiting for the cooldown
+func ResetBreaker(connector GMAPIConnector) error {
+	gm, ok := connector.(*gmAPIConnector)
+	if !ok {
+		return fmt.Errorf("%T has no circuit breaker", connector)
+	}
+	gm.breaker.Reset()
+	return nil
 }
 
 type GMVehicleResponse struct {
@@ -70,8 +170,7 @@ func (gm *gmAPIConnector) GetVehicle(vehicleID int64) (res gmVehicleData, err *s
 	// Make the request to GM to get vehicle information
 	resp, requestErr := gm.makeRequest(getVehicle, "POST", requestBody, nil)
 	if requestErr != nil {
-		clientErr := "Internal Error"
-		err = shared.NewAPIError(http.StatusInternalServerError, requestErr, clientErr).SetInternalErrorMessage("GetVehicle: Failed to send request")
+		err = requestError(requestErr, "GetVehicle: Failed to send request")
 		return
 	}
 
@@ -162,8 +261,7 @@ func (gm *gmAPIConnector) GetVehicleDoors(vehicleID int64) (res []GMVehicleDoorD
 	// Make initial request to GM
 	resp, requestErr := gm.makeRequest(getVehicleDoors, "POST", requestBody, nil)
 	if requestErr != nil {
-		clientErr := "Internal Error"
-		err = shared.NewAPIError(http.StatusInternalServerError, requestErr, clientErr).SetInternalErrorMessage("GetVehicleDoors: Failed to send request")
+		err = requestError(requestErr, "GetVehicleDoors: Failed to send request")
 		return
 	}
 
@@ -222,6 +320,8 @@ func (gm *gmAPIConnector) GetVehicleDoors(vehicleID int64) (res []GMVehicleDoorD
 		return
 	}
 
+	// an empty array is a vehicle without doors, not a missing result
+	res = make([]GMVehicleDoorData, 0, len(data.Values))
 	for _, gmDoorResponse := range data.Values {
 		var flattenedGMDoorResponse GMVehicleDoorData
 
@@ -261,8 +361,7 @@ func (gm *gmAPIConnector) GetVehicleEnergyStatus(vehicleID int64) (fuelLevel, ba
 	// Make the request to GM
 	resp, requestErr := gm.makeRequest(getVehicleEnergyLevel, "POST", requestBody, nil)
 	if requestErr != nil {
-		clientErr := "Internal Error"
-		err = shared.NewAPIError(http.StatusInternalServerError, requestErr, clientErr).SetInternalErrorMessage("GetVehicleEnergyStatus: Failed to send request")
+		err = requestError(requestErr, "GetVehicleEnergyStatus: Failed to send request")
 		return
 	}
 
@@ -350,8 +449,7 @@ func (gm *gmAPIConnector) SendVehicleEngineAction(vehicleID int64, action string
 	// Make request to GM
 	resp, requestErr := gm.makeRequest(postVehicleEngineAction, "POST", requestBody, nil)
 	if requestErr != nil {
-		clientErr := "Internal Error"
-		err = shared.NewAPIError(http.StatusInternalServerError, requestErr, clientErr).SetInternalErrorMessage("SendVehicleEngineAction: Failed to send request")
+		err = requestError(requestErr, "SendVehicleEngineAction: Failed to send request")
 		return
 	}
 
@@ -403,8 +501,7 @@ func (gm *gmAPIConnector) SendVehicleEngineAction(vehicleID int64, action string
 
 // makeRequest ... wrapper for making HTTP requests
 func (gm *gmAPIConnector) makeRequest(endpoint, method string, body []byte, params url.Values) (resp *http.Response, err error) {
-	client := &http.Client{}
-	URL, err := url.Parse(fmt.Sprintf("%s/%s", gmAPIURL, endpoint))
+	URL, err := url.Parse(fmt.Sprintf("%s/%s", gm.baseURL.Load().(string), endpoint))
 	if err != nil {
 		return nil, err
 	}
@@ -420,9 +517,35 @@ func (gm *gmAPIConnector) makeRequest(endpoint, method string, body []byte, para
 
 	req.Header.Add("Content-Type", "application/json")
 
-	resp, err = client.Do(req)
+	if gm.limiter != nil {
+		if err = gm.limiter.Wait(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = gm.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	resp, err = gm.client.Do(req)
 	if err != nil {
+		gm.breaker.Failure()
 		return nil, err
 	}
+
+	// GM failing to serve the request counts against the breaker, a rejected request doesn't
+	if resp.StatusCode >= http.StatusInternalServerError {
+		gm.breaker.Failure()
+	} else {
+		gm.breaker.Success()
+	}
 	return resp, nil
 }
+
+// requestError ... fails fast with a 503 while the circuit breaker is open, otherwise reports the failed request as an internal error
+func requestError(requestErr error, internalErr string) *shared.APIError {
+	if errors.Is(requestErr, circuitbreaker.ErrOpen) {
+		return shared.NewAPIError(http.StatusServiceUnavailable, requestErr, "GM API is unavailable").SetInternalErrorMessage(internalErr)
+	}
+	return shared.NewAPIError(http.StatusInternalServerError, requestErr, "Internal Error").SetInternalErrorMessage(internalErr)
+}
@@ -2,11 +2,14 @@ package gmapiconnector
 
 import (
 	"app_api/shared"
+	"app_api/shared/circuitbreaker"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
@@ -324,3 +327,102 @@ func TestSendVehicleEngineActionSuccess(t *testing.T) {
 	assert.Nil(t, err, "SendVehicleEngineAction success")
 	assert.Equal(t, testGmVehicleEngineResponse.Result, res)
 }
+
+func TestCircuitBreakerFailsFast(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", fmt.Sprintf("%s/%s", gmAPIURL, getVehicle),
+		httpmock.NewStringResponder(502, "Bad Gateway"))
+
+	gm := NewGMAPIConnector(WithCircuitBreaker(2, time.Minute))
+
+	for i := 0; i < 2; i++ {
+		_, err := gm.GetVehicle(1234)
+		assert.NotNil(t, err)
+		assert.Equal(t, http.StatusInternalServerError, err.ErrorCode)
+	}
+
+	_, err := gm.GetVehicle(1234)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, err.ErrorCode)
+	assert.Equal(t, "GM API is unavailable", err.ClientErrorMessage)
+	assert.Equal(t, 2, httpmock.GetTotalCallCount(), "An open breaker shouldn't send requests to GM")
+
+	state, breakerErr := BreakerState(gm)
+	assert.NoError(t, breakerErr)
+	assert.Equal(t, circuitbreaker.STATE_OPEN, state)
+
+	assert.NoError(t, ResetBreaker(gm))
+	state, _ = BreakerState(gm)
+	assert.Equal(t, circuitbreaker.STATE_CLOSED, state)
+	_, err = gm.GetVehicle(1234)
+	assert.Equal(t, http.StatusInternalServerError, err.ErrorCode)
+	assert.Equal(t, 3, httpmock.GetTotalCallCount(), "A reset breaker sends requests to GM again")
+
+	_, breakerErr = BreakerState(NewMockGMAPIConnector())
+	assert.Error(t, breakerErr)
+	assert.Error(t, ResetBreaker(NewMockGMAPIConnector()))
+}
+
+func TestRateLimit(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", fmt.Sprintf("%s/%s", gmAPIURL, getVehicleEnergyLevel),
+		httpmock.NewStringResponder(200, `{"status": "200", "data": {"tankLevel": {"type": "Number", "value": "30.2"}, "batteryLevel": {"type": "Null", "value": "null"}}}`))
+
+	gm := NewGMAPIConnector(WithRateLimit(20, 1))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, _, err := gm.GetVehicleEnergyStatus(1234)
+		assert.Nil(t, err)
+	}
+
+	// the first request uses the burst, the next two wait 50ms each
+	assert.True(t, time.Since(start) >= 90*time.Millisecond)
+}
+
+func TestReconfigure(t *testing.T) {
+	slow := NewSimulator(WithLatency(100*time.Millisecond, 0))
+	oldServer := httptest.NewServer(slow)
+	defer oldServer.Close()
+	sim := NewSimulator()
+	newServer := httptest.NewServer(sim)
+	defer newServer.Close()
+
+	gm := NewGMAPIConnector(WithBaseURL(oldServer.URL), WithRateLimit(10, 20), WithCircuitBreaker(5, time.Minute))
+
+	done := make(chan *shared.APIError)
+	go func() {
+		_, err := gm.GetVehicle(1234)
+		done <- err
+	}()
+	for slow.Requests() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	err := Reconfigure(gm, Settings{BaseURL: newServer.URL + "/", RateLimit: 50, RateBurst: 5, BreakerThreshold: 2, BreakerCooldown: time.Second})
+	assert.NoError(t, err)
+
+	// the request in flight completes against the old GM, the next ones go to the new one
+	assert.Nil(t, <-done)
+	_, apiErr := gm.GetVehicle(1234)
+	assert.Nil(t, apiErr)
+	assert.Equal(t, int64(1), slow.Requests())
+	assert.Equal(t, int64(1), sim.Requests())
+
+	connector := gm.(*gmAPIConnector)
+	rate, burst := connector.limiter.Limit()
+	assert.Equal(t, 50.0, rate)
+	assert.Equal(t, 5, burst)
+	threshold, cooldown := connector.breaker.Limits()
+	assert.Equal(t, 2, threshold)
+	assert.Equal(t, time.Second, cooldown)
+
+	assert.NoError(t, Reconfigure(gm, Settings{}))
+	assert.Equal(t, gmAPIURL, connector.baseURL.Load())
+
+	assert.Error(t, Reconfigure(NewMockGMAPIConnector(), Settings{}))
+}
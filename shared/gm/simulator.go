@@ -0,0 +1,174 @@
+package gmapiconnector
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Simulator ... serves the GM API locally, answering for the same vehicles as GM's sandbox, so the API can be load tested
+// without GM's own latency, rate limits and random failures. Point the connector at it with WithBaseURL
+type Simulator struct {
+	latency   time.Duration
+	jitter    time.Duration
+	errorRate float64
+	requests  int64
+}
+
+// SimulatorOption ... configures how the simulator behaves
+type SimulatorOption func(*Simulator)
+
+// WithLatency ... delays every response by latency, plus up to jitter more, picked at random
+func WithLatency(latency, jitter time.Duration) SimulatorOption {
+	return func(s *Simulator) {
+		s.latency = latency
+		s.jitter = jitter
+	}
+}
+
+// WithErrorRate ... fails that fraction of the requests, from 0 to 1, with a 500 as GM does when it is overloaded
+func WithErrorRate(rate float64) SimulatorOption {
+	return func(s *Simulator) {
+		s.errorRate = rate
+	}
+}
+
+// NewSimulator ... returns a simulator answering right away, without errors
+func NewSimulator(opts ...SimulatorOption) *Simulator {
+	s := &Simulator{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Requests ... the number of requests the simulator received
+func (s *Simulator) Requests() int64 {
+	return atomic.LoadInt64(&s.requests)
+}
+
+// gmRequest ... the body of every GM request
+type gmRequest struct {
+	ID           string `json:"id"`
+	Command      string `json:"command"`
+	ResponseType string `json:"responseType"`
+}
+
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&s.requests, 1)
+
+	if !s.wait(r) {
+		return
+	}
+	if s.errorRate > 0 && rand.Float64() < s.errorRate {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	service := strings.TrimPrefix(r.URL.Path, "/")
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req gmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ResponseType != jsonResponseType {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	// like GM, an unknown vehicle is reported in the body of a 200
+	if req.ID != "1234" && req.ID != "1235" {
+		writeSimulated(w, map[string]interface{}{
+			"status": "404",
+			"reason": fmt.Sprintf("Vehicle id: %s not found.", req.ID),
+		})
+		return
+	}
+
+	switch service {
+	case getVehicle:
+		writeSimulated(w, map[string]interface{}{
+			"service": "getVehicleInfo",
+			"status":  "200",
+			"data": map[string]DataValue{
+				"vin":           {"String", "123123412412"},
+				"color":         {"String", "Metallic Silver"},
+				"fourDoorSedan": {"Boolean", "True"},
+				"twoDoorCoupe":  {"Boolean", "False"},
+				"driveTrain":    {"String", "v8"},
+			},
+		})
+
+	case getVehicleDoors:
+		writeSimulated(w, map[string]interface{}{
+			"service": "getSecurityStatus",
+			"status":  "200",
+			"data": Doors{DoorsArrayDataValue{
+				Type: "Array",
+				Values: []map[string]DataValue{
+					{"location": {"String", "frontLeft"}, "locked": {"Boolean", "True"}},
+					{"location": {"String", "frontRight"}, "locked": {"Boolean", "True"}},
+				},
+			}},
+		})
+
+	case getVehicleEnergyLevel:
+		// 1234 runs on fuel and 1235 on battery, as in GM's sandbox
+		tank, battery := DataValue{"Number", "33.5"}, DataValue{"Null", "null"}
+		if req.ID == "1235" {
+			tank, battery = DataValue{"Null", "null"}, DataValue{"Number", "88.55"}
+		}
+		writeSimulated(w, map[string]interface{}{
+			"service": "getEnergy",
+			"status":  "200",
+			"data":    map[string]DataValue{"tankLevel": tank, "batteryLevel": battery},
+		})
+
+	case postVehicleEngineAction:
+		if req.Command != ENGINE_START && req.Command != ENGINE_STOP {
+			writeSimulated(w, map[string]interface{}{
+				"status": "400",
+				"reason": fmt.Sprintf("Invalid command: %s", req.Command),
+			})
+			return
+		}
+		writeSimulated(w, map[string]interface{}{
+			"service":      "actionEngine",
+			"status":       "200",
+			"actionResult": ActionResult{EXECUTED},
+		})
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// wait ... sleeps for the latency of a response, returning false if the client gave up meanwhile
+func (s *Simulator) wait(r *http.Request) bool {
+	delay := s.latency
+	if s.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(s.jitter)))
+	}
+	if delay <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func writeSimulated(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,66 @@
+package gmapiconnector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulatorServesTheConnector(t *testing.T) {
+	sim := NewSimulator()
+	server := httptest.NewServer(sim)
+	defer server.Close()
+
+	gm := NewGMAPIConnector(WithBaseURL(server.URL + "/"))
+
+	info, err := gm.GetVehicle(1234)
+	assert.Nil(t, err)
+	assert.Equal(t, gmVehicleData{"123123412412", "Metallic Silver", true, false, "v8"}, info)
+
+	doors, err := gm.GetVehicleDoors(1235)
+	assert.Nil(t, err)
+	assert.Equal(t, []GMVehicleDoorData{{"frontLeft", true}, {"frontRight", true}}, doors)
+
+	fuel, battery, err := gm.GetVehicleEnergyStatus(1234)
+	assert.Nil(t, err)
+	assert.Equal(t, 33.5, *fuel)
+	assert.Nil(t, battery)
+
+	fuel, battery, err = gm.GetVehicleEnergyStatus(1235)
+	assert.Nil(t, err)
+	assert.Nil(t, fuel)
+	assert.Equal(t, 88.55, *battery)
+
+	res, err := gm.SendVehicleEngineAction(1234, ENGINE_START)
+	assert.Nil(t, err)
+	assert.Equal(t, ActionResult{EXECUTED}, res)
+
+	_, err = gm.GetVehicle(1236)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusInternalServerError, err.ErrorCode)
+	assert.Contains(t, err.ErrorMessage.Error(), "Vehicle id: 1236 not found.")
+
+	assert.Equal(t, int64(6), sim.Requests())
+}
+
+func TestSimulatorLatencyAndErrors(t *testing.T) {
+	server := httptest.NewServer(NewSimulator(WithLatency(30*time.Millisecond, 10*time.Millisecond)))
+	defer server.Close()
+
+	gm := NewGMAPIConnector(WithBaseURL(server.URL))
+	start := time.Now()
+	_, err := gm.GetVehicle(1234)
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) >= 30*time.Millisecond)
+
+	failing := httptest.NewServer(NewSimulator(WithErrorRate(1)))
+	defer failing.Close()
+
+	gm = NewGMAPIConnector(WithBaseURL(failing.URL))
+	_, err = gm.GetVehicle(1234)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.ErrorMessage.Error(), "non-200 response")
+}
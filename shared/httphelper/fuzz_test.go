@@ -0,0 +1,71 @@
+package httphelper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// Run a target with e.g. go test ./shared/httphelper -run '^$' -fuzz FuzzHandleJSONErrors -fuzztime 1m. Crashers are
+// saved under testdata/fuzz, and are committed so they run with the other tests from then on.
+
+var fuzzSeeds = []string{good, badForm, unexpEOF, invalidVal, unknownField, failsValidation, empty, multiple,
+	`{"integer": 1e400}`, `{"integer": 1.5}`, `{"string": "\ud800"}`, `[]`, `null`, `{"bool": null}`}
+
+func FuzzHandleJSONErrors(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		dst := validatedJSON{}
+		dec, err := HandleJSONErrors(context.Background(), bytes.NewReader(body), &dst)
+		if err != nil {
+			// whatever the body, it is the client's mistake
+			if err.ErrorCode != http.StatusBadRequest || err.ClientErrorMessage == "" || err.ErrorMessage == nil {
+				t.Fatalf("malformed error %+v for %q", err, body)
+			}
+			return
+		}
+		if dec == nil {
+			t.Fatal("no decoder without an error")
+		}
+
+		// what was decoded round trips
+		b, marshalErr := json.Marshal(dst)
+		if marshalErr != nil {
+			t.Fatal(marshalErr)
+		}
+		again := validatedJSON{}
+		if _, err := HandleJSONErrors(context.Background(), bytes.NewReader(b), &again); err != nil {
+			t.Fatalf("%s doesn't decode again: %+v", b, err)
+		}
+		if !reflect.DeepEqual(dst, again) {
+			t.Fatalf("%+v decoded again as %+v", dst, again)
+		}
+	})
+}
+
+func FuzzDecodeJSONBody(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		err := decodeTestHelper(string(body), goodCType, &validatedJSON{})
+		if err == nil {
+			return
+		}
+		switch err.ErrorCode {
+		case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		default:
+			t.Fatalf("status %d for %q", err.ErrorCode, body)
+		}
+		if len(err.ValidationErrors) > 0 && err.ClientErrorMessage != "Request body failed validation" {
+			t.Fatalf("validation errors with %q", err.ClientErrorMessage)
+		}
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"app_api/shared"
+	"app_api/shared/validator"
 
 	"github.com/golang/gddo/httputil/header"
 )
@@ -71,6 +72,8 @@ func HandleJSONErrors(ctx context.Context, body io.Reader, dst interface{}) (*js
 }
 
 // DecodeJSONBody ... decode json body and perform various checks
+// Once decoded, the `validate` struct tags on dst are applied and any failures are returned as a 400 with
+// the list of field errors attached
 func DecodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}) *shared.APIError {
 	ctx := r.Context()
 
@@ -98,5 +101,12 @@ func DecodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}) *sh
 		e := shared.NewAPIError(http.StatusBadRequest, errors.New(msg), msg)
 		return e
 	}
+
+	if fieldErrors := validator.Validate(dst); len(fieldErrors) > 0 {
+		msg := "Request body failed validation"
+		e := shared.NewAPIError(http.StatusBadRequest, fmt.Errorf("%s: %d field error(s)", msg, len(fieldErrors)), msg).
+			SetValidationErrors(fieldErrors)
+		return e
+	}
 	return nil
 }
@@ -22,11 +22,20 @@ type testJSON struct {
 	Bool    bool    `json:"bool"`
 }
 
+// validatedJSON ... testJSON with validation rules
+type validatedJSON struct {
+	Integer int     `json:"integer" validate:"max=10"`
+	String  string  `json:"string" validate:"required"`
+	Float   float64 `json:"float"`
+	Bool    bool    `json:"bool"`
+}
+
 const good = `{"integer": 1, "string": "characters", "float": 1.5, "bool": true}`
 const badForm = `{"integer":1,"string":"characters","float":1.5,"bool" true}`
 const unexpEOF = `{`
 const invalidVal = `{"integer":1,"string":123,"float":1.5,"bool":"hello"}`
 const unknownField = `{"integer":1,"unknown":"field","string":"characters","float":1.5,"bool":true}`
+const failsValidation = `{"integer":11,"float":1.5,"bool":true}`
 const empty = ``
 const multiple = `{"integer":1,"string":"characters","float":1.5,"bool":true}{"integer":1,"string":"characters","float":1.5,"bool":true}`
 const goodCType = "application/json"
@@ -34,15 +43,17 @@ const badCType = "application/x-www-form-urlencoded"
 
 // HELPER FUNCTIONS
 func jsonTestHelper(jsonString string, cType string) *shared.APIError {
-	body := testJSON{}
+	return decodeTestHelper(jsonString, cType, &testJSON{})
+}
 
+func decodeTestHelper(jsonString string, cType string, dst interface{}) *shared.APIError {
 	jsb := bytes.NewBuffer([]byte(jsonString))
 
 	r, _ := http.NewRequest("POST", "/v3/account", jsb)
 	w := httptest.NewRecorder()
 	r.Header.Set("Content-Type", cType)
 
-	err := DecodeJSONBody(w, r, &body)
+	err := DecodeJSONBody(w, r, dst)
 	return err
 }
 
@@ -134,3 +145,13 @@ func TestMultiple(t *testing.T) {
 
 	testhelper.CheckResponseCode(t, http.StatusBadRequest, err.ErrorCode)
 }
+
+func TestFailsValidation(t *testing.T) {
+	err := decodeTestHelper(failsValidation, goodCType, &validatedJSON{})
+
+	testhelper.CheckResponseCode(t, http.StatusBadRequest, err.ErrorCode)
+	assert.Equal(t, []shared.FieldError{
+		{Field: "integer", Rule: "max", Message: "integer must be at most 10"},
+		{Field: "string", Rule: "required", Message: "string is required"},
+	}, err.ValidationErrors)
+}
@@ -0,0 +1,56 @@
+package httphelper
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"app_api/shared"
+)
+
+// ParsePagination ... reads the offset and limit query parameters used by list endpoints returning a BatchResponse
+func ParsePagination(r *http.Request, defaultLimit, maxLimit int64) (offset, limit int64, err *shared.APIError) {
+	query := r.URL.Query()
+	limit = defaultLimit
+
+	if raw := query.Get("offset"); raw != "" {
+		parsed, parseErr := strconv.ParseInt(raw, 10, 64)
+		if parseErr != nil || parsed < 0 {
+			err = shared.NewAPIError(http.StatusBadRequest, fmt.Errorf("Invalid offset: %s", raw), "offset must be a non-negative integer")
+			return
+		}
+		offset = parsed
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		parsed, parseErr := strconv.ParseInt(raw, 10, 64)
+		if parseErr != nil || parsed < 1 || parsed > maxLimit {
+			err = shared.NewAPIError(http.StatusBadRequest, fmt.Errorf("Invalid limit: %s", raw), fmt.Sprintf("limit must be an integer between 1 and %d", maxLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	return
+}
+
+// Paginate ... returns the BatchResponse for the page of items selected by offset and limit
+func Paginate(items []interface{}, offset, limit int64) BatchResponse {
+	total := int64(len(items))
+
+	start, end := offset, offset+limit
+	if start > total {
+		start = total
+	}
+	if end > total {
+		end = total
+	}
+
+	return BatchResponse{
+		Result: items[start:end],
+		Length: end - start,
+		Offset: offset,
+		Limit:  limit,
+		Total:  total,
+	}
+}
@@ -0,0 +1,46 @@
+package httphelper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	testhelper "app_api/shared/testhelpers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePaginationDefaults(t *testing.T) {
+	r := httptest.NewRequest("GET", "/vehicles", nil)
+
+	offset, limit, err := ParsePagination(r, 100, 500)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), offset)
+	assert.Equal(t, int64(100), limit)
+}
+
+func TestParsePagination(t *testing.T) {
+	r := httptest.NewRequest("GET", "/vehicles?offset=20&limit=10", nil)
+
+	offset, limit, err := ParsePagination(r, 100, 500)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(20), offset)
+	assert.Equal(t, int64(10), limit)
+}
+
+func TestParsePaginationInvalid(t *testing.T) {
+	for _, query := range []string{"offset=-1", "offset=abc", "limit=0", "limit=501"} {
+		r := httptest.NewRequest("GET", "/vehicles?"+query, nil)
+
+		_, _, err := ParsePagination(r, 100, 500)
+		testhelper.CheckResponseCode(t, http.StatusBadRequest, err.ErrorCode)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	items := []interface{}{1, 2, 3, 4, 5}
+
+	assert.Equal(t, BatchResponse{Result: []interface{}{2, 3}, Length: 2, Offset: 1, Limit: 2, Total: 5}, Paginate(items, 1, 2))
+	assert.Equal(t, BatchResponse{Result: []interface{}{5}, Length: 1, Offset: 4, Limit: 2, Total: 5}, Paginate(items, 4, 2))
+	assert.Equal(t, BatchResponse{Result: []interface{}{}, Length: 0, Offset: 10, Limit: 2, Total: 5}, Paginate(items, 10, 2))
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-	"strings"
 
 	"app_api/shared"
 	loghelper "app_api/shared/loghelpers"
@@ -67,8 +66,8 @@ func NewResponse(ctx context.Context, w http.ResponseWriter, result interface{},
 			Error:   1,
 			Message: apiError.ClientErrorMessage,
 		}
-		if apiError.ValidationErrorMessage != "" {
-			resp.Result = map[string]interface{}{"validation_error": strings.Split(apiError.ValidationErrorMessage, "\n")}
+		if len(apiError.ValidationErrors) > 0 {
+			resp.Result = map[string]interface{}{"validation_errors": apiError.ValidationErrors}
 		}
 
 		jResult, err := resp.marshal()
@@ -66,3 +66,16 @@ func TestNewResponseError(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
 	assert.Equal(t, jExpected, body)
 }
+
+func TestNewResponseValidationError(t *testing.T) {
+	w := httptest.NewRecorder()
+	msg := "Request body failed validation"
+	e := shared.NewAPIError(http.StatusBadRequest, errors.New(msg), msg).
+		SetValidationErrors([]shared.FieldError{{Field: "action", Rule: "oneof", Message: "action must be one of [START, STOP]"}})
+	ctx := context.Background()
+	NewResponse(ctx, w, nil, e)
+	resp := w.Result()
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.JSONEq(t, `{"error":1,"message":"Request body failed validation","result":{"validation_errors":[{"field":"action","rule":"oneof","message":"action must be one of [START, STOP]"}]}}`, string(body))
+}
@@ -65,8 +65,8 @@ func LogErrors(ctx context.Context, err *shared.APIError) {
 		"RequestID":     GetRequestID(ctx),
 		"Request":       GetRequestPath(ctx),
 	})
-	if err.ValidationErrorMessage != "" {
-		logMessage = logMessage.WithFields(log.Fields{"ValidationError": err.ValidationErrorMessage})
+	if len(err.ValidationErrors) > 0 {
+		logMessage = logMessage.WithFields(log.Fields{"ValidationErrors": err.ValidationErrors})
 	}
 	caller := err.Caller()
 	if caller != "" {
@@ -104,11 +104,11 @@ func LogErrorsNoCTX(err *shared.APIError) {
 		errMsg = err.ErrorMessage.Error()
 	}
 	log.WithFields(log.Fields{
-		"Error":           errMsg,
-		"ErrorCode":       err.ErrorCode,
-		"InternalError":   err.InternalErrorMessage,
-		"ClientError":     err.ClientErrorMessage,
-		"ValidationError": err.ValidationErrorMessage,
-		"Caller":          err.Caller(),
+		"Error":            errMsg,
+		"ErrorCode":        err.ErrorCode,
+		"InternalError":    err.InternalErrorMessage,
+		"ClientError":      err.ClientErrorMessage,
+		"ValidationErrors": err.ValidationErrors,
+		"Caller":           err.Caller(),
 	}).Error()
 }
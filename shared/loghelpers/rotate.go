@@ -0,0 +1,236 @@
+package loghelper
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BACKUP_TIME_FORMAT ... the time a log file was rotated at, in the name of its backup e.g. app_api-2020-11-05T18-30-00.000.log
+const BACKUP_TIME_FORMAT = "2006-01-02T15-04-05.000"
+
+// rotatingFile ... a log file moved aside to a backup, and started anew, once it reaches the size or age of its
+// rotation. Backups are compressed and pruned in the background
+type rotatingFile struct {
+	path     string
+	rotation Rotation
+	now      func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	rotateAt time.Time
+
+	millMu sync.Mutex
+	mill   sync.WaitGroup
+}
+
+// openRotatingFile ... opens the log file at path, appending to it
+func openRotatingFile(path string, rotation Rotation) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, rotation: rotation, now: time.Now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open ... opens the file, with mu locked. An existing file is rotated on the first write once its interval is over
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file, f.size = file, info.Size()
+	if f.rotation.Every > 0 {
+		start := f.now()
+		if f.size > 0 {
+			start = info.ModTime()
+		}
+		f.rotateAt = start.UTC().Truncate(f.rotation.Every).Add(f.rotation.Every)
+	}
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, errors.New("log file closed")
+	}
+
+	full := f.rotation.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.rotation.MaxSize
+	due := !f.rotateAt.IsZero() && !f.now().Before(f.rotateAt)
+	if full || due {
+		if err := f.rotate(); err != nil {
+			if f.file == nil {
+				return 0, err
+			}
+			// the file is kept, and its rotation tried again on the next write
+			fmt.Fprintf(os.Stderr, "failed to rotate the log file %s: %v\n", f.path, err)
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate ... moves the file aside to a backup and opens a new one, with mu locked
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	if err := os.Rename(f.path, f.backupName(f.now())); err != nil {
+		if openErr := f.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.mill.Add(1)
+	go func() {
+		defer f.mill.Done()
+		f.millBackups()
+	}()
+	return nil
+}
+
+// Close ... closes the file, once the backups are compressed and pruned
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	f.mill.Wait()
+	return err
+}
+
+// backupName ... the name of the backup of the file rotated at t, e.g. app_api-2020-11-05T18-30-00.000.log
+func (f *rotatingFile) backupName(t time.Time) string {
+	prefix, ext := f.backupPrefix()
+	return prefix + t.UTC().Format(BACKUP_TIME_FORMAT) + ext
+}
+
+// backupPrefix ... the path of the backups up to their time, and their extension
+func (f *rotatingFile) backupPrefix() (string, string) {
+	ext := filepath.Ext(f.path)
+	return strings.TrimSuffix(f.path, ext) + "-", ext
+}
+
+// backup ... a rotated log file
+type backup struct {
+	path       string
+	rotatedAt  time.Time
+	compressed bool
+}
+
+// backups ... the backups of the file, newest first
+func (f *rotatingFile) backups() ([]backup, error) {
+	infos, err := ioutil.ReadDir(filepath.Dir(f.path))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, ext := f.backupPrefix()
+	prefix = filepath.Base(prefix)
+	var res []backup
+	for _, info := range infos {
+		name := info.Name()
+		compressed := strings.HasSuffix(name, ext+".gz")
+		if !strings.HasPrefix(name, prefix) || !(compressed || strings.HasSuffix(name, ext)) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+		rotatedAt, err := time.Parse(BACKUP_TIME_FORMAT, stamp)
+		if err != nil {
+			continue
+		}
+		res = append(res, backup{path: filepath.Join(filepath.Dir(f.path), name), rotatedAt: rotatedAt, compressed: compressed})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].rotatedAt.After(res[j].rotatedAt) })
+	return res, nil
+}
+
+// millBackups ... removes the backups beyond MaxBackups or older than MaxAge, and compresses the others. Errors go to
+// stderr, as the logs may be what is failing
+func (f *rotatingFile) millBackups() {
+	f.millMu.Lock()
+	defer f.millMu.Unlock()
+
+	backups, err := f.backups()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to list the log backups: %v\n", err)
+		return
+	}
+	for i, b := range backups {
+		tooMany := f.rotation.MaxBackups > 0 && i >= f.rotation.MaxBackups
+		tooOld := f.rotation.MaxAge > 0 && f.now().Sub(b.rotatedAt) > f.rotation.MaxAge
+		switch {
+		case tooMany || tooOld:
+			err = os.Remove(b.path)
+		case f.rotation.Compress && !b.compressed:
+			err = compress(b.path)
+		default:
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to mill the log backup %s: %v\n", b.path, err)
+		}
+	}
+}
+
+// compress ... gzips the file at path next to it, then removes it
+func compress(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(path + ".gz")
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
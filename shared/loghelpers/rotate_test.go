@@ -0,0 +1,125 @@
+package loghelper
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClock ... a time moved forward by hand
+type testClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *testClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newTestFile(t *testing.T, rotation Rotation, clock *testClock) (*rotatingFile, string) {
+	dir, err := ioutil.TempDir("", "loghelpers")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "app_api.log")
+	f := &rotatingFile{path: path, rotation: rotation, now: clock.now}
+	require.NoError(t, f.open())
+	return f, dir
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotatingFileBySize(t *testing.T) {
+	clock := &testClock{t: time.Date(2020, 11, 5, 18, 30, 0, 0, time.UTC)}
+	f, dir := newTestFile(t, Rotation{MaxSize: 20}, clock)
+
+	for _, line := range []string{"polled 1234\n", "polled 1235\n", "polled 1236\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+		clock.advance(time.Second)
+	}
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, []string{"app_api-2020-11-05T18-30-01.000.log", "app_api-2020-11-05T18-30-02.000.log", "app_api.log"}, dirNames(t, dir))
+	content, err := ioutil.ReadFile(filepath.Join(dir, "app_api.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "polled 1236\n", string(content))
+
+	_, err = f.Write([]byte("polled 1237\n"))
+	assert.Error(t, err, "the file is closed")
+}
+
+func TestRotatingFileByTime(t *testing.T) {
+	clock := &testClock{t: time.Date(2020, 11, 5, 23, 59, 0, 0, time.UTC)}
+	f, dir := newTestFile(t, Rotation{Every: 24 * time.Hour}, clock)
+
+	_, err := f.Write([]byte("polled 1234\n"))
+	require.NoError(t, err)
+	clock.advance(30 * time.Second)
+	_, err = f.Write([]byte("polled 1235\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app_api.log"}, dirNames(t, dir), "the day isn't over")
+
+	clock.advance(time.Minute)
+	_, err = f.Write([]byte("polled 1236\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, []string{"app_api-2020-11-06T00-00-30.000.log", "app_api.log"}, dirNames(t, dir))
+}
+
+func TestRotatingFileRetention(t *testing.T) {
+	clock := &testClock{t: time.Date(2020, 11, 5, 18, 30, 0, 0, time.UTC)}
+	f, dir := newTestFile(t, Rotation{MaxSize: 1, MaxBackups: 2, MaxAge: time.Hour, Compress: true}, clock)
+
+	// a backup older than MaxAge, left from a previous run
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "app_api-2020-11-05T17-00-00.000.log"), []byte("old\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "app_api-notes.log"), []byte("not a backup\n"), 0644))
+
+	for _, line := range []string{"a\n", "b\n", "c\n", "d\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+		clock.advance(time.Second)
+	}
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, []string{
+		"app_api-2020-11-05T18-30-02.000.log.gz",
+		"app_api-2020-11-05T18-30-03.000.log.gz",
+		"app_api-notes.log",
+		"app_api.log",
+	}, dirNames(t, dir))
+
+	file, err := os.Open(filepath.Join(dir, "app_api-2020-11-05T18-30-03.000.log.gz"))
+	require.NoError(t, err)
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "c\n", string(content))
+}
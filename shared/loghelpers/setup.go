@@ -0,0 +1,309 @@
+package loghelper
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	FORMAT_LOGFMT = "logfmt"
+	FORMAT_JSON   = "json"
+
+	SINK_FILE   = "file"
+	SINK_STDOUT = "stdout"
+	SINK_SYSLOG = "syslog"
+
+	// SYSLOG_TAG ... the tag of the messages sent to syslog
+	SYSLOG_TAG = "app_api"
+)
+
+// Options ... where and how the logs are written
+type Options struct {
+	// Format ... FORMAT_LOGFMT or FORMAT_JSON, logfmt when empty
+	Format string
+	// Sinks ... the sinks every log is written to, stdout when empty
+	Sinks []string
+	// File ... the file of SINK_FILE
+	File     string
+	Rotation Rotation
+	// Level ... the level logs are written from
+	Level log.Level
+	// PackageLevels ... the level of the packages logging from another level, see ParseLevels
+	PackageLevels map[string]log.Level
+}
+
+// Rotation ... when the log file is rotated, and how long the rotated files are kept
+type Rotation struct {
+	// MaxSize ... the bytes from which the file is rotated, never when 0
+	MaxSize int64
+	// Every ... the interval the file is rotated at, from midnight UTC, never when 0
+	Every time.Duration
+	// MaxBackups ... the rotated files kept, all when 0
+	MaxBackups int
+	// MaxAge ... how long rotated files are kept, forever when 0
+	MaxAge time.Duration
+	// Compress ... gzips the rotated files
+	Compress bool
+}
+
+// hookCloser ... a sink writing entries by level, such as syslog
+type hookCloser interface {
+	log.Hook
+	io.Closer
+}
+
+// Logging ... the sinks the logs are written to
+type Logging struct {
+	formatter log.Formatter
+	out       io.Writer
+	hooks     []hookCloser
+	closers   []io.Closer
+}
+
+// current ... the logging of the latest Setup, nil until then
+var current *Logging
+
+// Setup ... writes the logs of the standard logger to the sinks of opts, in its format and from its levels. Close
+// the returned logging on shutdown, to flush the sinks
+func Setup(opts Options) (*Logging, error) {
+	var formatter log.Formatter
+	switch opts.Format {
+	case "", FORMAT_LOGFMT:
+		formatter = &log.TextFormatter{DisableColors: true, FullTimestamp: true, QuoteEmptyFields: true}
+	case FORMAT_JSON:
+		formatter = &log.JSONFormatter{}
+	default:
+		return nil, fmt.Errorf("unknown log format %q", opts.Format)
+	}
+
+	sinks := opts.Sinks
+	if len(sinks) == 0 {
+		sinks = []string{SINK_STDOUT}
+	}
+	l := &Logging{formatter: &levelFilter{formatter: formatter}}
+	var writers multiWriter
+	for _, sink := range sinks {
+		switch sink {
+		case SINK_STDOUT:
+			writers = append(writers, os.Stdout)
+		case SINK_FILE:
+			file, err := openRotatingFile(opts.File, opts.Rotation)
+			if err != nil {
+				l.Close()
+				return nil, err
+			}
+			writers = append(writers, file)
+			l.closers = append(l.closers, file)
+		case SINK_SYSLOG:
+			hook, err := newSyslogHook(formatter)
+			if err != nil {
+				l.Close()
+				return nil, err
+			}
+			l.hooks = append(l.hooks, hook)
+			l.closers = append(l.closers, hook)
+		default:
+			l.Close()
+			return nil, fmt.Errorf("unknown log sink %q", sink)
+		}
+	}
+	l.out = writers
+
+	std := log.StandardLogger()
+	std.SetReportCaller(false)
+	std.SetFormatter(l.formatter)
+	std.SetOutput(l.out)
+	for _, hook := range l.hooks {
+		std.AddHook(hook)
+	}
+	SetLevel(opts.Level)
+	SetPackageLevels(opts.PackageLevels)
+	current = l
+	return l, nil
+}
+
+// Close ... flushes and closes the sinks
+func (l *Logging) Close() error {
+	var res error
+	for _, closer := range l.closers {
+		if err := closer.Close(); err != nil && res == nil {
+			res = err
+		}
+	}
+	return res
+}
+
+// NewLogger ... returns a logger writing to the sinks of the latest Setup in its format, or where the standard logger
+// does before any, from the info level whatever the levels of Setup, e.g. for logs which must never be filtered
+func NewLogger() *log.Logger {
+	logger := log.New()
+	logger.SetLevel(log.InfoLevel)
+	if current == nil {
+		logger.SetOutput(log.StandardLogger().Out)
+		return logger
+	}
+	logger.SetFormatter(current.formatter)
+	logger.SetOutput(current.out)
+	for _, hook := range current.hooks {
+		logger.AddHook(hook)
+	}
+	return logger
+}
+
+// multiWriter ... writes to every writer, skipping the empty writes of the entries filtered out
+type multiWriter []io.Writer
+
+func (w multiWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	var res error
+	for _, writer := range w {
+		if _, err := writer.Write(p); err != nil && res == nil {
+			res = err
+		}
+	}
+	return len(p), res
+}
+
+// levels ... the level of the standard logger, and of the packages logging from another level. The standard logger
+// runs at the most verbose of them, and the entries of the other packages are then filtered out by allowed
+var levels struct {
+	sync.RWMutex
+	level    log.Level
+	packages map[string]log.Level
+}
+
+// Level ... the level logs are written from, besides the packages with a level of their own
+func Level() log.Level {
+	levels.RLock()
+	defer levels.RUnlock()
+	if len(levels.packages) == 0 {
+		return log.GetLevel()
+	}
+	return levels.level
+}
+
+// SetLevel ... writes logs from level, besides the packages with a level of their own
+func SetLevel(level log.Level) {
+	levels.Lock()
+	defer levels.Unlock()
+	levels.level = level
+	applyLevels()
+}
+
+// SetPackageLevels ... writes the logs of the packages from their own level, the others' from Level
+func SetPackageLevels(packages map[string]log.Level) {
+	levels.Lock()
+	defer levels.Unlock()
+	if len(levels.packages) == 0 {
+		levels.level = log.GetLevel()
+	}
+	levels.packages = packages
+	applyLevels()
+}
+
+// applyLevels ... runs the standard logger at the most verbose level, with levels locked
+func applyLevels() {
+	level := levels.level
+	for _, l := range levels.packages {
+		if l > level {
+			level = l
+		}
+	}
+	log.SetLevel(level)
+}
+
+// ParseLevels ... parses the levels of packages, e.g. "gm=debug,apis/poller=warning". A package is named by its import
+// path, or its end e.g. gm for app_api/shared/gm; the longest name matching a package applies
+func ParseLevels(raw string) (map[string]log.Level, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	res := make(map[string]log.Level)
+	for _, item := range strings.Split(raw, ",") {
+		parts := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid package level %q, e.g. gm=debug", strings.TrimSpace(item))
+		}
+		level, err := log.ParseLevel(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid level %q of %s", parts[1], parts[0])
+		}
+		res[parts[0]] = level
+	}
+	return res, nil
+}
+
+// allowed ... whether the entry is at or above the level of the package logging it. Only the entries of the standard
+// logger are filtered
+func allowed(entry *log.Entry) bool {
+	if entry.Logger != log.StandardLogger() {
+		return true
+	}
+	levels.RLock()
+	defer levels.RUnlock()
+	if len(levels.packages) == 0 {
+		return true
+	}
+
+	level, matched := levels.level, -1
+	pkg := callerPackage()
+	for name, l := range levels.packages {
+		if (pkg == name || strings.HasSuffix(pkg, "/"+name)) && len(name) > matched {
+			level, matched = l, len(name)
+		}
+	}
+	return entry.Level <= level
+}
+
+// levelFilter ... formats the entries allowed, and nothing for the others
+type levelFilter struct {
+	formatter log.Formatter
+}
+
+func (f *levelFilter) Format(entry *log.Entry) ([]byte, error) {
+	if !allowed(entry) {
+		return nil, nil
+	}
+	return f.formatter.Format(entry)
+}
+
+// ownPackage ... the import path of this package, whose helpers log on behalf of their caller
+var ownPackage = packageOf(runtime.FuncForPC(reflect.ValueOf(packageOf).Pointer()).Name())
+
+// callerPackage ... the import path of the package logging, the first caller outside logrus and the helpers of this
+// package
+func callerPackage() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		pkg := packageOf(frame.Function)
+		helper := pkg == ownPackage && !strings.HasSuffix(frame.File, "_test.go")
+		if !helper && !strings.HasPrefix(pkg, "github.com/sirupsen/logrus") {
+			return pkg
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// packageOf ... the import path of the package of a function, e.g. app_api/apis/poller for
+// app_api/apis/poller.(*Service).poll
+func packageOf(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
@@ -0,0 +1,124 @@
+package loghelper
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setUpTestLogging ... sets up the standard logger to log to a file, restoring it after the test
+func setUpTestLogging(t *testing.T, opts Options) string {
+	dir, err := ioutil.TempDir("", "loghelpers")
+	require.NoError(t, err)
+
+	std := log.StandardLogger()
+	out, formatter, level := std.Out, std.Formatter, std.GetLevel()
+	t.Cleanup(func() {
+		SetPackageLevels(nil)
+		std.SetOutput(out)
+		std.SetFormatter(formatter)
+		std.SetLevel(level)
+		current = nil
+		hook.Reset()
+		os.RemoveAll(dir)
+	})
+
+	opts.Sinks = []string{SINK_FILE}
+	opts.File = filepath.Join(dir, "app_api.log")
+	logging, err := Setup(opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { logging.Close() })
+	return opts.File
+}
+
+// readEntries ... the JSON entries of the log file
+func readEntries(t *testing.T, path string) []map[string]interface{} {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var entries []map[string]interface{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestSetup(t *testing.T) {
+	path := setUpTestLogging(t, Options{Format: FORMAT_JSON, Level: log.WarnLevel})
+
+	log.WithField("VehicleID", "1234").Warn("GM is slow")
+	log.Info("polled")
+	NewLogger().WithField("Audit", true).Info("admin request")
+
+	entries := readEntries(t, path)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "GM is slow", entries[0]["msg"])
+	assert.Equal(t, "warning", entries[0]["level"])
+	assert.Equal(t, "1234", entries[0]["VehicleID"])
+	assert.Equal(t, true, entries[1]["Audit"], "loggers of NewLogger write whatever the level")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	_, err = Setup(Options{Format: "xml"})
+	assert.Error(t, err)
+	_, err = Setup(Options{Sinks: []string{"kafka"}})
+	assert.Error(t, err)
+}
+
+func TestPackageLevels(t *testing.T) {
+	path := setUpTestLogging(t, Options{Format: FORMAT_JSON, Level: log.WarnLevel, PackageLevels: map[string]log.Level{"gm": log.DebugLevel}})
+
+	assert.Equal(t, log.WarnLevel, Level())
+	assert.Equal(t, log.DebugLevel, log.GetLevel(), "the standard logger runs at the most verbose level")
+	log.Debug("dropped, as this package logs from the warning level")
+
+	SetPackageLevels(map[string]log.Level{"gm": log.ErrorLevel, "shared/loghelpers": log.DebugLevel})
+	log.Debug("kept")
+	SetLevel(log.ErrorLevel)
+	log.Warn("kept, as the package level wins")
+	assert.Equal(t, log.ErrorLevel, Level())
+
+	SetPackageLevels(nil)
+	log.Warn("dropped")
+	assert.Equal(t, log.ErrorLevel, Level())
+
+	entries := readEntries(t, path)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "kept", entries[0]["msg"])
+	assert.Equal(t, "kept, as the package level wins", entries[1]["msg"])
+}
+
+func TestParseLevels(t *testing.T) {
+	levels, err := ParseLevels("gm=debug, apis/poller=warning")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]log.Level{"gm": log.DebugLevel, "apis/poller": log.WarnLevel}, levels)
+
+	levels, err = ParseLevels("")
+	require.NoError(t, err)
+	assert.Empty(t, levels)
+
+	_, err = ParseLevels("gm")
+	assert.Error(t, err)
+	_, err = ParseLevels("gm=loud")
+	assert.Error(t, err)
+}
+
+func TestPackageOf(t *testing.T) {
+	assert.Equal(t, "app_api/apis/poller", packageOf("app_api/apis/poller.(*Service).poll"))
+	assert.Equal(t, "main", packageOf("main.main"))
+	assert.Equal(t, "github.com/sirupsen/logrus", packageOf("github.com/sirupsen/logrus.(*Entry).Log"))
+	assert.Equal(t, "app_api/shared/loghelpers", ownPackage)
+}
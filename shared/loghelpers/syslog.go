@@ -0,0 +1,58 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package loghelper
+
+import (
+	"log/syslog"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// syslogHook ... sends the entries to the local syslog daemon, at the severity of their level
+type syslogHook struct {
+	writer    *syslog.Writer
+	formatter log.Formatter
+}
+
+// newSyslogHook ... connects to the local syslog daemon over its socket
+func newSyslogHook(formatter log.Formatter) (hookCloser, error) {
+	writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, SYSLOG_TAG)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogHook{writer: writer, formatter: formatter}, nil
+}
+
+func (h *syslogHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h *syslogHook) Fire(entry *log.Entry) error {
+	if !allowed(entry) {
+		return nil
+	}
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	msg := strings.TrimSuffix(string(line), "\n")
+	switch entry.Level {
+	case log.PanicLevel, log.FatalLevel:
+		return h.writer.Crit(msg)
+	case log.ErrorLevel:
+		return h.writer.Err(msg)
+	case log.WarnLevel:
+		return h.writer.Warning(msg)
+	case log.InfoLevel:
+		return h.writer.Info(msg)
+	default:
+		return h.writer.Debug(msg)
+	}
+}
+
+func (h *syslogHook) Close() error {
+	return h.writer.Close()
+}
@@ -0,0 +1,16 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package loghelper
+
+import (
+	"errors"
+	"runtime"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// newSyslogHook ... syslog has no local socket on this platform
+func newSyslogHook(formatter log.Formatter) (hookCloser, error) {
+	return nil, errors.New("syslog isn't supported on " + runtime.GOOS)
+}
@@ -0,0 +1,212 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter ... a token bucket allowing `rate` events per second, with bursts of up to `burst` events
+// A rate of zero or less disables limiting
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewLimiter ... returns a limiter that starts with a full bucket
+func NewLimiter(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// SetLimit ... changes the rate and burst of the limiter, keeping the tokens that are already available
+func (l *Limiter) SetLimit(rate float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.advance(l.now())
+	l.rate = rate
+	l.burst = float64(burst)
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+// Limit ... returns the current rate and burst of the limiter
+func (l *Limiter) Limit() (rate float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate, int(l.burst)
+}
+
+// Allow ... takes a token if one is available right now
+func (l *Limiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate <= 0 {
+		return true
+	}
+
+	l.advance(l.now())
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Wait ... blocks until a token is available or the context is done
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+
+	// reserve the token up front, so concurrent waiters queue up behind each other
+	l.advance(l.now())
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// hand the reservation back
+		l.mu.Lock()
+		l.tokens++
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// full ... whether the bucket has every token, so no caller is waiting for one
+func (l *Limiter) full(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate <= 0 {
+		return true
+	}
+	l.advance(now)
+	return l.tokens >= l.burst
+}
+
+// advance ... refills the bucket for the time elapsed since it was last used. Must be called with the lock held
+func (l *Limiter) advance(now time.Time) {
+	if !l.last.IsZero() && l.rate > 0 {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+}
+
+// IDLE_KEY_TIMEOUT ... how long a KeyedLimiter keeps the limiter of a key that isn't used once its bucket is full again.
+// A full bucket is what a new limiter starts with, so dropping it doesn't let the key through any sooner
+const IDLE_KEY_TIMEOUT = time.Minute
+
+// KeyedLimiter ... keeps a separate Limiter per key, e.g. one per vehicle. The limiters of idle keys are dropped, so
+// the keys ever used don't pile up
+type KeyedLimiter struct {
+	mu       sync.Mutex
+	rate     float64
+	burst    int
+	limiters map[string]*keyedLimiter
+	swept    time.Time
+	now      func() time.Time
+}
+
+type keyedLimiter struct {
+	*Limiter
+	// used ... when the limiter was last handed out by Get
+	used time.Time
+}
+
+// NewKeyedLimiter ... returns a KeyedLimiter where every key gets its own bucket with the given rate and burst
+func NewKeyedLimiter(rate float64, burst int) *KeyedLimiter {
+	return &KeyedLimiter{
+		rate:     rate,
+		burst:    burst,
+		limiters: make(map[string]*keyedLimiter),
+		now:      time.Now,
+	}
+}
+
+// Get ... returns the limiter for the key, creating it on first use. Callers use the limiter right away rather than
+// keep it, as it is dropped once it has been idle for IDLE_KEY_TIMEOUT
+func (k *KeyedLimiter) Get(key string) *Limiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now()
+	if now.Sub(k.swept) >= IDLE_KEY_TIMEOUT {
+		k.sweep(now)
+	}
+
+	limiter, ok := k.limiters[key]
+	if !ok {
+		limiter = &keyedLimiter{Limiter: NewLimiter(k.rate, k.burst)}
+		limiter.now = k.now
+		k.limiters[key] = limiter
+	}
+	limiter.used = now
+	return limiter.Limiter
+}
+
+// sweep ... drops the limiters not handed out for IDLE_KEY_TIMEOUT whose buckets are full. Must be called with the lock held
+func (k *KeyedLimiter) sweep(now time.Time) {
+	k.swept = now
+	for key, limiter := range k.limiters {
+		if now.Sub(limiter.used) >= IDLE_KEY_TIMEOUT && limiter.full(now) {
+			delete(k.limiters, key)
+		}
+	}
+}
+
+// Wait ... blocks until a token is available for the key or the context is done
+func (k *KeyedLimiter) Wait(ctx context.Context, key string) error {
+	return k.Get(key).Wait(ctx)
+}
+
+// SetLimit ... changes the rate and burst for every existing and future key
+func (k *KeyedLimiter) SetLimit(rate float64, burst int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.rate, k.burst = rate, burst
+	for _, limiter := range k.limiters {
+		limiter.SetLimit(rate, burst)
+	}
+}
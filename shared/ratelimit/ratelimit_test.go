@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterAllowBurst(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := NewLimiter(1, 2)
+	limiter.now = func() time.Time { return now }
+
+	assert.True(t, limiter.Allow())
+	assert.True(t, limiter.Allow())
+	assert.False(t, limiter.Allow(), "The burst should be exhausted.")
+
+	now = now.Add(time.Second)
+	assert.True(t, limiter.Allow(), "A token should be refilled after a second.")
+	assert.False(t, limiter.Allow())
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	limiter := NewLimiter(0, 1)
+
+	for i := 0; i < 100; i++ {
+		assert.True(t, limiter.Allow())
+	}
+	assert.NoError(t, limiter.Wait(context.Background()))
+}
+
+func TestLimiterWait(t *testing.T) {
+	limiter := NewLimiter(50, 1)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, limiter.Wait(context.Background()))
+	}
+
+	// the first token is free, the next two are 20ms apart
+	assert.True(t, time.Since(start) >= 35*time.Millisecond, "Wait should block until tokens are refilled.")
+}
+
+func TestLimiterWaitCancelled(t *testing.T) {
+	limiter := NewLimiter(0.1, 1)
+	assert.True(t, limiter.Allow())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, limiter.Wait(ctx))
+}
+
+func TestLimiterSetLimit(t *testing.T) {
+	limiter := NewLimiter(1, 5)
+	limiter.SetLimit(2, 1)
+
+	rate, burst := limiter.Limit()
+	assert.Equal(t, float64(2), rate)
+	assert.Equal(t, 1, burst)
+	assert.True(t, limiter.Allow())
+	assert.False(t, limiter.Allow(), "The available tokens should be capped to the new burst.")
+}
+
+func TestKeyedLimiter(t *testing.T) {
+	keyed := NewKeyedLimiter(0.1, 1)
+
+	assert.True(t, keyed.Get("1234").Allow())
+	assert.False(t, keyed.Get("1234").Allow())
+	assert.True(t, keyed.Get("1235").Allow(), "Every key should have its own bucket.")
+
+	keyed.SetLimit(0, 1)
+	assert.True(t, keyed.Get("1234").Allow(), "Existing keys should pick up the new limit.")
+}
+
+func TestKeyedLimiterDropsIdleKeys(t *testing.T) {
+	now := time.Unix(0, 0)
+	keyed := NewKeyedLimiter(0.01, 2)
+	keyed.now = func() time.Time { return now }
+
+	assert.True(t, keyed.Get("1234").Allow())
+	assert.True(t, keyed.Get("1235").Allow())
+	assert.True(t, keyed.Get("1235").Allow())
+
+	// 1234 has refilled its bucket by then, 1235 hasn't
+	now = now.Add(2 * time.Minute)
+	keyed.Get("1236")
+	assert.Len(t, keyed.limiters, 2)
+	assert.NotContains(t, keyed.limiters, "1234")
+	assert.Contains(t, keyed.limiters, "1235", "The bucket of a key that isn't full should be kept.")
+
+	// a limiter handed out is kept however full its bucket, until it has been idle long enough
+	now = now.Add(IDLE_KEY_TIMEOUT / 2)
+	keyed.Get("1234")
+	now = now.Add(IDLE_KEY_TIMEOUT / 2)
+	keyed.Get("1236")
+	assert.Contains(t, keyed.limiters, "1234")
+}
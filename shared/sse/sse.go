@@ -0,0 +1,110 @@
+package sse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"app_api/shared"
+)
+
+const (
+	HEADER_LAST_EVENT_ID = "Last-Event-ID"
+
+	// QUERY_LAST_EVENT_ID ... the query parameter fallback for clients that can't set headers on the initial request
+	QUERY_LAST_EVENT_ID = "lastEventId"
+)
+
+var errNotStreamable = errors.New("response writer does not support flushing")
+
+// Writer ... writes server-sent events (text/event-stream) to a response, flushing after every write so the client
+// receives each event as soon as it is written
+type Writer struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// NewWriter ... sends the event stream headers. Nothing is written if the response can't be streamed, so the error can
+// still be returned as a regular response
+func NewWriter(w http.ResponseWriter) (*Writer, *shared.APIError) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, shared.NewAPIError(http.StatusInternalServerError, errNotStreamable, "Streaming is not supported")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// stops nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &Writer{w: w, flusher: flusher}, nil
+}
+
+// Event ... writes an event with data encoded as JSON. An empty id or name is left out: without an id the client keeps
+// the last one it received, without a name the client dispatches a "message" event
+func (w *Writer) Event(id, name string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	if id != "" {
+		fmt.Fprintf(&b, "id: %s\n", sanitize(id))
+	}
+	if name != "" {
+		fmt.Fprintf(&b, "event: %s\n", sanitize(name))
+	}
+	fmt.Fprintf(&b, "data: %s\n\n", payload)
+
+	return w.write(b.String())
+}
+
+// Comment ... writes a comment, which clients ignore. Used as a heartbeat so idle connections aren't closed by proxies,
+// and so a disconnected client is noticed
+func (w *Writer) Comment(text string) error {
+	return w.write(": " + sanitize(text) + "\n\n")
+}
+
+// Retry ... tells the client how long to wait before reconnecting once the stream is closed
+func (w *Writer) Retry(d time.Duration) error {
+	return w.write("retry: " + strconv.FormatInt(d.Milliseconds(), 10) + "\n\n")
+}
+
+func (w *Writer) write(s string) error {
+	if _, err := w.w.Write([]byte(s)); err != nil {
+		return err
+	}
+	w.flusher.Flush()
+	return nil
+}
+
+// LastEventID ... returns the ID of the last event a reconnecting client received, from the Last-Event-ID header or the
+// lastEventId query parameter. Returns false for a fresh connection, or an ID that isn't a number
+func LastEventID(r *http.Request) (int64, bool) {
+	value := r.Header.Get(HEADER_LAST_EVENT_ID)
+	if value == "" {
+		value = r.URL.Query().Get(QUERY_LAST_EVENT_ID)
+	}
+	if value == "" {
+		return 0, false
+	}
+
+	id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// sanitize ... a line break would end the field early and let the rest be read as another field
+func sanitize(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
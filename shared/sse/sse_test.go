@@ -0,0 +1,68 @@
+package sse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// unflushable ... a response writer that can't stream
+type unflushable struct {
+	http.ResponseWriter
+}
+
+func TestWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	w, err := NewWriter(rec)
+	assert.Nil(t, err)
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+	assert.True(t, rec.Flushed)
+
+	assert.NoError(t, w.Retry(3*time.Second))
+	assert.NoError(t, w.Event("42", "door_unlocked", map[string]interface{}{"vehicleId": 1234}))
+	assert.NoError(t, w.Event("", "", []int{1, 2}))
+	assert.NoError(t, w.Comment("heartbeat"))
+	assert.NoError(t, w.Event("1\n2", "bad\r\nname", nil))
+
+	assert.Equal(t, "retry: 3000\n\n"+
+		"id: 42\nevent: door_unlocked\ndata: {\"vehicleId\":1234}\n\n"+
+		"data: [1,2]\n\n"+
+		": heartbeat\n\n"+
+		"id: 12\nevent: badname\ndata: null\n\n", rec.Body.String())
+
+	assert.Error(t, w.Event("1", "bad", func() {}))
+}
+
+func TestNewWriterWithoutFlusher(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	w, err := NewWriter(unflushable{rec})
+	assert.Nil(t, w)
+	assert.Equal(t, http.StatusInternalServerError, err.ErrorCode)
+	assert.Empty(t, rec.Header().Get("Content-Type"), "Nothing is written, so the error can still be returned")
+}
+
+func TestLastEventID(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/vehicles/1234/stream", nil)
+	_, ok := LastEventID(r)
+	assert.False(t, ok)
+
+	r.Header.Set(HEADER_LAST_EVENT_ID, "42")
+	id, ok := LastEventID(r)
+	assert.True(t, ok)
+	assert.Equal(t, int64(42), id)
+
+	r = httptest.NewRequest(http.MethodGet, "/vehicles/1234/stream?lastEventId=7", nil)
+	id, ok = LastEventID(r)
+	assert.True(t, ok)
+	assert.Equal(t, int64(7), id)
+
+	r.Header.Set(HEADER_LAST_EVENT_ID, "abc")
+	_, ok = LastEventID(r)
+	assert.False(t, ok)
+}
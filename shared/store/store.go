@@ -0,0 +1,26 @@
+package store
+
+import (
+	"encoding/binary"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Open ... opens the embedded database shared by the services that persist state, creating the file if it doesn't exist.
+// Each service owns its own buckets within the database
+func Open(path string) (*bolt.DB, error) {
+	return bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+}
+
+// Int64Key ... encodes an integer ID as a big endian key, so keys sort in numeric order
+func Int64Key(id int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id))
+	return b
+}
+
+// KeyInt64 ... decodes a key created with Int64Key
+func KeyInt64(b []byte) int64 {
+	return int64(binary.BigEndian.Uint64(b))
+}
@@ -0,0 +1,35 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db, err := Open(filepath.Join(dir, "test.db"))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("test"))
+		if err != nil {
+			return err
+		}
+		return b.Put(Int64Key(1234), []byte("value"))
+	})
+	assert.NoError(t, err)
+}
+
+func TestInt64KeyOrdering(t *testing.T) {
+	assert.Equal(t, int64(1234), KeyInt64(Int64Key(1234)))
+	assert.True(t, string(Int64Key(2)) < string(Int64Key(10)), "Keys should sort numerically.")
+}
@@ -0,0 +1,31 @@
+// Package storetest ... helpers for the tests of the services that persist state to the store
+package storetest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"app_api/shared/store"
+
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Open ... returns a fresh database, closed and removed once the test completes
+func Open(t testing.TB) *bolt.DB {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "store")
+	require.NoError(t, err)
+
+	db, err := store.Open(filepath.Join(dir, "test.db"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		db.Close()
+		os.RemoveAll(dir)
+	})
+	return db
+}
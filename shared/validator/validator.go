@@ -0,0 +1,298 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"app_api/shared"
+)
+
+// Validate ... walks a decoded request struct and applies the rules declared in its `validate` struct tags.
+// Rules are comma separated and applied in order, e.g.
+//
+//	Action string  `json:"action" validate:"required,oneof=START STOP"`
+//	IDs    []int64 `json:"vehicle_ids" validate:"required,min=1,max=500"`
+//	Name   string  `json:"name" validate:"pattern=^[a-z0-9-]+$"`
+//
+// Supported rules:
+//
+//	required        - the value must not be the zero value (nil pointers, empty strings/slices/maps)
+//	oneof=a b c     - the value, or every item of a slice, must be one of the space separated options
+//	min=n / max=n   - numeric bounds for numbers; length bounds for strings, slices and maps
+//	pattern=regexp  - the string, or every item of a slice, must match the regular expression. pattern must be the last rule, as the
+//	                  expression may itself contain commas
+//
+// A zero value is taken for a field left out, as the decoded request can't tell them apart, so only required applies
+// to it and the other rules are skipped: a count of 0 passes min=1. A field whose zero value is invalid, but which may be
+// left out, is a pointer, e.g. `Limit *int64 validate:"min=1"`, so an explicit 0 is checked and only nil is skipped.
+//
+// Nested structs, pointers to structs and slices of structs are validated recursively. Field paths use the
+// JSON names of the fields so they can be surfaced to the client as-is, e.g. "vehicles[2].id"
+//
+// Rules that span several fields can't be expressed in tags, so if v implements Validator its Validate method
+// is called once the tags pass
+func Validate(v interface{}) []shared.FieldError {
+	var errs []shared.FieldError
+	validateValue(reflect.ValueOf(v), "", &errs)
+
+	if validator, ok := v.(Validator); ok && len(errs) == 0 {
+		errs = validator.Validate()
+	}
+	return errs
+}
+
+// Validator ... implemented by request structs with rules spanning several fields, e.g. "one of a or b is required"
+type Validator interface {
+	Validate() []shared.FieldError
+}
+
+const tagName = "validate"
+
+var patternCache sync.Map
+
+func validateValue(val reflect.Value, path string, errs *[]shared.FieldError) {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return
+		}
+		val = val.Elem()
+	}
+
+	switch val.Kind() {
+	case reflect.Struct:
+		validateStruct(val, path, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			validateValue(val.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+func validateStruct(val reflect.Value, path string, errs *[]shared.FieldError) {
+	t := val.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+
+		fieldPath := joinPath(path, jsonName(field))
+		fieldVal := val.Field(i)
+
+		if tag, ok := field.Tag.Lookup(tagName); ok && tag != "-" {
+			if !applyRules(fieldVal, fieldPath, tag, errs) {
+				// don't descend into a value that has already failed its own rules
+				continue
+			}
+		}
+
+		validateValue(fieldVal, fieldPath, errs)
+	}
+}
+
+// applyRules ... returns false if any rule failed for the field
+func applyRules(val reflect.Value, path, tag string, errs *[]shared.FieldError) bool {
+	rules := splitRules(tag)
+
+	if isZero(val) {
+		for _, rule := range rules {
+			if rule == "required" {
+				*errs = append(*errs, shared.FieldError{Field: path, Rule: "required", Message: fmt.Sprintf("%s is required", path)})
+				return false
+			}
+		}
+		// optional and missing - nothing else to check
+		return true
+	}
+
+	val = indirect(val)
+
+	for _, rule := range rules {
+		name, param := rule, ""
+		if idx := strings.Index(rule, "="); idx >= 0 {
+			name, param = rule[:idx], rule[idx+1:]
+		}
+
+		var msg string
+		switch name {
+		case "required":
+			continue
+		case "oneof":
+			msg = checkOneOf(val, path, param)
+		case "min":
+			msg = checkBound(val, path, param, true)
+		case "max":
+			msg = checkBound(val, path, param, false)
+		case "pattern":
+			msg = checkPattern(val, path, param)
+		default:
+			msg = fmt.Sprintf("%s has an unknown validation rule %q", path, name)
+		}
+
+		if msg != "" {
+			*errs = append(*errs, shared.FieldError{Field: path, Rule: name, Message: msg})
+			return false
+		}
+	}
+
+	return true
+}
+
+// splitRules ... splits on commas, except for pattern which consumes the remainder of the tag
+func splitRules(tag string) []string {
+	var rules []string
+	for tag != "" {
+		if strings.HasPrefix(tag, "pattern=") {
+			rules = append(rules, tag)
+			break
+		}
+
+		idx := strings.Index(tag, ",")
+		if idx < 0 {
+			rules = append(rules, tag)
+			break
+		}
+
+		rules = append(rules, tag[:idx])
+		tag = tag[idx+1:]
+	}
+	return rules
+}
+
+func checkOneOf(val reflect.Value, path, param string) string {
+	options := strings.Fields(param)
+
+	var actual string
+	switch val.Kind() {
+	case reflect.String:
+		actual = val.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = strconv.FormatInt(val.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		actual = strconv.FormatUint(val.Uint(), 10)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			if msg := checkOneOf(indirect(val.Index(i)), fmt.Sprintf("%s[%d]", path, i), param); msg != "" {
+				return msg
+			}
+		}
+		return ""
+	default:
+		return fmt.Sprintf("%s does not support the oneof rule", path)
+	}
+
+	for _, option := range options {
+		if actual == option {
+			return ""
+		}
+	}
+	return fmt.Sprintf("%s must be one of [%s]", path, strings.Join(options, ", "))
+}
+
+func checkBound(val reflect.Value, path, param string, isMin bool) string {
+	bound, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return fmt.Sprintf("%s has an invalid bound %q", path, param)
+	}
+
+	var actual float64
+	var noun string
+	switch val.Kind() {
+	case reflect.String:
+		actual, noun = float64(len([]rune(val.String()))), "characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		actual, noun = float64(val.Len()), "items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = float64(val.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		actual = float64(val.Uint())
+	case reflect.Float32, reflect.Float64:
+		actual = val.Float()
+	default:
+		return fmt.Sprintf("%s does not support bounds", path)
+	}
+
+	switch {
+	case isMin && actual < bound && noun != "":
+		return fmt.Sprintf("%s must contain at least %s %s", path, param, noun)
+	case isMin && actual < bound:
+		return fmt.Sprintf("%s must be at least %s", path, param)
+	case !isMin && actual > bound && noun != "":
+		return fmt.Sprintf("%s must contain at most %s %s", path, param, noun)
+	case !isMin && actual > bound:
+		return fmt.Sprintf("%s must be at most %s", path, param)
+	}
+	return ""
+}
+
+func checkPattern(val reflect.Value, path, param string) string {
+	if val.Kind() == reflect.Slice || val.Kind() == reflect.Array {
+		for i := 0; i < val.Len(); i++ {
+			if msg := checkPattern(indirect(val.Index(i)), fmt.Sprintf("%s[%d]", path, i), param); msg != "" {
+				return msg
+			}
+		}
+		return ""
+	}
+
+	if val.Kind() != reflect.String {
+		return fmt.Sprintf("%s does not support the pattern rule", path)
+	}
+
+	var re *regexp.Regexp
+	if cached, ok := patternCache.Load(param); ok {
+		re = cached.(*regexp.Regexp)
+	} else {
+		compiled, err := regexp.Compile(param)
+		if err != nil {
+			return fmt.Sprintf("%s has an invalid pattern %q", path, param)
+		}
+		patternCache.Store(param, compiled)
+		re = compiled
+	}
+
+	if !re.MatchString(val.String()) {
+		return fmt.Sprintf("%s must match the pattern %s", path, param)
+	}
+	return ""
+}
+
+func isZero(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return val.IsNil()
+	case reflect.Slice, reflect.Map:
+		return val.Len() == 0
+	}
+	return val.IsZero()
+}
+
+func indirect(val reflect.Value) reflect.Value {
+	for (val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface) && !val.IsNil() {
+		val = val.Elem()
+	}
+	return val
+}
+
+func jsonName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "" || tag == "-" {
+		return field.Name
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name
+	}
+	return field.Name
+}
+
+func joinPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
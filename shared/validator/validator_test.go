@@ -0,0 +1,132 @@
+package validator
+
+import (
+	"testing"
+
+	"app_api/shared"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testNested struct {
+	ID   int64  `json:"id" validate:"required,min=1"`
+	Code string `json:"code" validate:"pattern=^[a-z]+(,[a-z]+)*$"`
+}
+
+type testRequest struct {
+	Action   string       `json:"action" validate:"required,oneof=START STOP"`
+	Count    int          `json:"count" validate:"min=1,max=10"`
+	Name     string       `json:"name" validate:"max=5"`
+	Ratio    *float64     `json:"ratio" validate:"min=0,max=1"`
+	Limit    *int64       `json:"limit" validate:"min=1"`
+	Sections []string     `json:"sections" validate:"oneof=doors fuel"`
+	Nested   *testNested  `json:"nested"`
+	Items    []testNested `json:"items" validate:"max=2"`
+	Tags     []string     `json:"tags" validate:"pattern=^[a-z]+$"`
+	Ignored  string       `json:"-"`
+}
+
+func TestValidateSuccess(t *testing.T) {
+	ratio := 0.5
+	req := testRequest{
+		Action:   "START",
+		Count:    3,
+		Name:     "abc",
+		Ratio:    &ratio,
+		Sections: []string{"doors"},
+		Nested:   &testNested{ID: 1, Code: "ab,cd"},
+		Items:    []testNested{{ID: 2}},
+	}
+
+	assert.Empty(t, Validate(&req))
+}
+
+func TestValidateRequired(t *testing.T) {
+	errs := Validate(&testRequest{Count: 1})
+
+	assert.Equal(t, []shared.FieldError{{Field: "action", Rule: "required", Message: "action is required"}}, errs)
+}
+
+func TestValidateOneOf(t *testing.T) {
+	errs := Validate(&testRequest{Action: "FOOBAR", Count: 1, Sections: []string{"doors", "engine"}})
+
+	assert.Equal(t, []shared.FieldError{
+		{Field: "action", Rule: "oneof", Message: "action must be one of [START, STOP]"},
+		{Field: "sections", Rule: "oneof", Message: "sections[1] must be one of [doors, fuel]"},
+	}, errs)
+}
+
+func TestValidateBounds(t *testing.T) {
+	ratio := 1.5
+	errs := Validate(&testRequest{Action: "STOP", Count: 11, Name: "abcdef", Ratio: &ratio})
+
+	assert.Equal(t, []shared.FieldError{
+		{Field: "count", Rule: "max", Message: "count must be at most 10"},
+		{Field: "name", Rule: "max", Message: "name must contain at most 5 characters"},
+		{Field: "ratio", Rule: "max", Message: "ratio must be at most 1"},
+	}, errs)
+}
+
+func TestValidateZeroValues(t *testing.T) {
+	// a zero count is taken as left out
+	assert.Empty(t, Validate(&testRequest{Action: "STOP"}))
+
+	limit := int64(0)
+	errs := Validate(&testRequest{Action: "STOP", Limit: &limit})
+	assert.Equal(t, []shared.FieldError{{Field: "limit", Rule: "min", Message: "limit must be at least 1"}}, errs)
+}
+
+func TestValidateNestedPaths(t *testing.T) {
+	errs := Validate(&testRequest{
+		Action: "STOP",
+		Count:  1,
+		Nested: &testNested{ID: 1, Code: "AB"},
+		Items:  []testNested{{ID: 1}, {ID: -1}},
+	})
+
+	assert.Equal(t, []shared.FieldError{
+		{Field: "nested.code", Rule: "pattern", Message: "nested.code must match the pattern ^[a-z]+(,[a-z]+)*$"},
+		{Field: "items[1].id", Rule: "min", Message: "items[1].id must be at least 1"},
+	}, errs)
+}
+
+func TestValidateSlicePattern(t *testing.T) {
+	errs := Validate(&testRequest{Action: "STOP", Count: 1, Tags: []string{"van", "Depot 7"}})
+
+	assert.Equal(t, []shared.FieldError{{Field: "tags", Rule: "pattern", Message: "tags[1] must match the pattern ^[a-z]+$"}}, errs)
+}
+
+func TestValidateSliceLength(t *testing.T) {
+	errs := Validate(&testRequest{Action: "STOP", Count: 1, Items: []testNested{{ID: 1}, {ID: 2}, {ID: 3}}})
+
+	assert.Equal(t, []shared.FieldError{{Field: "items", Rule: "max", Message: "items must contain at most 2 items"}}, errs)
+}
+
+func TestValidateNonStruct(t *testing.T) {
+	var m map[string]interface{}
+
+	assert.Empty(t, Validate(&m))
+	assert.Empty(t, Validate(nil))
+}
+
+type testEitherRequest struct {
+	A string `json:"a"`
+	B string `json:"b" validate:"max=3"`
+}
+
+func (req testEitherRequest) Validate() []shared.FieldError {
+	if req.A == "" && req.B == "" {
+		return []shared.FieldError{{Field: "a", Rule: "required_without", Message: "a or b is required"}}
+	}
+	return nil
+}
+
+func TestValidateValidatorInterface(t *testing.T) {
+	assert.Equal(t, []shared.FieldError{{Field: "a", Rule: "required_without", Message: "a or b is required"}}, Validate(&testEitherRequest{}))
+	assert.Empty(t, Validate(&testEitherRequest{B: "abc"}))
+
+	// tag failures are reported on their own
+	errs := Validate(&testEitherRequest{B: "abcd"})
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "max", errs[0].Rule)
+}
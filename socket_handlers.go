@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+
+	"app_api/shared/auth"
+	loghelper "app_api/shared/loghelpers"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+)
+
+// upgrader ... the default origin check only accepts browsers on the API's own host. Mobile clients don't send an Origin
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// serveSocket ... /ws GET
+//
+// swagger:operation GET /ws Vehicles serveSocket
+//
+// Subscribes to vehicle events and sends commands over one WebSocket connection
+//
+// ---
+// summary: Subscribes to vehicle events and sends commands over one WebSocket connection
+// description: >
+//   Every message is a JSON SocketMessage. Clients send "subscribe" and "unsubscribe" with vehicleIds or groups, and
+//   "command" with a vehicleId and action. Each is answered by an "ack" or an "error" echoing its id. Events of the
+//   subscribed vehicles are sent as "event" messages. Subscribing requires the vehicles:read scope and commands the
+//   vehicles:command scope, as over REST. A client that falls behind has events dropped, and is told how many with an
+//   "error" of code 429. One that keeps falling behind is disconnected with close code 1013
+// schemes:
+// - wss
+// parameters:
+// - name: X-API-Key
+//   in: header
+//   description: The API key, required once keys are configured
+//   required: false
+//   type: string
+// responses:
+//   '101':
+//     description: >
+//       Switching protocols to WebSocket.
+//   '400':
+//     description: "Bad request e.g. not a WebSocket handshake"
+//   '401':
+//     description: "Missing or invalid API key"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "API key required"
+func (env *Env) serveSocket(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, _ := auth.PrincipalFromContext(ctx)
+
+	// on failure the upgrader has already responded with an error
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.WithContext(ctx).WithFields(log.Fields{
+			"ErrorMessage": err,
+			"RequestID":    loghelper.GetRequestID(ctx),
+			"Request":      loghelper.GetRequestPath(ctx),
+		}).Warn("WebSocket upgrade failed")
+		return
+	}
+
+	env.Services.RealtimeService.Serve(conn, principal)
+}
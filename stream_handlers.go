@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"app_api/apis/events"
+	"app_api/apis/registry"
+	"app_api/apis/vehicle"
+	"app_api/shared"
+	"app_api/shared/httphelper"
+	loghelper "app_api/shared/loghelpers"
+	"app_api/shared/sse"
+)
+
+const (
+	// streamHeartbeat ... how often a comment is sent on an idle stream, so proxies don't close it and disconnected clients are noticed
+	streamHeartbeat = 15 * time.Second
+	// streamRetry ... how long clients wait before reconnecting
+	streamRetry = 3 * time.Second
+	// streamBuffer ... how many events a client can fall behind before events are dropped for it
+	streamBuffer = 64
+
+	streamEventSnapshot = "snapshot"
+	streamEventError    = "error"
+)
+
+// streamVehicle ... /vehicles/{vehicle_id}/stream GET
+//
+// swagger:operation GET /vehicles/{vehicle_id}/stream Vehicles streamVehicle
+//
+// Streams live updates of a vehicle as server-sent events
+//
+// ---
+// summary: Streams live updates of a vehicle as server-sent events
+// description: >
+//   The stream opens with a "snapshot" event holding a Snapshot of the vehicle, followed by an event named after its type
+//   for every change, holding an Event. Every event has an id, and a client reconnecting with the Last-Event-ID header
+//   receives the changes it missed instead of a new snapshot, as long as they are still in the replay buffer.
+//   A comment is sent every 15 seconds while the stream is idle
+// produces:
+// - text/event-stream
+// schemes:
+// - https
+// parameters:
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// - name: fields
+//   in: query
+//   description: Comma separated list of sections to include in the snapshot. Defaults to every section
+//   required: false
+//   type: array
+//   collectionFormat: csv
+//   items:
+//     type: string
+//     enum: [vehicle, doors, fuel, battery]
+// - name: Last-Event-ID
+//   in: header
+//   description: The id of the last event received, to resume a stream
+//   required: false
+//   type: integer
+// - name: lastEventId
+//   in: query
+//   description: The id of the last event received, for clients that can't set the Last-Event-ID header
+//   required: false
+//   type: integer
+// responses:
+//   '200':
+//     description: >
+//       Stream of snapshot and change events.
+//     schema:
+//       type: "string"
+//   '400':
+//     description: "Bad request e.g. Invalid vehicle_id or unsupported field"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Vehicle ID must be an integer"
+func (env *Env) streamVehicle(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vehicleID, apiErr := vehicleIDFromRequest(r)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	sections, apiErr := vehicle.ParseSnapshotSections(r.URL.Query().Get("fields"))
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	stream, sub, resumed, apiErr := env.openStream(w, r, func(event events.Event) bool { return event.VehicleID == vehicleID })
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+	defer sub.Close()
+
+	if !resumed {
+		// the subscription is taken first, so no change is missed while the snapshot is fetched
+		snapshot, apiErr := env.Services.VehicleService.GetVehicleSnapshot(vehicleID, sections)
+		if err := writeSnapshot(ctx, stream, sub, snapshot, apiErr); err != nil {
+			return
+		}
+	}
+
+	serveStream(ctx, stream, sub, nil)
+}
+
+// streamFleet ... /fleet/stream GET
+//
+// swagger:operation GET /fleet/stream Fleet streamFleet
+//
+// Streams live updates of every registered vehicle, or the vehicles of a group, as server-sent events
+//
+// ---
+// summary: Streams live updates of every registered vehicle, or the vehicles of a group, as server-sent events
+// description: >
+//   The stream opens with a "snapshot" event for every vehicle of the page selected by offset and limit, in the order of
+//   their IDs, holding a BatchResult, followed by an event named after its type for every change of any vehicle of the
+//   fleet or group, holding an Event. Later pages of snapshots are read from streams with a higher offset. Every event has an id, and a client reconnecting with the Last-Event-ID
+//   header receives the changes it missed instead of new snapshots, as long as they are still in the replay buffer.
+//   A comment is sent every 15 seconds while the stream is idle
+// produces:
+// - text/event-stream
+// schemes:
+// - https
+// parameters:
+// - name: group
+//   in: query
+//   description: Only stream the vehicles of the group
+//   required: false
+//   type: string
+// - name: fields
+//   in: query
+//   description: Comma separated list of sections to include in the snapshots. Defaults to every section
+//   required: false
+//   type: array
+//   collectionFormat: csv
+//   items:
+//     type: string
+//     enum: [vehicle, doors, fuel, battery]
+// - name: offset
+//   in: query
+//   description: The index of the first vehicle to send a snapshot of
+//   required: false
+//   type: integer
+// - name: limit
+//   in: query
+//   description: The maximum number of vehicles to send a snapshot of. Defaults to 100, at most 500
+//   required: false
+//   type: integer
+// - name: Last-Event-ID
+//   in: header
+//   description: The id of the last event received, to resume a stream
+//   required: false
+//   type: integer
+// - name: lastEventId
+//   in: query
+//   description: The id of the last event received, for clients that can't set the Last-Event-ID header
+//   required: false
+//   type: integer
+// responses:
+//   '200':
+//     description: >
+//       Stream of snapshot and change events.
+//     schema:
+//       type: "string"
+//   '400':
+//     description: "Bad request e.g. unsupported field or invalid limit"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Unsupported field \"wheels\", must be one of [vehicle, doors, fuel, battery]"
+//   '404':
+//     description: "Group not found"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Group not found"
+func (env *Env) streamFleet(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sections, apiErr := vehicle.ParseSnapshotSections(r.URL.Query().Get("fields"))
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	offset, limit, apiErr := httphelper.ParsePagination(r, defaultListLimit, maxListLimit)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	// resolving the group up front also rejects unknown groups before the stream starts
+	group := r.URL.Query().Get("group")
+	vehicleIDs, apiErr := env.Services.RegistryService.ResolveVehicleIDs(registry.Filter{Group: group})
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	// only a page of the vehicles gets a snapshot, so a stream of a large fleet doesn't read all of it from GM at once
+	start, end := offset, offset+limit
+	if total := int64(len(vehicleIDs)); end > total {
+		end = total
+		if start > total {
+			start = total
+		}
+	}
+	vehicleIDs = vehicleIDs[start:end]
+
+	stream, sub, resumed, apiErr := env.openStream(w, r, nil)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+	defer sub.Close()
+
+	if !resumed {
+		results, apiErr := env.Services.VehicleService.GetVehicleSnapshots(vehicleIDs, sections)
+		if apiErr != nil {
+			if err := writeSnapshot(ctx, stream, sub, nil, apiErr); err != nil {
+				return
+			}
+		}
+		for _, result := range results {
+			if err := writeSnapshot(ctx, stream, sub, result, nil); err != nil {
+				return
+			}
+		}
+	}
+
+	// membership is checked as events arrive, so vehicles joining or leaving the group during the stream are followed.
+	// The registry is read here rather than in the bus filter, which runs while the bus is locked
+	var accept func(events.Event) bool
+	if group != "" {
+		accept = func(event events.Event) bool {
+			registration, apiErr := env.Services.RegistryService.GetVehicle(event.VehicleID)
+			if apiErr != nil {
+				return false
+			}
+			for _, g := range registration.Groups {
+				if g == group {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	serveStream(ctx, stream, sub, accept)
+}
+
+// openStream ... starts the event stream and subscribes to the bus, resuming from the Last-Event-ID of a reconnecting client.
+// Returns false when the client needs a snapshot, as it is new or missed more than the bus can replay
+func (env *Env) openStream(w http.ResponseWriter, r *http.Request, filter func(events.Event) bool) (*sse.Writer, *events.Subscription, bool, *shared.APIError) {
+	stream, apiErr := sse.NewWriter(w)
+	if apiErr != nil {
+		return nil, nil, false, apiErr
+	}
+	// a failed write means the client is gone, which the first event or heartbeat notices
+	stream.Retry(streamRetry)
+
+	lastEventID, ok := sse.LastEventID(r)
+	if !ok {
+		return stream, env.Services.EventBus.Subscribe(streamBuffer, filter), false, nil
+	}
+
+	sub, resumed := env.Services.EventBus.SubscribeFrom(lastEventID, streamBuffer, filter)
+	return stream, sub, resumed, nil
+}
+
+// writeSnapshot ... writes a snapshot event, or an error event if the snapshot failed. Snapshots carry the ID of the last event
+// before the subscription, so a client reconnecting after the snapshot resumes from there
+func writeSnapshot(ctx context.Context, stream *sse.Writer, sub *events.Subscription, snapshot interface{}, apiErr *shared.APIError) error {
+	id := strconv.FormatInt(sub.StartID(), 10)
+	if apiErr != nil {
+		loghelper.LogErrors(ctx, apiErr)
+		return stream.Event(id, streamEventError, map[string]string{"message": apiErr.ClientErrorMessage})
+	}
+	return stream.Event(id, streamEventSnapshot, snapshot)
+}
+
+// serveStream ... writes the events of the subscription accepted by accept, or every event if accept is nil, with heartbeats
+// in between, until the client disconnects
+func serveStream(ctx context.Context, stream *sse.Writer, sub *events.Subscription, accept func(events.Event) bool) {
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// the client disconnected
+			return
+		case <-heartbeat.C:
+			if err := stream.Comment("heartbeat"); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if accept != nil && !accept(event) {
+				continue
+			}
+			if err := stream.Event(strconv.FormatInt(event.ID, 10), event.Type, event); err != nil {
+				return
+			}
+		}
+	}
+}
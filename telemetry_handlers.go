@@ -0,0 +1,208 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"app_api/apis/telemetry"
+	"app_api/shared/httphelper"
+)
+
+// getVehicleFuelHistory ... /vehicles/{vehicle_id}/fuel/history GET
+//
+// swagger:operation GET /vehicles/{vehicle_id}/fuel/history Vehicles getVehicleFuelHistory
+//
+// Returns the recorded fuel levels of a vehicle over a time range
+//
+// ---
+// summary: Returns the fuel levels recorded whenever the vehicle was read from GM, optionally downsampled into intervals
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// - name: from
+//   in: query
+//   description: RFC3339 start of the range, inclusive. Defaults to 24 hours before to
+//   required: false
+//   type: string
+//   format: date-time
+// - name: to
+//   in: query
+//   description: RFC3339 end of the range, exclusive. Defaults to now
+//   required: false
+//   type: string
+//   format: date-time
+// - name: interval
+//   in: query
+//   description: Downsample the readings into points of this width, e.g. 15m or 1h. Raw readings are returned when omitted
+//   required: false
+//   type: string
+// - name: aggregation
+//   in: query
+//   description: How readings within an interval are combined. Defaults to last
+//   required: false
+//   type: string
+//   enum: [min, max, avg, last]
+// responses:
+//   '200':
+//     description: >
+//       History object.
+//     schema:
+//       $ref: "#/definitions/TelemetryHistory"
+//   '400':
+//     description: "Bad request e.g. Invalid time range"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "from must be before to"
+func (env *Env) getVehicleFuelHistory(w http.ResponseWriter, r *http.Request) {
+	env.getVehicleHistory(w, r, telemetry.METRIC_FUEL)
+}
+
+// getVehicleBatteryHistory ... /vehicles/{vehicle_id}/battery/history GET
+//
+// swagger:operation GET /vehicles/{vehicle_id}/battery/history Vehicles getVehicleBatteryHistory
+//
+// Returns the recorded battery levels of a vehicle over a time range
+//
+// ---
+// summary: Returns the battery levels recorded whenever the vehicle was read from GM, optionally downsampled into intervals
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// - name: from
+//   in: query
+//   description: RFC3339 start of the range, inclusive. Defaults to 24 hours before to
+//   required: false
+//   type: string
+//   format: date-time
+// - name: to
+//   in: query
+//   description: RFC3339 end of the range, exclusive. Defaults to now
+//   required: false
+//   type: string
+//   format: date-time
+// - name: interval
+//   in: query
+//   description: Downsample the readings into points of this width, e.g. 15m or 1h. Raw readings are returned when omitted
+//   required: false
+//   type: string
+// - name: aggregation
+//   in: query
+//   description: How readings within an interval are combined. Defaults to last
+//   required: false
+//   type: string
+//   enum: [min, max, avg, last]
+// responses:
+//   '200':
+//     description: >
+//       History object.
+//     schema:
+//       $ref: "#/definitions/TelemetryHistory"
+//   '400':
+//     description: "Bad request e.g. Invalid time range"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "from must be before to"
+func (env *Env) getVehicleBatteryHistory(w http.ResponseWriter, r *http.Request) {
+	env.getVehicleHistory(w, r, telemetry.METRIC_BATTERY)
+}
+
+// getVehicleDoorsHistory ... /vehicles/{vehicle_id}/doors/history GET
+//
+// swagger:operation GET /vehicles/{vehicle_id}/doors/history Vehicles getVehicleDoorsHistory
+//
+// Returns the recorded number of unlocked doors of a vehicle over a time range
+//
+// ---
+// summary: Returns the number of unlocked doors recorded whenever the doors of the vehicle were read from GM, optionally downsampled into intervals
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: vehicle_id
+//   in: path
+//   description: The vehicle ID number
+//   required: true
+//   type: integer
+// - name: from
+//   in: query
+//   description: RFC3339 start of the range, inclusive. Defaults to 24 hours before to
+//   required: false
+//   type: string
+//   format: date-time
+// - name: to
+//   in: query
+//   description: RFC3339 end of the range, exclusive. Defaults to now
+//   required: false
+//   type: string
+//   format: date-time
+// - name: interval
+//   in: query
+//   description: Downsample the readings into points of this width, e.g. 15m or 1h. Raw readings are returned when omitted
+//   required: false
+//   type: string
+// - name: aggregation
+//   in: query
+//   description: How readings within an interval are combined. Defaults to last
+//   required: false
+//   type: string
+//   enum: [min, max, avg, last]
+// responses:
+//   '200':
+//     description: >
+//       History object.
+//     schema:
+//       $ref: "#/definitions/TelemetryHistory"
+//   '400':
+//     description: "Bad request e.g. Invalid time range"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "from must be before to"
+func (env *Env) getVehicleDoorsHistory(w http.ResponseWriter, r *http.Request) {
+	env.getVehicleHistory(w, r, telemetry.METRIC_DOORS_UNLOCKED)
+}
+
+// getVehicleHistory ... responds with the history of a metric for the requested vehicle
+func (env *Env) getVehicleHistory(w http.ResponseWriter, r *http.Request, metric string) {
+	ctx := r.Context()
+
+	vehicleID, apiErr := vehicleIDFromRequest(r)
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	query, apiErr := telemetry.ParseHistoryQuery(r.URL.Query(), time.Now().UTC())
+	if apiErr != nil {
+		httphelper.NewResponse(ctx, w, nil, apiErr)
+		return
+	}
+
+	history, apiErr := env.Services.TelemetryService.GetHistory(vehicleID, metric, query)
+
+	httphelper.NewResponse(ctx, w, history, apiErr)
+	return
+}
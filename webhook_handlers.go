@@ -0,0 +1,375 @@
+package main
+
+import (
+	"net/http"
+
+	"app_api/apis/webhook"
+	"app_api/shared/httphelper"
+
+	"github.com/gorilla/mux"
+)
+
+// createWebhook ... /webhooks POST
+//
+// swagger:operation POST /webhooks Webhooks createWebhook
+//
+// Subscribes a URL to vehicle events
+//
+// ---
+// summary: Subscribes a URL to vehicle events, optionally filtered by vehicle, group and event type
+// description: >
+//   Events are POSTed as JSON. Every delivery carries the X-Webhook-Id, X-Webhook-Delivery, X-Webhook-Event and
+//   X-Webhook-Timestamp headers, and an X-Webhook-Signature of "sha256=" followed by the hex HMAC-SHA256, keyed with
+//   the secret, of the timestamp and the body joined by a period. Deliveries are only sent to public addresses, not
+//   loopback, link-local or private ones, and redirects aren't followed. Any response other than 2xx is retried with
+//   exponential backoff, and the delivery is dead-lettered once it runs out of attempts
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: body
+//   in: body
+//   description: body parameters
+//   schema:
+//     "$ref": "#/definitions/WebhookSubscriptionRequest"
+//   required: true
+// responses:
+//   '200':
+//     description: >
+//       Subscription object, with its secret.
+//     schema:
+//       $ref: "#/definitions/WebhookSubscription"
+//   '400':
+//     description: "Bad request e.g. a body that fails validation"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Request body failed validation"
+func (env *Env) createWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req := webhook.SubscriptionRequest{}
+
+	// validate json body
+	if err := httphelper.DecodeJSONBody(w, r, &req); err != nil {
+		httphelper.NewResponse(ctx, w, nil, err)
+		return
+	}
+
+	subscription, apiErr := env.Services.WebhookService.CreateSubscription(req)
+
+	httphelper.NewResponse(ctx, w, subscription, apiErr)
+	return
+}
+
+// listWebhooks ... /webhooks GET
+//
+// swagger:operation GET /webhooks Webhooks listWebhooks
+//
+// Returns every webhook subscription
+//
+// ---
+// summary: Returns every webhook subscription, oldest first. Secrets are not returned
+// produces:
+// - application/json
+// schemes:
+// - https
+// responses:
+//   '200':
+//     description: >
+//       List of subscriptions.
+//     schema:
+//       type: "array"
+//       items:
+//         $ref: "#/definitions/WebhookSubscription"
+func (env *Env) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	subscriptions, apiErr := env.Services.WebhookService.ListSubscriptions()
+
+	httphelper.NewResponse(ctx, w, subscriptions, apiErr)
+	return
+}
+
+// getWebhook ... /webhooks/{webhook_id} GET
+//
+// swagger:operation GET /webhooks/{webhook_id} Webhooks getWebhook
+//
+// Returns a webhook subscription
+//
+// ---
+// summary: Returns a webhook subscription. The secret is not returned
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: webhook_id
+//   in: path
+//   description: The subscription ID
+//   required: true
+//   type: string
+// responses:
+//   '200':
+//     description: >
+//       Subscription object.
+//     schema:
+//       $ref: "#/definitions/WebhookSubscription"
+//   '404':
+//     description: "Webhook subscription not found"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Webhook subscription not found"
+func (env *Env) getWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	subscription, apiErr := env.Services.WebhookService.GetSubscription(mux.Vars(r)["webhook_id"])
+
+	httphelper.NewResponse(ctx, w, subscription, apiErr)
+	return
+}
+
+// updateWebhook ... /webhooks/{webhook_id} PUT
+//
+// swagger:operation PUT /webhooks/{webhook_id} Webhooks updateWebhook
+//
+// Replaces the URL and filters of a webhook subscription
+//
+// ---
+// summary: Replaces the URL and filters of a webhook subscription. The secret is only replaced if a new one is given
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: webhook_id
+//   in: path
+//   description: The subscription ID
+//   required: true
+//   type: string
+// - name: body
+//   in: body
+//   description: body parameters
+//   schema:
+//     "$ref": "#/definitions/WebhookSubscriptionRequest"
+//   required: true
+// responses:
+//   '200':
+//     description: >
+//       Subscription object.
+//     schema:
+//       $ref: "#/definitions/WebhookSubscription"
+//   '400':
+//     description: "Bad request e.g. a body that fails validation"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Request body failed validation"
+//   '404':
+//     description: "Webhook subscription not found"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Webhook subscription not found"
+func (env *Env) updateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req := webhook.SubscriptionRequest{}
+
+	// validate json body
+	if err := httphelper.DecodeJSONBody(w, r, &req); err != nil {
+		httphelper.NewResponse(ctx, w, nil, err)
+		return
+	}
+
+	subscription, apiErr := env.Services.WebhookService.UpdateSubscription(mux.Vars(r)["webhook_id"], req)
+
+	httphelper.NewResponse(ctx, w, subscription, apiErr)
+	return
+}
+
+// deleteWebhook ... /webhooks/{webhook_id} DELETE
+//
+// swagger:operation DELETE /webhooks/{webhook_id} Webhooks deleteWebhook
+//
+// Deletes a webhook subscription
+//
+// ---
+// summary: Deletes a webhook subscription with its delivery log and dead letters
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: webhook_id
+//   in: path
+//   description: The subscription ID
+//   required: true
+//   type: string
+// responses:
+//   '200':
+//     description: >
+//       The subscription was deleted.
+//   '404':
+//     description: "Webhook subscription not found"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Webhook subscription not found"
+func (env *Env) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	apiErr := env.Services.WebhookService.DeleteSubscription(mux.Vars(r)["webhook_id"])
+
+	httphelper.NewResponse(ctx, w, nil, apiErr)
+	return
+}
+
+// listWebhookDeliveries ... /webhooks/{webhook_id}/deliveries GET
+//
+// swagger:operation GET /webhooks/{webhook_id}/deliveries Webhooks listWebhookDeliveries
+//
+// Returns the latest deliveries of a webhook subscription
+//
+// ---
+// summary: Returns the latest deliveries of a webhook subscription with every attempt, newest first
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: webhook_id
+//   in: path
+//   description: The subscription ID
+//   required: true
+//   type: string
+// responses:
+//   '200':
+//     description: >
+//       List of deliveries.
+//     schema:
+//       type: "array"
+//       items:
+//         $ref: "#/definitions/WebhookDelivery"
+//   '404':
+//     description: "Webhook subscription not found"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Webhook subscription not found"
+func (env *Env) listWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	deliveries, apiErr := env.Services.WebhookService.ListDeliveries(mux.Vars(r)["webhook_id"])
+
+	httphelper.NewResponse(ctx, w, deliveries, apiErr)
+	return
+}
+
+// listWebhookDeadLetters ... /webhooks/{webhook_id}/dead-letters GET
+//
+// swagger:operation GET /webhooks/{webhook_id}/dead-letters Webhooks listWebhookDeadLetters
+//
+// Returns the deliveries of a webhook subscription that ran out of attempts
+//
+// ---
+// summary: Returns the deliveries of a webhook subscription that ran out of attempts, newest first
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: webhook_id
+//   in: path
+//   description: The subscription ID
+//   required: true
+//   type: string
+// responses:
+//   '200':
+//     description: >
+//       List of dead-lettered deliveries.
+//     schema:
+//       type: "array"
+//       items:
+//         $ref: "#/definitions/WebhookDelivery"
+//   '404':
+//     description: "Webhook subscription not found"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Webhook subscription not found"
+func (env *Env) listWebhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	deliveries, apiErr := env.Services.WebhookService.ListDeadLetters(mux.Vars(r)["webhook_id"])
+
+	httphelper.NewResponse(ctx, w, deliveries, apiErr)
+	return
+}
+
+// retryWebhookDeadLetter ... /webhooks/{webhook_id}/dead-letters/{delivery_id}/retry POST
+//
+// swagger:operation POST /webhooks/{webhook_id}/dead-letters/{delivery_id}/retry Webhooks retryWebhookDeadLetter
+//
+// Retries a dead-lettered delivery
+//
+// ---
+// summary: Takes a delivery out of the dead letters and retries it with a fresh set of attempts
+// produces:
+// - application/json
+// schemes:
+// - https
+// parameters:
+// - name: webhook_id
+//   in: path
+//   description: The subscription ID
+//   required: true
+//   type: string
+// - name: delivery_id
+//   in: path
+//   description: The delivery ID
+//   required: true
+//   type: string
+// responses:
+//   '200':
+//     description: >
+//       The delivery, pending its first new attempt.
+//     schema:
+//       $ref: "#/definitions/WebhookDelivery"
+//   '404':
+//     description: "Webhook subscription or dead letter not found"
+//     schema:
+//       type: "object"
+//       properties:
+//         message:
+//           type: "string"
+//           example: "Dead letter not found"
+func (env *Env) retryWebhookDeadLetter(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	delivery, apiErr := env.Services.WebhookService.RetryDeadLetter(vars["webhook_id"], vars["delivery_id"])
+
+	httphelper.NewResponse(ctx, w, delivery, apiErr)
+	return
+}